@@ -3,6 +3,8 @@ package ash
 import (
 	"fmt"
 	"io"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/conthing/utils/common"
@@ -19,35 +21,81 @@ const (
 	ASH_CONTROLBYTE_RETX   = byte(0x08)
 )
 
-var ashResetSuccess = false
-var ashRecvRstackFrame = make(chan byte, 1)
-var ashNeedSendProcess = make(chan byte, 16) //AshWrite会被不同的线程调用
-
-var ashRejectCondition = false
-var ashImmediatelyAck = false
-var ashLastRejectCondition = false
-
-var ashRecvNakFrame = false
-var ashRecvErrorFrame []byte
-
-//最近一次发送的时间，用来判断超时重发
-var ashResendTime *time.Time // todo 这个时间使用有问题 send 和 resend 同时存在时
-var ashResendCnt byte
-
-var rxIndexNext byte     /*下一个接收报文的index，自己报文中的ackNum*/
-var rxIndexNextSent byte //= byte(7) /*已经发送出去的ackNum*/
-
-var rxbuffer [8][]byte
-var rxPutPtr byte
-
-var txbuffer [8][]byte //todo 发送失败怎么清空
-var txPutPtr byte
-var txIndexNext byte       /*下一个发送报文的index，自己报文中的frmNum*/
-var txIndexConfirming byte /*正在等待ACK的报文index*/
-
-var TransceiverStep byte
+// Link 一条ASH链路，拥有自己的收发缓存、序号、收发线程和接收回调，一个NCP对应一个Link
+type Link struct {
+	transport Transport
+	recvFunc  func([]byte) error
+
+	// mutex 保护收发缓存、序号、窗口和复位状态，收发线程和调用Send/Reset/InitVariables/State的线程共用。
+	// 持有mutex时不能调用recvFunc，也不能堵塞在channel上
+	mutex sync.Mutex
+
+	resetSuccess    bool
+	recvRstackFrame chan byte
+	needSendProcess chan byte //Send会被不同的线程调用
+
+	rejectCondition     bool
+	immediatelyAck      bool
+	lastRejectCondition bool
+
+	recvNakFrame         bool
+	recvErrorFrame       []byte
+	recvUnexpectedRstack []byte // 不是Reset发起的RSTACK，NCP自己复位了
+
+	resetting     int32 // Reset正在等待RSTACK
+	recovering    int32 // 正在自动复位
+	down          int32 // transport出错，正在重连
+	resetHandlers []func()
+	events        chan LinkEvent
+	stop          chan struct{} // Close时关闭，收发线程和重连随之退出
+	stopMutex     sync.Mutex
+
+	// 发送窗口和自适应的ACK超时t_rx_ack，每个未确认的帧有自己的超时时间
+	settings StAshSettings
+	ackTime  time.Duration
+	txFrames [8]stTxFrame
+
+	stats      StAshStats
+	statsMutex sync.Mutex
+
+	rxIndexNext     byte /*下一个接收报文的index，自己报文中的ackNum*/
+	rxIndexNextSent byte //= byte(7) /*已经发送出去的ackNum*/
+
+	rxbuffer [8][]byte
+	rxPutPtr byte
+
+	txbuffer          [8][]byte //todo 发送失败怎么清空
+	txPutPtr          byte
+	txIndexNext       byte /*下一个发送报文的index，自己报文中的frmNum*/
+	txIndexConfirming byte /*正在等待ACK的报文index*/
+
+	// 帧解析状态
+	readStatusEsc        bool
+	readStatusSubstitute bool
+	readBufferOffset     byte
+	readBuffer           []byte
+
+	// transport接收缓存
+	rcvBuff     []byte
+	rcvStartPtr int
+
+	// 软件流控，XOFF期间要发送的字节放在txPending
+	flowControl FlowControl
+	txMutex     sync.Mutex
+	xoff        bool
+	xoffTime    time.Time
+	txPending   []byte
+
+	// 收发线程所处的阶段和进入的时间，见 State
+	stage     int32
+	stageTime int64
+
+	// ReconnectInterval transport出错后重新打开的间隔
+	ReconnectInterval time.Duration
+}
 
-var AshRecv func([]byte) error
+// DefaultLink 包级函数使用的默认链路
+var DefaultLink = NewLink()
 
 var AshTraceOn bool
 
@@ -57,46 +105,63 @@ func ashTrace(format string, v ...interface{}) {
 	}
 }
 
-// InitVariables 在AshReset成功后必须调用，恢复原始的状态
-func InitVariables() {
-	// 清空 ashNeedSendProcess
+// NewLink 创建一条ASH链路，StartTransceiver后才能使用
+func NewLink() *Link {
+	return &Link{
+		recvRstackFrame: make(chan byte, 1),
+		needSendProcess: make(chan byte, 16),
+		events:          make(chan LinkEvent, 8),
+		readBuffer:      make([]byte, 256),
+		rcvBuff:         make([]byte, 1200),
+		settings:        defaultAshSettings(),
+		ackTime:         ASH_ACK_TIME_INIT,
+		stats:           StAshStats{Since: time.Now()},
+
+		ReconnectInterval: time.Second * 2,
+	}
+}
+
+// InitVariables 在Reset成功后必须调用，恢复原始的状态
+func (l *Link) InitVariables() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// 清空 needSendProcess
 	select {
-	case <-ashNeedSendProcess:
+	case <-l.needSendProcess:
 	default:
 	}
 
-	ashRejectCondition = false
-	ashImmediatelyAck = false
-	ashLastRejectCondition = false
+	l.rejectCondition = false
+	l.immediatelyAck = false
+	l.lastRejectCondition = false
 
-	ashRecvNakFrame = false
-	ashRecvErrorFrame = nil
+	l.recvNakFrame = false
+	l.recvErrorFrame = nil
+	l.recvUnexpectedRstack = nil
 
-	ashResendTime = nil
-	ashResendCnt = 0
+	l.ackTime = l.settings.AckTimeInit
+	for i := range l.txFrames {
+		l.txFrames[i] = stTxFrame{}
+	}
 
-	rxIndexNext = 0
-	rxIndexNextSent = 0 //byte(7) /*已经发送出去的ackNum*/
+	l.rxIndexNext = 0
+	l.rxIndexNextSent = 0 //byte(7) /*已经发送出去的ackNum*/
 
-	for i := range rxbuffer {
-		rxbuffer[i] = nil
+	for i := range l.rxbuffer {
+		l.rxbuffer[i] = nil
 	}
-	rxPutPtr = 0
+	l.rxPutPtr = 0
 
-	for i := range txbuffer {
-		txbuffer[i] = nil
+	for i := range l.txbuffer {
+		l.txbuffer[i] = nil
 	}
-	txPutPtr = 0
-	txIndexNext = 0       /*下一个发送报文的index，自己报文中的frmNum*/
-	txIndexConfirming = 0 /*正在等待ACK的报文index*/
-
-	// 最后 ashResetSuccess 变有效
-	ashResetSuccess = true
-}
+	l.txPutPtr = 0
+	l.txIndexNext = 0       /*下一个发送报文的index，自己报文中的frmNum*/
+	l.txIndexConfirming = 0 /*正在等待ACK的报文index*/
 
-func SprintVariables() (str string) {
-	return fmt.Sprintf("TransceiverStep=%v \nashResetSuccess=%v \nashResendTime=%v \nashResendCnt=%v \ntxIndexNext=%v \ntxIndexConfirming=%v \ntxPutPtr=%v \ntxbuffer=%v",
-		TransceiverStep, ashResetSuccess, ashResendTime, ashResendCnt, txIndexNext, txIndexConfirming, txPutPtr, txbuffer)
+	// 最后 resetSuccess 变有效
+	l.resetSuccess = true
 }
 
 func inc(index byte) byte {
@@ -119,66 +184,55 @@ func dataFrmPseudoRandom(data []byte) {
 	}
 }
 
-func getAckNumForData() byte { /*host不能通过DAT进行ACK*/
+func (l *Link) getAckNumForData() byte { /*host不能通过DAT进行ACK*/
 	//rxIndexNextSent = rxIndexNext
-	return rxIndexNextSent
+	return l.rxIndexNextSent
 }
-func getAckNumForAck() byte { /*发送报文中的ackNum字段，调用此函数后才算ACK过*/ /*host不能通过DAT进行ACK*/
+func (l *Link) getAckNumForAck() byte { /*发送报文中的ackNum字段，调用此函数后才算ACK过*/ /*host不能通过DAT进行ACK*/
 	//rxIndexNextSent = rxIndexNext
-	return rxIndexNext //inc(rxIndexNextSent)
+	return l.rxIndexNext //inc(rxIndexNextSent)
 }
 
-func needAckFrame() bool {
-	return rxIndexNextSent != rxIndexNext
+func (l *Link) needAckFrame() bool {
+	return l.rxIndexNextSent != l.rxIndexNext
 }
 
-func sendReady() bool {
+func (l *Link) sendReady() bool {
 	/*txIndexConfirming使对方报文中最新的acknum，是acked+1，txIndexNext是发送过的+1，txIndexConfirming在追赶txIndexNext*/
-	return txIndexNext == txIndexConfirming
+	return l.outstanding() < l.settings.WindowSize
 }
 
-func getSendBuffer() (ashDataFrame []byte) {
-	data := txbuffer[txIndexNext]
+func (l *Link) getSendBuffer() (ashDataFrame []byte) {
+	data := l.txbuffer[l.txIndexNext]
 	if data != nil {
-		control := byte(ASH_CONTROLBYTE_DATA | byte(txIndexNext<<4) | getAckNumForData())
+		control := byte(ASH_CONTROLBYTE_DATA | byte(l.txIndexNext<<4) | l.getAckNumForData())
 		ashDataFrame = []byte{control}
 		ashDataFrame = append(ashDataFrame, data...)
-		txIndexNext = inc(txIndexNext)
+		l.txFrameSent(l.txIndexNext, false)
+		l.txIndexNext = inc(l.txIndexNext)
 		return
 	}
 	return nil
 }
 
-func getResendBuffer() (ashDataFrame []byte) {
-	if smallthan(txIndexConfirming, txIndexNext) {
-		data := txbuffer[txIndexConfirming]
-		if data != nil {
-			control := byte(ASH_CONTROLBYTE_DATA | byte(txIndexConfirming<<4) | getAckNumForData() | ASH_CONTROLBYTE_RETX)
-			ashDataFrame = []byte{control}
-			ashDataFrame = append(ashDataFrame, data...)
-			return
-		}
-	}
-	return nil
-}
-
-func ackNumProcess(ackNum byte) error {
-	if !smallthan(txIndexNext, ackNum) { //ackNum > txIndexNext 超前ACK了
-		if smallthan(txIndexConfirming, ackNum) {
-			for txIndexConfirming != ackNum {
-				txbuffer[txIndexConfirming] = nil //已发送成功
-				txIndexConfirming = inc(txIndexConfirming)
+func (l *Link) ackNumProcess(ackNum byte) error {
+	if !smallthan(l.txIndexNext, ackNum) { //ackNum > txIndexNext 超前ACK了
+		if smallthan(l.txIndexConfirming, ackNum) {
+			for l.txIndexConfirming != ackNum {
+				l.ackTimeUpdate(l.txIndexConfirming)
+				l.txbuffer[l.txIndexConfirming] = nil //已发送成功
+				l.txIndexConfirming = inc(l.txIndexConfirming)
 			}
 		}
 		return nil
 	}
-	return fmt.Errorf("ASH recv ackNum(%d) ahead of send frmNum(%d)", ackNum, txIndexNext)
+	return fmt.Errorf("ASH recv ackNum(%d) ahead of send frmNum(%d)", ackNum, l.txIndexNext)
 }
 
 // ashRecvFrame 接收报文处理
-func ashRecvFrame(frame []byte) error {
+func (l *Link) ashRecvFrame(frame []byte) error {
 	if frame == nil { //表示底层收到非法报文，如crc错误，这里要触发NAK
-		ashRejectCondition = true
+		l.rejectCondition = true
 		return nil
 	}
 
@@ -190,283 +244,294 @@ func ashRecvFrame(frame []byte) error {
 		if len(frame) == 3 {
 			ashTrace("ASH recv RSTACK frame < 0x%x", frame)
 			if frame[1] != 0x02 {
-				ashRejectCondition = true
+				l.rejectCondition = true
 				return fmt.Errorf("ASH recv unknown version in RSTACK frame")
 			}
-			ashRecvRstackFrame <- frame[2]
+			if atomic.LoadInt32(&l.resetting) == 0 {
+				common.Log.Warnf("ASH recv RSTACK frame without RST < 0x%x", frame)
+				l.recvUnexpectedRstack = frame[2:]
+				return nil
+			}
+			select {
+			case l.recvRstackFrame <- frame[2]:
+			default: // 重复的RSTACK
+			}
 		} else {
-			ashRejectCondition = true
+			l.rejectCondition = true
 			return fmt.Errorf("ASH recv RSTACK frame length error < 0x%x", frame)
 		}
 	} else if control == ASH_CONTROLBYTE_ERROR {
 		if len(frame) == 3 {
 			common.Log.Warnf("ASH recv ERROR frame < 0x%x", frame) //todo 测试下ERROR frame的格式
 			if frame[1] != 0x02 {
-				ashRejectCondition = true
+				l.rejectCondition = true
 				return fmt.Errorf("ASH recv unknown version in ERROR frame")
 			}
-			ashRecvErrorFrame = frame[2:]
+			l.recvErrorFrame = frame[2:]
 		} else {
-			ashRejectCondition = true
+			l.rejectCondition = true
 			return fmt.Errorf("ASH recv ERROR frame length error < 0x%x", frame)
 		}
-	} else if ashResetSuccess == false { // RSTACK 没收到之前不应该收到其他报文
+	} else if l.resetSuccess == false { // RSTACK 没收到之前不应该收到其他报文
 		return fmt.Errorf("ASH recv other frame before RSTACK < 0x%x", frame)
 	} else if byte(control&0x80) == ASH_CONTROLBYTE_DATA {
 		dataFrmPseudoRandom(frame[1:])
-		err := ackNumProcess(ackNum)
+		err := l.ackNumProcess(ackNum)
 		if err != nil {
-			ashRejectCondition = true
+			l.rejectCondition = true
 			return fmt.Errorf("ASH recv DAT frame with invalid ackNum: %v < 0x%x", err, frame)
 		}
 
 		/*更新frmNumNext*/
-		if frmNum == rxIndexNext {
-			rxIndexNext = inc(rxIndexNext)
+		if frmNum == l.rxIndexNext {
+			l.rxIndexNext = inc(l.rxIndexNext)
 			ashTrace("ASH recv < 0x%x", frame)
-			rxbuffer[frmNum] = frame[1:]
-			//if AshRecv != nil {
-			//	err = AshRecv(frame[1:])
-			//	if err != nil {
-			//		ashRejectCondition = true
-			//		return err
-			//	}
-			//}
-			ashRejectCondition = false
-			if !smallthan(rxIndexNextSent, rxIndexNext) {
+			l.statsUpdate(func(s *StAshStats) { s.RxData++ })
+			l.rxbuffer[frmNum] = frame[1:]
+			l.rejectCondition = false
+			if !smallthan(l.rxIndexNextSent, l.rxIndexNext) {
 				// rxIndexNext刚增加过，如果rxIndexNextSent-rxIndexNext达到-3，接收报文堆积了3条，需要先处理
 				return errAshRecvHandleBusy
 			}
 
-		} else if smallthan(rxIndexNext, frmNum) {
-			ashRejectCondition = true
-			return fmt.Errorf("ASH recv discontinuous frame sequence. frmNum=%d, reTx=%v, expect frmNum=%d < 0x%x", frmNum, reTx, rxIndexNext, frame)
+		} else if smallthan(l.rxIndexNext, frmNum) {
+			l.rejectCondition = true
+			return fmt.Errorf("ASH recv discontinuous frame sequence. frmNum=%d, reTx=%v, expect frmNum=%d < 0x%x", frmNum, reTx, l.rxIndexNext, frame)
 		} else {
 			if reTx {
-				ashImmediatelyAck = true //重发的报文，立刻ACK
-				common.Log.Warnf("ASH recv repeative resend frame. frmNum=%d, reTx=%v, expect frmNum=%d < 0x%x", frmNum, reTx, rxIndexNext, frame)
+				l.immediatelyAck = true //重发的报文，立刻ACK
+				common.Log.Warnf("ASH recv repeative resend frame. frmNum=%d, reTx=%v, expect frmNum=%d < 0x%x", frmNum, reTx, l.rxIndexNext, frame)
 			} else { /*初发的帧比想收的帧序号还要小*/
-				ashRejectCondition = true
-				return fmt.Errorf("ASH recv frame sequence rollback. frmNum=%d, reTx=%v, expect frmNum=%d < 0x%x", frmNum, reTx, rxIndexNext, frame)
+				l.rejectCondition = true
+				return fmt.Errorf("ASH recv frame sequence rollback. frmNum=%d, reTx=%v, expect frmNum=%d < 0x%x", frmNum, reTx, l.rxIndexNext, frame)
 			}
 		}
 	} else if (byte)(control&0xE0) == ASH_CONTROLBYTE_ACK {
 		if len(frame) == 1 {
-			err := ackNumProcess(ackNum)
+			err := l.ackNumProcess(ackNum)
 			if err != nil {
-				ashRejectCondition = true
+				l.rejectCondition = true
 				return fmt.Errorf("ASH recv ACK frame with invalid ackNum: %v < 0x%x", err, frame)
 			}
 			ashTrace("ASH recv ACK frame < 0x%x", frame)
 		} else {
-			ashRejectCondition = true
+			l.rejectCondition = true
 			return fmt.Errorf("ASH recv ACK frame length error < 0x%x", frame)
 		}
 	} else if (byte)(control&0xE0) == ASH_CONTROLBYTE_NAK {
 		if len(frame) == 1 {
-			err := ackNumProcess(ackNum)
+			err := l.ackNumProcess(ackNum)
 			if err != nil {
-				ashRejectCondition = true
+				l.rejectCondition = true
 				return fmt.Errorf("ASH recv NAK frame with invalid ackNum: %v < 0x%x", err, frame)
 			}
 			common.Log.Warnf("ASH recv NAK frame < 0x%x", frame)
-			ashRecvNakFrame = true
+			l.statsUpdate(func(s *StAshStats) { s.NakReceived++ })
+			l.recvNakFrame = true
 		} else {
-			ashRejectCondition = true
+			l.rejectCondition = true
 			return fmt.Errorf("ASH recv NAK frame length error < 0x%x", frame)
 		}
 	} else {
-		ashRejectCondition = true
+		l.rejectCondition = true
 		return fmt.Errorf("ASH recv unknown frame control 0x%x", control)
 	}
 
 	return nil
 }
 
-func ashAckProcess() bool {
-	if ashRejectCondition == false {
-		ashLastRejectCondition = false
+// ashAckProcess 发送NAK或者ACK，返回ACK过的要交给recvFunc的报文，调用时要持有mutex
+func (l *Link) ashAckProcess() (bool, [][]byte) {
+	if l.rejectCondition == false {
+		l.lastRejectCondition = false
 	}
-	if ashLastRejectCondition == false && ashRejectCondition == true {
-		ashLastRejectCondition = true
-		err := ashSendNakFrame()
+	if l.lastRejectCondition == false && l.rejectCondition == true {
+		l.lastRejectCondition = true
+		err := l.ashSendNakFrame()
 		if err != nil {
 			common.Log.Errorf("ASH send NAK frame failed: %v", err)
 		}
-		return true
-	} else if needAckFrame() || ashImmediatelyAck {
-
-		err := ashSendAckFrame()
+		return true, nil
+	} else if l.needAckFrame() || l.immediatelyAck {
+		var received [][]byte
+		err := l.ashSendAckFrame()
 		if err != nil {
 			common.Log.Errorf("ASH send ACK frame failed: %v", err)
 		} else {
-			for rxIndexNextSent != rxIndexNext {
-				if AshRecv != nil && rxbuffer[rxIndexNextSent] != nil {
-					err := AshRecv(rxbuffer[rxIndexNextSent])
-					if err != nil {
-						common.Log.Errorf("ASH recv process failed: %v", err)
-						//ashRejectCondition = true
-						//return err
-					}
+			for l.rxIndexNextSent != l.rxIndexNext {
+				if l.rxbuffer[l.rxIndexNextSent] != nil {
+					received = append(received, l.rxbuffer[l.rxIndexNextSent])
 				}
-				rxIndexNextSent = inc(rxIndexNextSent)
+				l.rxIndexNextSent = inc(l.rxIndexNextSent)
 			}
-			ashImmediatelyAck = false
+			l.immediatelyAck = false
 		}
-		return true
+		return true, received
 	}
-	return false
+	return false, nil
 }
 
-func ashResendProcess() (bool, error) {
-	ashDataFrame := getResendBuffer()
-	if ashDataFrame != nil {
-		if ashResendCnt < 2 {
-			ashResendCnt++
-			//if ashResendCnt > 1 {
-			//	ashTrace("ASH %dth resend ", ashResendCnt)
-			//}
-			ashTrace("ASH %dth resend > 0x%x", ashResendCnt, ashDataFrame)
-			dataFrmPseudoRandom(ashDataFrame[1:])
-			err := ashSendFrame(ashDataFrame)
-			if err != nil {
-				common.Log.Errorf("ASH resend failed: %v", err)
-			}
-			resendTime := time.Now().Add(time.Second * time.Duration(3+ashResendCnt)) //第一次间隔3秒，第2次4秒...
-			ashResendTime = &resendTime
-		} else {
-			ashResendCnt = 0
-			return false, fmt.Errorf("ASH resend exceed max count")
+// ashDispatch 把收到的报文按顺序交给recvFunc，不能持有mutex，recvFunc里可能会调用Send
+func (l *Link) ashDispatch(received [][]byte) {
+	if l.recvFunc == nil || len(received) == 0 {
+		return
+	}
+	l.setStage(LINK_STAGE_DISPATCH)
+	for _, data := range received {
+		err := l.recvFunc(data)
+		if err != nil {
+			common.Log.Errorf("ASH recv process failed: %v", err)
 		}
-		return true, nil
 	}
-	return false, nil
 }
 
-func ashSendProcess() bool {
-	if sendReady() {
-		ashDataFrame := getSendBuffer()
-		if ashDataFrame != nil {
-			ashTrace("ASH send > 0x%x", ashDataFrame)
-			dataFrmPseudoRandom(ashDataFrame[1:])
-			err := ashSendFrame(ashDataFrame)
-			if err != nil {
-				common.Log.Errorf("ASH send failed: %v", err)
-			}
-			resendTime := time.Now().Add(time.Second * 3)
-			ashResendTime = &resendTime
-			ashResendCnt = 0
-			return true
+// ashSendProcess 在发送窗口允许的范围内把缓存的报文都发出去
+func (l *Link) ashSendProcess() bool {
+	sent := false
+	for l.sendReady() {
+		ashDataFrame := l.getSendBuffer()
+		if ashDataFrame == nil {
+			break
 		}
+		ashTrace("ASH send > 0x%x", ashDataFrame)
+		dataFrmPseudoRandom(ashDataFrame[1:])
+		err := l.ashSendFrame(ashDataFrame)
+		if err != nil {
+			common.Log.Errorf("ASH send failed: %v", err)
+		}
+		l.statsUpdate(func(s *StAshStats) { s.TxData++ })
+		sent = true
 	}
-	return false
+	return sent
 }
 
-func ashSendResetFrame() error {
+func (l *Link) ashSendResetFrame() error {
 	frame := []byte{ASH_CONTROLBYTE_RST}
 	ashTrace("ASH send RST frame")
-	return ashSendFrame(frame)
+	return l.ashSendFrame(frame)
 }
-func ashSendAckFrame() error {
-	frame := []byte{ASH_CONTROLBYTE_ACK | getAckNumForAck()}
+func (l *Link) ashSendAckFrame() error {
+	frame := []byte{ASH_CONTROLBYTE_ACK | l.getAckNumForAck()}
 	ashTrace("ASH send ACK frame > 0x%x", frame)
-	return ashSendFrame(frame)
+	return l.ashSendFrame(frame)
 }
-func ashSendNakFrame() error {
-	frame := []byte{ASH_CONTROLBYTE_NAK | getAckNumForData()}
+func (l *Link) ashSendNakFrame() error {
+	frame := []byte{ASH_CONTROLBYTE_NAK | l.getAckNumForData()}
 	ashTrace("ASH send NAK frame > 0x%x", frame)
-	return ashSendFrame(frame)
+	l.statsUpdate(func(s *StAshStats) { s.NakSent++ })
+	return l.ashSendFrame(frame)
 }
 
-// ashTransceiver 收发任务
-func ashTransceiver(errChan chan error) {
-	AshSerialFlush()
+// ashTransceiver 收发任务，stop关闭后退出
+func (l *Link) ashTransceiver(errChan chan error, stop <-chan struct{}) {
+	l.Flush()
 	for {
-		resent := false
 		acknaksent := false //一次循环发送了ACK就不发DAT了
-		TransceiverStep = 0
+		l.setStage(LINK_STAGE_IDLE)
 		select {
-		case <-ashNeedSendProcess:
+		case <-stop:
+			ashTrace("ASH transceiver stopped")
+			return
+		case <-l.needSendProcess:
 		case <-time.After(time.Millisecond * 10):
-			TransceiverStep = 1
-			err := AshSerialRecv()
-			TransceiverStep = 2
-			if err == io.EOF {
-				continue
+			l.setStage(LINK_STAGE_RECEIVE)
+			err := l.Recv()
+			if err == io.EOF { // 没有收到数据也要检查ACK超时
+				break
 			} else if err != nil {
+				if reopener, ok := l.transport.(Reopener); ok {
+					if !l.reconnect(reopener, err, stop) {
+						ashTrace("ASH transceiver stopped while reconnecting")
+						return
+					}
+					continue
+				}
 				errChan <- err
 				return
 			}
-			TransceiverStep = 3
 
-			if ashRecvErrorFrame != nil { //todo 将来改成内部处理
-				errChan <- fmt.Errorf("ASH recv ERROR frame errcode=0x%x", ashRecvErrorFrame[0])
-				return
+			var received [][]byte
+			l.mutex.Lock()
+			if l.recvErrorFrame != nil { // NCP进入FAILED状态，要重新RST
+				resetCode := l.recvErrorFrame[0]
+				l.recvErrorFrame = nil
+				l.resetSuccess = false
+				l.recover("ERROR frame", resetCode)
+			}
+			if l.recvUnexpectedRstack != nil { // NCP自己复位了，状态全部丢失，重新RST
+				resetCode := l.recvUnexpectedRstack[0]
+				l.recvUnexpectedRstack = nil
+				l.resetSuccess = false
+				l.recover("unexpected RSTACK", resetCode)
 			}
 
-			if ashResetSuccess { // 没收到RSTACK之前不处理
-				if ashRecvNakFrame {
-					ashRecvNakFrame = false
-					//ashImmediatelyAck = true //resent = ashResendProcess()
-				}
+			if l.resetSuccess { // 没收到RSTACK之前不处理，收到的NAK在下面重发
 				/*重发和发送ACK的处理，最好在所有收到的报文处理完后进行一次性调用*/
-				acknaksent = ashAckProcess()
+				acknaksent, received = l.ashAckProcess()
 			}
+			l.mutex.Unlock()
+			l.ashDispatch(received)
 		}
-		TransceiverStep = 4
-		if ashResetSuccess && !acknaksent { // 没收到RSTACK之前不处理
-			TransceiverStep = 5
-			if resent == false && ashResendTime != nil && time.Now().After(*ashResendTime) {
-				TransceiverStep = 6
-				var fatal error
-				resent, fatal = ashResendProcess()
-				if fatal != nil {
-					errChan <- fatal
-					return
-				}
+		l.setStage(LINK_STAGE_SEND)
+		l.flowControlProcess()
+		l.mutex.Lock()
+		if l.resetSuccess && !acknaksent && !l.txPaused() { // 没收到RSTACK之前不处理，XOFF期间不发DAT
+			resent, err := l.ashResendProcess()
+			if err != nil { // 重发次数用完，NCP没有响应，复位NCP
+				l.resetSuccess = false
+				l.recover(err.Error(), ERROR_EXCEEDED_MAXIMUM_ACK_TIMEOUT_COUNT)
+			} else if !resent {
+				_ = l.ashSendProcess()
 			}
-			TransceiverStep = 7
-			_ = ashSendProcess()
-			TransceiverStep = 8
 		}
+		l.mutex.Unlock()
 	}
 }
 
-// AshSend 写发送报文缓存
-func AshSend(data []byte) error {
-	if ashResetSuccess != true {
+// Send 写发送报文缓存
+func (l *Link) Send(data []byte) error {
+	l.mutex.Lock()
+	if l.resetSuccess != true {
+		l.mutex.Unlock()
 		return fmt.Errorf("ASH RST not finished")
 	}
-	if txbuffer[txPutPtr] != nil {
+	if l.txbuffer[l.txPutPtr] != nil {
+		l.mutex.Unlock()
 		return fmt.Errorf("ASH write overflow")
 	}
-	txbuffer[txPutPtr] = data //保存发送数据，以备重发
-	txPutPtr = inc(txPutPtr)
-	ashNeedSendProcess <- 1
+	l.txbuffer[l.txPutPtr] = data //保存发送数据，以备重发
+	l.txPutPtr = inc(l.txPutPtr)
+	l.mutex.Unlock()
+	l.needSendProcess <- 1
 	return nil
 }
 
-// AshReset 复位NCP
-func AshReset() error {
+// Reset 复位NCP
+func (l *Link) Reset() error {
 	ashTrace("ASH RST")
-	err := ashSendCancelByte()
+	err := l.ashSendCancelByte()
 	if err != nil {
 		return fmt.Errorf("ASH RST failed: %v", err)
 	}
 
-	ashResetSuccess = false
-	// 清空 ashRecvRstackFrame
+	l.mutex.Lock()
+	l.resetSuccess = false
+	l.mutex.Unlock()
+	// 清空 recvRstackFrame
 	select {
-	case <-ashRecvRstackFrame:
+	case <-l.recvRstackFrame:
 	default:
 	}
+	atomic.StoreInt32(&l.resetting, 1)
+	defer atomic.StoreInt32(&l.resetting, 0)
 
 	for i := 0; i < 5; i++ {
-		_ = ashSendResetFrame() //不管发送是否成功，没有收到回复就超时重发
+		_ = l.ashSendResetFrame() //不管发送是否成功，没有收到回复就超时重发
 
 		select {
-		case rstcode := <-ashRecvRstackFrame:
+		case rstcode := <-l.recvRstackFrame:
 			ashTrace("ASH RSTACK 0x%x", rstcode)
+			l.statsUpdate(func(s *StAshStats) { s.Resets++ })
 			return nil
 		case <-time.After(time.Millisecond * 3000):
 			common.Log.Errorf("ASH RST miss RSTACK")
@@ -475,8 +540,43 @@ func AshReset() error {
 	return fmt.Errorf("ASH failed to recv RSTACK after 5 retry")
 }
 
-// AshStartTransceiver 开启串口收发线程，AshReset前就要运行起来
-func AshStartTransceiver(recvFunc func([]byte) error, errChan chan error) {
-	AshRecv = recvFunc
-	go ashTransceiver(errChan)
+// StartTransceiver 在transport上开启收发线程，Reset前就要运行起来
+func (l *Link) StartTransceiver(transport Transport, recvFunc func([]byte) error, errChan chan error) {
+	l.transport = transport
+	l.recvFunc = recvFunc
+	l.flowControl = FLOW_CONTROL_NONE
+	if fc, ok := transport.(FlowController); ok {
+		l.flowControl = fc.FlowControl()
+	}
+	ashTrace("ASH flow control %s", l.flowControl)
+	stop := make(chan struct{})
+	l.stopMutex.Lock()
+	l.stop = stop
+	l.stopMutex.Unlock()
+	go l.ashTransceiver(errChan, stop)
+}
+
+// InitVariables 在AshReset成功后必须调用，恢复原始的状态
+func InitVariables() {
+	DefaultLink.InitVariables()
+}
+
+// AshSend 写发送报文缓存
+func AshSend(data []byte) error {
+	return DefaultLink.Send(data)
+}
+
+// AshReset 复位NCP
+func AshReset() error {
+	return DefaultLink.Reset()
+}
+
+// AshEvents DefaultLink的链路事件通道
+func AshEvents() <-chan LinkEvent {
+	return DefaultLink.Events()
+}
+
+// AshStartTransceiver 在transport上开启收发线程，AshReset前就要运行起来
+func AshStartTransceiver(transport Transport, recvFunc func([]byte) error, errChan chan error) {
+	DefaultLink.StartTransceiver(transport, recvFunc, errChan)
 }
@@ -0,0 +1,169 @@
+package ash
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/conthing/utils/common"
+)
+
+type LinkEventType byte
+
+const (
+	LINK_EVENT_RESET        = LinkEventType(1) // NCP复位后链路重新建立，上层要重新配置NCP
+	LINK_EVENT_RESET_FAILED = LinkEventType(2) // NCP复位失败
+	LINK_EVENT_DOWN         = LinkEventType(3) // Transport读写出错，比如USB串口被拔出，开始重连
+	LINK_EVENT_UP           = LinkEventType(4) // Transport重新打开，接着会复位NCP
+)
+
+var linkEventTypeNameMap = map[LinkEventType]string{
+	LINK_EVENT_RESET:        "RESET",
+	LINK_EVENT_RESET_FAILED: "RESET_FAILED",
+	LINK_EVENT_DOWN:         "DOWN",
+	LINK_EVENT_UP:           "UP",
+}
+
+func (t LinkEventType) String() string {
+	name, ok := linkEventTypeNameMap[t]
+	if !ok {
+		name = fmt.Sprintf("UNKNOWN_EVENT_%d", t)
+	}
+	return name
+}
+
+// LinkEvent 链路事件，从 Link.Events 读取
+type LinkEvent struct {
+	Type      LinkEventType
+	Cause     string // 触发事件的原因
+	ResetCode byte   // ERROR或RSTACK帧里的reset code
+	Err       error
+}
+
+func (e LinkEvent) String() string {
+	s := fmt.Sprintf("%s cause(%s) resetCode(0x%02x)", e.Type, e.Cause, e.ResetCode)
+	if e.Err != nil {
+		s += fmt.Sprintf(" err(%v)", e.Err)
+	}
+	return s
+}
+
+// Events 链路事件通道，没有人读取时事件会被丢弃
+func (l *Link) Events() <-chan LinkEvent {
+	return l.events
+}
+
+// OnReset 注册NCP复位后的处理函数，在链路自动复位成功后、InitVariables之前按注册顺序调用，
+// 一般用来初始化上层在ASH接收线程里会用到的变量
+func (l *Link) OnReset(handler func()) {
+	l.resetHandlers = append(l.resetHandlers, handler)
+}
+
+func (l *Link) sendEvent(event LinkEvent) {
+	select {
+	case l.events <- event:
+	default:
+		common.Log.Warnf("ASH link event channel full, drop event %s", event)
+	}
+}
+
+// recover NCP出错或者自己复位后重新RST，运行在独立的线程，因为Reset要等收发线程收到RSTACK
+func (l *Link) recover(cause string, resetCode byte) {
+	if !atomic.CompareAndSwapInt32(&l.recovering, 0, 1) {
+		return // 已经在复位了
+	}
+	go func() {
+		defer atomic.StoreInt32(&l.recovering, 0)
+		common.Log.Errorf("ASH %s reset code 0x%02x(%s), reset NCP", cause, resetCode, resetCodeToString(resetCode))
+		err := l.Reset()
+		if err != nil {
+			common.Log.Errorf("ASH reset after %s failed: %v", cause, err)
+			l.sendEvent(LinkEvent{Type: LINK_EVENT_RESET_FAILED, Cause: cause, ResetCode: resetCode, Err: err})
+			return
+		}
+		for _, handler := range l.resetHandlers {
+			handler()
+		}
+		l.InitVariables() // 上层的变量初始化完成后，最后调用ASH的变量初始化
+		common.Log.Infof("ASH link reset OK after %s", cause)
+		l.sendEvent(LinkEvent{Type: LINK_EVENT_RESET, Cause: cause, ResetCode: resetCode})
+	}()
+}
+
+// Up Transport是否可用，设备丢失后到重新打开之前返回false
+func (l *Link) Up() bool {
+	return atomic.LoadInt32(&l.down) == 0
+}
+
+// reconnect 运行在收发线程里，不停地重新打开transport，成功后复位NCP。
+// 重连期间调用了 Close 时放弃重连并返回false
+func (l *Link) reconnect(reopener Reopener, cause error, stop <-chan struct{}) bool {
+	l.setStage(LINK_STAGE_RECONNECT)
+	atomic.StoreInt32(&l.down, 1)
+	l.mutex.Lock()
+	l.resetSuccess = false
+	l.mutex.Unlock()
+	common.Log.Errorf("ASH link down: %v", cause)
+	l.sendEvent(LinkEvent{Type: LINK_EVENT_DOWN, Cause: "transport error", Err: cause})
+
+	for {
+		select {
+		case <-stop:
+			return false
+		case <-time.After(l.ReconnectInterval):
+		}
+		err := reopener.Reopen()
+		if err == nil {
+			break
+		}
+		ashTrace("ASH reopen transport failed: %v", err)
+	}
+	select {
+	case <-stop: // 重新打开的同时被Close了
+		_ = l.transport.Close()
+		return false
+	default:
+	}
+
+	// 丢弃断开前没有解析完的数据
+	l.readStatusEsc = false
+	l.readStatusSubstitute = false
+	l.readBufferOffset = 0
+	l.rcvStartPtr = 0
+
+	atomic.StoreInt32(&l.down, 0)
+	common.Log.Infof("ASH link up")
+	l.sendEvent(LinkEvent{Type: LINK_EVENT_UP, Cause: "transport reopened"})
+	l.recover("link up", RESET_UNKNOWN_REASON)
+	return true
+}
+
+const (
+	RESET_UNKNOWN_REASON                     = byte(0x00)
+	RESET_EXTERNAL                           = byte(0x01)
+	RESET_POWER_ON                           = byte(0x02)
+	RESET_WATCHDOG                           = byte(0x03)
+	RESET_ASSERT                             = byte(0x06)
+	RESET_BOOTLOADER                         = byte(0x09)
+	RESET_SOFTWARE                           = byte(0x0B)
+	ERROR_EXCEEDED_MAXIMUM_ACK_TIMEOUT_COUNT = byte(0x51)
+)
+
+var resetCodeNameMap = map[byte]string{
+	RESET_UNKNOWN_REASON:                     "RESET_UNKNOWN_REASON",
+	RESET_EXTERNAL:                           "RESET_EXTERNAL",
+	RESET_POWER_ON:                           "RESET_POWER_ON",
+	RESET_WATCHDOG:                           "RESET_WATCHDOG",
+	RESET_ASSERT:                             "RESET_ASSERT",
+	RESET_BOOTLOADER:                         "RESET_BOOTLOADER",
+	RESET_SOFTWARE:                           "RESET_SOFTWARE",
+	ERROR_EXCEEDED_MAXIMUM_ACK_TIMEOUT_COUNT: "ERROR_EXCEEDED_MAXIMUM_ACK_TIMEOUT_COUNT",
+}
+
+func resetCodeToString(code byte) string {
+	name, ok := resetCodeNameMap[code]
+	if !ok {
+		name = fmt.Sprintf("UNKNOWN_RESET_CODE_%02X", code)
+	}
+	return name
+}
@@ -0,0 +1,115 @@
+package ash
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// unplugTransport 可以模拟拔出的pipeTransport，拔出后读写都出错，Reopen失败failReopen次后重新插上
+type unplugTransport struct {
+	*pipeTransport
+	mutex      sync.Mutex
+	unplugged  bool
+	failReopen int
+	reopens    int
+}
+
+var errUnplugged = errors.New("device unplugged")
+
+func (t *unplugTransport) Read(p []byte) (int, error) {
+	t.mutex.Lock()
+	unplugged := t.unplugged
+	t.mutex.Unlock()
+	if unplugged {
+		time.Sleep(time.Millisecond * 10)
+		return 0, errUnplugged
+	}
+	return t.pipeTransport.Read(p)
+}
+
+func (t *unplugTransport) Reopen() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.reopens++
+	if t.reopens <= t.failReopen {
+		return errUnplugged
+	}
+	t.unplugged = false
+	return nil
+}
+
+func (t *unplugTransport) reopenCount() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.reopens
+}
+
+func (t *unplugTransport) unplug() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.unplugged = true
+}
+
+func waitEvent(t *testing.T, l *Link, eventType LinkEventType) LinkEvent {
+	select {
+	case event := <-l.Events():
+		if event.Type != eventType {
+			t.Fatalf("link event = %s, want %s", event, eventType)
+		}
+		return event
+	case <-time.After(time.Second * 2):
+		t.Fatalf("link event %s not sent", eventType)
+		return LinkEvent{}
+	}
+}
+
+// TestReconnect transport出错后发出DOWN，重新打开成功后发出UP，然后自动复位NCP发出RESET
+func TestReconnect(t *testing.T) {
+	transport := &unplugTransport{pipeTransport: newPipeTransport(), failReopen: 2}
+	l := NewLink()
+	l.ReconnectInterval = time.Millisecond * 20
+	l.StartTransceiver(transport, func([]byte) error { return nil }, make(chan error, 1))
+	defer l.Close()
+	time.Sleep(time.Millisecond * 30) // 收发线程启动时会清空transport
+
+	transport.unplug()
+	event := waitEvent(t, l, LINK_EVENT_DOWN)
+	if event.Err == nil || l.Up() {
+		t.Fatalf("event = %s, Up = %v", event, l.Up())
+	}
+	if err := l.Send([]byte{0x01}); err == nil {
+		t.Fatal("Send succeeded while link down")
+	}
+
+	waitEvent(t, l, LINK_EVENT_UP)
+	if !l.Up() || transport.reopenCount() != 3 {
+		t.Fatalf("Up = %v, reopens = %d", l.Up(), transport.reopenCount())
+	}
+	waitWritten(t, transport.pipeTransport, ashFrame([]byte{ASH_CONTROLBYTE_RST}))
+	transport.in <- ashFrame([]byte{ASH_CONTROLBYTE_RSTACK, 0x02, RESET_SOFTWARE})
+	waitEvent(t, l, LINK_EVENT_RESET)
+	if !l.State().Connected {
+		t.Fatalf("State = %s", l.State())
+	}
+}
+
+// TestReconnectClose 重连期间Close，收发线程退出，不再重新打开
+func TestReconnectClose(t *testing.T) {
+	transport := &unplugTransport{pipeTransport: newPipeTransport(), failReopen: 1 << 30}
+	l := NewLink()
+	l.ReconnectInterval = time.Millisecond * 20
+	l.StartTransceiver(transport, func([]byte) error { return nil }, make(chan error, 1))
+	time.Sleep(time.Millisecond * 30)
+
+	transport.unplug()
+	waitEvent(t, l, LINK_EVENT_DOWN)
+	l.Close()
+	time.Sleep(time.Millisecond * 100)
+	reopens := transport.reopenCount()
+	time.Sleep(time.Millisecond * 100)
+	if n := transport.reopenCount(); n != reopens {
+		t.Fatalf("reopened %d times after Close", n-reopens)
+	}
+}
@@ -0,0 +1,132 @@
+package ash
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/conthing/utils/common"
+)
+
+// FlowControl 串口流控方式
+type FlowControl byte
+
+const (
+	FLOW_CONTROL_NONE     = FlowControl(0)
+	FLOW_CONTROL_SOFTWARE = FlowControl(1) // XON/XOFF，由ASH层处理
+	FLOW_CONTROL_HARDWARE = FlowControl(2) // RTS/CTS，由串口驱动处理
+)
+
+// ashXoffTimeout 收到XOFF后最长暂停发送的时间，NCP的XON丢失时自动恢复
+const ashXoffTimeout = time.Second
+
+// ashTxPendingMax XOFF期间最多排队的字节数，超过后丢弃新的帧，DAT帧没有ACK会被重发
+const ashTxPendingMax = 2048
+
+func (fc FlowControl) String() string {
+	switch fc {
+	case FLOW_CONTROL_NONE:
+		return "none"
+	case FLOW_CONTROL_SOFTWARE:
+		return "software"
+	case FLOW_CONTROL_HARDWARE:
+		return "hardware"
+	}
+	return fmt.Sprintf("unknown(%d)", byte(fc))
+}
+
+// ParseFlowControl 解析配置里的流控方式 none/software/hardware，也接受 xonxoff/rtscts，
+// 为空时和以前一样使用XON/XOFF
+func ParseFlowControl(s string) (FlowControl, error) {
+	switch strings.ToLower(s) {
+	case "none":
+		return FLOW_CONTROL_NONE, nil
+	case "software", "xonxoff", "":
+		return FLOW_CONTROL_SOFTWARE, nil
+	case "hardware", "rtscts":
+		return FLOW_CONTROL_HARDWARE, nil
+	}
+	return FLOW_CONTROL_NONE, fmt.Errorf("unknown flow control %q", s)
+}
+
+// FlowController 知道自己流控方式的Transport，Link根据它决定是否处理XON/XOFF
+type FlowController interface {
+	FlowControl() FlowControl
+}
+
+// rxXonXoff 收到NCP发来的XON/XOFF
+func (l *Link) rxXonXoff(xoff bool) {
+	if l.flowControl != FLOW_CONTROL_SOFTWARE {
+		return
+	}
+	l.txMutex.Lock()
+	defer l.txMutex.Unlock()
+	if xoff {
+		if !l.xoff {
+			l.xoff = true
+			l.xoffTime = time.Now()
+			l.statsUpdate(func(s *StAshStats) { s.XoffReceived++ })
+			ashFrameTrace("rx XOFF, tx paused")
+		}
+	} else if l.xoff {
+		l.xoff = false
+		ashFrameTrace("rx XON after %v, tx resumed", time.Since(l.xoffTime))
+	}
+}
+
+// txPausedLocked 是否被XOFF暂停发送，调用时要持有txMutex
+func (l *Link) txPausedLocked() bool {
+	if !l.xoff {
+		return false
+	}
+	if time.Since(l.xoffTime) > ashXoffTimeout {
+		common.Log.Warnf("ASH no XON in %v after XOFF, tx resumed", ashXoffTimeout)
+		l.xoff = false
+		return false
+	}
+	return true
+}
+
+// transportWrite 写transport，XOFF期间先放进txPending，收到XON后由收发线程发出
+func (l *Link) transportWrite(buffer []byte) error {
+	if l.transport == nil {
+		return fmt.Errorf("transport not open")
+	}
+	l.txMutex.Lock()
+	defer l.txMutex.Unlock()
+	if l.txPausedLocked() {
+		if len(l.txPending)+len(buffer) > ashTxPendingMax {
+			return fmt.Errorf("tx paused by XOFF, pending %d bytes full", len(l.txPending))
+		}
+		l.txPending = append(l.txPending, buffer...)
+		ashFrameTrace("tx paused by XOFF, pending %d bytes", len(l.txPending))
+		return nil
+	}
+	if len(l.txPending) != 0 { // 保证先发暂停期间排队的字节
+		buffer = append(l.txPending, buffer...)
+		l.txPending = nil
+	}
+	_, err := l.transport.Write(buffer)
+	return err
+}
+
+// flowControlProcess 运行在收发线程里，XOFF结束后发出排队的字节
+func (l *Link) flowControlProcess() {
+	l.txMutex.Lock()
+	defer l.txMutex.Unlock()
+	if len(l.txPending) == 0 || l.txPausedLocked() {
+		return
+	}
+	_, err := l.transport.Write(l.txPending)
+	if err != nil {
+		common.Log.Errorf("ASH tx pending bytes after XON failed: %v", err)
+	}
+	l.txPending = nil
+}
+
+// txPaused 是否被XOFF暂停发送
+func (l *Link) txPaused() bool {
+	l.txMutex.Lock()
+	defer l.txMutex.Unlock()
+	return l.txPausedLocked()
+}
@@ -0,0 +1,85 @@
+package ash
+
+import (
+	"testing"
+	"time"
+)
+
+// xonXoffTransport 使用XON/XOFF软件流控的pipeTransport
+type xonXoffTransport struct {
+	*pipeTransport
+}
+
+func (t xonXoffTransport) FlowControl() FlowControl {
+	return FLOW_CONTROL_SOFTWARE
+}
+
+// noDataWritten 在d时间内host没有写出DATA帧
+func noDataWritten(t *testing.T, transport *pipeTransport, d time.Duration) {
+	timeout := time.After(d)
+	for {
+		select {
+		case written := <-transport.written:
+			if written[0]&0x80 == ASH_CONTROLBYTE_DATA {
+				t.Fatalf("DATA frame 0x%x written while XOFF", written)
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
+
+// TestXonXoff 收到XOFF后停止发送，收到XON后发出排队的帧
+func TestXonXoff(t *testing.T) {
+	pipe := newPipeTransport()
+	l := startLink(t, xonXoffTransport{pipe}, pipe, &StAshSettings{})
+	defer l.Close()
+
+	pipe.in <- []byte{ASH_XOFF}
+	time.Sleep(time.Millisecond * 30)
+	if err := l.Send([]byte{0x01}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	noDataWritten(t, pipe, time.Millisecond*300)
+
+	pipe.in <- []byte{ASH_XON}
+	waitDataWritten(t, pipe)
+	if stats := l.Stats(); stats.XoffReceived != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+// TestXoffTimeout 收到XOFF后一直没有XON，ashXoffTimeout后自动恢复发送
+func TestXoffTimeout(t *testing.T) {
+	pipe := newPipeTransport()
+	l := startLink(t, xonXoffTransport{pipe}, pipe, &StAshSettings{})
+	defer l.Close()
+
+	pipe.in <- []byte{ASH_XOFF}
+	time.Sleep(time.Millisecond * 30)
+	start := time.Now()
+	if err := l.Send([]byte{0x01}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	noDataWritten(t, pipe, ashXoffTimeout-time.Millisecond*100)
+	waitDataWritten(t, pipe)
+	if elapsed := time.Since(start); elapsed < ashXoffTimeout-time.Millisecond*50 {
+		t.Fatalf("DATA frame written %v after XOFF", elapsed)
+	}
+}
+
+// TestXonXoffIgnored 没有使用软件流控的transport，收到的XOFF被忽略，不影响发送
+func TestXonXoffIgnored(t *testing.T) {
+	l, pipe := startPipeLink(t, &StAshSettings{})
+	defer l.Close()
+
+	pipe.in <- []byte{ASH_XOFF}
+	time.Sleep(time.Millisecond * 30)
+	if err := l.Send([]byte{0x01}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	waitDataWritten(t, pipe)
+	if stats := l.Stats(); stats.XoffReceived != 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
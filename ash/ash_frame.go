@@ -28,11 +28,6 @@ const (
 	ASH_FLIP = byte(0x20) /*!< XOR mask used in byte stuffing */
 )
 
-var readStatusEsc = false
-var readStatusSubstitute = false
-var readBufferOffset = byte(0)
-var readBuffer = make([]byte, 256)
-
 var AshFrameTraceOn bool
 
 func ashFrameTrace(format string, v ...interface{}) {
@@ -41,73 +36,72 @@ func ashFrameTrace(format string, v ...interface{}) {
 	}
 }
 
-func ashFrameRxByteParse(recvChar byte) (err error) {
+func (l *Link) ashFrameRxByteParse(recvChar byte) (err error) {
 	msgDone := false
 
-	if readStatusSubstitute {
+	if l.readStatusSubstitute {
 		// ASH_SUB. ignore until next ASH_FLAG
 		if recvChar == ASH_FLAG {
-			readStatusSubstitute = false
+			l.readStatusSubstitute = false
 		}
-	} else if readStatusEsc {
-		readBuffer[readBufferOffset] = (byte)(recvChar ^ ASH_FLIP)
-		readBufferOffset++
-		readStatusEsc = false
+	} else if l.readStatusEsc {
+		l.readBuffer[l.readBufferOffset] = (byte)(recvChar ^ ASH_FLIP)
+		l.readBufferOffset++
+		l.readStatusEsc = false
 	} else if recvChar == ASH_ESC {
-		readStatusEsc = true
-	} else if recvChar == ASH_XON {
-		ashFrameTrace("rx XON after: 0x%x", readBuffer[:readBufferOffset])
-		msgDone = true
+		l.readStatusEsc = true
+	} else if recvChar == ASH_XON { // XON/XOFF可能夹在帧中间，不影响正在接收的帧
+		l.rxXonXoff(false)
 	} else if recvChar == ASH_XOFF {
-		common.Log.Warnf("rx XOFF after: 0x%x", readBuffer[:readBufferOffset])
-		msgDone = true
+		l.rxXonXoff(true)
 	} else if recvChar == ASH_SUB {
-		common.Log.Warnf("rx SUB after: 0x%x", readBuffer[:readBufferOffset])
+		common.Log.Warnf("rx SUB after: 0x%x", l.readBuffer[:l.readBufferOffset])
+		l.statsUpdate(func(s *StAshStats) { s.SubstituteBytes++ })
 		msgDone = true
-		readStatusSubstitute = true
+		l.readStatusSubstitute = true
 	} else if recvChar == ASH_CAN {
-		ashFrameTrace("rx CANCEL after: 0x%x", readBuffer[:readBufferOffset])
+		ashFrameTrace("rx CANCEL after: 0x%x", l.readBuffer[:l.readBufferOffset])
+		l.statsUpdate(func(s *StAshStats) { s.CancelBytes++ })
 		msgDone = true
 	} else if recvChar == ASH_FLAG {
 		msgDone = true
-		readStatusSubstitute = false
+		l.readStatusSubstitute = false
 
-		crc16 := crc16.CRC16CCITTFalse(readBuffer[:readBufferOffset])
+		crc16 := crc16.CRC16CCITTFalse(l.readBuffer[:l.readBufferOffset])
 
-		if readBufferOffset <= 2 {
-			common.Log.Warnf("rx frame too short < 0x%x", readBuffer[:readBufferOffset])
-			err = fmt.Errorf("rx frame too short < 0x%x", readBuffer[:readBufferOffset])
+		if l.readBufferOffset <= 2 {
+			common.Log.Warnf("rx frame too short < 0x%x", l.readBuffer[:l.readBufferOffset])
+			l.statsUpdate(func(s *StAshStats) { s.FrameErrors++ })
+			err = fmt.Errorf("rx frame too short < 0x%x", l.readBuffer[:l.readBufferOffset])
 		} else if crc16 != 0 {
-			common.Log.Warnf("rx frame crc error < 0x%x", readBuffer[:readBufferOffset])
-			_ = ashRecvFrame(nil) //crc不对发送NAK
-			err = fmt.Errorf("rx frame crc error < 0x%x", readBuffer[:readBufferOffset])
+			common.Log.Warnf("rx frame crc error < 0x%x", l.readBuffer[:l.readBufferOffset])
+			l.statsUpdate(func(s *StAshStats) { s.CrcErrors++ })
+			_ = l.ashRecvFrame(nil) //crc不对发送NAK
+			err = fmt.Errorf("rx frame crc error < 0x%x", l.readBuffer[:l.readBufferOffset])
 		} else {
-			ashFrameTrace("rx < 0x%x", readBuffer[:readBufferOffset])
+			ashFrameTrace("rx < 0x%x", l.readBuffer[:l.readBufferOffset])
+			l.statsUpdate(func(s *StAshStats) { s.RxFrames++ })
 			//将接收的数据deepcopy
-			frame := make([]byte, readBufferOffset-2)
+			frame := make([]byte, l.readBufferOffset-2)
 			for i := range frame {
-				frame[i] = readBuffer[i]
+				frame[i] = l.readBuffer[i]
 			}
 			//ashRecvFrame 中很可能会发生进程调度
-			err = ashRecvFrame(frame)
+			err = l.ashRecvFrame(frame)
 		}
 	} else {
-		readBuffer[readBufferOffset] = recvChar
-		readBufferOffset++
+		l.readBuffer[l.readBufferOffset] = recvChar
+		l.readBufferOffset++
 	}
 	if msgDone {
-		readStatusEsc = false
-		readBufferOffset = 0
+		l.readStatusEsc = false
+		l.readBufferOffset = 0
 	}
 	return
 }
 
-func ashSendCancelByte() error {
-	if ashSerial == nil {
-		return fmt.Errorf("tx CANCEL failed. serial port not open")
-	}
-
-	_, err := ashSerial.Write([]byte{ASH_CAN})
+func (l *Link) ashSendCancelByte() error {
+	err := l.transportWrite([]byte{ASH_CAN})
 	if err != nil {
 		return fmt.Errorf("tx CANCEL failed. %v", err)
 	}
@@ -115,11 +109,8 @@ func ashSendCancelByte() error {
 	return nil
 }
 
-func ashSendFrame(frame []byte) error {
-	if ashSerial == nil {
-		return fmt.Errorf("tx failed. serial port not open")
-	}
-
+// ashSendFrame 加上CRC、转义和FLAG后发送，软件流控收到XOFF时暂停发送，收到XON后继续
+func (l *Link) ashSendFrame(frame []byte) error {
 	crc16 := crc16.CRC16CCITTFalse(frame)
 	frmWithCrc := append(frame, byte((crc16>>8)&0xff), byte(crc16&0xff))
 	var writeBuffer []byte
@@ -132,10 +123,11 @@ func ashSendFrame(frame []byte) error {
 	}
 	writeBuffer = append(writeBuffer, ASH_FLAG)
 
-	_, err := ashSerial.Write(writeBuffer)
+	err := l.transportWrite(writeBuffer)
 	if err != nil {
 		return fmt.Errorf("tx 0x%x failed. %v", writeBuffer, err)
 	}
 	ashFrameTrace("tx > 0x%x", writeBuffer)
+	l.statsUpdate(func(s *StAshStats) { s.TxFrames++ })
 	return nil
 }
@@ -4,77 +4,170 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/conthing/utils/common"
 	"github.com/jacobsa/go-serial/serial"
 )
 
-var ashSerial io.ReadWriteCloser
-var ashSerialXonXoff bool
 var errAshRecvHandleBusy = errors.New("Recv handle busy")
 
-//AshSerialOpen 打开串口
-func AshSerialOpen(name string, baud uint, rtsCts bool) (err error) {
+// SerialTransport 本地串口，USB串口拔出后 Read 返回错误，重新插上后可以 Reopen
+type SerialTransport struct {
+	mutex       sync.Mutex
+	options     serial.OpenOptions
+	flowControl FlowControl
+	port        io.ReadWriteCloser
+	lastCheck   time.Time
+}
+
+// AshSerialOpen 打开串口，返回的Transport交给 AshStartTransceiver 使用。
+// rtsCts为true时使用RTS/CTS，否则由ASH层处理XON/XOFF
+func AshSerialOpen(name string, baud uint, rtsCts bool) (Transport, error) {
+	if rtsCts {
+		return AshSerialOpenFlowControl(name, baud, FLOW_CONTROL_HARDWARE)
+	}
+	return AshSerialOpenFlowControl(name, baud, FLOW_CONTROL_SOFTWARE)
+}
+
+// AshSerialOpenFlowControl 按指定的流控方式打开串口。
+// flowControl为FLOW_CONTROL_HARDWARE时由串口驱动处理RTS/CTS，为FLOW_CONTROL_SOFTWARE时由ASH层处理XON/XOFF
+func AshSerialOpenFlowControl(name string, baud uint, flowControl FlowControl) (Transport, error) {
 	options := serial.OpenOptions{
 		PortName:              name,
 		BaudRate:              baud,
 		DataBits:              8,
 		StopBits:              1,
 		ParityMode:            serial.PARITY_NONE,
-		RTSCTSFlowControl:     rtsCts,
+		RTSCTSFlowControl:     flowControl == FLOW_CONTROL_HARDWARE,
 		MinimumReadSize:       0,
 		InterCharacterTimeout: 50,
 	}
 
-	ashSerialXonXoff = !rtsCts
+	port, err := serial.Open(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open serial. %v", err)
+	}
+	ashTrace("ASH serial %s baud %d flow control %s", name, baud, flowControl)
+	return &SerialTransport{options: options, flowControl: flowControl, port: port}, nil
+}
+
+// FlowControl 打开串口时选择的流控方式
+func (t *SerialTransport) FlowControl() FlowControl {
+	return t.flowControl
+}
+
+func (t *SerialTransport) getPort() (io.ReadWriteCloser, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.port == nil {
+		return nil, fmt.Errorf("serial %s not open", t.options.PortName)
+	}
+	return t.port, nil
+}
+
+// deviceLost USB串口拔出后read只会返回0字节，每秒检查一次设备文件是否还在
+func (t *SerialTransport) deviceLost() bool {
+	if !strings.HasPrefix(t.options.PortName, "/") || time.Since(t.lastCheck) < time.Second {
+		return false
+	}
+	t.lastCheck = time.Now()
+	_, err := os.Stat(t.options.PortName)
+	return os.IsNotExist(err)
+}
+
+func (t *SerialTransport) Read(p []byte) (int, error) {
+	port, err := t.getPort()
+	if err != nil {
+		return 0, err
+	}
+	n, err := port.Read(p)
+	if n == 0 && err == io.EOF && t.deviceLost() {
+		return 0, fmt.Errorf("serial device %s lost", t.options.PortName)
+	}
+	return n, err
+}
+
+func (t *SerialTransport) Write(p []byte) (int, error) {
+	port, err := t.getPort()
+	if err != nil {
+		return 0, err
+	}
+	return port.Write(p)
+}
+
+func (t *SerialTransport) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.port == nil {
+		return nil
+	}
+	err := t.port.Close()
+	t.port = nil
+	return err
+}
 
-	ashSerial, err = serial.Open(options)
+// Reopen 用原来的参数重新打开串口
+func (t *SerialTransport) Reopen() error {
+	_ = t.Close()
+	port, err := serial.Open(t.options)
 	if err != nil {
-		ashSerial = nil
 		return fmt.Errorf("failed to open serial. %v", err)
 	}
+	t.mutex.Lock()
+	t.port = port
+	t.lastCheck = time.Time{}
+	t.mutex.Unlock()
 	return nil
 }
 
-// AshSerialClose 关闭串口
-func AshSerialClose() {
-	if ashSerial != nil {
-		ashSerial.Close()
+// Close 停止收发线程并关闭当前使用的Transport，正在重连时停止重连
+func (l *Link) Close() {
+	l.stopMutex.Lock()
+	if l.stop != nil {
+		close(l.stop)
+		l.stop = nil
+	}
+	l.stopMutex.Unlock()
+	if l.transport != nil {
+		l.transport.Close()
 	}
 }
 
-func AshSerialFlush() {
-	if ashSerial != nil {
+func (l *Link) Flush() {
+	if l.transport != nil {
 		dummy := make([]byte, 1200)
-		ashSerial.Read(dummy)
+		l.transport.Read(dummy)
 	}
 }
 
-var rcvBuff = make([]byte, 1200)
-var rcvStartPtr = 0
-
-// AshSerialRecv 串口接收
-func AshSerialRecv() error {
-	if ashSerial == nil {
-		return fmt.Errorf("failed to recv. serial port not open")
+// Recv 从transport接收并解析
+func (l *Link) Recv() error {
+	if l.transport == nil {
+		return fmt.Errorf("failed to recv. transport not open")
 	}
-	n, err := ashSerial.Read(rcvBuff[rcvStartPtr:]) //保留上次busy后剩余字节
+	n, err := l.transport.Read(l.rcvBuff[l.rcvStartPtr:]) //保留上次busy后剩余字节
 	if n != 0 {
-		len := n + rcvStartPtr
-		rcvStartPtr = 0
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		len := n + l.rcvStartPtr
+		l.rcvStartPtr = 0
 		busy := false
 		offset := 0
-		for i, d := range rcvBuff[:len] {
+		for i, d := range l.rcvBuff[:len] {
 			if busy {
-				rcvBuff[i-offset] = d //将后面的剩余字节搬到前面来
+				l.rcvBuff[i-offset] = d //将后面的剩余字节搬到前面来
 			} else {
-				parseErr := ashFrameRxByteParse(d)
+				parseErr := l.ashFrameRxByteParse(d)
 				if parseErr == errAshRecvHandleBusy {
 					busy = true
 					offset = i + 1
-					rcvStartPtr = len - offset
-					if rcvStartPtr != 0 {
-						common.Log.Warnf("recv %d bytes but %d bytes remain unhandled", len, rcvStartPtr)
+					l.rcvStartPtr = len - offset
+					if l.rcvStartPtr != 0 {
+						common.Log.Warnf("recv %d bytes but %d bytes remain unhandled", len, l.rcvStartPtr)
 					}
 				} else if parseErr != nil {
 					common.Log.Errorf("serial recv 0x%02x parse error %v", d, parseErr)
@@ -89,3 +182,17 @@ func AshSerialRecv() error {
 	}
 	return nil
 }
+
+// AshSerialClose 关闭当前使用的Transport
+func AshSerialClose() {
+	DefaultLink.Close()
+}
+
+func AshSerialFlush() {
+	DefaultLink.Flush()
+}
+
+// AshSerialRecv 串口接收
+func AshSerialRecv() error {
+	return DefaultLink.Recv()
+}
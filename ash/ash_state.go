@@ -0,0 +1,119 @@
+package ash
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// LinkStage 收发线程正在做的事，线程卡住时用来判断卡在哪里
+type LinkStage int32
+
+const (
+	LINK_STAGE_IDLE      = LinkStage(0) // 等待发送请求或者接收超时
+	LINK_STAGE_RECEIVE   = LinkStage(1) // 从transport读取并解析帧
+	LINK_STAGE_DISPATCH  = LinkStage(2) // 把收到的DATA交给上层的接收回调
+	LINK_STAGE_SEND      = LinkStage(3) // 重发和发送DATA帧
+	LINK_STAGE_RECONNECT = LinkStage(4) // transport出错，正在重新打开
+)
+
+var linkStageNameMap = map[LinkStage]string{
+	LINK_STAGE_IDLE:      "IDLE",
+	LINK_STAGE_RECEIVE:   "RECEIVE",
+	LINK_STAGE_DISPATCH:  "DISPATCH",
+	LINK_STAGE_SEND:      "SEND",
+	LINK_STAGE_RECONNECT: "RECONNECT",
+}
+
+func (s LinkStage) String() string {
+	name, ok := linkStageNameMap[s]
+	if !ok {
+		name = fmt.Sprintf("UNKNOWN_STAGE_%d", s)
+	}
+	return name
+}
+
+// StOutstandingFrame 已发送还没有被确认的DATA帧
+type StOutstandingFrame struct {
+	FrmNum  byte          `json:"frmnum"`
+	Retries byte          `json:"retries"`
+	Age     time.Duration `json:"age"`     // 第一次发送到现在的时间
+	Timeout time.Duration `json:"timeout"` // 距离超时重发的时间，负数表示已经超时
+}
+
+// StLinkState ASH链路状态的快照
+type StLinkState struct {
+	Connected   bool                 `json:"connected"`  // RST成功，可以收发DATA
+	Up          bool                 `json:"up"`         // transport可用
+	Resetting   bool                 `json:"resetting"`  // 正在等待RSTACK
+	Recovering  bool                 `json:"recovering"` // 正在自动复位NCP
+	Stage       string               `json:"stage"`      // 收发线程正在做的事
+	StageAge    time.Duration        `json:"stageage"`   // 进入当前阶段的时间
+	FlowControl string               `json:"flowcontrol"`
+	TxPaused    bool                 `json:"txpaused"` // 收到XOFF暂停发送
+	WindowSize  byte                 `json:"windowsize"`
+	AckTime     time.Duration        `json:"acktime"`  // 当前的t_rx_ack
+	TxFrmNum    byte                 `json:"txfrmnum"` // 下一个发送的frmNum
+	TxAckNum    byte                 `json:"txacknum"` // NCP确认到的frmNum
+	TxQueued    int                  `json:"txqueued"` // 等待发送窗口的DATA帧
+	Outstanding []StOutstandingFrame `json:"outstanding"`
+	RxFrmNum    byte                 `json:"rxfrmnum"` // 下一个期望收到的frmNum
+	RxAckNum    byte                 `json:"rxacknum"` // 已经发出ACK的ackNum
+}
+
+func (s StLinkState) String() string {
+	str := fmt.Sprintf("connected(%v) up(%v) stage(%s %v) tx(frmNum=%d ackNum=%d queued=%d paused=%v) rx(frmNum=%d ackNum=%d) t_rx_ack(%v)",
+		s.Connected, s.Up, s.Stage, s.StageAge.Truncate(time.Millisecond), s.TxFrmNum, s.TxAckNum, s.TxQueued, s.TxPaused, s.RxFrmNum, s.RxAckNum, s.AckTime)
+	for _, f := range s.Outstanding {
+		str += fmt.Sprintf(" outstanding(frmNum=%d retries=%d age=%v)", f.FrmNum, f.Retries, f.Age.Truncate(time.Millisecond))
+	}
+	return str
+}
+
+func (l *Link) setStage(stage LinkStage) {
+	atomic.StoreInt32(&l.stage, int32(stage))
+	atomic.StoreInt64(&l.stageTime, time.Now().UnixNano())
+}
+
+// State 返回链路状态的快照，可以在任何线程调用
+func (l *Link) State() StLinkState {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := time.Now()
+	state := StLinkState{
+		Connected:   l.resetSuccess,
+		Up:          l.Up(),
+		Resetting:   atomic.LoadInt32(&l.resetting) != 0,
+		Recovering:  atomic.LoadInt32(&l.recovering) != 0,
+		Stage:       LinkStage(atomic.LoadInt32(&l.stage)).String(),
+		StageAge:    now.Sub(time.Unix(0, atomic.LoadInt64(&l.stageTime))),
+		FlowControl: l.flowControl.String(),
+		TxPaused:    l.txPaused(),
+		WindowSize:  l.settings.WindowSize,
+		AckTime:     l.ackTime,
+		TxFrmNum:    l.txIndexNext,
+		TxAckNum:    l.txIndexConfirming,
+		RxFrmNum:    l.rxIndexNext,
+		RxAckNum:    l.rxIndexNextSent,
+	}
+	for i := l.txIndexNext; i != l.txPutPtr; i = inc(i) {
+		if l.txbuffer[i] != nil {
+			state.TxQueued++
+		}
+	}
+	for i := l.txIndexConfirming; i != l.txIndexNext; i = inc(i) {
+		f := l.txFrames[i]
+		state.Outstanding = append(state.Outstanding, StOutstandingFrame{
+			FrmNum:  i,
+			Retries: f.retries,
+			Age:     now.Sub(f.firstSent),
+			Timeout: f.deadline.Sub(now),
+		})
+	}
+	return state
+}
+
+// AshState DefaultLink的状态快照
+func AshState() StLinkState {
+	return DefaultLink.State()
+}
@@ -0,0 +1,112 @@
+package ash
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// startPipeLink 在pipeTransport上启动收发线程并完成RST
+func startPipeLink(t *testing.T, settings *StAshSettings) (*Link, *pipeTransport) {
+	transport := newPipeTransport()
+	return startLink(t, transport, transport, settings), transport
+}
+
+// startLink 在transport上启动收发线程，通过pipe和host收发，完成RST
+func startLink(t *testing.T, transport Transport, pipe *pipeTransport, settings *StAshSettings) *Link {
+	l := NewLink()
+	if err := l.Set(settings); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	l.StartTransceiver(transport, func([]byte) error { return nil }, make(chan error, 1))
+	time.Sleep(time.Millisecond * 30) // 收发线程启动时会清空transport
+
+	done := make(chan error, 1)
+	go func() { done <- l.Reset() }()
+	waitWritten(t, pipe, ashFrame([]byte{ASH_CONTROLBYTE_RST}))
+	pipe.in <- ashFrame([]byte{ASH_CONTROLBYTE_RSTACK, 0x02, RESET_SOFTWARE})
+	if err := <-done; err != nil {
+		l.Close()
+		t.Fatalf("Reset: %v", err)
+	}
+	l.InitVariables()
+	return l
+}
+
+// waitDataWritten 等待host写出一个DATA帧，跳过ACK等其它帧
+func waitDataWritten(t *testing.T, transport *pipeTransport) {
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case written := <-transport.written:
+			if written[0]&0x80 == ASH_CONTROLBYTE_DATA {
+				return
+			}
+		case <-timeout:
+			t.Fatal("DATA frame not written")
+		}
+	}
+}
+
+// waitFor 每10ms检查一次cond，1秒后还不满足时失败
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in 1s")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+// TestState 收发线程运行时在其它线程取快照
+func TestState(t *testing.T) {
+	l, transport := startPipeLink(t, &StAshSettings{WindowSize: 2, AckTimeInit: time.Second * 2})
+	defer l.Close()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = l.State().String()
+			}
+		}
+	}()
+	// NCP确认每一帧，窗口一直在移动
+	for i := 0; i < 20; i++ {
+		if err := l.Send([]byte{byte(i)}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+		waitDataWritten(t, transport)
+		transport.in <- ashFrame([]byte{ASH_CONTROLBYTE_ACK | byte(i+1)&7})
+	}
+	waitFor(t, func() bool { return l.State().TxAckNum == 20&7 })
+
+	// NCP不再回ACK，窗口里的2帧发出去后停下
+	for i := 0; i < 5; i++ {
+		if err := l.Send([]byte{byte(i)}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	waitDataWritten(t, transport)
+	waitDataWritten(t, transport)
+	time.Sleep(time.Millisecond * 50)
+	close(stop)
+	wg.Wait()
+
+	state := l.State()
+	if !state.Connected || state.WindowSize != 2 || state.TxFrmNum != 6 || state.TxAckNum != 4 || state.TxQueued != 3 || len(state.Outstanding) != 2 {
+		t.Fatalf("State = %s", state)
+	}
+	for i, f := range state.Outstanding {
+		if f.FrmNum != byte(4+i) || f.Retries != 0 || f.Timeout <= 0 {
+			t.Fatalf("outstanding %d = %+v", i, f)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package ash
+
+import (
+	"time"
+)
+
+// StAshStats ASH链路的统计计数，用来诊断串口线路和NCP的通信质量
+type StAshStats struct {
+	TxFrames        uint64    `json:"txframes"`        // 发送的所有帧，包括RST/ACK/NAK
+	RxFrames        uint64    `json:"rxframes"`        // 收到的CRC正确的帧
+	TxData          uint64    `json:"txdata"`          // 第一次发送的DATA帧
+	RxData          uint64    `json:"rxdata"`          // 按顺序收到的DATA帧
+	Retransmits     uint64    `json:"retransmits"`     // 重发的DATA帧
+	AckTimeouts     uint64    `json:"acktimeouts"`     // 等待ACK超时的次数
+	NakSent         uint64    `json:"naksent"`         // 发送的NAK
+	NakReceived     uint64    `json:"nakreceived"`     // 收到的NAK
+	CrcErrors       uint64    `json:"crcerrors"`       // CRC错误的帧
+	FrameErrors     uint64    `json:"frameerrors"`     // 太短的帧
+	CancelBytes     uint64    `json:"cancelbytes"`     // 收到的CAN字节
+	SubstituteBytes uint64    `json:"substitutebytes"` // 收到的SUB字节，UART底层出错
+	XoffReceived    uint64    `json:"xoffreceived"`    // 收到的XOFF
+	Resets          uint64    `json:"resets"`          // 收到RSTACK的次数
+	Since           time.Time `json:"since"`           // 开始计数的时间
+}
+
+func (l *Link) statsUpdate(update func(s *StAshStats)) {
+	l.statsMutex.Lock()
+	update(&l.stats)
+	l.statsMutex.Unlock()
+}
+
+// Stats 返回统计计数的快照
+func (l *Link) Stats() StAshStats {
+	l.statsMutex.Lock()
+	defer l.statsMutex.Unlock()
+	return l.stats
+}
+
+// ResetStats 计数清0，返回清0之前的快照
+func (l *Link) ResetStats() StAshStats {
+	l.statsMutex.Lock()
+	defer l.statsMutex.Unlock()
+	stats := l.stats
+	l.stats = StAshStats{Since: time.Now()}
+	return stats
+}
+
+// AshStats DefaultLink的统计计数
+func AshStats() StAshStats {
+	return DefaultLink.Stats()
+}
+
+// AshResetStats DefaultLink的计数清0，返回清0之前的快照
+func AshResetStats() StAshStats {
+	return DefaultLink.ResetStats()
+}
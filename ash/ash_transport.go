@@ -0,0 +1,114 @@
+package ash
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Transport ASH层的底层字节流，可以是本地串口、TCP透传（ser2net等）或者测试用的模拟NCP
+// Read 在一段时间内没有收到数据时应返回 (0, io.EOF)，收发线程以此作为空闲轮询的依据
+type Transport interface {
+	io.ReadWriteCloser
+}
+
+// Reopener 设备丢失后可以重新打开的Transport，收发线程读出错后用 Reopen 重连
+type Reopener interface {
+	Reopen() error
+}
+
+// TcpTransport 通过TCP连接到串口服务器上的NCP
+type TcpTransport struct {
+	mutex       sync.Mutex
+	address     string
+	conn        net.Conn
+	readTimeout time.Duration
+}
+
+func tcpDial(address string) (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", address, time.Second*5)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect %s. %v", address, err)
+	}
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		_ = tcpConn.SetNoDelay(true)
+	}
+	return conn, nil
+}
+
+// AshTcpOpen 连接TCP串口服务器，address形如 "192.168.1.10:4001"
+func AshTcpOpen(address string) (Transport, error) {
+	conn, err := tcpDial(address)
+	if err != nil {
+		return nil, err
+	}
+	return &TcpTransport{address: address, conn: conn, readTimeout: time.Millisecond * 50}, nil
+}
+
+func (t *TcpTransport) getConn() (net.Conn, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.conn == nil {
+		return nil, fmt.Errorf("tcp %s not connected", t.address)
+	}
+	return t.conn, nil
+}
+
+// Read 读超时转换成io.EOF，和串口的行为保持一致
+func (t *TcpTransport) Read(p []byte) (int, error) {
+	conn, err := t.getConn()
+	if err != nil {
+		return 0, err
+	}
+	err = conn.SetReadDeadline(time.Now().Add(t.readTimeout))
+	if err != nil {
+		return 0, err
+	}
+	n, err := conn.Read(p)
+	if err != nil {
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			if n != 0 {
+				return n, nil
+			}
+			return 0, io.EOF
+		}
+		if err == io.EOF {
+			return n, io.ErrUnexpectedEOF // 对端关闭连接不能当作空闲
+		}
+	}
+	return n, err
+}
+
+func (t *TcpTransport) Write(p []byte) (int, error) {
+	conn, err := t.getConn()
+	if err != nil {
+		return 0, err
+	}
+	return conn.Write(p)
+}
+
+func (t *TcpTransport) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.conn == nil {
+		return nil
+	}
+	err := t.conn.Close()
+	t.conn = nil
+	return err
+}
+
+// Reopen 断开后重新连接串口服务器
+func (t *TcpTransport) Reopen() error {
+	_ = t.Close()
+	conn, err := tcpDial(t.address)
+	if err != nil {
+		return err
+	}
+	t.mutex.Lock()
+	t.conn = conn
+	t.mutex.Unlock()
+	return nil
+}
@@ -0,0 +1,159 @@
+package ash
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/conthing/utils/crc16"
+)
+
+// pipeTransport 测试用的Transport，host写出的字节记在written，in里的字节作为NCP发来的数据
+type pipeTransport struct {
+	in      chan []byte
+	written chan []byte
+}
+
+func newPipeTransport() *pipeTransport {
+	return &pipeTransport{in: make(chan []byte, 16), written: make(chan []byte, 64)}
+}
+
+func (t *pipeTransport) Read(p []byte) (int, error) {
+	select {
+	case data := <-t.in:
+		return copy(p, data), nil
+	case <-time.After(time.Millisecond * 10):
+		return 0, io.EOF
+	}
+}
+
+func (t *pipeTransport) Write(p []byte) (int, error) {
+	t.written <- append([]byte(nil), p...)
+	return len(p), nil
+}
+
+func (t *pipeTransport) Close() error {
+	return nil
+}
+
+// ashFrame 加上CRC、转义和FLAG，和NCP发出的帧一样
+func ashFrame(frame []byte) []byte {
+	crc := crc16.CRC16CCITTFalse(frame)
+	var data []byte
+	for _, b := range append(frame, byte(crc>>8), byte(crc)) {
+		if b == ASH_XON || b == ASH_XOFF || b == ASH_SUB || b == ASH_CAN || b == ASH_ESC || b == ASH_FLAG {
+			data = append(data, ASH_ESC, b^ASH_FLIP)
+		} else {
+			data = append(data, b)
+		}
+	}
+	return append(data, ASH_FLAG)
+}
+
+// waitWritten 等待host写出data
+func waitWritten(t *testing.T, transport *pipeTransport, data []byte) {
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case written := <-transport.written:
+			if bytes.Equal(written, data) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("0x%x not written to transport", data)
+		}
+	}
+}
+
+// TestTransceiverTransport 收发线程只通过Transport接口和NCP通信
+func TestTransceiverTransport(t *testing.T) {
+	transport := newPipeTransport()
+	l := NewLink()
+	l.StartTransceiver(transport, func([]byte) error { return nil }, make(chan error, 1))
+	defer l.Close()
+	time.Sleep(time.Millisecond * 30) // 收发线程启动时会清空transport
+
+	done := make(chan error, 1)
+	go func() { done <- l.Reset() }()
+	waitWritten(t, transport, []byte{ASH_CAN})
+	waitWritten(t, transport, ashFrame([]byte{ASH_CONTROLBYTE_RST}))
+	transport.in <- ashFrame([]byte{ASH_CONTROLBYTE_RSTACK, 0x02, RESET_SOFTWARE})
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Reset: %v", err)
+		}
+	case <-time.After(time.Second * 2):
+		t.Fatal("Reset not finished after RSTACK")
+	}
+	if stats := l.Stats(); stats.Resets != 1 || stats.RxFrames != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestTcpTransport(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer listener.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	transport, err := AshTcpOpen(listener.Addr().String())
+	if err != nil {
+		t.Fatalf("AshTcpOpen: %v", err)
+	}
+	defer transport.Close()
+	server := <-accepted
+
+	// 没有数据时返回io.EOF，收发线程当作空闲
+	buf := make([]byte, 16)
+	if n, err := transport.Read(buf); n != 0 || err != io.EOF {
+		t.Fatalf("Read without data = %d, %v", n, err)
+	}
+	if _, err = server.Write([]byte{0x01, 0x02}); err != nil {
+		t.Fatalf("server Write: %v", err)
+	}
+	if n, err := transport.Read(buf); n != 2 || err != nil {
+		t.Fatalf("Read = %d, %v", n, err)
+	}
+	if _, err = transport.Write([]byte{0x03}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if n, err := server.Read(buf); n != 1 || buf[0] != 0x03 {
+		t.Fatalf("server Read = 0x%x, %v", buf[:n], err)
+	}
+
+	// 对端关闭连接不是空闲，Reopen后重新连上
+	server.Close()
+	if _, err = transport.Read(buf); err == nil || err == io.EOF {
+		t.Fatalf("Read after peer closed = %v", err)
+	}
+	if err = transport.(Reopener).Reopen(); err != nil {
+		t.Fatalf("Reopen: %v", err)
+	}
+	server = <-accepted
+	defer server.Close()
+	if _, err = transport.Write([]byte{0x04}); err != nil {
+		t.Fatalf("Write after Reopen: %v", err)
+	}
+	if n, err := server.Read(buf); n != 1 || buf[0] != 0x04 {
+		t.Fatalf("server Read after Reopen = 0x%x, %v", buf[:n], err)
+	}
+
+	transport.Close()
+	if _, err = transport.Write([]byte{0x05}); err == nil {
+		t.Fatal("Write after Close succeeded")
+	}
+}
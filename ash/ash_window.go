@@ -0,0 +1,185 @@
+package ash
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/conthing/utils/common"
+)
+
+const (
+	// ASH协议规定的发送窗口和ACK超时时间
+	ASH_WINDOW_SIZE_MAX   = byte(7)
+	ASH_ACK_TIME_INIT     = time.Millisecond * 1600
+	ASH_ACK_TIME_MIN      = time.Millisecond * 400
+	ASH_ACK_TIME_MAX      = time.Millisecond * 3200
+	ASH_MAX_RETRIES       = byte(3) // 同一个DATA帧最多重发次数，超过后复位NCP
+	ashAckTimeSampleShift = 3       // t_rx_ack = 7/8*t_rx_ack + 1/2*实测ACK时间
+)
+
+// StAshSettings ASH发送窗口和重发设置，为0的字段使用默认值
+type StAshSettings struct {
+	WindowSize  byte          // 未被确认的DATA帧的最大数量，1-7，默认1
+	MaxRetries  byte          // 同一个DATA帧最多重发次数，默认3
+	AckTimeInit time.Duration // t_rx_ack初始值，默认1.6秒
+	AckTimeMin  time.Duration // t_rx_ack最小值，默认0.4秒
+	AckTimeMax  time.Duration // t_rx_ack最大值，默认3.2秒
+}
+
+// stTxFrame 已发送还没有被确认的DATA帧的状态
+type stTxFrame struct {
+	firstSent time.Time // 第一次发送的时间
+	sentTime  time.Time // 最近一次发送的时间
+	deadline  time.Time // 超过这个时间没有ACK就重发
+	retries   byte      // 重发次数
+}
+
+func defaultAshSettings() StAshSettings {
+	return StAshSettings{
+		WindowSize:  1,
+		MaxRetries:  ASH_MAX_RETRIES,
+		AckTimeInit: ASH_ACK_TIME_INIT,
+		AckTimeMin:  ASH_ACK_TIME_MIN,
+		AckTimeMax:  ASH_ACK_TIME_MAX,
+	}
+}
+
+// Set 修改发送窗口和重发设置，要在 StartTransceiver 之前调用
+func (l *Link) Set(settings *StAshSettings) error {
+	s := defaultAshSettings()
+	if settings.WindowSize > ASH_WINDOW_SIZE_MAX {
+		return fmt.Errorf("ASH window size %d out of range 1-%d", settings.WindowSize, ASH_WINDOW_SIZE_MAX)
+	}
+	if settings.WindowSize != 0 {
+		s.WindowSize = settings.WindowSize
+	}
+	if settings.MaxRetries != 0 {
+		s.MaxRetries = settings.MaxRetries
+	}
+	if settings.AckTimeInit != 0 {
+		s.AckTimeInit = settings.AckTimeInit
+	}
+	if settings.AckTimeMin != 0 {
+		s.AckTimeMin = settings.AckTimeMin
+	}
+	if settings.AckTimeMax != 0 {
+		s.AckTimeMax = settings.AckTimeMax
+	}
+	if s.AckTimeMin > s.AckTimeMax || s.AckTimeInit < s.AckTimeMin || s.AckTimeInit > s.AckTimeMax {
+		return fmt.Errorf("ASH ack time init(%v) min(%v) max(%v) invalid", s.AckTimeInit, s.AckTimeMin, s.AckTimeMax)
+	}
+	l.mutex.Lock()
+	l.settings = s
+	l.ackTime = s.AckTimeInit
+	l.mutex.Unlock()
+	return nil
+}
+
+// Settings 返回当前的发送窗口和重发设置
+func (l *Link) Settings() StAshSettings {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.settings
+}
+
+func (l *Link) ackTimeClamp(t time.Duration) time.Duration {
+	if t < l.settings.AckTimeMin {
+		return l.settings.AckTimeMin
+	}
+	if t > l.settings.AckTimeMax {
+		return l.settings.AckTimeMax
+	}
+	return t
+}
+
+// ackTimeUpdate 第一次发送就被确认的帧，用实测的ACK时间调整t_rx_ack，重发过的帧不参与计算
+func (l *Link) ackTimeUpdate(index byte) {
+	f := &l.txFrames[index]
+	if f.retries != 0 || f.sentTime.IsZero() {
+		return
+	}
+	measured := time.Since(f.sentTime)
+	l.ackTime = l.ackTimeClamp(l.ackTime - l.ackTime>>ashAckTimeSampleShift + measured/2)
+}
+
+// txFrameSent 记录DATA帧的发送时间，按当前的t_rx_ack计算超时
+func (l *Link) txFrameSent(index byte, reTx bool) {
+	f := &l.txFrames[index]
+	now := time.Now()
+	if reTx {
+		f.retries++
+	} else {
+		f.retries = 0
+		f.firstSent = now
+	}
+	f.sentTime = now
+	f.deadline = now.Add(l.ackTime)
+}
+
+// ackTimeoutExpired 最早发送的未确认帧是否已经超时
+func (l *Link) ackTimeoutExpired() bool {
+	if l.txIndexConfirming == l.txIndexNext {
+		return false
+	}
+	for i := l.txIndexConfirming; i != l.txIndexNext; i = inc(i) {
+		if time.Now().After(l.txFrames[i].deadline) {
+			return true
+		}
+	}
+	return false
+}
+
+// outstanding 已发送还没有被确认的DATA帧数量
+func (l *Link) outstanding() byte {
+	return (l.txIndexNext - l.txIndexConfirming) & 7
+}
+
+// ashRetransmit 从最早没有确认的帧开始，把发出去的帧全部重发一遍（go-back-N），
+// 某个帧的重发次数超过 MaxRetries 时返回错误
+func (l *Link) ashRetransmit(cause string) error {
+	for i := l.txIndexConfirming; i != l.txIndexNext; i = inc(i) {
+		data := l.txbuffer[i]
+		if data == nil {
+			continue
+		}
+		if l.txFrames[i].retries >= l.settings.MaxRetries {
+			return fmt.Errorf("frmNum(%d) resend exceed max count %d", i, l.settings.MaxRetries)
+		}
+		control := byte(ASH_CONTROLBYTE_DATA | byte(i<<4) | l.getAckNumForData() | ASH_CONTROLBYTE_RETX)
+		ashDataFrame := append([]byte{control}, data...)
+		l.txFrameSent(i, true)
+		l.statsUpdate(func(s *StAshStats) { s.Retransmits++ })
+		ashTrace("ASH %dth resend(%s) t_rx_ack=%v > 0x%x", l.txFrames[i].retries, cause, l.ackTime, ashDataFrame)
+		dataFrmPseudoRandom(ashDataFrame[1:])
+		err := l.ashSendFrame(ashDataFrame)
+		if err != nil {
+			common.Log.Errorf("ASH resend failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// ashResendProcess 处理NAK和ACK超时的重发。ACK超时时t_rx_ack加倍，NAK不改变t_rx_ack
+func (l *Link) ashResendProcess() (bool, error) {
+	if l.outstanding() == 0 {
+		l.recvNakFrame = false
+		return false, nil
+	}
+	cause := ""
+	if l.recvNakFrame {
+		l.recvNakFrame = false
+		cause = "NAK"
+	} else if l.ackTimeoutExpired() {
+		l.ackTime = l.ackTimeClamp(l.ackTime * 2)
+		l.statsUpdate(func(s *StAshStats) { s.AckTimeouts++ })
+		cause = "timeout"
+	} else {
+		return false, nil
+	}
+	return true, l.ashRetransmit(cause)
+}
+
+// AshSet 修改DefaultLink的发送窗口和重发设置，要在 AshStartTransceiver 之前调用
+func AshSet(settings *StAshSettings) error {
+	return DefaultLink.Set(settings)
+}
@@ -0,0 +1,165 @@
+package ash
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+// stubTransport 记录写出的帧，没有数据可读
+type stubTransport struct {
+	frames int
+}
+
+func (t *stubTransport) Read(p []byte) (int, error) {
+	return 0, io.EOF
+}
+
+func (t *stubTransport) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if b == ASH_FLAG {
+			t.frames++
+		}
+	}
+	return len(p), nil
+}
+
+func (t *stubTransport) Close() error {
+	return nil
+}
+
+func newTestLink(t *testing.T, settings *StAshSettings) (*Link, *stubTransport) {
+	l := NewLink()
+	if err := l.Set(settings); err != nil {
+		t.Fatalf("Set(%+v): %v", settings, err)
+	}
+	transport := &stubTransport{}
+	l.transport = transport
+	l.InitVariables()
+	return l, transport
+}
+
+// send 写发送缓存，并像收发线程一样取走发送通知
+func send(t *testing.T, l *Link, data []byte) {
+	if err := l.Send(data); err != nil {
+		t.Fatalf("Send(0x%x): %v", data, err)
+	}
+	<-l.needSendProcess
+}
+
+func TestSet(t *testing.T) {
+	l := NewLink()
+	if err := l.Set(&StAshSettings{}); err != nil || l.Settings() != defaultAshSettings() {
+		t.Fatalf("Set(zero) = %+v, %v", l.Settings(), err)
+	}
+	invalid := []StAshSettings{
+		{WindowSize: ASH_WINDOW_SIZE_MAX + 1},
+		{AckTimeMin: time.Second * 4},
+		{AckTimeInit: time.Millisecond * 100},
+		{AckTimeInit: time.Second * 4},
+	}
+	for _, settings := range invalid {
+		if err := l.Set(&settings); err == nil {
+			t.Errorf("Set(%+v) succeeded", settings)
+		}
+	}
+	if err := l.Set(&StAshSettings{WindowSize: 4, AckTimeInit: time.Millisecond * 500}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if l.Settings().WindowSize != 4 || l.ackTime != time.Millisecond*500 {
+		t.Fatalf("Settings = %+v ackTime = %v", l.Settings(), l.ackTime)
+	}
+}
+
+func TestSendWindow(t *testing.T) {
+	l, transport := newTestLink(t, &StAshSettings{WindowSize: 3})
+	for i := 0; i < 5; i++ {
+		send(t, l, []byte{byte(i)})
+	}
+
+	l.ashSendProcess()
+	if l.outstanding() != 3 || transport.frames != 3 {
+		t.Fatalf("outstanding = %d, frames = %d, want 3", l.outstanding(), transport.frames)
+	}
+
+	// NCP确认了前2帧，窗口空出2个位置
+	if err := l.ackNumProcess(2); err != nil {
+		t.Fatalf("ackNumProcess: %v", err)
+	}
+	l.ashSendProcess()
+	if l.outstanding() != 3 || transport.frames != 5 || l.txIndexConfirming != 2 || l.txIndexNext != 5 {
+		t.Fatalf("outstanding = %d, frames = %d, confirming = %d, next = %d", l.outstanding(), transport.frames, l.txIndexConfirming, l.txIndexNext)
+	}
+
+	if err := l.ackNumProcess(7); err == nil {
+		t.Fatal("ackNum ahead of frmNum accepted")
+	}
+}
+
+func TestAckTimeAdaptive(t *testing.T) {
+	settings := &StAshSettings{AckTimeInit: time.Millisecond * 800, AckTimeMin: time.Millisecond * 400, AckTimeMax: time.Millisecond * 1600}
+	l, _ := newTestLink(t, settings)
+
+	// 很快被确认的帧让t_rx_ack变小，但不低于最小值
+	for i := 0; i < 20; i++ {
+		send(t, l, []byte{byte(i)})
+		l.ashSendProcess()
+		if err := l.ackNumProcess(l.txIndexNext); err != nil {
+			t.Fatalf("ackNumProcess: %v", err)
+		}
+	}
+	if l.ackTime != settings.AckTimeMin {
+		t.Fatalf("ackTime after fast ACKs = %v, want %v", l.ackTime, settings.AckTimeMin)
+	}
+
+	// ACK超时时t_rx_ack加倍并重发，不超过最大值
+	send(t, l, []byte{0xaa})
+	l.ashSendProcess()
+	index := l.txIndexConfirming
+	for _, want := range []time.Duration{time.Millisecond * 800, time.Millisecond * 1600, time.Millisecond * 1600} {
+		l.txFrames[index].deadline = time.Now().Add(-time.Millisecond)
+		resent, err := l.ashResendProcess()
+		if !resent || err != nil {
+			t.Fatalf("ashResendProcess = %v, %v", resent, err)
+		}
+		if l.ackTime != want {
+			t.Fatalf("ackTime after timeout = %v, want %v", l.ackTime, want)
+		}
+	}
+
+	// 重发过的帧被确认后不参与t_rx_ack的计算
+	if err := l.ackNumProcess(l.txIndexNext); err != nil {
+		t.Fatalf("ackNumProcess: %v", err)
+	}
+	if l.ackTime != settings.AckTimeMax {
+		t.Fatalf("ackTime after retransmitted ACK = %v, want %v", l.ackTime, settings.AckTimeMax)
+	}
+}
+
+func TestRetransmitLimit(t *testing.T) {
+	l, transport := newTestLink(t, &StAshSettings{WindowSize: 2, MaxRetries: 2})
+	for i := 0; i < 2; i++ {
+		send(t, l, []byte{byte(i)})
+	}
+	l.ashSendProcess()
+
+	// NAK重发窗口里的所有帧（go-back-N），不改变t_rx_ack
+	l.recvNakFrame = true
+	resent, err := l.ashResendProcess()
+	if !resent || err != nil || transport.frames != 4 || l.ackTime != ASH_ACK_TIME_INIT {
+		t.Fatalf("ashResendProcess after NAK = %v, %v, frames = %d, ackTime = %v", resent, err, transport.frames, l.ackTime)
+	}
+	stats := l.Stats()
+	if stats.Retransmits != 2 || stats.AckTimeouts != 0 {
+		t.Fatalf("stats after NAK = %+v", stats)
+	}
+
+	l.recvNakFrame = true
+	if _, err = l.ashResendProcess(); err != nil {
+		t.Fatalf("second resend: %v", err)
+	}
+	l.recvNakFrame = true
+	if _, err = l.ashResendProcess(); err == nil {
+		t.Fatal("resend beyond MaxRetries succeeded")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/conthing/ezsp/ezsp"
+	"github.com/conthing/ezsp/interview"
 	"github.com/conthing/ezsp/zcl"
 
 	"github.com/conthing/utils/common"
@@ -78,6 +79,8 @@ type StNode struct {
 	Channel                      byte
 	RSSI                         int8
 	LQI                          byte
+
+	Interview *interview.StDevice // interview的结果，没有interview或者还没有结束时为nil
 }
 
 type StC4Callbacks struct {
@@ -90,6 +93,37 @@ var C4Callbacks StC4Callbacks
 
 var Nodes sync.Map
 
+// interviewCh interview的结果在它自己的goroutine里发布，放到这里交给C4Tick，和callback在同一个线程修改Nodes
+var interviewCh = make(chan *interview.StDevice, 16)
+
+func queueInterview(e *interview.InterviewEvent) {
+	device := e.Device
+	select {
+	case interviewCh <- &device:
+	default:
+		common.Log.Errorf("interview result of 0x%016x dropped", device.Eui64)
+	}
+}
+
+// stMessageSent 确认发送的结果，在等待的goroutine里得到，放到sentCh交给C4Tick调用 C4MessageSentHandler
+type stMessageSent struct {
+	eui64          uint64
+	profileId      uint16
+	clusterId      uint16
+	localEndpoint  byte
+	remoteEndpoint byte
+	message        []byte
+	success        bool
+}
+
+var sentCh = make(chan *stMessageSent, 16)
+
+func messageSentHandler(sent *stMessageSent) {
+	if C4Callbacks.C4MessageSentHandler != nil {
+		C4Callbacks.C4MessageSentHandler(sent.eui64, sent.profileId, sent.clusterId, sent.localEndpoint, sent.remoteEndpoint, sent.message, sent.success)
+	}
+}
+
 func findNodeIDbyEui64(eui64 uint64) (nodeID uint16) {
 	nodeID = ezsp.EMBER_NULL_NODE_ID
 	Nodes.Range(func(key, value interface{}) bool {
@@ -110,10 +144,13 @@ var MTORRIntervalCnt = 0
 
 func C4Tick() {
 	select {
-	case cbs := <-ezsp.CallbackCh:
-		for _, cb := range cbs {
-			ezsp.EzspCallbackDispatch(cb)
-		}
+	case cb := <-ezsp.CallbackCh:
+		ezsp.EzspCallbackDispatch(cb)
+		ezsp.EzspCallbackDispatchPending()
+	case device := <-interviewCh:
+		InterviewHandler(device)
+	case sent := <-sentCh:
+		messageSentHandler(sent)
 	case <-time.After(time.Second * 3):
 		Nodes.Range(func(key, value interface{}) bool {
 			if node, ok := value.(StNode); ok {
@@ -123,7 +160,7 @@ func C4Tick() {
 		})
 		if MTORRIntervalCnt == 0 { //使用两个After有问题
 			MTORRIntervalCnt = MTORRIntervalTime[MTORRIntervalOffset] / 3
-			if ezsp.MeshStatusUp {
+			if ezsp.MeshStatusUp() {
 				if MTORRIntervalOffset < len(MTORRIntervalTime)-1 {
 					MTORRIntervalOffset++
 				}
@@ -420,12 +457,48 @@ func SetPermission(permission *StPermission) (err error) {
 	return
 }
 
+var subscription *ezsp.Subscription
+
 func C4Init() {
-	ezsp.NcpCallbacks.NcpTrustCenterJoinHandler = TrustCenterJoinHandler
-	ezsp.NcpCallbacks.NcpMessageSentHandler = MessageSentHandler
-	ezsp.NcpCallbacks.NcpIncomingMessageHandler = IncomingMessageHandler
-	ezsp.NcpCallbacks.NcpIncomingSenderEui64Handler = IncomingSenderEui64Handler
+	subscription.Unsubscribe()
+	subscription = ezsp.Subscribe(ezsp.EventFilter{FrameIDs: []uint16{
+		ezsp.EZSP_TRUST_CENTER_JOIN_HANDLER,
+		ezsp.EZSP_MESSAGE_SENT_HANDLER,
+		ezsp.EZSP_INCOMING_MESSAGE_HANDLER,
+		ezsp.EZSP_INCOMING_SENDER_EUI64_HANDLER,
+		interview.INTERVIEW_EVENT_ID}}, eventHandler)
+}
+
+func eventHandler(event ezsp.Event) {
+	switch e := event.(type) {
+	case *ezsp.TrustCenterJoinEvent:
+		TrustCenterJoinHandler(e.NewNodeId, e.NewNodeEui64, e.DeviceUpdateStatus, e.JoinDecision, e.ParentOfNewNode)
+	case *ezsp.MessageSentEvent:
+		MessageSentHandler(e.OutgoingMessageType, e.IndexOrDestination, &e.ApsFrame, e.MessageTag, e.EmberStatus, e.Message)
+	case *ezsp.IncomingMessageEvent:
+		IncomingMessageHandler(e.IncomingMessageType, &e.ApsFrame, e.LastHopLqi, e.LastHopRssi, e.Sender, e.BindingIndex, e.AddressIndex, e.Message)
+	case *ezsp.IncomingSenderEui64Event:
+		IncomingSenderEui64Handler(e.SenderEui64)
+	case *interview.InterviewEvent:
+		queueInterview(e)
+	}
+}
 
+// InterviewHandler 把interview的结果写到节点记录里，在C4Tick的线程里调用
+func InterviewHandler(device *interview.StDevice) {
+	nodeID := findNodeIDbyEui64(device.Eui64)
+	value, ok := Nodes.Load(nodeID) // 从map中加载
+	if !ok {
+		common.Log.Debugf("interview result of 0x%016x, node not found", device.Eui64)
+		return
+	}
+	node, ok := value.(StNode)
+	if !ok {
+		common.Log.Errorf("Nodes map unsupported type")
+		return
+	}
+	node.Interview = device
+	Nodes.Store(node.NodeID, node) // map中存储
 }
 
 func TrustCenterJoinHandler(newNodeId uint16,
@@ -474,7 +547,7 @@ func MessageSentHandler(outgoingMessageType byte,
 	if apsFrame.ProfileId == C4_PROFILE || apsFrame.ProfileId == ZDO_PROFILE {
 		return
 	}
-	if messageTag == 0 { //应用层不关心时tag==0
+	if messageTag == 0 || ezsp.IsConfirmedTag(messageTag) { //应用层不关心时tag==0，确认发送的结果由句柄返回
 		return
 	}
 	var node StNode
@@ -578,9 +651,13 @@ func IncomingMessageHandler(incomingMessageType byte,
 				respFrame.ClusterId = apsFrame.ClusterId
 				respFrame.SourceEndpoint = apsFrame.DestinationEndpoint
 				respFrame.DestinationEndpoint = apsFrame.SourceEndpoint
-				respFrame.Options = getSendOptions(sender, respFrame.ProfileId, respFrame.ClusterId, byte(len(resp)))
+				respFrame.Options, _ = getSendOptions(sender, respFrame.ProfileId, respFrame.ClusterId, len(resp))
 
-				ezsp.EzspSendUnicast(ezsp.EMBER_OUTGOING_DIRECT, sender, &respFrame, 0, resp)
+				if len(resp) > ezsp.MAX_UNFRAGMENTED_LENGTH { // 回复不分片
+					common.Log.Errorf("C25D response length %d exceeds %d", len(resp), ezsp.MAX_UNFRAGMENTED_LENGTH)
+				} else {
+					ezsp.EzspSendUnicast(ezsp.EMBER_OUTGOING_DIRECT, sender, &respFrame, 0, resp)
+				}
 			} else if incomingMessageType == ezsp.EMBER_INCOMING_BROADCAST {
 				ezsp.NcpSendMTORR()
 			}
@@ -601,43 +678,74 @@ func IncomingMessageHandler(incomingMessageType byte,
 	}
 }
 
-var unicastTagSequence = byte(0)
+func unicastApsFrame(eui64 uint64, profileId uint16, clusterId uint16,
+	localEndpoint byte, remoteEndpoint byte, message []byte) (nodeID uint16, apsFrame ezsp.EmberApsFrame, err error) {
+	nodeID = findNodeIDbyEui64(eui64)
+	if nodeID == ezsp.EMBER_NULL_NODE_ID {
+		err = fmt.Errorf("unknow EUI64 %016x", eui64)
+		return
+	}
+	apsFrame.ProfileId = profileId
+	apsFrame.ClusterId = clusterId
+	apsFrame.SourceEndpoint = localEndpoint
+	apsFrame.DestinationEndpoint = remoteEndpoint
+	apsFrame.Options, err = getSendOptions(nodeID, profileId, clusterId, len(message))
+	return
+}
 
-func nextSequence() byte {
-	unicastTagSequence++
-	if unicastTagSequence == 0 || unicastTagSequence == 0xff {
-		unicastTagSequence = 1
+// sendQueued 放进 ezsp.DefaultSendQueue，同一个节点的消息按顺序发送，失败后重发，发送前设置源路由
+func sendQueued(eui64 uint64, profileId uint16, clusterId uint16,
+	localEndpoint byte, remoteEndpoint byte, message []byte) (*ezsp.OutgoingMessage, error) {
+	nodeID, apsFrame, err := unicastApsFrame(eui64, profileId, clusterId, localEndpoint, remoteEndpoint, message)
+	if err != nil {
+		return nil, err
 	}
-	return unicastTagSequence
+	return ezsp.SendQueued(ezsp.EMBER_OUTGOING_DIRECT, nodeID, &apsFrame, message)
 }
 
+// SendUnicast 把单播放进发送队列后返回，needConfirm为true时最终结果通过 C4MessageSentHandler 上报，
+// 上报和其它callback一样在C4Tick里进行
 func SendUnicast(eui64 uint64, profileId uint16, clusterId uint16,
 	localEndpoint byte, remoteEndpoint byte,
 	message []byte, needConfirm bool) (err error) {
 	common.Log.Debugf("SendUnicast %016x ...", eui64)
 
-	nodeID := findNodeIDbyEui64(eui64)
-	if nodeID == ezsp.EMBER_NULL_NODE_ID {
-		return fmt.Errorf("unknow EUI64 %016x", eui64)
+	m, err := sendQueued(eui64, profileId, clusterId, localEndpoint, remoteEndpoint, message)
+	if err != nil {
+		return
 	}
-	var apsFrame ezsp.EmberApsFrame
-	apsFrame.ProfileId = profileId
-	apsFrame.ClusterId = clusterId
-	apsFrame.SourceEndpoint = localEndpoint
-	apsFrame.DestinationEndpoint = remoteEndpoint
-	apsFrame.Options = getSendOptions(nodeID, profileId, clusterId, byte(len(message)))
-	tag := byte(0)
 	if needConfirm {
-		tag = nextSequence()
+		go func() {
+			err := m.Wait()
+			if err != nil {
+				common.Log.Errorf("SendUnicast %016x failed: %v", eui64, err)
+			}
+			sentCh <- &stMessageSent{eui64, profileId, clusterId, localEndpoint, remoteEndpoint, message, err == nil}
+		}()
 	}
-
-	// todo 设置路由表
-	err = ezsp.NcpSetSourceRoute(nodeID)
-	_, err = ezsp.EzspSendUnicast(ezsp.EMBER_OUTGOING_DIRECT, nodeID, &apsFrame, tag, message)
 	return
 }
 
-func getSendOptions(destination uint16, profileId uint16, clusterId uint16, messageLength byte) (options uint16) {
+// SendUnicastConfirmed 把单播放进发送队列，返回的句柄在收到APS ACK的结果、重发用完或者超时后完成，
+// 结果只通过句柄返回，不会再通过 C4MessageSentHandler 上报
+func SendUnicastConfirmed(eui64 uint64, profileId uint16, clusterId uint16,
+	localEndpoint byte, remoteEndpoint byte,
+	message []byte) (*ezsp.SendHandle, error) {
+	common.Log.Debugf("SendUnicastConfirmed %016x ...", eui64)
+
+	m, err := sendQueued(eui64, profileId, clusterId, localEndpoint, remoteEndpoint, message)
+	if err != nil {
+		return nil, err
+	}
+	return m.Handle(), nil
+}
+
+// getSendOptions 按消息长度选择APS选项，单帧放不下时去掉EUI64选项，超过分片能发送的长度时返回错误
+func getSendOptions(destination uint16, profileId uint16, clusterId uint16, messageLength int) (options uint16, err error) {
+	if messageLength > ezsp.MaxMessageLength() {
+		err = fmt.Errorf("message length %d exceeds max %d", messageLength, ezsp.MaxMessageLength())
+		return
+	}
 	if profileId == 0xc25d && clusterId == 0x0001 {
 		if destination >= ezsp.EMBER_BROADCAST_ADDRESS {
 			options = /*ezsp.EMBER_APS_OPTION_RETRY |*/ ezsp.EMBER_APS_OPTION_SOURCE_EUI64
@@ -648,7 +756,7 @@ func getSendOptions(destination uint16, profileId uint16, clusterId uint16, mess
 
 		if messageLength <= 66 { /*66不溢*/
 			options = /*ezsp.EMBER_APS_OPTION_RETRY | */ ezsp.EMBER_APS_OPTION_SOURCE_EUI64 | ezsp.EMBER_APS_OPTION_DESTINATION_EUI64
-		} else if messageLength <= 74 { /*67~74*/
+		} else if messageLength <= ezsp.MAX_UNFRAGMENTED_LENGTH { /*67~74*/
 			options = /*ezsp.EMBER_APS_OPTION_RETRY | */ ezsp.EMBER_APS_OPTION_SOURCE_EUI64
 		} else {
 			options = ezsp.EMBER_APS_OPTION_NONE
@@ -672,7 +780,7 @@ func RemoveDevice(eui64 uint64) (err error) {
 
 func RemoveNetwork() (err error) {
 	common.Log.Debugf("RemoveNetwork()")
-	if !ezsp.MeshStatusUp {
+	if !ezsp.MeshStatusUp() {
 		return ErrMeshNotExist
 	}
 	notEmpty := false
@@ -694,7 +802,7 @@ func SetRadioChannel(channel byte) (err error) {
 
 func FormNetwork(radioChannel byte) (err error) {
 	common.Log.Debugf("FormNetwork(%d)", radioChannel)
-	if ezsp.MeshStatusUp {
+	if ezsp.MeshStatusUp() {
 		return ErrMeshAlreadyExist
 	} else {
 		return ezsp.NcpFormNetwork(radioChannel, true)
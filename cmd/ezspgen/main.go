@@ -0,0 +1,321 @@
+// ezspgen 从 ezsp/spec/ezsp.json 生成EZSP的常量、名字表、结构体和命令，
+// 在ezsp目录下用 go generate 运行
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+type stSpec struct {
+	Enums    []stEnum    `json:"enums"`
+	Structs  []stStruct  `json:"structs"`
+	Commands []stCommand `json:"commands"`
+}
+
+// stEnum 一组常量，可以带名字表和转换成名字的函数
+type stEnum struct {
+	Comment    string        `json:"comment"`
+	Type       string        `json:"type"`
+	NameFunc   string        `json:"nameFunc"`
+	NameMap    string        `json:"nameMap"`
+	Unknown    string        `json:"unknown"`    // 名字表里没有时的格式，参数是值
+	TrimPrefix string        `json:"trimPrefix"` // 名字表里的名字去掉这个前缀
+	Groups     []stEnumGroup `json:"groups"`
+}
+
+type stEnumGroup struct {
+	Comment string        `json:"comment"`
+	Values  []stEnumValue `json:"values"`
+}
+
+type stEnumValue struct {
+	Name     string   `json:"name"`
+	Value    string   `json:"value"`
+	Doc      []string `json:"doc"`
+	Callback bool     `json:"callback"` // 帧ID是callback，放进allCallbackIDs
+}
+
+type stStruct struct {
+	Name   string    `json:"name"`
+	Doc    []string  `json:"doc"`
+	Fields []stField `json:"fields"`
+}
+
+// stField 结构体字段、命令参数或者回复，Tag是 Marshal/Unmarshal 的ezsp tag
+type stField struct {
+	Name string   `json:"name"`
+	Type string   `json:"type"`
+	Tag  string   `json:"tag"`
+	Doc  []string `json:"doc"`
+}
+
+// stCommand 一个EZSP命令，Status为ember/ezsp时回复的第一个字节是状态，不成功时返回 EmberError/EzspError
+type stCommand struct {
+	Name     string    `json:"name"`
+	Frame    string    `json:"frame"`
+	Status   string    `json:"status"`
+	Doc      []string  `json:"doc"`
+	Params   []stField `json:"params"`
+	Response []stField `json:"response"`
+}
+
+func main() {
+	specFile := flag.String("spec", "spec/ezsp.json", "spec file")
+	constFile := flag.String("const", "ezsp_const_gen.go", "output file of enums")
+	apiFile := flag.String("api", "ezsp_api_gen.go", "output file of structs and commands")
+	pkg := flag.String("pkg", "ezsp", "package name")
+	flag.Parse()
+
+	data, err := ioutil.ReadFile(*specFile)
+	if err != nil {
+		fatal(err)
+	}
+	var spec stSpec
+	if err = json.Unmarshal(data, &spec); err != nil {
+		fatal(fmt.Errorf("parse %s: %v", *specFile, err))
+	}
+	if err = write(*constFile, genConst(&spec, *pkg)); err != nil {
+		fatal(err)
+	}
+	if err = write(*apiFile, genApi(&spec, *pkg)); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "ezspgen: %v\n", err)
+	os.Exit(1)
+}
+
+func write(name string, src []byte) error {
+	out, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("format %s: %v\n%s", name, err, src)
+	}
+	return ioutil.WriteFile(name, out, 0644)
+}
+
+func header(b *bytes.Buffer, pkg string, imports ...string) {
+	fmt.Fprintf(b, "// Code generated by ezspgen from spec/ezsp.json. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	if len(imports) > 0 {
+		fmt.Fprintf(b, "import (\n")
+		for _, i := range imports {
+			fmt.Fprintf(b, "\t%q\n", i)
+		}
+		fmt.Fprintf(b, ")\n\n")
+	}
+}
+
+func comment(b *bytes.Buffer, indent string, doc []string) {
+	for _, l := range doc {
+		fmt.Fprintf(b, "%s// %s\n", indent, l)
+	}
+}
+
+func genConst(spec *stSpec, pkg string) []byte {
+	var b bytes.Buffer
+	header(&b, pkg, "fmt")
+	for _, e := range spec.Enums {
+		fmt.Fprintf(&b, "// **************** %s ****************\nconst (\n", e.Comment)
+		for i, g := range e.Groups {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			if g.Comment != "" {
+				fmt.Fprintf(&b, "\t// %s\n", g.Comment)
+			}
+			for _, v := range g.Values {
+				comment(&b, "\t", v.Doc)
+				fmt.Fprintf(&b, "\t%s = %s(%s)\n", v.Name, e.Type, v.Value)
+			}
+		}
+		b.WriteString(")\n\n")
+
+		if e.NameMap == "" {
+			continue
+		}
+		if e.NameFunc != "" {
+			fmt.Fprintf(&b, "// ID to string\nfunc %s(id %s) string {\n", e.NameFunc, e.Type)
+			fmt.Fprintf(&b, "\tname, ok := %s[id]\n\tif !ok {\n\t\tname = fmt.Sprintf(%q, id)\n\t}\n\treturn name\n}\n\n", e.NameMap, e.Unknown)
+		}
+		fmt.Fprintf(&b, "var %s = map[%s]string{\n", e.NameMap, e.Type)
+		for i, g := range e.Groups {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			if g.Comment != "" {
+				fmt.Fprintf(&b, "\t// %s\n", g.Comment)
+			}
+			for _, v := range g.Values {
+				fmt.Fprintf(&b, "\t%s: %q,\n", v.Name, strings.TrimPrefix(v.Name, e.TrimPrefix))
+			}
+		}
+		b.WriteString("}\n\n")
+	}
+
+	b.WriteString("var allCallbackIDs = [...]uint16{\n")
+	for _, e := range spec.Enums {
+		for _, g := range e.Groups {
+			for _, v := range g.Values {
+				if v.Callback {
+					fmt.Fprintf(&b, "\t%s,\n", v.Name)
+				}
+			}
+		}
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+func exported(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// fieldList 生成结构体字段
+func fieldList(b *bytes.Buffer, fields []stField) {
+	for _, f := range fields {
+		comment(b, "\t", f.Doc)
+		if f.Tag != "" {
+			fmt.Fprintf(b, "\t%s %s `ezsp:%q`\n", exported(f.Name), f.Type, f.Tag)
+		} else {
+			fmt.Fprintf(b, "\t%s %s\n", exported(f.Name), f.Type)
+		}
+	}
+}
+
+// signature 返回参数列表、参数名、返回值列表
+func signature(cmd *stCommand) (params string, args string, results string) {
+	var p, a, r []string
+	for _, f := range cmd.Params {
+		p = append(p, f.Name+" "+f.Type)
+		a = append(a, f.Name)
+	}
+	for _, f := range cmd.Response {
+		r = append(r, f.Name+" "+f.Type)
+	}
+	r = append(r, "err error")
+	return strings.Join(p, ", "), strings.Join(a, ", "), strings.Join(r, ", ")
+}
+
+// verb 格式化参数和回复用的动词，帧ID、状态等无符号整数和字节串用十六进制
+func verb(typ string) string {
+	switch typ {
+	case "byte", "uint16", "uint32", "uint64", "[]byte":
+		return "0x%x"
+	case "bool", "int8", "[]uint16":
+		return "%v"
+	}
+	return "%+v"
+}
+
+func genApi(spec *stSpec, pkg string) []byte {
+	var b bytes.Buffer
+	imports := []string{"context"}
+	for _, cmd := range spec.Commands {
+		if cmd.Status != "" && len(cmd.Params) > 0 { // 错误信息里格式化参数
+			imports = append(imports, "fmt")
+			break
+		}
+	}
+	header(&b, pkg, imports...)
+
+	for _, s := range spec.Structs {
+		comment(&b, "", s.Doc)
+		fmt.Fprintf(&b, "type %s struct {\n", s.Name)
+		fieldList(&b, s.Fields)
+		b.WriteString("}\n\n")
+	}
+
+	for i := range spec.Commands {
+		cmd := &spec.Commands[i]
+		name := "Ezsp" + cmd.Name
+		params, args, results := signature(cmd)
+		ctxParams := "ctx context.Context"
+		if params != "" {
+			ctxParams += ", " + params
+		}
+		bgArgs := "context.Background()"
+		if args != "" {
+			bgArgs += ", " + args
+		}
+
+		var formats []string
+		for _, f := range cmd.Params {
+			formats = append(formats, verb(f.Type))
+		}
+		occurAt := fmt.Sprintf("%q", name+"("+strings.Join(formats, ", ")+")")
+		if args != "" {
+			occurAt = fmt.Sprintf("fmt.Sprintf(%s, %s)", occurAt, args)
+		}
+
+		comment(&b, "", cmd.Doc)
+		fmt.Fprintf(&b, "func (c *Client) %sContext(%s) (%s) {\n", name, ctxParams, results)
+		paramsValue := "nil"
+		if len(cmd.Params) > 0 {
+			b.WriteString("\tparams := struct {\n")
+			fieldList(&b, cmd.Params)
+			fmt.Fprintf(&b, "\t}{%s}\n", args)
+			paramsValue = "&params"
+		}
+		if cmd.Status == "" && len(cmd.Response) == 0 {
+			b.WriteString("\tvar resp struct{}\n")
+		} else {
+			b.WriteString("\tvar resp struct {\n")
+			if cmd.Status != "" {
+				b.WriteString("\t\tStatus byte\n")
+			}
+			fieldList(&b, cmd.Response)
+			b.WriteString("\t}\n")
+		}
+		fmt.Fprintf(&b, "\terr = c.ezspCommand(ctx, %s, %s, &resp)\n\tif err == nil {\n", cmd.Frame, paramsValue)
+		for _, f := range cmd.Response {
+			fmt.Fprintf(&b, "\t\t%s = resp.%s\n", f.Name, exported(f.Name))
+		}
+		switch cmd.Status {
+		case "ember":
+			fmt.Fprintf(&b, "\t\tif resp.Status != EMBER_SUCCESS {\n\t\t\terr = EmberError{resp.Status, %s}\n\t\t\treturn\n\t\t}\n", occurAt)
+		case "ezsp":
+			fmt.Fprintf(&b, "\t\tif resp.Status != EZSP_SUCCESS {\n\t\t\terr = EzspError{resp.Status, %s}\n\t\t\treturn\n\t\t}\n", occurAt)
+		}
+		trace := name + "(" + strings.Join(formats, ", ") + ")"
+		traceArgs := cmd.Params
+		if len(cmd.Response) > 0 {
+			var rf []string
+			for _, f := range cmd.Response {
+				rf = append(rf, f.Name+"("+verb(f.Type)+")")
+			}
+			trace += " get " + strings.Join(rf, " ")
+		}
+		traceArgs = append(append([]stField{}, traceArgs...), cmd.Response...)
+		fmt.Fprintf(&b, "\t\tezspApiTrace(%q", trace)
+		for _, f := range traceArgs {
+			fmt.Fprintf(&b, ", %s", f.Name)
+		}
+		b.WriteString(")\n\t}\n\treturn\n}\n\n")
+
+		fmt.Fprintf(&b, "func (c *Client) %s(%s) (%s) {\n\treturn c.%sContext(%s)\n}\n\n", name, params, results, name, bgArgs)
+	}
+
+	b.WriteString("// DefaultClient 上的EZSP命令\n\n")
+	for i := range spec.Commands {
+		cmd := &spec.Commands[i]
+		name := "Ezsp" + cmd.Name
+		params, args, results := signature(cmd)
+		ctxParams := "ctx context.Context"
+		ctxArgs := "ctx"
+		if params != "" {
+			ctxParams += ", " + params
+			ctxArgs += ", " + args
+		}
+		fmt.Fprintf(&b, "func %s(%s) (%s) {\n\treturn DefaultClient.%s(%s)\n}\n\n", name, params, results, name, args)
+		fmt.Fprintf(&b, "func %sContext(%s) (%s) {\n\treturn DefaultClient.%sContext(%s)\n}\n\n", name, ctxParams, results, name, ctxArgs)
+	}
+	return b.Bytes()
+}
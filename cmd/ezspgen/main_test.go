@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/format"
+	"io/ioutil"
+	"testing"
+)
+
+// TestGenerated 生成的代码和提交的 ezsp_const_gen.go、ezsp_api_gen.go 一致，
+// 修改spec或者生成器以后要在ezsp目录下运行 go generate
+func TestGenerated(t *testing.T) {
+	data, err := ioutil.ReadFile("../../ezsp/spec/ezsp.json")
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	var spec stSpec
+	if err = json.Unmarshal(data, &spec); err != nil {
+		t.Fatalf("parse spec: %v", err)
+	}
+	for name, src := range map[string][]byte{
+		"../../ezsp/ezsp_const_gen.go": genConst(&spec, "ezsp"),
+		"../../ezsp/ezsp_api_gen.go":   genApi(&spec, "ezsp"),
+	} {
+		want, err := format.Source(src)
+		if err != nil {
+			t.Fatalf("format %s: %v", name, err)
+		}
+		got, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is out of date, run go generate in ezsp", name)
+		}
+	}
+}
+
+func TestVerb(t *testing.T) {
+	cases := map[string]string{
+		"byte":                   "0x%x",
+		"uint16":                 "0x%x",
+		"[]byte":                 "0x%x",
+		"int8":                   "%v",
+		"bool":                   "%v",
+		"[]uint16":               "%v",
+		"EmberNetworkParameters": "%+v",
+	}
+	for typ, want := range cases {
+		if got := verb(typ); got != want {
+			t.Errorf("verb(%s) = %s, want %s", typ, got, want)
+		}
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/conthing/ezsp/ash"
+	"github.com/conthing/ezsp/ezsp/ncpsim"
 	"github.com/conthing/ezsp/zgb"
 
 	"github.com/conthing/utils/common"
@@ -20,9 +22,10 @@ type stConfig struct {
 }
 
 type stSerialConfig struct {
-	Name   string
-	Baud   uint
-	RtsCts bool
+	Name        string
+	Baud        uint
+	FlowControl string // none/software/hardware，为空时按RtsCts选择
+	RtsCts      bool
 }
 
 var cfg = stConfig{}
@@ -50,14 +53,40 @@ func boot(_ interface{}) (needRetry bool, err error) {
 	zgb.TraceSet(&cfg.TraceSettings)
 	zgb.NetworkSet(&cfg.NetworkSettings)
 
-	err = ash.AshSerialOpen(cfg.Serial.Name, cfg.Serial.Baud, cfg.Serial.RtsCts)
-	if err != nil {
-		common.Log.Errorf("failed to open serial %v", cfg.Serial.Name)
-		return false, err
+	var transport ash.Transport
+	if cfg.Serial.Name == "sim" {
+		// 没有硬件时使用内存里的模拟NCP
+		transport = ncpsim.New()
+		common.Log.Infof("Use NCP simulator")
+	} else if strings.HasPrefix(cfg.Serial.Name, "tcp://") {
+		// NCP挂在ser2net之类的串口服务器上
+		transport, err = ash.AshTcpOpen(strings.TrimPrefix(cfg.Serial.Name, "tcp://"))
+		if err != nil {
+			common.Log.Errorf("failed to connect %v", cfg.Serial.Name)
+			return false, err
+		}
+		common.Log.Infof("Connect success %s", cfg.Serial.Name)
+	} else {
+		flowControl := ash.FLOW_CONTROL_SOFTWARE
+		if cfg.Serial.FlowControl != "" {
+			flowControl, err = ash.ParseFlowControl(cfg.Serial.FlowControl)
+			if err != nil {
+				common.Log.Errorf("invalid serial config: %v", err)
+				return false, err
+			}
+		} else if cfg.Serial.RtsCts {
+			flowControl = ash.FLOW_CONTROL_HARDWARE
+		}
+		transport, err = ash.AshSerialOpenFlowControl(cfg.Serial.Name, cfg.Serial.Baud, flowControl)
+		if err != nil {
+			common.Log.Errorf("failed to open serial %v", cfg.Serial.Name)
+			return false, err
+		}
+
+		// Time it took to start service
+		common.Log.Infof("Open Serial success port=%s baud=%d flowcontrol=%s", cfg.Serial.Name, cfg.Serial.Baud, flowControl)
 	}
-
-	// Time it took to start service
-	common.Log.Infof("Open Serial success port=%s baud=%d", cfg.Serial.Name, cfg.Serial.Baud)
+	zgb.TransportSet(transport)
 
 	return false, nil
 }
@@ -82,7 +111,7 @@ func main() {
 
 func listenForInterrupt(errChan chan error) {
 	go func() {
-		c := make(chan os.Signal)
+		c := make(chan os.Signal, 1)
 		signal.Notify(c, syscall.SIGINT)
 		errChan <- fmt.Errorf("%s", <-c)
 	}()
@@ -3,7 +3,7 @@
 package ezsp
 
 import (
-	"encoding/binary"
+	"context"
 	"fmt"
 
 	"github.com/conthing/utils/common"
@@ -18,17 +18,6 @@ type EzspError struct {
 	OccurAt    string
 }
 
-type EmberNetworkParameters struct {
-	ExtendedPanId uint64
-	PanId         uint16
-	RadioTxPower  int8
-	RadioChannel  byte
-	JoinMethod    byte
-	NwkManagerId  uint16
-	NwkUpdateId   byte
-	Channels      uint32
-}
-
 /** @brief This describes the Initial Security features and requirements that
  *  will be used when forming or joining the network.  */
 type EmberInitialSecurityState struct {
@@ -69,32 +58,6 @@ type EmberInitialSecurityState struct {
 	preconfiguredTrustCenterEui64 uint64
 }
 
-type EmberApsFrame struct {
-	/** The application profile ID that describes the format of the message. */
-	ProfileId uint16
-	/** The cluster ID for this message. */
-	ClusterId uint16
-	/** The source endpoint. */
-	SourceEndpoint byte
-	/** The destination endpoint. */
-	DestinationEndpoint byte
-	/** A bitmask of options from the enumeration above. */
-	Options uint16
-	/** The group ID for this message, if it is multicast mode. */
-	GroupId uint16
-	/** The sequence number. */
-	Sequence byte
-}
-
-type EmberZigbeeNetwork struct {
-	Channel       byte
-	PanId         uint16
-	ExtendedPanId uint64
-	AllowingJoin  bool
-	StackProfile  byte
-	NwkUpdateId   byte
-}
-
 func (e EmberError) Error() string {
 	return fmt.Sprintf("%s get error emberStatus(%s)", e.OccurAt, emberStatusToString(e.EmberStatus))
 }
@@ -111,7 +74,7 @@ func ezspApiTrace(format string, v ...interface{}) {
 	}
 }
 
-func generalResponseError(response *EzspFrame, cmdID byte) error {
+func generalResponseError(response *EzspFrame, cmdID uint16) error {
 	if response == nil {
 		return fmt.Errorf("EZSP cmd 0x%x return nil response", cmdID)
 	}
@@ -127,762 +90,324 @@ func generalResponseError(response *EzspFrame, cmdID byte) error {
 	return nil
 }
 
-func generalResponseLengthEqual(response *EzspFrame, cmdID byte, respLen int) error {
-	if len(response.Data) != respLen {
-		return fmt.Errorf("EZSP cmd 0x%x get invalid response length, expect(%d) get(%d)", cmdID, respLen, len(response.Data))
-	}
-	return nil
-}
-
-func generalResponseLengthNoLessThan(response *EzspFrame, cmdID byte, respLen int) error {
-	if len(response.Data) < respLen {
-		return fmt.Errorf("EZSP cmd 0x%x get invalid response length, expect(>=%d) get(%d)", cmdID, respLen, len(response.Data))
-	}
-	return nil
+// stStatusResponse 只有一个状态字节的回复
+type stStatusResponse struct {
+	Status byte
 }
 
-func EzspVersion(desiredProtocolVersion byte) (protocolVersion byte, stackType byte, stackVersion uint16, err error) {
-	response, err := EzspFrameSend(EZSP_VERSION, []byte{desiredProtocolVersion})
-	if err == nil {
-		err = generalResponseError(response, EZSP_VERSION)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_VERSION, 4)
-			if err == nil {
-				protocolVersion = response.Data[0]
-				stackType = response.Data[1]
-				stackVersion = binary.LittleEndian.Uint16(response.Data[2:])
-				if desiredProtocolVersion != protocolVersion {
-					err = fmt.Errorf("EzspVersion get unexpected protocolVersion(0x%x) != desired(0x%x)", protocolVersion, desiredProtocolVersion)
-					return
-				}
-				ezspApiTrace("EzspVersion get protocolVersion(0x%x) stackType(0x%x) stackVersion(0x%x)", protocolVersion, stackType, stackVersion)
-			}
+// ezspCommand 用 Marshal 编码params（nil表示没有参数）发送命令，回复用 Unmarshal 解码到response，
+// 回复的长度和response的结构不一致时返回错误
+func (c *Client) ezspCommand(ctx context.Context, cmdID uint16, params interface{}, response interface{}) error {
+	var data []byte
+	if params != nil {
+		var err error
+		data, err = Marshal(params)
+		if err != nil {
+			return fmt.Errorf("EZSP cmd 0x%x marshal params failed: %v", cmdID, err)
 		}
 	}
-	return
-}
-
-func EzspGetValue(valueId byte) (value []byte, err error) {
-	response, err := EzspFrameSend(EZSP_GET_VALUE, []byte{valueId})
-	if err == nil {
-		err = generalResponseError(response, EZSP_GET_VALUE)
-		if err == nil {
-			err = generalResponseLengthNoLessThan(response, EZSP_GET_VALUE, 2)
-			if err == nil {
-				ezspStatus := response.Data[0]
-				valueLength := response.Data[1]
-				err = generalResponseLengthEqual(response, EZSP_GET_VALUE, 2+int(valueLength))
-				if err == nil {
-					if ezspStatus != EZSP_SUCCESS {
-						err = EzspError{ezspStatus, fmt.Sprintf("EzspGetValue(0x%x)", valueId)}
-						return
-					}
-					value = response.Data[2:]
-					ezspApiTrace("EzspGetValue(0x%x) get value(0x%x)", valueId, value)
-				}
-			}
-		}
+	resp, err := c.EzspFrameSendContext(ctx, cmdID, data)
+	if err != nil {
+		return err
 	}
-	return
-}
-
-func EzspSetValue(valueId byte, value []byte) (err error) {
-	data := []byte{valueId, byte(len(value))}
-	data = append(data, value...)
-	response, err := EzspFrameSend(EZSP_SET_VALUE, data)
-	if err == nil {
-		err = generalResponseError(response, EZSP_SET_VALUE)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_SET_VALUE, 1)
-			if err == nil {
-				ezspStatus := response.Data[0]
-				if ezspStatus != EZSP_SUCCESS {
-					err = EzspError{ezspStatus, fmt.Sprintf("EzspSetValue(0x%x, 0x%x)", valueId, value)}
-					return
-				}
-				ezspApiTrace("EzspSetValue(0x%x, 0x%x)", valueId, value)
-			}
-		}
+	err = generalResponseError(resp, cmdID)
+	if err != nil {
+		return err
 	}
-	return
-}
-
-func EzspGetConfigurationValue(configId byte) (value uint16, err error) {
-	response, err := EzspFrameSend(EZSP_GET_CONFIGURATION_VALUE, []byte{configId})
-	if err == nil {
-		err = generalResponseError(response, EZSP_GET_CONFIGURATION_VALUE)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_GET_CONFIGURATION_VALUE, 3)
-			if err == nil {
-				ezspStatus := response.Data[0]
-				value = binary.LittleEndian.Uint16(response.Data[1:])
-				if ezspStatus != EZSP_SUCCESS {
-					err = EzspError{ezspStatus, fmt.Sprintf("EzspGetConfigurationValue(%s)", configIDToName(configId))}
-					return
-				}
-				ezspApiTrace("EzspGetConfigurationValue(%s) get 0x%x", configIDToName(configId), value)
-			}
-		}
+	err = Unmarshal(resp.Data, response)
+	if err != nil {
+		return fmt.Errorf("EZSP cmd 0x%x get invalid response: %v", cmdID, err)
 	}
-	return
+	return nil
 }
 
-func EzspSetConfigurationValue(configId byte, value uint16) (err error) {
-	response, err := EzspFrameSend(EZSP_SET_CONFIGURATION_VALUE, []byte{configId, byte(value), byte(value >> 8)})
-	if err == nil {
-		err = generalResponseError(response, EZSP_SET_CONFIGURATION_VALUE)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_SET_CONFIGURATION_VALUE, 1)
-			if err == nil {
-				ezspStatus := response.Data[0]
-				if ezspStatus != EZSP_SUCCESS {
-					err = EzspError{ezspStatus, fmt.Sprintf("EzspSetConfigurationValue(%s, 0x%x)", configIDToName(configId), value)}
-					return
-				}
-				ezspApiTrace("EzspSetConfigurationValue(%s, 0x%x) success", configIDToName(configId), value)
-			}
-		}
-	}
-	return
-}
+// 一般的EZSP命令由 ezspgen 从 spec/ezsp.json 生成，见 ezsp_api_gen.go。
+// 下面的命令要协商版本、按版本解码、传指针参数或者做额外检查，保留手写
 
-func EzspSetPolicy(policyId byte, decisionId byte) (err error) {
-	response, err := EzspFrameSend(EZSP_SET_POLICY, []byte{policyId, decisionId})
-	if err == nil {
-		err = generalResponseError(response, EZSP_SET_POLICY)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_SET_POLICY, 1)
-			if err == nil {
-				ezspStatus := response.Data[0]
-				if ezspStatus != EZSP_SUCCESS {
-					err = EzspError{ezspStatus, fmt.Sprintf("EzspSetPolicy(0x%x, 0x%x)", policyId, decisionId)}
-					return
-				}
-				ezspApiTrace("EzspSetPolicy(0x%x, 0x%x) success", policyId, decisionId)
-			}
-		}
+func (c *Client) EzspVersionContext(ctx context.Context, desiredProtocolVersion byte) (protocolVersion byte, stackType byte, stackVersion uint16, err error) {
+	var resp struct {
+		ProtocolVersion byte
+		StackType       byte
+		StackVersion    uint16
 	}
-	return
-}
-
-func EzspGetEUI64() (eui64 uint64, err error) {
-	response, err := EzspFrameSend(EZSP_GET_EUI64, []byte{})
+	err = c.ezspCommand(ctx, EZSP_VERSION, struct{ DesiredProtocolVersion byte }{desiredProtocolVersion}, &resp)
 	if err == nil {
-		err = generalResponseError(response, EZSP_GET_EUI64)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_GET_EUI64, 8)
-			if err == nil {
-				eui64 = binary.LittleEndian.Uint64(response.Data)
-				ezspApiTrace("EzspGetEUI64 0x%016x", eui64)
-			}
-		}
-	}
-	return
-}
-
-func EzspSetGpioCurrentConfiguration(portPin byte, cfg byte, out byte) (err error) {
-	response, err := EzspFrameSend(EZSP_SET_GPIO_CURRENT_CONFIGURATION, []byte{portPin, cfg, out})
-	if err == nil {
-		err = generalResponseError(response, EZSP_SET_GPIO_CURRENT_CONFIGURATION)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_SET_GPIO_CURRENT_CONFIGURATION, 1)
-			if err == nil {
-				ezspStatus := response.Data[0]
-				if ezspStatus != EZSP_SUCCESS {
-					err = EzspError{ezspStatus, fmt.Sprintf("EzspSetGpioCurrentConfiguration(%d, %d, %d)", portPin, cfg, out)}
-					return
-				}
-				ezspApiTrace("EzspSetGpioCurrentConfiguration(%d, %d, %d) success", portPin, cfg, out)
-			}
+		protocolVersion, stackType, stackVersion = resp.ProtocolVersion, resp.StackType, resp.StackVersion
+		if desiredProtocolVersion != protocolVersion {
+			err = fmt.Errorf("EzspVersion get unexpected protocolVersion(0x%x) != desired(0x%x)", protocolVersion, desiredProtocolVersion)
+			return
 		}
+		c.setVersionInfo(newVersionInfo(protocolVersion, stackType, stackVersion)) // 之后的命令按协商的版本选择帧头
+		ezspApiTrace("EzspVersion get protocolVersion(0x%x) stackType(0x%x) stackVersion(0x%x)", protocolVersion, stackType, stackVersion)
 	}
 	return
 }
 
-func EzspSetRadioPower(power int8) (err error) {
-	response, err := EzspFrameSend(EZSP_SET_RADIO_POWER, []byte{byte(power)})
-	if err == nil {
-		err = generalResponseError(response, EZSP_SET_RADIO_POWER)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_SET_RADIO_POWER, 1)
-			if err == nil {
-				emberStatus := response.Data[0]
-				if emberStatus != EMBER_SUCCESS {
-					err = EmberError{emberStatus, fmt.Sprintf("EzspSetRadioPower(%d)", power)}
-					return
-				}
-				ezspApiTrace("EzspSetRadioPower(%d)", power)
-			}
-		}
-	}
-	return
+func (c *Client) EzspVersion(desiredProtocolVersion byte) (protocolVersion byte, stackType byte, stackVersion uint16, err error) {
+	return c.EzspVersionContext(context.Background(), desiredProtocolVersion)
 }
 
-func EzspGetMfgToken(tokenId byte) (tokenData []byte, err error) {
-	response, err := EzspFrameSend(EZSP_GET_MFG_TOKEN, []byte{tokenId})
-	if err == nil {
-		err = generalResponseError(response, EZSP_GET_MFG_TOKEN)
-		if err == nil {
-			err = generalResponseLengthNoLessThan(response, EZSP_GET_MFG_TOKEN, 1)
-			if err == nil {
-				valueLength := response.Data[0]
-				err = generalResponseLengthEqual(response, EZSP_GET_MFG_TOKEN, 1+int(valueLength))
-				if err == nil {
-					tokenData = response.Data[1:]
-					ezspApiTrace("EzspGetMfgToken(0x%x) get tokenData(0x%x)", tokenId, tokenData)
-				}
-			}
-		}
+func (c *Client) EzspGetTokenContext(ctx context.Context, tokenId byte) (tokenData []byte, err error) {
+	var resp struct {
+		EmberStatus byte
+		TokenData   [8]byte
 	}
-	return
-}
-
-func EzspSetMfgToken(tokenId byte, tokenData []byte) (err error) {
-	data := []byte{tokenId, byte(len(tokenData))}
-	data = append(data, tokenData...)
-	response, err := EzspFrameSend(EZSP_SET_MFG_TOKEN, data)
+	err = c.ezspCommand(ctx, EZSP_GET_TOKEN, struct{ TokenId byte }{tokenId}, &resp)
 	if err == nil {
-		err = generalResponseError(response, EZSP_SET_MFG_TOKEN)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_SET_MFG_TOKEN, 1)
-			if err == nil {
-				emberStatus := response.Data[0]
-				if emberStatus != EMBER_SUCCESS {
-					err = EmberError{emberStatus, fmt.Sprintf("EzspSetMfgToken(0x%x, 0x%x)", tokenId, tokenData)}
-					return
-				}
-				ezspApiTrace("EzspSetMfgToken(0x%x, 0x%x)", tokenId, tokenData)
-			}
+		tokenData = resp.TokenData[:]
+		if resp.EmberStatus != EMBER_SUCCESS {
+			err = EmberError{resp.EmberStatus, fmt.Sprintf("EzspGetToken(0x%x)", tokenId)}
+			return
 		}
+		ezspApiTrace("EzspGetToken(0x%x) get tokenData(0x%x)", tokenId, tokenData)
 	}
 	return
 }
 
-func EzspGetToken(tokenId byte) (tokenData []byte, err error) {
-	response, err := EzspFrameSend(EZSP_GET_TOKEN, []byte{tokenId})
-	if err == nil {
-		err = generalResponseError(response, EZSP_GET_TOKEN)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_GET_TOKEN, 9)
-			if err == nil {
-				emberStatus := response.Data[0]
-				tokenData = response.Data[1:]
-				if emberStatus != EMBER_SUCCESS {
-					err = EmberError{emberStatus, fmt.Sprintf("EzspGetToken(0x%x)", tokenId)}
-					return
-				}
-				ezspApiTrace("EzspGetToken(0x%x) get tokenData(0x%x)", tokenId, tokenData)
-			}
-		}
-	}
-	return
+func (c *Client) EzspGetToken(tokenId byte) (tokenData []byte, err error) {
+	return c.EzspGetTokenContext(context.Background(), tokenId)
 }
 
-func EzspSetToken(tokenId byte, tokenData []byte) (err error) {
+func (c *Client) EzspSetTokenContext(ctx context.Context, tokenId byte, tokenData []byte) (err error) {
 	if len(tokenData) != 8 {
 		err = fmt.Errorf("EzspSetToken(0x%x, 0x%x) tokenData lenght != 8", tokenId, tokenData)
 		return
 	}
-	data := []byte{tokenId}
-	data = append(data, tokenData...)
-	response, err := EzspFrameSend(EZSP_SET_TOKEN, data)
+	params := struct {
+		TokenId   byte
+		TokenData [8]byte
+	}{TokenId: tokenId}
+	copy(params.TokenData[:], tokenData)
+	var resp stStatusResponse
+	err = c.ezspCommand(ctx, EZSP_SET_TOKEN, &params, &resp)
 	if err == nil {
-		err = generalResponseError(response, EZSP_SET_TOKEN)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_SET_TOKEN, 1)
-			if err == nil {
-				emberStatus := response.Data[0]
-				if emberStatus != EMBER_SUCCESS {
-					err = EmberError{emberStatus, fmt.Sprintf("EzspSetToken(0x%x, 0x%x)", tokenId, tokenData)}
-					return
-				}
-				ezspApiTrace("EzspSetToken(0x%x, 0x%x)", tokenId, tokenData)
-			}
+		if resp.Status != EMBER_SUCCESS {
+			err = EmberError{resp.Status, fmt.Sprintf("EzspSetToken(0x%x, 0x%x)", tokenId, tokenData)}
+			return
 		}
+		ezspApiTrace("EzspSetToken(0x%x, 0x%x)", tokenId, tokenData)
 	}
 	return
 }
 
-func EzspGetNetworkParameters() (nodeType byte, parameters *EmberNetworkParameters, err error) {
-	response, err := EzspFrameSend(EZSP_GET_NETWORK_PARAMETERS, []byte{})
-	if err == nil {
-		err = generalResponseError(response, EZSP_GET_NETWORK_PARAMETERS)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_GET_NETWORK_PARAMETERS, 22)
-			if err == nil {
-				emberStatus := response.Data[0]
-				nodeType = response.Data[1]
-				p := EmberNetworkParameters{}
-				p.ExtendedPanId = binary.LittleEndian.Uint64(response.Data[2:])
-				p.PanId = binary.LittleEndian.Uint16(response.Data[10:])
-				p.RadioTxPower = int8(response.Data[12])
-				p.RadioChannel = response.Data[13]
-				p.JoinMethod = response.Data[14]
-				p.NwkManagerId = binary.LittleEndian.Uint16(response.Data[15:])
-				p.NwkUpdateId = response.Data[17]
-				p.Channels = binary.LittleEndian.Uint32(response.Data[18:])
-				parameters = &p
-
-				if emberStatus != EMBER_SUCCESS {
-					err = EmberError{emberStatus, "EzspGetNetworkParameters()"}
-					return
-				}
-				ezspApiTrace("EzspGetNetworkParameters() get nodeType(%d) parameters(%+v)", nodeType, *parameters)
-			}
-		}
-	}
-	return
+func (c *Client) EzspSetToken(tokenId byte, tokenData []byte) (err error) {
+	return c.EzspSetTokenContext(context.Background(), tokenId, tokenData)
 }
 
-func EzspNetworkInit() (err error) {
-	response, err := EzspFrameSend(EZSP_NETWORK_INIT, []byte{})
-	if err == nil {
-		err = generalResponseError(response, EZSP_NETWORK_INIT)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_NETWORK_INIT, 1)
-			if err == nil {
-				emberStatus := response.Data[0]
-				if emberStatus != EMBER_SUCCESS {
-					err = EmberError{emberStatus, "EzspNetworkInit()"}
-					return
-				}
-				ezspApiTrace("EzspNetworkInit()")
-			}
-		}
+func (c *Client) EzspGetNetworkParametersContext(ctx context.Context) (nodeType byte, parameters *EmberNetworkParameters, err error) {
+	var resp struct {
+		EmberStatus byte
+		NodeType    byte
+		Parameters  EmberNetworkParameters
 	}
-	return
-}
-
-func EzspFormNetwork(para *EmberNetworkParameters) (err error) {
-	data := make([]byte, 20)
-	binary.LittleEndian.PutUint64(data, para.ExtendedPanId)
-	binary.LittleEndian.PutUint16(data[8:], para.PanId)
-	data[10] = byte(para.RadioTxPower)
-	data[11] = para.RadioChannel
-	data[12] = para.JoinMethod
-	binary.LittleEndian.PutUint16(data[13:], para.NwkManagerId)
-	data[15] = para.NwkUpdateId
-	binary.LittleEndian.PutUint32(data[16:], para.Channels)
-
-	response, err := EzspFrameSend(EZSP_FORM_NETWORK, data)
+	err = c.ezspCommand(ctx, EZSP_GET_NETWORK_PARAMETERS, nil, &resp)
 	if err == nil {
-		err = generalResponseError(response, EZSP_FORM_NETWORK)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_FORM_NETWORK, 1)
-			if err == nil {
-				emberStatus := response.Data[0]
-				if emberStatus != EMBER_SUCCESS {
-					err = EmberError{emberStatus, "ezspFormNetwork()"}
-					return
-				}
-				ezspApiTrace("ezspFormNetwork()")
-			}
+		nodeType = resp.NodeType
+		parameters = &resp.Parameters
+		if resp.EmberStatus != EMBER_SUCCESS {
+			err = EmberError{resp.EmberStatus, "EzspGetNetworkParameters()"}
+			return
 		}
+		ezspApiTrace("EzspGetNetworkParameters() get nodeType(%d) parameters(%+v)", nodeType, *parameters)
 	}
 	return
 }
 
-func EzspSetInitialSecurityState(state *EmberInitialSecurityState) (err error) {
-	data := make([]byte, 43)
-	binary.LittleEndian.PutUint16(data, state.bitmask)
-	for i, v := range state.preconfiguredKey {
-		data[2+i] = v
-	}
-	for i, v := range state.networkKey {
-		data[18+i] = v
-	}
-	data[34] = state.networkKeySequenceNumber
-	binary.LittleEndian.PutUint64(data[35:], state.preconfiguredTrustCenterEui64)
-
-	response, err := EzspFrameSend(EZSP_SET_INITIAL_SECURITY_STATE, data)
-	if err == nil {
-		err = generalResponseError(response, EZSP_SET_INITIAL_SECURITY_STATE)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_SET_INITIAL_SECURITY_STATE, 1)
-			if err == nil {
-				emberStatus := response.Data[0]
-				if emberStatus != EMBER_SUCCESS {
-					err = EmberError{emberStatus, "EzspSetInitialSecurityState()"}
-					return
-				}
-				ezspApiTrace("EzspSetInitialSecurityState()")
-			}
-		}
-	}
-	return
+func (c *Client) EzspGetNetworkParameters() (nodeType byte, parameters *EmberNetworkParameters, err error) {
+	return c.EzspGetNetworkParametersContext(context.Background())
 }
 
-func EzspStartScan(scanType byte, channelMask uint32, duration byte) (err error) {
-	response, err := EzspFrameSend(EZSP_START_SCAN, []byte{scanType, byte(channelMask), byte(channelMask >> 8), byte(channelMask >> 16), byte(channelMask >> 24), duration})
+func (c *Client) EzspFormNetworkContext(ctx context.Context, para *EmberNetworkParameters) (err error) {
+	var resp stStatusResponse
+	err = c.ezspCommand(ctx, EZSP_FORM_NETWORK, para, &resp)
 	if err == nil {
-		err = generalResponseError(response, EZSP_START_SCAN)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_START_SCAN, 1)
-			if err == nil {
-				emberStatus := response.Data[0]
-				if emberStatus != EMBER_SUCCESS {
-					err = EmberError{emberStatus, "EzspStartScan()"}
-					return
-				}
-				ezspApiTrace("EzspStartScan()")
-			}
+		if resp.Status != EMBER_SUCCESS {
+			err = EmberError{resp.Status, "ezspFormNetwork()"}
+			return
 		}
+		ezspApiTrace("ezspFormNetwork()")
 	}
 	return
 }
 
-func EzspLookupEui64ByNodeId(nodeId uint16) (eui64 uint64, err error) {
-	response, err := EzspFrameSend(EZSP_LOOKUP_EUI64_BY_NODE_ID, []byte{byte(nodeId), byte(nodeId >> 8)})
-	if err == nil {
-		err = generalResponseError(response, EZSP_LOOKUP_EUI64_BY_NODE_ID)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_LOOKUP_EUI64_BY_NODE_ID, 9)
-			if err == nil {
-				emberStatus := response.Data[0]
-				eui64 = binary.LittleEndian.Uint64(response.Data[1:])
-				if emberStatus != EMBER_SUCCESS {
-					err = EmberError{emberStatus, fmt.Sprintf("EzspLookupEui64ByNodeId(%04x)", nodeId)}
-					return
-				}
-				ezspApiTrace("EzspLookupEui64ByNodeId(%04x) = 0x%016x", nodeId, eui64)
-			}
-		}
-	}
-	return
+func (c *Client) EzspFormNetwork(para *EmberNetworkParameters) (err error) {
+	return c.EzspFormNetworkContext(context.Background(), para)
 }
 
-func EzspLookupNodeIdByEui64(eui64 uint64) (nodeId uint16, err error) {
-	data := make([]byte, 8)
-	binary.LittleEndian.PutUint64(data, eui64)
-
-	response, err := EzspFrameSend(EZSP_LOOKUP_NODE_ID_BY_EUI64, data)
+func (c *Client) EzspSetInitialSecurityStateContext(ctx context.Context, state *EmberInitialSecurityState) (err error) {
+	var resp stStatusResponse
+	err = c.ezspCommand(ctx, EZSP_SET_INITIAL_SECURITY_STATE, state, &resp)
 	if err == nil {
-		err = generalResponseError(response, EZSP_LOOKUP_NODE_ID_BY_EUI64)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_LOOKUP_NODE_ID_BY_EUI64, 2)
-			if err == nil {
-				nodeId = binary.LittleEndian.Uint16(response.Data)
-				if nodeId == EMBER_NULL_NODE_ID {
-					err = fmt.Errorf("EzspLookupNodeIdByEui64(%016x) failed", eui64)
-					return
-				}
-				ezspApiTrace("EzspLookupNodeIdByEui64(%016x) = 0x%04x", eui64, nodeId)
-			}
+		if resp.Status != EMBER_SUCCESS {
+			err = EmberError{resp.Status, "EzspSetInitialSecurityState()"}
+			return
 		}
+		ezspApiTrace("EzspSetInitialSecurityState()")
 	}
 	return
 }
 
-func EzspAddressTableEntryIsActive(addressTableIndex byte) (active bool, err error) {
-	response, err := EzspFrameSend(EZSP_ADDRESS_TABLE_ENTRY_IS_ACTIVE, []byte{addressTableIndex})
-	if err == nil {
-		err = generalResponseError(response, EZSP_ADDRESS_TABLE_ENTRY_IS_ACTIVE)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_ADDRESS_TABLE_ENTRY_IS_ACTIVE, 1)
-			if err == nil {
-				active = (response.Data[0] != 0)
-				ezspApiTrace("EzspAddressTableEntryIsActive(%d) = %v", addressTableIndex, active)
-			}
-		}
-	}
-	return
+func (c *Client) EzspSetInitialSecurityState(state *EmberInitialSecurityState) (err error) {
+	return c.EzspSetInitialSecurityStateContext(context.Background(), state)
 }
 
-func EzspGetAddressTableRemoteEui64(addressTableIndex byte) (eui64 uint64, err error) {
-	response, err := EzspFrameSend(EZSP_GET_ADDRESS_TABLE_REMOTE_EUI64, []byte{addressTableIndex})
+func (c *Client) EzspLookupNodeIdByEui64Context(ctx context.Context, eui64 uint64) (nodeId uint16, err error) {
+	var resp struct{ NodeId uint16 }
+	err = c.ezspCommand(ctx, EZSP_LOOKUP_NODE_ID_BY_EUI64, struct{ Eui64 uint64 }{eui64}, &resp)
 	if err == nil {
-		err = generalResponseError(response, EZSP_GET_ADDRESS_TABLE_REMOTE_EUI64)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_GET_ADDRESS_TABLE_REMOTE_EUI64, 8)
-			if err == nil {
-				eui64 = binary.LittleEndian.Uint64(response.Data)
-				ezspApiTrace("EzspGetAddressTableRemoteEui64(%d) = 0x%016x", addressTableIndex, eui64)
-			}
+		nodeId = resp.NodeId
+		if nodeId == EMBER_NULL_NODE_ID {
+			err = fmt.Errorf("EzspLookupNodeIdByEui64(%016x) failed", eui64)
+			return
 		}
+		ezspApiTrace("EzspLookupNodeIdByEui64(%016x) = 0x%04x", eui64, nodeId)
 	}
 	return
 }
 
-func EzspGetAddressTableRemoteNodeId(addressTableIndex byte) (nodeID uint16, err error) {
-	response, err := EzspFrameSend(EZSP_GET_ADDRESS_TABLE_REMOTE_NODE_ID, []byte{addressTableIndex})
-	if err == nil {
-		err = generalResponseError(response, EZSP_GET_ADDRESS_TABLE_REMOTE_NODE_ID)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_GET_ADDRESS_TABLE_REMOTE_NODE_ID, 2)
-			if err == nil {
-				nodeID = binary.LittleEndian.Uint16(response.Data)
-				ezspApiTrace("EzspGetAddressTableRemoteNodeId(%d) = 0x%04x", addressTableIndex, nodeID)
-			}
-		}
-	}
-	return
+func (c *Client) EzspLookupNodeIdByEui64(eui64 uint64) (nodeId uint16, err error) {
+	return c.EzspLookupNodeIdByEui64Context(context.Background(), eui64)
 }
 
-func EzspSendManyToOneRouteRequest(concentratorType uint16, radius byte) (err error) {
-	response, err := EzspFrameSend(EZSP_SEND_MANY_TO_ONE_ROUTE_REQUEST, []byte{byte(concentratorType), byte(concentratorType >> 8), radius})
-	if err == nil {
-		err = generalResponseError(response, EZSP_SEND_MANY_TO_ONE_ROUTE_REQUEST)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_SEND_MANY_TO_ONE_ROUTE_REQUEST, 1)
-			if err == nil {
-				emberStatus := response.Data[0]
-				if emberStatus != EMBER_SUCCESS {
-					err = EmberError{emberStatus, "EzspSendManyToOneRouteRequest()"}
-					return
-				}
-				ezspApiTrace("EzspSendManyToOneRouteRequest()")
-			}
+// stSendResponse sendUnicast/sendBroadcast的回复
+type stSendResponse struct {
+	EmberStatus byte
+	Sequence    byte
+}
+
+func (c *Client) EzspSendUnicastContext(ctx context.Context, outgoingMessageType byte, indexOrDestination uint16, apsFrame *EmberApsFrame, messageTag byte, message []byte) (sequence byte, err error) {
+	params := struct {
+		Type               byte
+		IndexOrDestination uint16
+		ApsFrame           EmberApsFrame
+		MessageTag         byte
+		Message            []byte `ezsp:"len8"`
+	}{outgoingMessageType, indexOrDestination, *apsFrame, messageTag, message}
+	var resp stSendResponse
+	err = c.ezspCommand(ctx, EZSP_SEND_UNICAST, &params, &resp)
+	if err == nil {
+		sequence = resp.Sequence
+		if resp.EmberStatus != EMBER_SUCCESS {
+			err = EmberError{resp.EmberStatus, "EzspSendUnicast()"}
+			return
 		}
+		ezspApiTrace("EzspSendUnicast() return seq=%d", sequence)
 	}
 	return
 }
 
-func EzspPermitJoining(duration byte) (err error) {
-	response, err := EzspFrameSend(EZSP_PERMIT_JOINING, []byte{duration})
-	if err == nil {
-		err = generalResponseError(response, EZSP_PERMIT_JOINING)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_PERMIT_JOINING, 1)
-			if err == nil {
-				emberStatus := response.Data[0]
-				if emberStatus != EMBER_SUCCESS {
-					err = EmberError{emberStatus, "EzspPermitJoining()"}
-					return
-				}
-				ezspApiTrace("EzspPermitJoining(%d)", duration)
-			}
-		}
-	}
-	return
+func (c *Client) EzspSendUnicast(outgoingMessageType byte, indexOrDestination uint16, apsFrame *EmberApsFrame, messageTag byte, message []byte) (sequence byte, err error) {
+	return c.EzspSendUnicastContext(context.Background(), outgoingMessageType, indexOrDestination, apsFrame, messageTag, message)
 }
 
-func EzspRemoveDevice(destShort uint16, destLong uint64, targetLong uint64) (err error) {
-	data := make([]byte, 18)
-	binary.LittleEndian.PutUint16(data, destShort)
-	binary.LittleEndian.PutUint64(data[2:], destLong)
-	binary.LittleEndian.PutUint64(data[10:], targetLong)
-
-	response, err := EzspFrameSend(EZSP_REMOVE_DEVICE, data)
+func (c *Client) EzspSendBroadcastContext(ctx context.Context, destination uint16, apsFrame *EmberApsFrame, radius byte, messageTag byte, message []byte) (sequence byte, err error) {
+	params := struct {
+		Destination uint16
+		ApsFrame    EmberApsFrame
+		Radius      byte
+		MessageTag  byte
+		Message     []byte `ezsp:"len8"`
+	}{destination, *apsFrame, radius, messageTag, message}
+	var resp stSendResponse
+	err = c.ezspCommand(ctx, EZSP_SEND_BROADCAST, &params, &resp)
 	if err == nil {
-		err = generalResponseError(response, EZSP_REMOVE_DEVICE)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_REMOVE_DEVICE, 1)
-			if err == nil {
-				emberStatus := response.Data[0]
-				if emberStatus != EMBER_SUCCESS {
-					err = EmberError{emberStatus, "EzspRemoveDevice()"}
-					return
-				}
-				ezspApiTrace("EzspRemoveDevice(%x)", targetLong)
-			}
+		sequence = resp.Sequence
+		if resp.EmberStatus != EMBER_SUCCESS {
+			err = EmberError{resp.EmberStatus, "EzspSendBroadcast()"}
+			return
 		}
+		ezspApiTrace("EzspSendBroadcast() return seq=%d", sequence)
 	}
 	return
 }
 
-func EzspSendUnicast(outgoingMessageType byte, indexOrDestination uint16, apsFrame *EmberApsFrame, messageTag byte, message []byte) (sequence byte, err error) {
-	data := []byte{
-		outgoingMessageType,
-		byte(indexOrDestination),
-		byte(indexOrDestination >> 8),
-		byte(apsFrame.ProfileId),
-		byte(apsFrame.ProfileId >> 8),
-		byte(apsFrame.ClusterId),
-		byte(apsFrame.ClusterId >> 8),
-		apsFrame.SourceEndpoint,
-		apsFrame.DestinationEndpoint,
-		byte(apsFrame.Options),
-		byte(apsFrame.Options >> 8),
-		byte(apsFrame.GroupId),
-		byte(apsFrame.GroupId >> 8),
-		apsFrame.Sequence,
-		messageTag,
-		byte(len(message))}
-	data = append(data, message...)
-	response, err := EzspFrameSend(EZSP_SEND_UNICAST, data)
-	if err == nil {
-		err = generalResponseError(response, EZSP_SEND_UNICAST)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_SEND_UNICAST, 2)
-			if err == nil {
-				emberStatus := response.Data[0]
-				sequence = response.Data[1]
-				if emberStatus != EMBER_SUCCESS {
-					err = EmberError{emberStatus, "EzspSendUnicast()"}
-					return
-				}
-				ezspApiTrace("EzspSendUnicast() return seq=%d", sequence)
-			}
-		}
-	}
-	return
+func (c *Client) EzspSendBroadcast(destination uint16, apsFrame *EmberApsFrame, radius byte, messageTag byte, message []byte) (sequence byte, err error) {
+	return c.EzspSendBroadcastContext(context.Background(), destination, apsFrame, radius, messageTag, message)
 }
 
-func EzspSendBroadcast(destination uint16, apsFrame *EmberApsFrame, radius byte, messageTag byte, message []byte) (sequence byte, err error) {
-	data := []byte{
-		byte(destination),
-		byte(destination >> 8),
-		byte(apsFrame.ProfileId),
-		byte(apsFrame.ProfileId >> 8),
-		byte(apsFrame.ClusterId),
-		byte(apsFrame.ClusterId >> 8),
-		apsFrame.SourceEndpoint,
-		apsFrame.DestinationEndpoint,
-		byte(apsFrame.Options),
-		byte(apsFrame.Options >> 8),
-		byte(apsFrame.GroupId),
-		byte(apsFrame.GroupId >> 8),
-		apsFrame.Sequence,
-		radius,
-		messageTag,
-		byte(len(message))}
-	data = append(data, message...)
-	response, err := EzspFrameSend(EZSP_SEND_BROADCAST, data)
+func (c *Client) EzspSendReplyContext(ctx context.Context, sender uint16, apsFrame *EmberApsFrame, message []byte) (err error) {
+	params := struct {
+		Sender   uint16
+		ApsFrame EmberApsFrame
+		Message  []byte `ezsp:"len8"`
+	}{sender, *apsFrame, message}
+	var resp stStatusResponse
+	err = c.ezspCommand(ctx, EZSP_SEND_REPLY, &params, &resp)
 	if err == nil {
-		err = generalResponseError(response, EZSP_SEND_BROADCAST)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_SEND_BROADCAST, 2)
-			if err == nil {
-				emberStatus := response.Data[0]
-				sequence = response.Data[1]
-				if emberStatus != EMBER_SUCCESS {
-					err = EmberError{emberStatus, "EzspSendBroadcast()"}
-					return
-				}
-				ezspApiTrace("EzspSendBroadcast() return seq=%d", sequence)
-			}
+		if resp.Status != EMBER_SUCCESS {
+			err = EmberError{resp.Status, "EzspSendReply()"}
+			return
 		}
+		ezspApiTrace("EzspSendReply() return")
 	}
 	return
 }
 
-func EzspSendReply(sender uint16, apsFrame *EmberApsFrame, message []byte) (err error) {
-	if message == nil {
-		message = make([]byte, 0)
-	}
-	data := []byte{
-		byte(sender),
-		byte(sender >> 8),
-		byte(apsFrame.ProfileId),
-		byte(apsFrame.ProfileId >> 8),
-		byte(apsFrame.ClusterId),
-		byte(apsFrame.ClusterId >> 8),
-		apsFrame.SourceEndpoint,
-		apsFrame.DestinationEndpoint,
-		byte(apsFrame.Options),
-		byte(apsFrame.Options >> 8),
-		byte(apsFrame.GroupId),
-		byte(apsFrame.GroupId >> 8),
-		apsFrame.Sequence,
-		byte(len(message))}
-	data = append(data, message...)
-	response, err := EzspFrameSend(EZSP_SEND_REPLY, data)
-	if err == nil {
-		err = generalResponseError(response, EZSP_SEND_REPLY)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_SEND_REPLY, 1)
-			if err == nil {
-				emberStatus := response.Data[0]
-				if emberStatus != EMBER_SUCCESS {
-					err = EmberError{emberStatus, "EzspSendReply()"}
-					return
-				}
-				ezspApiTrace("EzspSendReply() return")
-			}
-		}
-	}
-	return
+func (c *Client) EzspSendReply(sender uint16, apsFrame *EmberApsFrame, message []byte) (err error) {
+	return c.EzspSendReplyContext(context.Background(), sender, apsFrame, message)
 }
 
-func EzspLeaveNetwork() (err error) {
-	response, err := EzspFrameSend(EZSP_LEAVE_NETWORK, []byte{})
+func (c *Client) EzspAddEndpointContext(ctx context.Context, endpoint byte, profileId uint16, deviceId uint16, deviceVersion byte, inputClusterList []uint16, outputClusterList []uint16) (err error) {
+	// 两个列表的长度在列表之前，列表连在一起放在最后
+	params := struct {
+		Endpoint           byte
+		ProfileId          uint16
+		DeviceId           uint16
+		DeviceVersion      byte
+		InputClusterCount  byte
+		OutputClusterCount byte
+		ClusterList        []uint16 `ezsp:"rest"`
+	}{endpoint, profileId, deviceId, deviceVersion, byte(len(inputClusterList)), byte(len(outputClusterList)),
+		append(append([]uint16{}, inputClusterList...), outputClusterList...)}
+	var resp stStatusResponse
+	err = c.ezspCommand(ctx, EZSP_ADD_ENDPOINT, &params, &resp)
 	if err == nil {
-		err = generalResponseError(response, EZSP_LEAVE_NETWORK)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_LEAVE_NETWORK, 1)
-			if err == nil {
-				emberStatus := response.Data[0]
-				if emberStatus != EMBER_SUCCESS {
-					err = EmberError{emberStatus, "EzspLeaveNetwork()"}
-					return
-				}
-				ezspApiTrace("EzspLeaveNetwork()")
-			}
+		if resp.Status != EZSP_SUCCESS {
+			err = EzspError{resp.Status, fmt.Sprintf("EzspAddEndpoint(%d, ...)", endpoint)}
+			return
 		}
+		ezspApiTrace("EzspAddEndpoint(%d, ...)", endpoint)
 	}
 	return
 }
 
-func EzspSetRadioChannel(channel byte) (err error) {
-	response, err := EzspFrameSend(EZSP_SET_RADIO_CHANNEL, []byte{channel})
-	if err == nil {
-		err = generalResponseError(response, EZSP_SET_RADIO_CHANNEL)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_SET_RADIO_CHANNEL, 1)
-			if err == nil {
-				emberStatus := response.Data[0]
-				if emberStatus != EMBER_SUCCESS {
-					err = EmberError{emberStatus, "EzspSetRadioChannel()"}
-					return
-				}
-				ezspApiTrace("EzspSetRadioChannel(%d)", channel)
-			}
-		}
-	}
-	return
+func (c *Client) EzspAddEndpoint(endpoint byte, profileId uint16, deviceId uint16, deviceVersion byte, inputClusterList []uint16, outputClusterList []uint16) (err error) {
+	return c.EzspAddEndpointContext(context.Background(), endpoint, profileId, deviceId, deviceVersion, inputClusterList, outputClusterList)
 }
 
-func EzspSetSourceRoute(destination uint16, relayList []uint16) (err error) {
-	relayCount := len(relayList)
-
-	data := []byte{byte(destination), byte(destination >> 8), byte(relayCount)}
-	for _, r := range relayList {
-		data = append(data, byte(r), byte(r>>8))
+// EzspGetChildDataContext 读取子节点表的第index项，没有子节点时返回EMBER_NOT_JOINED的 EmberError。
+// 协议版本8开始回复是 EmberChildData 结构，之前只有id、eui64和类型
+func (c *Client) EzspGetChildDataContext(ctx context.Context, index byte) (childData EmberChildData, err error) {
+	var status byte
+	if c.VersionInfo().ProtocolVersion >= EZSP_EXTENDED_FRAME_PROTOCOL_VERSION {
+		var resp struct {
+			Status    byte
+			ChildData EmberChildData
+		}
+		err = c.ezspCommand(ctx, EZSP_GET_CHILD_DATA, struct{ Index byte }{index}, &resp)
+		status, childData = resp.Status, resp.ChildData
+	} else {
+		var resp struct {
+			Status     byte
+			ChildId    uint16
+			ChildEui64 uint64
+			ChildType  byte
+		}
+		err = c.ezspCommand(ctx, EZSP_GET_CHILD_DATA, struct{ Index byte }{index}, &resp)
+		status, childData = resp.Status, EmberChildData{Eui64: resp.ChildEui64, Type: resp.ChildType, Id: resp.ChildId}
 	}
-	response, err := EzspFrameSend(EZSP_SET_SOURCE_ROUTE, data)
 	if err == nil {
-		err = generalResponseError(response, EZSP_SET_SOURCE_ROUTE)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_SET_SOURCE_ROUTE, 1)
-			if err == nil {
-				emberStatus := response.Data[0]
-				if emberStatus != EMBER_SUCCESS {
-					err = EmberError{emberStatus, fmt.Sprintf("EzspSetSourceRoute(0x%x)", destination)}
-					return
-				}
-				ezspApiTrace("EzspSetSourceRoute(0x%x)", destination)
-			}
+		if status != EMBER_SUCCESS {
+			err = EmberError{status, fmt.Sprintf("EzspGetChildData(%d)", index)}
+			return
 		}
+		ezspApiTrace("EzspGetChildData(%d) get %+v", index, childData)
 	}
 	return
 }
 
-func EzspAddEndpoint(endpoint byte, profileId uint16, deviceId uint16, deviceVersion byte, inputClusterList []uint16, outputClusterList []uint16) (err error) {
-	inputClusterCount := len(inputClusterList)
-	outputClusterCount := len(outputClusterList)
-	data := []byte{endpoint, byte(profileId), byte(profileId >> 8), byte(deviceId), byte(deviceId >> 8), deviceVersion, byte(inputClusterCount), byte(outputClusterCount)}
-	for _, r := range inputClusterList {
-		data = append(data, byte(r), byte(r>>8))
-	}
-	for _, r := range outputClusterList {
-		data = append(data, byte(r), byte(r>>8))
-	}
-	response, err := EzspFrameSend(EZSP_ADD_ENDPOINT, data)
-	if err == nil {
-		err = generalResponseError(response, EZSP_ADD_ENDPOINT)
-		if err == nil {
-			err = generalResponseLengthEqual(response, EZSP_ADD_ENDPOINT, 1)
-			if err == nil {
-				ezspStatus := response.Data[0]
-				if ezspStatus != EZSP_SUCCESS {
-					err = EzspError{ezspStatus, fmt.Sprintf("EzspAddEndpoint(%d, ...)", endpoint)}
-					return
-				}
-				ezspApiTrace("EzspAddEndpoint(%d, ...)", endpoint)
-			}
-		}
-	}
-	return
+func (c *Client) EzspGetChildData(index byte) (childData EmberChildData, err error) {
+	return c.EzspGetChildDataContext(context.Background(), index)
 }
 
 // EzspGetValue API
@@ -906,54 +431,86 @@ func (v EmberVersion) String() (str string) {
 	return
 }
 
-func EzspGetValue_VERSION_INFO() (emberVersion *EmberVersion, err error) {
-	value, err := EzspGetValue(EZSP_VALUE_VERSION_INFO)
+func (c *Client) EzspGetValue_VERSION_INFOContext(ctx context.Context) (emberVersion *EmberVersion, err error) {
+	value, err := c.EzspGetValueContext(ctx, EZSP_VALUE_VERSION_INFO)
 	if err == nil {
-		if len(value) != 7 {
-			err = fmt.Errorf("EzspGetValue_VERSION_INFO get invalid value length expect(%d) get(%d)", 7, len(value))
+		v := EmberVersion{}
+		err = Unmarshal(value, &v)
+		if err != nil {
+			err = fmt.Errorf("EzspGetValue_VERSION_INFO get invalid value: %v", err)
 			return
 		}
-		emberVersion = &EmberVersion{Build: binary.LittleEndian.Uint16(value), Major: value[2], Minor: value[3], Patch: value[4], Special: value[5], VerType: value[6]}
+		emberVersion = &v
 	}
 	return
 }
 
-func EzspSetValue_MAXIMUM_INCOMING_TRANSFER_SIZE(size uint16) (err error) {
-	return EzspSetValue(EZSP_VALUE_MAXIMUM_INCOMING_TRANSFER_SIZE, []byte{byte(size), byte(size >> 8)})
+func (c *Client) EzspGetValue_VERSION_INFO() (emberVersion *EmberVersion, err error) {
+	return c.EzspGetValue_VERSION_INFOContext(context.Background())
+}
+
+func (c *Client) EzspSetValue_MAXIMUM_INCOMING_TRANSFER_SIZEContext(ctx context.Context, size uint16) (err error) {
+	return c.EzspSetValueContext(ctx, EZSP_VALUE_MAXIMUM_INCOMING_TRANSFER_SIZE, []byte{byte(size), byte(size >> 8)})
 }
-func EzspSetValue_MAXIMUM_OUTGOING_TRANSFER_SIZE(size uint16) (err error) {
-	return EzspSetValue(EZSP_VALUE_MAXIMUM_OUTGOING_TRANSFER_SIZE, []byte{byte(size), byte(size >> 8)})
+
+func (c *Client) EzspSetValue_MAXIMUM_INCOMING_TRANSFER_SIZE(size uint16) (err error) {
+	return c.EzspSetValue_MAXIMUM_INCOMING_TRANSFER_SIZEContext(context.Background(), size)
+}
+func (c *Client) EzspSetValue_MAXIMUM_OUTGOING_TRANSFER_SIZEContext(ctx context.Context, size uint16) (err error) {
+	return c.EzspSetValueContext(ctx, EZSP_VALUE_MAXIMUM_OUTGOING_TRANSFER_SIZE, []byte{byte(size), byte(size >> 8)})
+}
+
+func (c *Client) EzspSetValue_MAXIMUM_OUTGOING_TRANSFER_SIZE(size uint16) (err error) {
+	return c.EzspSetValue_MAXIMUM_OUTGOING_TRANSFER_SIZEContext(context.Background(), size)
 }
 
-func EzspSetValue_EXTENDED_SECURITY_BITMASK(mask uint16) (err error) {
-	return EzspSetValue(EZSP_VALUE_EXTENDED_SECURITY_BITMASK, []byte{byte(mask), byte(mask >> 8)})
+func (c *Client) EzspSetValue_EXTENDED_SECURITY_BITMASKContext(ctx context.Context, mask uint16) (err error) {
+	return c.EzspSetValueContext(ctx, EZSP_VALUE_EXTENDED_SECURITY_BITMASK, []byte{byte(mask), byte(mask >> 8)})
 }
 
-func EzspSetMfgToken_MFG_PHY_CONFIG(phyConfig uint16) (err error) {
-	return EzspSetMfgToken(EZSP_MFG_PHY_CONFIG, []byte{byte(phyConfig), byte(phyConfig >> 8)})
+func (c *Client) EzspSetValue_EXTENDED_SECURITY_BITMASK(mask uint16) (err error) {
+	return c.EzspSetValue_EXTENDED_SECURITY_BITMASKContext(context.Background(), mask)
 }
-func EzspGetMfgToken_MFG_PHY_CONFIG() (phyConfig uint16, err error) {
-	value, err := EzspGetMfgToken(EZSP_MFG_PHY_CONFIG)
+
+func (c *Client) EzspSetMfgToken_MFG_PHY_CONFIGContext(ctx context.Context, phyConfig uint16) (err error) {
+	return c.EzspSetMfgTokenContext(ctx, EZSP_MFG_PHY_CONFIG, []byte{byte(phyConfig), byte(phyConfig >> 8)})
+}
+
+func (c *Client) EzspSetMfgToken_MFG_PHY_CONFIG(phyConfig uint16) (err error) {
+	return c.EzspSetMfgToken_MFG_PHY_CONFIGContext(context.Background(), phyConfig)
+}
+func (c *Client) EzspGetMfgToken_MFG_PHY_CONFIGContext(ctx context.Context) (phyConfig uint16, err error) {
+	value, err := c.EzspGetMfgTokenContext(ctx, EZSP_MFG_PHY_CONFIG)
 	if err == nil {
-		if len(value) != 2 {
-			err = fmt.Errorf("EzspGetMfgToken_MFG_PHY_CONFIG get invalid value length expect(%d) get(%d)", 2, len(value))
+		var token struct{ PhyConfig uint16 }
+		err = Unmarshal(value, &token)
+		if err != nil {
+			err = fmt.Errorf("EzspGetMfgToken_MFG_PHY_CONFIG get invalid value: %v", err)
 			return
 		}
-		phyConfig = binary.LittleEndian.Uint16(value)
+		phyConfig = token.PhyConfig
 	}
 	return
 }
 
-func EzspCallback() (err error) {
-	response, err := EzspFrameSend(EZSP_CALLBACK, []byte{})
+func (c *Client) EzspGetMfgToken_MFG_PHY_CONFIG() (phyConfig uint16, err error) {
+	return c.EzspGetMfgToken_MFG_PHY_CONFIGContext(context.Background())
+}
+
+func (c *Client) EzspCallbackContext(ctx context.Context) (err error) {
+	response, err := c.EzspFrameSendContext(ctx, EZSP_CALLBACK, []byte{})
 	if err == nil {
 		if response == nil { //正常应该返回nil，真正的callback从EzspCallbackDispatch处理
 			return nil
 		}
 		if response.FrameID == EZSP_INVALID_COMMAND {
-			return fmt.Errorf("EZSP cmd 0x%x return invalid command ezspStatus(%s)", EZSP_CALLBACK, ezspStatusToString(response.Data[0]))
+			return generalResponseError(response, EZSP_CALLBACK)
 		}
 		return fmt.Errorf("EZSP_CALLBACK should not have response")
 	}
 	return
 }
+
+func (c *Client) EzspCallback() (err error) {
+	return c.EzspCallbackContext(context.Background())
+}
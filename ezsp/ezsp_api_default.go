@@ -0,0 +1,157 @@
+package ezsp
+
+import "context"
+
+// DefaultClient 上的EZSP命令，兼容原来的包级函数
+
+func EzspVersion(desiredProtocolVersion byte) (protocolVersion byte, stackType byte, stackVersion uint16, err error) {
+	return DefaultClient.EzspVersion(desiredProtocolVersion)
+}
+
+func EzspVersionContext(ctx context.Context, desiredProtocolVersion byte) (protocolVersion byte, stackType byte, stackVersion uint16, err error) {
+	return DefaultClient.EzspVersionContext(ctx, desiredProtocolVersion)
+}
+
+func EzspGetToken(tokenId byte) (tokenData []byte, err error) {
+	return DefaultClient.EzspGetToken(tokenId)
+}
+
+func EzspGetTokenContext(ctx context.Context, tokenId byte) (tokenData []byte, err error) {
+	return DefaultClient.EzspGetTokenContext(ctx, tokenId)
+}
+
+func EzspSetToken(tokenId byte, tokenData []byte) (err error) {
+	return DefaultClient.EzspSetToken(tokenId, tokenData)
+}
+
+func EzspSetTokenContext(ctx context.Context, tokenId byte, tokenData []byte) (err error) {
+	return DefaultClient.EzspSetTokenContext(ctx, tokenId, tokenData)
+}
+
+func EzspGetNetworkParameters() (nodeType byte, parameters *EmberNetworkParameters, err error) {
+	return DefaultClient.EzspGetNetworkParameters()
+}
+
+func EzspGetNetworkParametersContext(ctx context.Context) (nodeType byte, parameters *EmberNetworkParameters, err error) {
+	return DefaultClient.EzspGetNetworkParametersContext(ctx)
+}
+
+func EzspFormNetwork(para *EmberNetworkParameters) (err error) {
+	return DefaultClient.EzspFormNetwork(para)
+}
+
+func EzspFormNetworkContext(ctx context.Context, para *EmberNetworkParameters) (err error) {
+	return DefaultClient.EzspFormNetworkContext(ctx, para)
+}
+
+func EzspSetInitialSecurityState(state *EmberInitialSecurityState) (err error) {
+	return DefaultClient.EzspSetInitialSecurityState(state)
+}
+
+func EzspSetInitialSecurityStateContext(ctx context.Context, state *EmberInitialSecurityState) (err error) {
+	return DefaultClient.EzspSetInitialSecurityStateContext(ctx, state)
+}
+
+func EzspLookupNodeIdByEui64(eui64 uint64) (nodeId uint16, err error) {
+	return DefaultClient.EzspLookupNodeIdByEui64(eui64)
+}
+
+func EzspLookupNodeIdByEui64Context(ctx context.Context, eui64 uint64) (nodeId uint16, err error) {
+	return DefaultClient.EzspLookupNodeIdByEui64Context(ctx, eui64)
+}
+
+func EzspSendUnicast(outgoingMessageType byte, indexOrDestination uint16, apsFrame *EmberApsFrame, messageTag byte, message []byte) (sequence byte, err error) {
+	return DefaultClient.EzspSendUnicast(outgoingMessageType, indexOrDestination, apsFrame, messageTag, message)
+}
+
+func EzspSendUnicastContext(ctx context.Context, outgoingMessageType byte, indexOrDestination uint16, apsFrame *EmberApsFrame, messageTag byte, message []byte) (sequence byte, err error) {
+	return DefaultClient.EzspSendUnicastContext(ctx, outgoingMessageType, indexOrDestination, apsFrame, messageTag, message)
+}
+
+func EzspSendBroadcast(destination uint16, apsFrame *EmberApsFrame, radius byte, messageTag byte, message []byte) (sequence byte, err error) {
+	return DefaultClient.EzspSendBroadcast(destination, apsFrame, radius, messageTag, message)
+}
+
+func EzspSendBroadcastContext(ctx context.Context, destination uint16, apsFrame *EmberApsFrame, radius byte, messageTag byte, message []byte) (sequence byte, err error) {
+	return DefaultClient.EzspSendBroadcastContext(ctx, destination, apsFrame, radius, messageTag, message)
+}
+
+func EzspSendReply(sender uint16, apsFrame *EmberApsFrame, message []byte) (err error) {
+	return DefaultClient.EzspSendReply(sender, apsFrame, message)
+}
+
+func EzspSendReplyContext(ctx context.Context, sender uint16, apsFrame *EmberApsFrame, message []byte) (err error) {
+	return DefaultClient.EzspSendReplyContext(ctx, sender, apsFrame, message)
+}
+
+func EzspAddEndpoint(endpoint byte, profileId uint16, deviceId uint16, deviceVersion byte, inputClusterList []uint16, outputClusterList []uint16) (err error) {
+	return DefaultClient.EzspAddEndpoint(endpoint, profileId, deviceId, deviceVersion, inputClusterList, outputClusterList)
+}
+
+func EzspAddEndpointContext(ctx context.Context, endpoint byte, profileId uint16, deviceId uint16, deviceVersion byte, inputClusterList []uint16, outputClusterList []uint16) (err error) {
+	return DefaultClient.EzspAddEndpointContext(ctx, endpoint, profileId, deviceId, deviceVersion, inputClusterList, outputClusterList)
+}
+
+func EzspGetValue_VERSION_INFO() (emberVersion *EmberVersion, err error) {
+	return DefaultClient.EzspGetValue_VERSION_INFO()
+}
+
+func EzspGetValue_VERSION_INFOContext(ctx context.Context) (emberVersion *EmberVersion, err error) {
+	return DefaultClient.EzspGetValue_VERSION_INFOContext(ctx)
+}
+
+func EzspSetValue_MAXIMUM_INCOMING_TRANSFER_SIZE(size uint16) (err error) {
+	return DefaultClient.EzspSetValue_MAXIMUM_INCOMING_TRANSFER_SIZE(size)
+}
+
+func EzspSetValue_MAXIMUM_INCOMING_TRANSFER_SIZEContext(ctx context.Context, size uint16) (err error) {
+	return DefaultClient.EzspSetValue_MAXIMUM_INCOMING_TRANSFER_SIZEContext(ctx, size)
+}
+
+func EzspSetValue_MAXIMUM_OUTGOING_TRANSFER_SIZE(size uint16) (err error) {
+	return DefaultClient.EzspSetValue_MAXIMUM_OUTGOING_TRANSFER_SIZE(size)
+}
+
+func EzspSetValue_MAXIMUM_OUTGOING_TRANSFER_SIZEContext(ctx context.Context, size uint16) (err error) {
+	return DefaultClient.EzspSetValue_MAXIMUM_OUTGOING_TRANSFER_SIZEContext(ctx, size)
+}
+
+func EzspSetValue_EXTENDED_SECURITY_BITMASK(mask uint16) (err error) {
+	return DefaultClient.EzspSetValue_EXTENDED_SECURITY_BITMASK(mask)
+}
+
+func EzspSetValue_EXTENDED_SECURITY_BITMASKContext(ctx context.Context, mask uint16) (err error) {
+	return DefaultClient.EzspSetValue_EXTENDED_SECURITY_BITMASKContext(ctx, mask)
+}
+
+func EzspSetMfgToken_MFG_PHY_CONFIG(phyConfig uint16) (err error) {
+	return DefaultClient.EzspSetMfgToken_MFG_PHY_CONFIG(phyConfig)
+}
+
+func EzspSetMfgToken_MFG_PHY_CONFIGContext(ctx context.Context, phyConfig uint16) (err error) {
+	return DefaultClient.EzspSetMfgToken_MFG_PHY_CONFIGContext(ctx, phyConfig)
+}
+
+func EzspGetChildData(index byte) (childData EmberChildData, err error) {
+	return DefaultClient.EzspGetChildData(index)
+}
+
+func EzspGetChildDataContext(ctx context.Context, index byte) (childData EmberChildData, err error) {
+	return DefaultClient.EzspGetChildDataContext(ctx, index)
+}
+
+func EzspGetMfgToken_MFG_PHY_CONFIG() (phyConfig uint16, err error) {
+	return DefaultClient.EzspGetMfgToken_MFG_PHY_CONFIG()
+}
+
+func EzspGetMfgToken_MFG_PHY_CONFIGContext(ctx context.Context) (phyConfig uint16, err error) {
+	return DefaultClient.EzspGetMfgToken_MFG_PHY_CONFIGContext(ctx)
+}
+
+func EzspCallback() (err error) {
+	return DefaultClient.EzspCallback()
+}
+
+func EzspCallbackContext(ctx context.Context) (err error) {
+	return DefaultClient.EzspCallbackContext(ctx)
+}
@@ -1,24 +1,9 @@
 package ezsp
 
 import (
-	"encoding/binary"
-
 	"github.com/conthing/utils/common"
 )
 
-type StEzspCallbacks struct {
-	EzspStackStatusHandler         func(emberStatus byte)
-	EzspMessageSentHandler         func(outgoingMessageType byte, indexOrDestination uint16, apsFrame *EmberApsFrame, messageTag byte, emberStatus byte, message []byte)
-	EzspIncomingSenderEui64Handler func(senderEui64 uint64)
-	EzspIncomingMessageHandler     func(incomingMessageType byte, apsFrame *EmberApsFrame, lastHopLqi byte, lastHopRssi int8, sender uint16, bindingIndex byte, addressIndex byte, message []byte)
-	EzspIncomingRouteErrorHandler  func(emberStatus byte, target uint16)
-	EzspIncomingRouteRecordHandler func(source uint16, sourceEui uint64, lastHopLqi byte, lastHopRssi int8, relay []uint16)
-	EzspTrustCenterJoinHandler     func(newNodeId uint16, newNodeEui64 uint64, deviceUpdateStatus byte, joinDecision byte, parentOfNewNode uint16)
-	EzspEnergyScanResultHandler    func(channel byte, maxRssiValue int8)
-	EzspScanCompleteHandler        func(channel byte, emberStatus byte)
-	EzspNetworkFoundHandler        func(networkFound *EmberZigbeeNetwork, lqi byte, rssi int8)
-}
-
 var EzspCallbackTraceOn bool
 
 func ezspCallbackTrace(format string, v ...interface{}) {
@@ -27,7 +12,10 @@ func ezspCallbackTrace(format string, v ...interface{}) {
 	}
 }
 
-func EzspCallbackDispatch(cb *EzspFrame) {
+// EzspCallbackDispatch 解析callback，先更新c自己的网络状态、源路由表和组网扫描状态，
+// 再把事件发布到c的事件总线 c.EventBus()。本库不认识的callback以 RawCallbackEvent 发布。
+// APS分片在c上回复确认并重组，cb要是c收到的callback
+func (c *Client) EzspCallbackDispatch(cb *EzspFrame) {
 
 	if cb == nil {
 		common.Log.Errorf("EzspCallbackDispatch with nil frame")
@@ -40,206 +28,71 @@ func EzspCallbackDispatch(cb *EzspFrame) {
 
 	//ezspCallbackTrace("callback:%s", frameIDToName(cb.FrameID))
 
-	switch cb.FrameID {
-	case EZSP_INCOMING_MESSAGE_HANDLER:
-		if len(cb.Data) < 17 {
-			common.Log.Errorf("EzspCallbackDispatch %s with invalid Data length %d", frameIDToName(cb.FrameID), len(cb.Data))
-			return
-		}
-
-		incomingMessageType := cb.Data[0]
-		apsFrame := EmberApsFrame{}
-		apsFrame.ProfileId = binary.LittleEndian.Uint16(cb.Data[1:])
-		apsFrame.ClusterId = binary.LittleEndian.Uint16(cb.Data[3:])
-		apsFrame.SourceEndpoint = cb.Data[5]
-		apsFrame.DestinationEndpoint = cb.Data[6]
-		apsFrame.Options = binary.LittleEndian.Uint16(cb.Data[7:])
-		apsFrame.GroupId = binary.LittleEndian.Uint16(cb.Data[9:])
-		apsFrame.Sequence = cb.Data[11]
-		lastHopLqi := cb.Data[12]
-		lastHopRssi := int8(cb.Data[13])
-		sender := binary.LittleEndian.Uint16(cb.Data[14:])
-		bindingIndex := cb.Data[16]
-		addressIndex := cb.Data[17]
-		messageLength := cb.Data[18]
-		if len(cb.Data) != int(messageLength)+19 {
-			common.Log.Errorf("EzspCallbackDispatch %s with invalid Data length %d", frameIDToName(cb.FrameID), len(cb.Data))
-			return
-		}
-
-		EzspIncomingMessageHandler(incomingMessageType,
-			&apsFrame,
-			lastHopLqi,
-			lastHopRssi,
-			sender,
-			bindingIndex,
-			addressIndex,
-			cb.Data[19:])
-
-	case EZSP_STACK_STATUS_HANDLER:
-		if len(cb.Data) != 1 {
-			common.Log.Errorf("EzspCallbackDispatch %s with invalid Data length %d", frameIDToName(cb.FrameID), len(cb.Data))
-			return
-		}
-		emberStatus := cb.Data[0]
-		EzspStackStatusHandler(emberStatus)
-
-	case EZSP_INCOMING_SENDER_EUI64_HANDLER:
-		if len(cb.Data) != 8 {
-			common.Log.Errorf("EzspCallbackDispatch %s with invalid Data length %d", frameIDToName(cb.FrameID), len(cb.Data))
-			return
-		}
-		senderEui64 := binary.LittleEndian.Uint64(cb.Data)
-		EzspIncomingSenderEui64Handler(senderEui64)
-
-	case EZSP_MESSAGE_SENT_HANDLER:
-		if len(cb.Data) < 17 {
-			common.Log.Errorf("EzspCallbackDispatch %s with invalid Data length %d", frameIDToName(cb.FrameID), len(cb.Data))
-			return
-		}
-		outgoingMessageType := cb.Data[0]
-		indexOrDestination := binary.LittleEndian.Uint16(cb.Data[1:])
-		apsFrame := EmberApsFrame{}
-		apsFrame.ProfileId = binary.LittleEndian.Uint16(cb.Data[3:])
-		apsFrame.ClusterId = binary.LittleEndian.Uint16(cb.Data[5:])
-		apsFrame.SourceEndpoint = cb.Data[7]
-		apsFrame.DestinationEndpoint = cb.Data[8]
-		apsFrame.Options = binary.LittleEndian.Uint16(cb.Data[9:])
-		apsFrame.GroupId = binary.LittleEndian.Uint16(cb.Data[11:])
-		apsFrame.Sequence = cb.Data[13]
-		messageTag := cb.Data[14]
-		emberStatus := cb.Data[15]
-		messageLength := cb.Data[16]
-		if len(cb.Data) != int(messageLength)+17 {
-			common.Log.Errorf("EzspCallbackDispatch %s with invalid Data length %d", frameIDToName(cb.FrameID), len(cb.Data))
-			return
-		}
+	if cb.FrameID == EZSP_NO_CALLBACKS {
+		ezspCallbackTrace("EZSP_NO_CALLBACKS")
+		return
+	}
 
-		EzspMessageSentHandler(outgoingMessageType,
-			indexOrDestination,
-			&apsFrame,
-			messageTag,
-			emberStatus,
-			cb.Data[17:])
+	event, err := callbackDecode(cb)
+	if err != nil {
+		common.Log.Errorf("EzspCallbackDispatch %v", err)
+		return
+	}
 
-	case EZSP_INCOMING_ROUTE_ERROR_HANDLER:
-		if len(cb.Data) != 3 {
-			common.Log.Errorf("EzspCallbackDispatch %s with invalid Data length %d", frameIDToName(cb.FrameID), len(cb.Data))
+	// APS分片收齐后才作为一条完整的消息处理
+	if e, ok := event.(*IncomingMessageEvent); ok && e.ApsFrame.Options&EMBER_APS_OPTION_FRAGMENT != 0 {
+		full, complete := c.fragmentReceive(e)
+		if !complete {
 			return
 		}
-		emberStatus := cb.Data[0]
-		target := binary.LittleEndian.Uint16(cb.Data[1:])
-		EzspIncomingRouteErrorHandler(emberStatus, target)
+		event = full
+	}
 
-	case EZSP_TRUST_CENTER_JOIN_HANDLER:
-		if len(cb.Data) != 14 {
-			common.Log.Errorf("EzspCallbackDispatch %s with invalid Data length %d", frameIDToName(cb.FrameID), len(cb.Data))
-			return
-		}
-		newNodeId := binary.LittleEndian.Uint16(cb.Data)
-		newNodeEui64 := binary.LittleEndian.Uint64(cb.Data[2:])
-		deviceUpdateStatus := cb.Data[10]
-		joinDecision := cb.Data[11]
-		parentOfNewNode := binary.LittleEndian.Uint16(cb.Data[12:])
-		EzspTrustCenterJoinHandler(newNodeId,
-			newNodeEui64,
-			deviceUpdateStatus,
-			joinDecision,
-			parentOfNewNode)
-	case EZSP_ENERGY_SCAN_RESULT_HANDLER:
-		if len(cb.Data) != 2 {
-			common.Log.Errorf("EzspCallbackDispatch %s with invalid Data length %d", frameIDToName(cb.FrameID), len(cb.Data))
-			return
-		}
-		channel := cb.Data[0]
-		maxRssiValue := int8(cb.Data[1])
-		EzspEnergyScanResultHandler(channel, maxRssiValue)
-	case EZSP_NETWORK_FOUND_HANDLER:
-		if len(cb.Data) != 16 {
-			common.Log.Errorf("EzspCallbackDispatch %s with invalid Data length %d", frameIDToName(cb.FrameID), len(cb.Data))
-			return
-		}
-		networkFound := EmberZigbeeNetwork{}
-		networkFound.Channel = cb.Data[0]
-		networkFound.PanId = binary.LittleEndian.Uint16(cb.Data[1:])
-		networkFound.ExtendedPanId = binary.LittleEndian.Uint64(cb.Data[3:])
-		networkFound.AllowingJoin = (cb.Data[11] != 0)
-		networkFound.StackProfile = cb.Data[12]
-		networkFound.NwkUpdateId = cb.Data[13]
-		lqi := cb.Data[14]
-		rssi := int8(cb.Data[15])
-		EzspNetworkFoundHandler(&networkFound, lqi, rssi)
-	case EZSP_SCAN_COMPLETE_HANDLER:
-		if len(cb.Data) != 2 {
-			common.Log.Errorf("EzspCallbackDispatch %s with invalid Data length %d", frameIDToName(cb.FrameID), len(cb.Data))
-			return
-		}
-		channel := cb.Data[0]
-		emberStatus := cb.Data[1]
-		EzspScanCompleteHandler(channel, emberStatus)
-	case EZSP_INCOMING_ROUTE_RECORD_HANDLER:
-		if len(cb.Data) < 13 {
-			common.Log.Errorf("EzspCallbackDispatch %s with invalid Data length %d", frameIDToName(cb.FrameID), len(cb.Data))
-			return
-		}
-		source := binary.LittleEndian.Uint16(cb.Data)
-		sourceEui := binary.LittleEndian.Uint64(cb.Data[2:])
-		lastHopLqi := cb.Data[10]
-		lastHopRssi := int8(cb.Data[11])
-		relayCount := cb.Data[12]
-		if len(cb.Data) != 13+2*int(relayCount) {
-			common.Log.Errorf("EzspCallbackDispatch %s with invalid Data length %d", frameIDToName(cb.FrameID), len(cb.Data))
-			return
-		}
-		relay := make([]uint16, relayCount)
-		for i := range relay {
-			relay[i] = binary.LittleEndian.Uint16(cb.Data[13+i*2:])
-		}
+	switch e := event.(type) {
+	case *IncomingMessageEvent:
+		EzspIncomingMessageHandler(e.IncomingMessageType,
+			&e.ApsFrame,
+			e.LastHopLqi,
+			e.LastHopRssi,
+			e.Sender,
+			e.BindingIndex,
+			e.AddressIndex,
+			e.Message)
+	case *StackStatusEvent:
+		c.EzspStackStatusHandler(e.EmberStatus)
+	case *IncomingSenderEui64Event:
+		EzspIncomingSenderEui64Handler(e.SenderEui64)
+	case *MessageSentEvent:
+		EzspMessageSentHandler(e.OutgoingMessageType,
+			e.IndexOrDestination,
+			&e.ApsFrame,
+			e.MessageTag,
+			e.EmberStatus,
+			e.Message)
+	case *IncomingRouteErrorEvent:
+		c.EzspIncomingRouteErrorHandler(e.EmberStatus, e.Target)
+	case *TrustCenterJoinEvent:
+		EzspTrustCenterJoinHandler(e.NewNodeId,
+			e.NewNodeEui64,
+			e.DeviceUpdateStatus,
+			e.JoinDecision,
+			e.ParentOfNewNode)
+	case *EnergyScanResultEvent:
+		c.EzspEnergyScanResultHandler(e.Channel, e.MaxRssiValue)
+	case *NetworkFoundEvent:
+		c.EzspNetworkFoundHandler(&e.NetworkFound, e.Lqi, e.Rssi)
+	case *ScanCompleteEvent:
+		c.EzspScanCompleteHandler(e.Channel, e.EmberStatus)
+	case *IncomingRouteRecordEvent:
+		c.EzspIncomingRouteRecordHandler(e.Source, e.SourceEui, e.LastHopLqi, e.LastHopRssi, e.Relay)
+	case *RawCallbackEvent:
+		ezspCallbackTrace("EzspCallbackDispatch unknown callback %s", frameIDToName(cb.FrameID))
+	}
 
-		EzspIncomingRouteRecordHandler(source, sourceEui, lastHopLqi, lastHopRssi, relay)
+	c.bus.Publish(event)
+}
 
-	case EZSP_NO_CALLBACKS:
-		ezspCallbackTrace("EZSP_NO_CALLBACKS")
-		//	case EZSP_STACK_TOKEN_CHANGED_HANDLER:
-		//	case EZSP_TIMER_HANDLER:
-		//	case EZSP_COUNTER_ROLLOVER_HANDLER:
-		//	case EZSP_CUSTOM_FRAME_HANDLER:
-		//	case EZSP_CHILD_JOIN_HANDLER:
-		//	case EZSP_REMOTE_SET_BINDING_HANDLER:
-		//	case EZSP_REMOTE_DELETE_BINDING_HANDLER:
-		//	case EZSP_POLL_COMPLETE_HANDLER:
-		//	case EZSP_POLL_HANDLER:
-		//	case EZSP_INCOMING_MANY_TO_ONE_ROUTE_REQUEST_HANDLER:
-		//	case EZSP_ID_CONFLICT_HANDLER:
-		//	case EZSP_MAC_PASSTHROUGH_MESSAGE_HANDLER:
-		//	case EZSP_MAC_FILTER_MATCH_MESSAGE_HANDLER:
-		//	case EZSP_RAW_TRANSMIT_COMPLETE_HANDLER:
-		//	case EZSP_SWITCH_NETWORK_KEY_HANDLER:
-		//	case EZSP_ZIGBEE_KEY_ESTABLISHMENT_HANDLER:
-		//	case EZSP_GENERATE_CBKE_KEYS_HANDLER:
-		//	case EZSP_CALCULATE_SMACS_HANDLER:
-		//	case EZSP_GENERATE_CBKE_KEYS_HANDLER283K1:
-		//	case EZSP_CALCULATE_SMACS_HANDLER283K1:
-		//	case EZSP_DSA_SIGN_HANDLER:
-		//	case EZSP_DSA_VERIFY_HANDLER:
-		//	case EZSP_MFGLIB_RX_HANDLER:
-		//	case EZSP_INCOMING_BOOTLOAD_MESSAGE_HANDLER:
-		//	case EZSP_BOOTLOAD_TRANSMIT_COMPLETE_HANDLER:
-		//	case EZSP_ZLL_NETWORK_FOUND_HANDLER:
-		//	case EZSP_ZLL_SCAN_COMPLETE_HANDLER:
-		//	case EZSP_ZLL_ADDRESS_ASSIGNMENT_HANDLER:
-		//	case EZSP_ZLL_TOUCH_LINK_TARGET_HANDLER:
-		//	case EZSP_RF4CE_INCOMING_MESSAGE_HANDLER:
-		//	case EZSP_RF4CE_MESSAGE_SENT_HANDLER:
-		//	case EZSP_RF4CE_DISCOVERY_COMPLETE_HANDLER:
-		//	case EZSP_RF4CE_DISCOVERY_REQUEST_HANDLER:
-		//	case EZSP_RF4CE_DISCOVERY_RESPONSE_HANDLER:
-		//	case EZSP_RF4CE_AUTO_DISCOVERY_RESPONSE_COMPLETE_HANDLER:
-		//	case EZSP_RF4CE_PAIR_COMPLETE_HANDLER:
-		//	case EZSP_RF4CE_PAIR_REQUEST_HANDLER:
-		//	case EZSP_RF4CE_UNPAIR_HANDLER:
-		//	case EZSP_RF4CE_UNPAIR_COMPLETE_HANDLER:
-	default:
-		common.Log.Errorf("EzspCallbackDispatch unsupported callback %s", frameIDToName(cb.FrameID))
-	}
+// EzspCallbackDispatch 分发DefaultClient收到的callback
+func EzspCallbackDispatch(cb *EzspFrame) {
+	DefaultClient.EzspCallbackDispatch(cb)
 }
@@ -0,0 +1,138 @@
+package ezsp
+
+import (
+	"fmt"
+)
+
+type EmberBindingTableEntry struct {
+	/** The type of binding. */
+	Type byte
+	/** The endpoint on the local node. */
+	Local byte
+	/** A cluster ID that matches one from the local endpoint's simple descriptor. */
+	ClusterId uint16
+	/** The endpoint on the remote node (specified by identifier). */
+	Remote byte
+	/** A 64-bit identifier: the EUI64 of the destination device for unicast bindings,
+	 *  or the 64-bit group address for multicast bindings. */
+	Identifier uint64
+	/** The index of the network the binding belongs to. */
+	NetworkIndex byte
+}
+
+type EmberZllSecurityAlgorithmData struct {
+	TransactionId uint32
+	ResponseId    uint32
+	Bitmask       uint16
+}
+
+type EmberZllNetwork struct {
+	ZigbeeNetwork         EmberZigbeeNetwork
+	SecurityAlgorithm     EmberZllSecurityAlgorithmData
+	Eui64                 uint64
+	NodeId                uint16
+	State                 uint16
+	NodeType              byte
+	NumberSubDevices      byte
+	TotalGroupIdentifiers byte
+	RssiCorrection        byte
+}
+
+type EmberZllDeviceInfoRecord struct {
+	IeeeAddress  uint64
+	EndpointId   byte
+	ProfileId    uint16
+	DeviceId     uint16
+	Version      byte
+	GroupIdCount byte
+}
+
+type EmberZllAddressAssignment struct {
+	NodeId         uint16
+	FreeNodeIdMin  uint16
+	FreeNodeIdMax  uint16
+	GroupIdMin     uint16
+	GroupIdMax     uint16
+	FreeGroupIdMin uint16
+	FreeGroupIdMax uint16
+}
+
+type EmberRf4ceVendorInfo struct {
+	VendorId     uint16
+	VendorString [7]byte
+}
+
+type EmberRf4ceApplicationInfo struct {
+	Capabilities   byte
+	UserString     [15]byte
+	DeviceTypeList [3]byte
+	ProfileIdList  [7]byte
+}
+
+// callbackEventMap 每个callback对应的事件，事件结构体的字段顺序和EZSP协议文档里的参数一致，
+// 用 Unmarshal 解析
+var callbackEventMap = map[uint16]func() Event{
+	EZSP_STACK_TOKEN_CHANGED_HANDLER:                    func() Event { return &StackTokenChangedEvent{} },
+	EZSP_TIMER_HANDLER:                                  func() Event { return &TimerEvent{} },
+	EZSP_COUNTER_ROLLOVER_HANDLER:                       func() Event { return &CounterRolloverEvent{} },
+	EZSP_CUSTOM_FRAME_HANDLER:                           func() Event { return &CustomFrameEvent{} },
+	EZSP_STACK_STATUS_HANDLER:                           func() Event { return &StackStatusEvent{} },
+	EZSP_ENERGY_SCAN_RESULT_HANDLER:                     func() Event { return &EnergyScanResultEvent{} },
+	EZSP_NETWORK_FOUND_HANDLER:                          func() Event { return &NetworkFoundEvent{} },
+	EZSP_SCAN_COMPLETE_HANDLER:                          func() Event { return &ScanCompleteEvent{} },
+	EZSP_CHILD_JOIN_HANDLER:                             func() Event { return &ChildJoinEvent{} },
+	EZSP_REMOTE_SET_BINDING_HANDLER:                     func() Event { return &RemoteSetBindingEvent{} },
+	EZSP_REMOTE_DELETE_BINDING_HANDLER:                  func() Event { return &RemoteDeleteBindingEvent{} },
+	EZSP_MESSAGE_SENT_HANDLER:                           func() Event { return &MessageSentEvent{} },
+	EZSP_POLL_COMPLETE_HANDLER:                          func() Event { return &PollCompleteEvent{} },
+	EZSP_POLL_HANDLER:                                   func() Event { return &PollEvent{} },
+	EZSP_INCOMING_SENDER_EUI64_HANDLER:                  func() Event { return &IncomingSenderEui64Event{} },
+	EZSP_INCOMING_MESSAGE_HANDLER:                       func() Event { return &IncomingMessageEvent{} },
+	EZSP_INCOMING_ROUTE_RECORD_HANDLER:                  func() Event { return &IncomingRouteRecordEvent{} },
+	EZSP_INCOMING_MANY_TO_ONE_ROUTE_REQUEST_HANDLER:     func() Event { return &IncomingManyToOneRouteRequestEvent{} },
+	EZSP_INCOMING_ROUTE_ERROR_HANDLER:                   func() Event { return &IncomingRouteErrorEvent{} },
+	EZSP_ID_CONFLICT_HANDLER:                            func() Event { return &IdConflictEvent{} },
+	EZSP_MAC_PASSTHROUGH_MESSAGE_HANDLER:                func() Event { return &MacPassthroughMessageEvent{} },
+	EZSP_MAC_FILTER_MATCH_MESSAGE_HANDLER:               func() Event { return &MacFilterMatchMessageEvent{} },
+	EZSP_RAW_TRANSMIT_COMPLETE_HANDLER:                  func() Event { return &RawTransmitCompleteEvent{} },
+	EZSP_SWITCH_NETWORK_KEY_HANDLER:                     func() Event { return &SwitchNetworkKeyEvent{} },
+	EZSP_ZIGBEE_KEY_ESTABLISHMENT_HANDLER:               func() Event { return &ZigbeeKeyEstablishmentEvent{} },
+	EZSP_TRUST_CENTER_JOIN_HANDLER:                      func() Event { return &TrustCenterJoinEvent{} },
+	EZSP_GENERATE_CBKE_KEYS_HANDLER:                     func() Event { return &GenerateCbkeKeysEvent{} },
+	EZSP_CALCULATE_SMACS_HANDLER:                        func() Event { return &CalculateSmacsEvent{} },
+	EZSP_GENERATE_CBKE_KEYS_HANDLER283K1:                func() Event { return &GenerateCbkeKeys283k1Event{} },
+	EZSP_CALCULATE_SMACS_HANDLER283K1:                   func() Event { return &CalculateSmacs283k1Event{} },
+	EZSP_DSA_SIGN_HANDLER:                               func() Event { return &DsaSignEvent{} },
+	EZSP_DSA_VERIFY_HANDLER:                             func() Event { return &DsaVerifyEvent{} },
+	EZSP_MFGLIB_RX_HANDLER:                              func() Event { return &MfglibRxEvent{} },
+	EZSP_INCOMING_BOOTLOAD_MESSAGE_HANDLER:              func() Event { return &IncomingBootloadMessageEvent{} },
+	EZSP_BOOTLOAD_TRANSMIT_COMPLETE_HANDLER:             func() Event { return &BootloadTransmitCompleteEvent{} },
+	EZSP_ZLL_NETWORK_FOUND_HANDLER:                      func() Event { return &ZllNetworkFoundEvent{} },
+	EZSP_ZLL_SCAN_COMPLETE_HANDLER:                      func() Event { return &ZllScanCompleteEvent{} },
+	EZSP_ZLL_ADDRESS_ASSIGNMENT_HANDLER:                 func() Event { return &ZllAddressAssignmentEvent{} },
+	EZSP_ZLL_TOUCH_LINK_TARGET_HANDLER:                  func() Event { return &ZllTouchLinkTargetEvent{} },
+	EZSP_RF4CE_INCOMING_MESSAGE_HANDLER:                 func() Event { return &Rf4ceIncomingMessageEvent{} },
+	EZSP_RF4CE_MESSAGE_SENT_HANDLER:                     func() Event { return &Rf4ceMessageSentEvent{} },
+	EZSP_RF4CE_DISCOVERY_COMPLETE_HANDLER:               func() Event { return &Rf4ceDiscoveryCompleteEvent{} },
+	EZSP_RF4CE_DISCOVERY_REQUEST_HANDLER:                func() Event { return &Rf4ceDiscoveryRequestEvent{} },
+	EZSP_RF4CE_DISCOVERY_RESPONSE_HANDLER:               func() Event { return &Rf4ceDiscoveryResponseEvent{} },
+	EZSP_RF4CE_AUTO_DISCOVERY_RESPONSE_COMPLETE_HANDLER: func() Event { return &Rf4ceAutoDiscoveryResponseCompleteEvent{} },
+	EZSP_RF4CE_PAIR_COMPLETE_HANDLER:                    func() Event { return &Rf4cePairCompleteEvent{} },
+	EZSP_RF4CE_PAIR_REQUEST_HANDLER:                     func() Event { return &Rf4cePairRequestEvent{} },
+	EZSP_RF4CE_UNPAIR_HANDLER:                           func() Event { return &Rf4ceUnpairEvent{} },
+	EZSP_RF4CE_UNPAIR_COMPLETE_HANDLER:                  func() Event { return &Rf4ceUnpairCompleteEvent{} },
+}
+
+// callbackDecode 把callback帧解析成事件，本库不认识的callback返回 RawCallbackEvent。
+// 和以前的最小长度检查一样，新固件在后面追加的参数被忽略
+func callbackDecode(cb *EzspFrame) (Event, error) {
+	newEvent, ok := callbackEventMap[cb.FrameID]
+	if !ok {
+		return &RawCallbackEvent{ID: cb.FrameID, Data: cb.Data}, nil
+	}
+	event := newEvent()
+	if err := UnmarshalLenient(cb.Data, event); err != nil {
+		return nil, fmt.Errorf("%s with invalid Data length %d: %v", frameIDToName(cb.FrameID), len(cb.Data), err)
+	}
+	return event, nil
+}
@@ -0,0 +1,50 @@
+package ezsp
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// TestCallbackDecodeAll 协议里的每个callback帧ID都有解码器，用随机参数编码后能解回这个帧ID的事件，
+// 参数的值不变（全0的参数见 TestCallbackDecode）
+func TestCallbackDecodeAll(t *testing.T) {
+	for _, id := range allCallbackIDs {
+		if id == EZSP_NO_CALLBACKS { // 没有参数，EzspCallbackDispatch 直接跳过
+			continue
+		}
+		newEvent, ok := callbackEventMap[id]
+		if !ok {
+			t.Errorf("%s has no decoder", frameIDToName(id))
+			continue
+		}
+		if newEvent().FrameID() != id {
+			t.Errorf("%s decoder creates event with frame ID 0x%x", frameIDToName(id), newEvent().FrameID())
+			continue
+		}
+
+		rnd := rand.New(rand.NewSource(int64(id)))
+		value, ok := quick.Value(reflect.TypeOf(newEvent()).Elem(), rnd)
+		if !ok {
+			t.Fatalf("%s: cannot generate %T", frameIDToName(id), newEvent())
+		}
+		data, err := Marshal(value.Addr().Interface())
+		if err != nil {
+			t.Fatalf("%s Marshal: %v", frameIDToName(id), err)
+		}
+		event, err := callbackDecode(&EzspFrame{FrameID: id, Callback: 2, Data: data})
+		if err != nil {
+			t.Errorf("%s decode 0x%x: %v", frameIDToName(id), data, err)
+			continue
+		}
+		if event.FrameID() != id || reflect.TypeOf(event) != reflect.TypeOf(newEvent()) {
+			t.Errorf("%s decoded to %T with frame ID 0x%x", frameIDToName(id), event, event.FrameID())
+			continue
+		}
+		if again, _ := Marshal(event); !bytes.Equal(again, data) {
+			t.Errorf("%s decoded %+v, encodes to 0x%x, want 0x%x", frameIDToName(id), event, again, data)
+		}
+	}
+}
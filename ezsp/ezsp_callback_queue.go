@@ -0,0 +1,127 @@
+package ezsp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/conthing/utils/common"
+)
+
+// CallbackPolicy callback队列满了以后的处理方式
+type CallbackPolicy byte
+
+const (
+	// CALLBACK_POLICY_BLOCK 等待消费者取走，不丢弃callback，默认的处理方式。等待期间ASH收发线程被堵住，
+	// NCP的callback也就停下来了，所以消费者不能在处理callback的线程里一直等待EZSP命令的回复，否则会堵到命令超时
+	CALLBACK_POLICY_BLOCK = CallbackPolicy(0)
+	// CALLBACK_POLICY_DROP_OLDEST 丢弃队列里最早的callback，每次丢弃都记日志并计数
+	CALLBACK_POLICY_DROP_OLDEST = CallbackPolicy(1)
+	// CALLBACK_POLICY_ERROR 丢弃新来的callback并报错
+	CALLBACK_POLICY_ERROR = CallbackPolicy(2)
+)
+
+const defaultCallbackQueueSize = 256
+
+var callbackPolicyNameMap = map[CallbackPolicy]string{
+	CALLBACK_POLICY_DROP_OLDEST: "DROP_OLDEST",
+	CALLBACK_POLICY_ERROR:       "ERROR",
+	CALLBACK_POLICY_BLOCK:       "BLOCK",
+}
+
+func (p CallbackPolicy) String() string {
+	name, ok := callbackPolicyNameMap[p]
+	if !ok {
+		name = fmt.Sprintf("UNKNOWN_POLICY_%d", p)
+	}
+	return name
+}
+
+// StCallbackQueueSettings callback队列的设置
+type StCallbackQueueSettings struct {
+	Size   int            // 队列长度，0使用默认的256
+	Policy CallbackPolicy // 默认 CALLBACK_POLICY_BLOCK，队列满时不丢弃
+}
+
+// CallbackQueueSet 修改callback队列的长度和满了以后的处理方式，要在 StartTransceiver 之前调用，
+// 会重新创建 CallbackCh
+func (c *Client) CallbackQueueSet(settings *StCallbackQueueSettings) {
+	size := settings.Size
+	if size <= 0 {
+		size = defaultCallbackQueueSize
+	}
+	c.callbackPolicy = settings.Policy
+	c.CallbackCh = make(chan *EzspFrame, size)
+}
+
+// enqueueCallback 按收到的顺序把callback放进 CallbackCh，运行在ASH收发线程里
+func (c *Client) enqueueCallback(frame *EzspFrame) error {
+	select {
+	case c.CallbackCh <- frame:
+		c.callbackDepthUpdate()
+		return nil
+	default:
+	}
+
+	c.statsUpdate(func(s *StEzspStats) { s.CallbackOverflows++ })
+	switch c.callbackPolicy {
+	case CALLBACK_POLICY_ERROR:
+		c.statsUpdate(func(s *StEzspStats) { s.CallbackDropped++ })
+		return fmt.Errorf("EZSP callback queue full(%d), drop %s", cap(c.CallbackCh), frame)
+	case CALLBACK_POLICY_DROP_OLDEST:
+		for {
+			select {
+			case c.CallbackCh <- frame:
+				c.callbackDepthUpdate()
+				return nil
+			default:
+			}
+			select {
+			case dropped := <-c.CallbackCh:
+				common.Log.Warnf("EZSP callback queue full, drop oldest %s", dropped)
+				c.statsUpdate(func(s *StEzspStats) { s.CallbackDropped++ })
+			default: // 刚好被消费者取走了
+			}
+		}
+	default:
+		start := time.Now()
+		common.Log.Warnf("EZSP callback queue full(%d), wait for consumer", cap(c.CallbackCh))
+		c.CallbackCh <- frame
+		c.statsUpdate(func(s *StEzspStats) {
+			s.CallbackBlocked++
+			s.CallbackBlockedTime += time.Since(start)
+		})
+		return nil
+	}
+}
+
+func (c *Client) callbackDepthUpdate() {
+	depth := len(c.CallbackCh)
+	c.statsUpdate(func(s *StEzspStats) {
+		if depth > s.CallbackQueueMax {
+			s.CallbackQueueMax = depth
+		}
+	})
+}
+
+// EzspCallbackDispatchPending 不等待，把 c.CallbackCh 里已经有的callback按顺序分发完
+func (c *Client) EzspCallbackDispatchPending() {
+	for {
+		select {
+		case cb := <-c.CallbackCh:
+			c.EzspCallbackDispatch(cb)
+		default:
+			return
+		}
+	}
+}
+
+// EzspCallbackDispatchPending 分发DefaultClient已经收到的callback
+func EzspCallbackDispatchPending() {
+	DefaultClient.EzspCallbackDispatchPending()
+}
+
+// CallbackQueueSet 修改DefaultClient的callback队列，要在 ash.AshStartTransceiver 之前调用
+func CallbackQueueSet(settings *StCallbackQueueSettings) {
+	DefaultClient.CallbackQueueSet(settings)
+	CallbackCh = DefaultClient.CallbackCh
+}
@@ -0,0 +1,79 @@
+package ezsp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/conthing/ezsp/ash"
+)
+
+func callbackFrames(n int) []*EzspFrame {
+	frames := make([]*EzspFrame, n)
+	for i := range frames {
+		frames[i] = &EzspFrame{Sequence: byte(i), Callback: 2, FrameID: EZSP_STACK_STATUS_HANDLER}
+	}
+	return frames
+}
+
+// TestCallbackQueueBlock 默认等待消费者，队列满时不丢弃，按顺序交付
+func TestCallbackQueueBlock(t *testing.T) {
+	c := NewClient(ash.NewLink())
+	c.CallbackQueueSet(&StCallbackQueueSettings{Size: 2})
+	frames := callbackFrames(4)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, frame := range frames {
+			if err := c.enqueueCallback(frame); err != nil {
+				t.Errorf("enqueueCallback: %v", err)
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("enqueueCallback did not block on a full queue")
+	case <-time.After(time.Millisecond * 50):
+	}
+	for i := range frames {
+		if cb := <-c.CallbackCh; cb != frames[i] {
+			t.Fatalf("callback %d = %s, want %s", i, cb, frames[i])
+		}
+	}
+	<-done
+	if stats := c.Stats(); stats.CallbackDropped != 0 || stats.CallbackBlocked == 0 || stats.CallbackBlockedTime <= 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+// TestCallbackQueueDrop 明确设置的丢弃方式，每次丢弃都计数
+func TestCallbackQueueDrop(t *testing.T) {
+	c := NewClient(ash.NewLink())
+	c.CallbackQueueSet(&StCallbackQueueSettings{Size: 2, Policy: CALLBACK_POLICY_DROP_OLDEST})
+	frames := callbackFrames(4)
+	for _, frame := range frames {
+		if err := c.enqueueCallback(frame); err != nil {
+			t.Fatalf("enqueueCallback: %v", err)
+		}
+	}
+	if cb := <-c.CallbackCh; cb != frames[2] {
+		t.Fatalf("oldest callback = %s, want %s", cb, frames[2])
+	}
+	if stats := c.Stats(); stats.CallbackDropped != 2 || stats.CallbackOverflows != 2 {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	c.CallbackQueueSet(&StCallbackQueueSettings{Size: 1, Policy: CALLBACK_POLICY_ERROR})
+	if err := c.enqueueCallback(frames[0]); err != nil {
+		t.Fatalf("enqueueCallback: %v", err)
+	}
+	if err := c.enqueueCallback(frames[1]); err == nil {
+		t.Fatal("enqueueCallback on a full queue succeeded")
+	}
+	if cb := <-c.CallbackCh; cb != frames[0] {
+		t.Fatalf("callback = %s, want %s", cb, frames[0])
+	}
+	if stats := c.Stats(); stats.CallbackDropped != 3 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
@@ -0,0 +1,219 @@
+package ezsp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+)
+
+// EZSP结构体的编解码。字段按定义的顺序编码，整数小端，bool占一个字节，
+// 数组按元素依次编码，嵌套的结构体展开编码。字段的tag:
+//
+//	`ezsp:"len8"`     切片，前面有一个字节的元素个数
+//	`ezsp:"rest"`     切片，占用剩下的全部数据，只能用在最后一个字段
+//	`ezsp:"optional"` 数据已经结束时保持零值，用于新版本协议追加的参数
+//	`ezsp:"-"`        不参与编解码
+//
+// 不导出的字段也会被编码，但 Unmarshal 不能写入不导出的字段
+
+// Marshal 把结构体（或者指向结构体的指针）编码成EZSP参数
+func Marshal(v interface{}) ([]byte, error) {
+	e := &encoder{}
+	if err := e.value(reflect.Indirect(reflect.ValueOf(v)), ""); err != nil {
+		return nil, err
+	}
+	return e.data, nil
+}
+
+// Unmarshal 把EZSP参数解码到v指向的结构体，数据不够或者有多余的数据都返回错误
+func Unmarshal(data []byte, v interface{}) error {
+	off, err := unmarshal(data, v)
+	if err != nil {
+		return err
+	}
+	if off != len(data) {
+		return fmt.Errorf("ezsp unmarshal %T: %d bytes left over of %d", v, len(data)-off, len(data))
+	}
+	return nil
+}
+
+// UnmarshalLenient 和 Unmarshal 一样，但忽略多余的数据，用于新固件可能在后面追加参数的callback
+func UnmarshalLenient(data []byte, v interface{}) error {
+	_, err := unmarshal(data, v)
+	return err
+}
+
+// unmarshal 返回解码用掉的字节数
+func unmarshal(data []byte, v interface{}) (int, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return 0, fmt.Errorf("ezsp unmarshal needs non-nil pointer, get %T", v)
+	}
+	d := &decoder{data: data}
+	if err := d.value(rv.Elem(), ""); err != nil {
+		return 0, err
+	}
+	return d.off, nil
+}
+
+type encoder struct {
+	data []byte
+}
+
+func (e *encoder) value(v reflect.Value, tag string) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.data = append(e.data, 1)
+		} else {
+			e.data = append(e.data, 0)
+		}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		e.uint(v.Uint(), int(v.Type().Size()))
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.uint(uint64(v.Int()), int(v.Type().Size()))
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := e.value(v.Index(i), ""); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		switch tag {
+		case "len8":
+			if v.Len() > 0xff {
+				return fmt.Errorf("ezsp marshal %s length %d > 255", v.Type(), v.Len())
+			}
+			e.data = append(e.data, byte(v.Len()))
+		case "rest":
+		default:
+			return fmt.Errorf("ezsp marshal %s needs len8 or rest tag", v.Type())
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := e.value(v.Index(i), ""); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			fieldTag := t.Field(i).Tag.Get("ezsp")
+			if fieldTag == "-" {
+				continue
+			}
+			if err := e.value(v.Field(i), fieldTag); err != nil {
+				return fmt.Errorf("%s.%s: %v", t.Name(), t.Field(i).Name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("ezsp marshal unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func (e *encoder) uint(u uint64, size int) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], u)
+	e.data = append(e.data, b[:size]...)
+}
+
+type decoder struct {
+	data []byte
+	off  int
+}
+
+func (d *decoder) next(n int, t reflect.Type) ([]byte, error) {
+	if d.off+n > len(d.data) {
+		return nil, fmt.Errorf("ezsp unmarshal %s needs %d bytes at offset %d, data length %d", t, n, d.off, len(d.data))
+	}
+	b := d.data[d.off : d.off+n]
+	d.off += n
+	return b, nil
+}
+
+func (d *decoder) value(v reflect.Value, tag string) error {
+	if tag == "optional" && d.off == len(d.data) {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := d.next(1, v.Type())
+		if err != nil {
+			return err
+		}
+		v.SetBool(b[0] != 0)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := d.uint(v.Type())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		u, err := d.uint(v.Type())
+		if err != nil {
+			return err
+		}
+		shift := 64 - 8*uint(v.Type().Size())
+		v.SetInt(int64(u<<shift) >> shift) // 符号扩展
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := d.value(v.Index(i), ""); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), 0, 0)
+		switch tag {
+		case "len8":
+			b, err := d.next(1, v.Type())
+			if err != nil {
+				return err
+			}
+			s = reflect.MakeSlice(v.Type(), int(b[0]), int(b[0]))
+			for i := 0; i < s.Len(); i++ {
+				if err := d.value(s.Index(i), ""); err != nil {
+					return err
+				}
+			}
+		case "rest":
+			for d.off < len(d.data) {
+				elem := reflect.New(v.Type().Elem()).Elem()
+				if err := d.value(elem, ""); err != nil {
+					return err
+				}
+				s = reflect.Append(s, elem)
+			}
+		default:
+			return fmt.Errorf("ezsp unmarshal %s needs len8 or rest tag", v.Type())
+		}
+		v.Set(s)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			fieldTag := t.Field(i).Tag.Get("ezsp")
+			if fieldTag == "-" {
+				continue
+			}
+			if !v.Field(i).CanSet() {
+				return fmt.Errorf("ezsp unmarshal %s.%s not exported", t.Name(), t.Field(i).Name)
+			}
+			if err := d.value(v.Field(i), fieldTag); err != nil {
+				return fmt.Errorf("%s.%s: %v", t.Name(), t.Field(i).Name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("ezsp unmarshal unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func (d *decoder) uint(t reflect.Type) (uint64, error) {
+	size := int(t.Size())
+	b, err := d.next(size, t)
+	if err != nil {
+		return 0, err
+	}
+	var buf [8]byte
+	copy(buf[:], b)
+	return binary.LittleEndian.Uint64(buf[:]), nil
+}
@@ -0,0 +1,166 @@
+package ezsp
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+type stCodecInner struct {
+	A uint16
+	B int8
+}
+
+type stCodecAll struct {
+	Flag    bool
+	U8      byte
+	U16     uint16
+	U32     uint32
+	U64     uint64
+	I16     int16
+	Array   [3]byte
+	Inner   stCodecInner
+	List    []uint16 `ezsp:"len8"`
+	Skipped byte     `ezsp:"-"`
+	Rest    []byte   `ezsp:"rest"`
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	v := stCodecAll{Flag: true, U8: 0x12, U16: 0x3456, U32: 0x789abcde, U64: 0x0123456789abcdef, I16: -2,
+		Array: [3]byte{1, 2, 3}, Inner: stCodecInner{A: 0xbeef, B: -128}, List: []uint16{0x0102, 0x0304}, Skipped: 9, Rest: []byte{0xaa, 0xbb}}
+	want := []byte{0x01, 0x12, 0x56, 0x34, 0xde, 0xbc, 0x9a, 0x78, 0xef, 0xcd, 0xab, 0x89, 0x67, 0x45, 0x23, 0x01, 0xfe, 0xff,
+		1, 2, 3, 0xef, 0xbe, 0x80, 2, 0x02, 0x01, 0x04, 0x03, 0xaa, 0xbb}
+
+	data, err := Marshal(&v)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Fatalf("Marshal = 0x%x, want 0x%x", data, want)
+	}
+
+	var got stCodecAll
+	if err = Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	v.Skipped = 0
+	if !reflect.DeepEqual(got, v) {
+		t.Fatalf("Unmarshal = %+v, want %+v", got, v)
+	}
+}
+
+func TestMarshalStructs(t *testing.T) {
+	values := []interface{}{
+		&EmberApsFrame{ProfileId: 0x0104, ClusterId: 0x0006, SourceEndpoint: 1, DestinationEndpoint: 2, Options: 0x1140, GroupId: 0x0203, Sequence: 7},
+		&EmberNetworkParameters{ExtendedPanId: 0x1122334455667788, PanId: 0x1234, RadioTxPower: -3, RadioChannel: 15, JoinMethod: 1, NwkManagerId: 0, NwkUpdateId: 2, Channels: 0x07fff800},
+		&IncomingMessageEvent{IncomingMessageType: EMBER_INCOMING_UNICAST, ApsFrame: EmberApsFrame{ProfileId: 0x0104, ClusterId: 0x0402},
+			LastHopLqi: 0xff, LastHopRssi: -40, Sender: 0x1001, BindingIndex: 0xff, AddressIndex: 0xff, Message: []byte{0x18, 0x01, 0x0a}},
+	}
+	for _, v := range values {
+		data, err := Marshal(v)
+		if err != nil {
+			t.Fatalf("Marshal(%T): %v", v, err)
+		}
+		got := reflect.New(reflect.TypeOf(v).Elem()).Interface()
+		if err = Unmarshal(data, got); err != nil {
+			t.Fatalf("Unmarshal(%T): %v", v, err)
+		}
+		if !reflect.DeepEqual(got, v) {
+			t.Fatalf("%T round trip = %+v, want %+v", v, got, v)
+		}
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	var v struct {
+		A uint16
+		B []byte `ezsp:"len8"`
+	}
+	if err := Unmarshal([]byte{1}, &v); err == nil {
+		t.Error("short uint16 accepted")
+	}
+	if err := Unmarshal([]byte{1, 0, 3, 0xaa}, &v); err == nil {
+		t.Error("short len8 slice accepted")
+	}
+	if err := Unmarshal([]byte{1, 0, 1, 0xaa, 0xbb}, &v); err == nil {
+		t.Error("left over data accepted")
+	}
+	if err := UnmarshalLenient([]byte{1, 0, 1, 0xaa, 0xbb}, &v); err != nil || v.A != 1 || !bytes.Equal(v.B, []byte{0xaa}) {
+		t.Errorf("UnmarshalLenient = %+v, %v", v, err)
+	}
+	if err := Unmarshal([]byte{1, 0, 0}, v); err == nil {
+		t.Error("Unmarshal to non-pointer accepted")
+	}
+
+	var untagged struct{ B []byte }
+	if _, err := Marshal(&untagged); err == nil {
+		t.Error("Marshal slice without tag accepted")
+	}
+	long := struct {
+		B []byte `ezsp:"len8"`
+	}{make([]byte, 256)}
+	if _, err := Marshal(&long); err == nil {
+		t.Error("Marshal len8 slice longer than 255 accepted")
+	}
+	var unexported struct{ a byte }
+	if err := Unmarshal([]byte{1}, &unexported); err == nil {
+		t.Error("Unmarshal to unexported field accepted")
+	}
+}
+
+func TestUnmarshalOptional(t *testing.T) {
+	var v struct {
+		A byte
+		B uint16 `ezsp:"optional"`
+	}
+	if err := Unmarshal([]byte{1}, &v); err != nil || v.A != 1 || v.B != 0 {
+		t.Fatalf("Unmarshal without optional field = %+v, %v", v, err)
+	}
+	if err := Unmarshal([]byte{1, 2, 0}, &v); err != nil || v.B != 2 {
+		t.Fatalf("Unmarshal with optional field = %+v, %v", v, err)
+	}
+}
+
+// TestCallbackDecode 每个callback事件编码后都能解码回来，新固件追加的参数被忽略，数据不够时报错
+func TestCallbackDecode(t *testing.T) {
+	for frameID, newEvent := range callbackEventMap {
+		data, err := Marshal(newEvent())
+		if err != nil {
+			t.Fatalf("Marshal %s: %v", frameIDToName(frameID), err)
+		}
+		for _, extra := range [][]byte{nil, {0x01, 0x02}} {
+			event, err := callbackDecode(&EzspFrame{FrameID: frameID, Data: append(append([]byte(nil), data...), extra...)})
+			if err != nil {
+				t.Fatalf("decode %s with %d extra bytes: %v", frameIDToName(frameID), len(extra), err)
+			}
+			if event.FrameID() != frameID {
+				t.Fatalf("decode %s get event %T", frameIDToName(frameID), event)
+			}
+			again, err := Marshal(event)
+			if err != nil || !bytes.Equal(again, data) {
+				t.Fatalf("%s round trip 0x%x, want 0x%x, %v", frameIDToName(frameID), again, data, err)
+			}
+		}
+		if len(data) > 0 {
+			if _, err = callbackDecode(&EzspFrame{FrameID: frameID, Data: data[:len(data)-1]}); err == nil && !hasOptionalTail(newEvent()) {
+				t.Fatalf("decode truncated %s accepted", frameIDToName(frameID))
+			}
+		}
+	}
+
+	event, err := callbackDecode(&EzspFrame{FrameID: 0x00fe, Data: []byte{1, 2}})
+	raw, ok := event.(*RawCallbackEvent)
+	if err != nil || !ok || raw.ID != 0x00fe || !bytes.Equal(raw.Data, []byte{1, 2}) {
+		t.Fatalf("decode unknown callback = %+v, %v", event, err)
+	}
+}
+
+// hasOptionalTail 最后一个字段可以省略或者占用剩下的数据时，少一个字节也能解码
+func hasOptionalTail(v interface{}) bool {
+	t := reflect.TypeOf(v).Elem()
+	if t.NumField() == 0 {
+		return false
+	}
+	tag := t.Field(t.NumField() - 1).Tag.Get("ezsp")
+	return tag == "optional" || tag == "rest"
+}
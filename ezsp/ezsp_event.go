@@ -0,0 +1,475 @@
+package ezsp
+
+// Event EZSP callback解析后的事件，由 Client.EzspCallbackDispatch 发布到这个Client的事件总线
+type Event interface {
+	FrameID() uint16
+}
+
+// HOST_EVENT_ID_MIN 开始的ID不是EZSP帧ID，留给其他包在事件总线上发布主机自己产生的事件，比如interview的结果
+const HOST_EVENT_ID_MIN = uint16(0xF000)
+
+// apsEvent 带APS帧头的事件，可以按profile和cluster过滤
+type apsEvent interface {
+	Event
+	aps() *EmberApsFrame
+}
+
+// IncomingMessageEvent 收到消息，incomingMessageHandler
+type IncomingMessageEvent struct {
+	IncomingMessageType byte
+	ApsFrame            EmberApsFrame
+	LastHopLqi          byte
+	LastHopRssi         int8
+	Sender              uint16
+	BindingIndex        byte
+	AddressIndex        byte
+	Message             []byte `ezsp:"len8"`
+}
+
+func (e *IncomingMessageEvent) FrameID() uint16     { return EZSP_INCOMING_MESSAGE_HANDLER }
+func (e *IncomingMessageEvent) aps() *EmberApsFrame { return &e.ApsFrame }
+
+// IncomingSenderEui64Event 紧接着的incomingMessageHandler的发送者EUI64，incomingSenderEui64Handler
+type IncomingSenderEui64Event struct {
+	SenderEui64 uint64
+}
+
+func (e *IncomingSenderEui64Event) FrameID() uint16 { return EZSP_INCOMING_SENDER_EUI64_HANDLER }
+
+// MessageSentEvent 消息发送完成，messageSentHandler
+type MessageSentEvent struct {
+	OutgoingMessageType byte
+	IndexOrDestination  uint16
+	ApsFrame            EmberApsFrame
+	MessageTag          byte
+	EmberStatus         byte
+	Message             []byte `ezsp:"len8"`
+}
+
+func (e *MessageSentEvent) FrameID() uint16     { return EZSP_MESSAGE_SENT_HANDLER }
+func (e *MessageSentEvent) aps() *EmberApsFrame { return &e.ApsFrame }
+
+// TrustCenterJoinEvent 设备入网、离网或者更新，trustCenterJoinHandler
+type TrustCenterJoinEvent struct {
+	NewNodeId          uint16
+	NewNodeEui64       uint64
+	DeviceUpdateStatus byte
+	JoinDecision       byte
+	ParentOfNewNode    uint16
+}
+
+func (e *TrustCenterJoinEvent) FrameID() uint16 { return EZSP_TRUST_CENTER_JOIN_HANDLER }
+
+// StackStatusEvent 网络状态变化，stackStatusHandler
+type StackStatusEvent struct {
+	EmberStatus byte
+}
+
+func (e *StackStatusEvent) FrameID() uint16 { return EZSP_STACK_STATUS_HANDLER }
+
+// IncomingRouteRecordEvent 收到route record，incomingRouteRecordHandler
+type IncomingRouteRecordEvent struct {
+	Source      uint16
+	SourceEui   uint64
+	LastHopLqi  byte
+	LastHopRssi int8
+	Relay       []uint16 `ezsp:"len8"`
+}
+
+func (e *IncomingRouteRecordEvent) FrameID() uint16 { return EZSP_INCOMING_ROUTE_RECORD_HANDLER }
+
+// IncomingRouteErrorEvent 路由出错，incomingRouteErrorHandler
+type IncomingRouteErrorEvent struct {
+	EmberStatus byte
+	Target      uint16
+}
+
+func (e *IncomingRouteErrorEvent) FrameID() uint16 { return EZSP_INCOMING_ROUTE_ERROR_HANDLER }
+
+// EnergyScanResultEvent 能量扫描结果，energyScanResultHandler
+type EnergyScanResultEvent struct {
+	Channel      byte
+	MaxRssiValue int8
+}
+
+func (e *EnergyScanResultEvent) FrameID() uint16 { return EZSP_ENERGY_SCAN_RESULT_HANDLER }
+
+// ScanCompleteEvent 扫描结束，scanCompleteHandler
+type ScanCompleteEvent struct {
+	Channel     byte
+	EmberStatus byte
+}
+
+func (e *ScanCompleteEvent) FrameID() uint16 { return EZSP_SCAN_COMPLETE_HANDLER }
+
+// NetworkFoundEvent 主动扫描发现网络，networkFoundHandler
+type NetworkFoundEvent struct {
+	NetworkFound EmberZigbeeNetwork
+	Lqi          byte
+	Rssi         int8
+}
+
+func (e *NetworkFoundEvent) FrameID() uint16 { return EZSP_NETWORK_FOUND_HANDLER }
+
+// StackTokenChangedEvent NVM3/SimEE token被修改，stackTokenChangedHandler
+type StackTokenChangedEvent struct {
+	TokenAddress uint16
+}
+
+func (e *StackTokenChangedEvent) FrameID() uint16 { return EZSP_STACK_TOKEN_CHANGED_HANDLER }
+
+// TimerEvent setTimer设置的定时器到期，timerHandler
+type TimerEvent struct {
+	TimerId byte
+}
+
+func (e *TimerEvent) FrameID() uint16 { return EZSP_TIMER_HANDLER }
+
+// CounterRolloverEvent 计数器溢出，counterRolloverHandler
+type CounterRolloverEvent struct {
+	Type byte // EmberCounterType
+}
+
+func (e *CounterRolloverEvent) FrameID() uint16 { return EZSP_COUNTER_ROLLOVER_HANDLER }
+
+// CustomFrameEvent NCP应用层自定义的帧，customFrameHandler
+type CustomFrameEvent struct {
+	Payload []byte `ezsp:"len8"`
+}
+
+func (e *CustomFrameEvent) FrameID() uint16 { return EZSP_CUSTOM_FRAME_HANDLER }
+
+// ChildJoinEvent 子节点加入或离开，childJoinHandler
+type ChildJoinEvent struct {
+	Index      byte
+	Joining    bool
+	ChildId    uint16
+	ChildEui64 uint64
+	ChildType  byte // EmberNodeType
+}
+
+func (e *ChildJoinEvent) FrameID() uint16 { return EZSP_CHILD_JOIN_HANDLER }
+
+// RemoteSetBindingEvent 远程设备要设置绑定，remoteSetBindingHandler
+type RemoteSetBindingEvent struct {
+	Entry          EmberBindingTableEntry
+	Index          byte
+	PolicyDecision byte
+}
+
+func (e *RemoteSetBindingEvent) FrameID() uint16 { return EZSP_REMOTE_SET_BINDING_HANDLER }
+
+// RemoteDeleteBindingEvent 远程设备要删除绑定，remoteDeleteBindingHandler
+type RemoteDeleteBindingEvent struct {
+	Index          byte
+	PolicyDecision byte
+}
+
+func (e *RemoteDeleteBindingEvent) FrameID() uint16 { return EZSP_REMOTE_DELETE_BINDING_HANDLER }
+
+// PollCompleteEvent 终端设备poll结束，pollCompleteHandler
+type PollCompleteEvent struct {
+	Status byte
+}
+
+func (e *PollCompleteEvent) FrameID() uint16 { return EZSP_POLL_COMPLETE_HANDLER }
+
+// PollEvent 子节点来poll，pollHandler，TransmitExpected 只有协议版本8以上才有
+type PollEvent struct {
+	ChildId          uint16
+	TransmitExpected bool `ezsp:"optional"`
+}
+
+func (e *PollEvent) FrameID() uint16 { return EZSP_POLL_HANDLER }
+
+// IncomingManyToOneRouteRequestEvent 收到其他concentrator的MTORR，incomingManyToOneRouteRequestHandler
+type IncomingManyToOneRouteRequestEvent struct {
+	Source uint16
+	LongId uint64
+	Cost   byte
+}
+
+func (e *IncomingManyToOneRouteRequestEvent) FrameID() uint16 {
+	return EZSP_INCOMING_MANY_TO_ONE_ROUTE_REQUEST_HANDLER
+}
+
+// IdConflictEvent 检测到短地址冲突，idConflictHandler
+type IdConflictEvent struct {
+	Id uint16
+}
+
+func (e *IdConflictEvent) FrameID() uint16 { return EZSP_ID_CONFLICT_HANDLER }
+
+// MacPassthroughMessageEvent 收到透传的MAC帧，macPassthroughMessageHandler
+type MacPassthroughMessageEvent struct {
+	MessageType byte // EmberMacPassthroughType
+	LastHopLqi  byte
+	LastHopRssi int8
+	Message     []byte `ezsp:"len8"`
+}
+
+func (e *MacPassthroughMessageEvent) FrameID() uint16 { return EZSP_MAC_PASSTHROUGH_MESSAGE_HANDLER }
+
+// MacFilterMatchMessageEvent 收到匹配MAC过滤器的帧，macFilterMatchMessageHandler
+type MacFilterMatchMessageEvent struct {
+	FilterIndexMatch      byte
+	LegacyPassthroughType byte
+	LastHopLqi            byte
+	LastHopRssi           int8
+	Message               []byte `ezsp:"len8"`
+}
+
+func (e *MacFilterMatchMessageEvent) FrameID() uint16 { return EZSP_MAC_FILTER_MATCH_MESSAGE_HANDLER }
+
+// RawTransmitCompleteEvent sendRawMessage发送完成，rawTransmitCompleteHandler
+type RawTransmitCompleteEvent struct {
+	Status byte
+}
+
+func (e *RawTransmitCompleteEvent) FrameID() uint16 { return EZSP_RAW_TRANSMIT_COMPLETE_HANDLER }
+
+// SwitchNetworkKeyEvent 切换到新的网络密钥，switchNetworkKeyHandler
+type SwitchNetworkKeyEvent struct {
+	SequenceNumber byte
+}
+
+func (e *SwitchNetworkKeyEvent) FrameID() uint16 { return EZSP_SWITCH_NETWORK_KEY_HANDLER }
+
+// ZigbeeKeyEstablishmentEvent 和其他设备建立link key的结果，zigbeeKeyEstablishmentHandler
+type ZigbeeKeyEstablishmentEvent struct {
+	Partner uint64
+	Status  byte // EmberKeyStatus
+}
+
+func (e *ZigbeeKeyEstablishmentEvent) FrameID() uint16 { return EZSP_ZIGBEE_KEY_ESTABLISHMENT_HANDLER }
+
+// GenerateCbkeKeysEvent generateCbkeKeys的结果，generateCbkeKeysHandler
+type GenerateCbkeKeysEvent struct {
+	Status             byte
+	EphemeralPublicKey [22]byte
+}
+
+func (e *GenerateCbkeKeysEvent) FrameID() uint16 { return EZSP_GENERATE_CBKE_KEYS_HANDLER }
+
+// CalculateSmacsEvent calculateSmacs的结果，calculateSmacsHandler
+type CalculateSmacsEvent struct {
+	Status        byte
+	InitiatorSmac [16]byte
+	ResponderSmac [16]byte
+}
+
+func (e *CalculateSmacsEvent) FrameID() uint16 { return EZSP_CALCULATE_SMACS_HANDLER }
+
+// GenerateCbkeKeys283k1Event generateCbkeKeys283k1的结果，generateCbkeKeysHandler283k1
+type GenerateCbkeKeys283k1Event struct {
+	Status             byte
+	EphemeralPublicKey [37]byte
+}
+
+func (e *GenerateCbkeKeys283k1Event) FrameID() uint16 { return EZSP_GENERATE_CBKE_KEYS_HANDLER283K1 }
+
+// CalculateSmacs283k1Event calculateSmacs283k1的结果，calculateSmacsHandler283k1
+type CalculateSmacs283k1Event struct {
+	Status        byte
+	InitiatorSmac [16]byte
+	ResponderSmac [16]byte
+}
+
+func (e *CalculateSmacs283k1Event) FrameID() uint16 { return EZSP_CALCULATE_SMACS_HANDLER283K1 }
+
+// DsaSignEvent dsaSign的结果，dsaSignHandler
+type DsaSignEvent struct {
+	Status  byte
+	Message []byte `ezsp:"len8"`
+}
+
+func (e *DsaSignEvent) FrameID() uint16 { return EZSP_DSA_SIGN_HANDLER }
+
+// DsaVerifyEvent dsaVerify的结果，dsaVerifyHandler
+type DsaVerifyEvent struct {
+	Status byte
+}
+
+func (e *DsaVerifyEvent) FrameID() uint16 { return EZSP_DSA_VERIFY_HANDLER }
+
+// MfglibRxEvent mfglib模式收到的包，mfglibRxHandler
+type MfglibRxEvent struct {
+	LinkQuality byte
+	Rssi        int8
+	Packet      []byte `ezsp:"len8"`
+}
+
+func (e *MfglibRxEvent) FrameID() uint16 { return EZSP_MFGLIB_RX_HANDLER }
+
+// IncomingBootloadMessageEvent 收到bootload消息，incomingBootloadMessageHandler
+type IncomingBootloadMessageEvent struct {
+	LongId      uint64
+	LastHopLqi  byte
+	LastHopRssi int8
+	Message     []byte `ezsp:"len8"`
+}
+
+func (e *IncomingBootloadMessageEvent) FrameID() uint16 {
+	return EZSP_INCOMING_BOOTLOAD_MESSAGE_HANDLER
+}
+
+// BootloadTransmitCompleteEvent bootload消息发送完成，bootloadTransmitCompleteHandler
+type BootloadTransmitCompleteEvent struct {
+	Status  byte
+	Message []byte `ezsp:"len8"`
+}
+
+func (e *BootloadTransmitCompleteEvent) FrameID() uint16 {
+	return EZSP_BOOTLOAD_TRANSMIT_COMPLETE_HANDLER
+}
+
+// ZllNetworkFoundEvent ZLL扫描发现网络，zllNetworkFoundHandler，IsDeviceInfoNull为true时DeviceInfo无效
+type ZllNetworkFoundEvent struct {
+	NetworkInfo      EmberZllNetwork
+	IsDeviceInfoNull bool
+	DeviceInfo       EmberZllDeviceInfoRecord
+	LastHopLqi       byte
+	LastHopRssi      int8
+}
+
+func (e *ZllNetworkFoundEvent) FrameID() uint16 { return EZSP_ZLL_NETWORK_FOUND_HANDLER }
+
+// ZllScanCompleteEvent ZLL扫描结束，zllScanCompleteHandler
+type ZllScanCompleteEvent struct {
+	Status byte
+}
+
+func (e *ZllScanCompleteEvent) FrameID() uint16 { return EZSP_ZLL_SCAN_COMPLETE_HANDLER }
+
+// ZllAddressAssignmentEvent ZLL分配的地址，zllAddressAssignmentHandler
+type ZllAddressAssignmentEvent struct {
+	AddressInfo EmberZllAddressAssignment
+	LastHopLqi  byte
+	LastHopRssi int8
+}
+
+func (e *ZllAddressAssignmentEvent) FrameID() uint16 { return EZSP_ZLL_ADDRESS_ASSIGNMENT_HANDLER }
+
+// ZllTouchLinkTargetEvent 作为touch link的目标被选中，zllTouchLinkTargetHandler
+type ZllTouchLinkTargetEvent struct {
+	NetworkInfo EmberZllNetwork
+}
+
+func (e *ZllTouchLinkTargetEvent) FrameID() uint16 { return EZSP_ZLL_TOUCH_LINK_TARGET_HANDLER }
+
+// Rf4ceIncomingMessageEvent 收到RF4CE消息，rf4ceIncomingMessageHandler
+type Rf4ceIncomingMessageEvent struct {
+	PairingIndex byte
+	ProfileId    byte
+	VendorId     uint16
+	TxOptions    byte
+	Message      []byte `ezsp:"len8"`
+}
+
+func (e *Rf4ceIncomingMessageEvent) FrameID() uint16 { return EZSP_RF4CE_INCOMING_MESSAGE_HANDLER }
+
+// Rf4ceMessageSentEvent RF4CE消息发送完成，rf4ceMessageSentHandler
+type Rf4ceMessageSentEvent struct {
+	Status       byte
+	PairingIndex byte
+	TxOptions    byte
+	ProfileId    byte
+	VendorId     uint16
+	MessageTag   byte
+	Message      []byte `ezsp:"len8"`
+}
+
+func (e *Rf4ceMessageSentEvent) FrameID() uint16 { return EZSP_RF4CE_MESSAGE_SENT_HANDLER }
+
+// Rf4ceDiscoveryCompleteEvent RF4CE discovery结束，rf4ceDiscoveryCompleteHandler
+type Rf4ceDiscoveryCompleteEvent struct {
+	Status byte
+}
+
+func (e *Rf4ceDiscoveryCompleteEvent) FrameID() uint16 { return EZSP_RF4CE_DISCOVERY_COMPLETE_HANDLER }
+
+// Rf4ceDiscoveryRequestEvent 收到RF4CE discovery请求，rf4ceDiscoveryRequestHandler
+type Rf4ceDiscoveryRequestEvent struct {
+	SrcIeeeAddr      uint64
+	NodeCapabilities byte
+	VendorInfo       EmberRf4ceVendorInfo
+	AppInfo          EmberRf4ceApplicationInfo
+	SearchDevType    byte
+	RxLinkQuality    byte
+}
+
+func (e *Rf4ceDiscoveryRequestEvent) FrameID() uint16 { return EZSP_RF4CE_DISCOVERY_REQUEST_HANDLER }
+
+// Rf4ceDiscoveryResponseEvent 收到RF4CE discovery回复，rf4ceDiscoveryResponseHandler
+type Rf4ceDiscoveryResponseEvent struct {
+	AtCapacity       bool
+	Channel          byte
+	PanId            uint16
+	SrcIeeeAddr      uint64
+	NodeCapabilities byte
+	VendorInfo       EmberRf4ceVendorInfo
+	AppInfo          EmberRf4ceApplicationInfo
+	RxLinkQuality    byte
+	DiscRequestLqi   byte
+}
+
+func (e *Rf4ceDiscoveryResponseEvent) FrameID() uint16 { return EZSP_RF4CE_DISCOVERY_RESPONSE_HANDLER }
+
+// Rf4ceAutoDiscoveryResponseCompleteEvent RF4CE自动discovery回复结束，rf4ceAutoDiscoveryResponseCompleteHandler
+type Rf4ceAutoDiscoveryResponseCompleteEvent struct {
+	Status           byte
+	SrcIeeeAddr      uint64
+	NodeCapabilities byte
+	VendorInfo       EmberRf4ceVendorInfo
+	AppInfo          EmberRf4ceApplicationInfo
+	SearchDevType    byte
+}
+
+func (e *Rf4ceAutoDiscoveryResponseCompleteEvent) FrameID() uint16 {
+	return EZSP_RF4CE_AUTO_DISCOVERY_RESPONSE_COMPLETE_HANDLER
+}
+
+// Rf4cePairCompleteEvent RF4CE配对结束，rf4cePairCompleteHandler
+type Rf4cePairCompleteEvent struct {
+	Status       byte
+	PairingIndex byte
+	VendorInfo   EmberRf4ceVendorInfo
+	AppInfo      EmberRf4ceApplicationInfo
+}
+
+func (e *Rf4cePairCompleteEvent) FrameID() uint16 { return EZSP_RF4CE_PAIR_COMPLETE_HANDLER }
+
+// Rf4cePairRequestEvent 收到RF4CE配对请求，rf4cePairRequestHandler
+type Rf4cePairRequestEvent struct {
+	Status                   byte
+	PairingIndex             byte
+	SrcIeeeAddr              uint64
+	NodeCapabilities         byte
+	VendorInfo               EmberRf4ceVendorInfo
+	AppInfo                  EmberRf4ceApplicationInfo
+	KeyExchangeTransferCount byte
+}
+
+func (e *Rf4cePairRequestEvent) FrameID() uint16 { return EZSP_RF4CE_PAIR_REQUEST_HANDLER }
+
+// Rf4ceUnpairEvent 对方解除RF4CE配对，rf4ceUnpairHandler
+type Rf4ceUnpairEvent struct {
+	PairingIndex byte
+}
+
+func (e *Rf4ceUnpairEvent) FrameID() uint16 { return EZSP_RF4CE_UNPAIR_HANDLER }
+
+// Rf4ceUnpairCompleteEvent 解除RF4CE配对结束，rf4ceUnpairCompleteHandler
+type Rf4ceUnpairCompleteEvent struct {
+	PairingIndex byte
+}
+
+func (e *Rf4ceUnpairCompleteEvent) FrameID() uint16 { return EZSP_RF4CE_UNPAIR_COMPLETE_HANDLER }
+
+// RawCallbackEvent 本库不认识的callback，原样发布，通常来自比本库新的NCP固件
+type RawCallbackEvent struct {
+	ID   uint16
+	Data []byte
+}
+
+func (e *RawCallbackEvent) FrameID() uint16 { return e.ID }
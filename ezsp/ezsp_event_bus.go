@@ -0,0 +1,131 @@
+package ezsp
+
+import (
+	"sync"
+
+	"github.com/conthing/utils/common"
+)
+
+// EventFilter 订阅事件的过滤条件，为空的字段不过滤，不为空时事件要匹配其中一个。
+// ProfileIds和ClusterIds只匹配带APS帧头的事件（收到消息和消息发送完成）
+type EventFilter struct {
+	FrameIDs   []uint16
+	ProfileIds []uint16
+	ClusterIds []uint16
+}
+
+func matchUint16(list []uint16, v uint16) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, i := range list {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Match 判断事件是否满足过滤条件
+func (f *EventFilter) Match(event Event) bool {
+	if !matchUint16(f.FrameIDs, event.FrameID()) {
+		return false
+	}
+	if len(f.ProfileIds) == 0 && len(f.ClusterIds) == 0 {
+		return true
+	}
+	e, ok := event.(apsEvent)
+	if !ok {
+		return false
+	}
+	return matchUint16(f.ProfileIds, e.aps().ProfileId) && matchUint16(f.ClusterIds, e.aps().ClusterId)
+}
+
+type subscriber struct {
+	id      uint64
+	filter  EventFilter
+	handler func(Event)
+}
+
+// EventBus 把EZSP callback事件发给所有订阅者，订阅者按订阅的顺序被调用
+type EventBus struct {
+	mutex       sync.RWMutex
+	nextID      uint64
+	subscribers []*subscriber
+}
+
+// Subscription 订阅的句柄，用来取消订阅
+type Subscription struct {
+	bus *EventBus
+	id  uint64
+}
+
+// DefaultEventBus DefaultClient的事件总线，包级的 EzspCallbackDispatch 在这里发布事件
+var DefaultEventBus = DefaultClient.EventBus()
+
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe 订阅满足filter的事件，handler在调用 EzspCallbackDispatch 的线程里执行，不能长时间堵塞
+func (b *EventBus) Subscribe(filter EventFilter, handler func(Event)) *Subscription {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.nextID++
+	b.subscribers = append(b.subscribers, &subscriber{id: b.nextID, filter: filter, handler: handler})
+	return &Subscription{bus: b, id: b.nextID}
+}
+
+// Unsubscribe 取消订阅，可以在handler里调用，重复调用没有影响
+func (s *Subscription) Unsubscribe() {
+	if s == nil || s.bus == nil {
+		return
+	}
+	b := s.bus
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for i, sub := range b.subscribers {
+		if sub.id == s.id {
+			b.subscribers = append(b.subscribers[:i:i], b.subscribers[i+1:]...)
+			break
+		}
+	}
+	s.bus = nil
+}
+
+// Publish 把事件发给所有匹配的订阅者，某个订阅者panic不影响其他订阅者
+func (b *EventBus) Publish(event Event) {
+	b.mutex.RLock()
+	subscribers := b.subscribers
+	b.mutex.RUnlock()
+	for _, sub := range subscribers {
+		if sub.filter.Match(event) {
+			callHandler(sub.handler, event)
+		}
+	}
+}
+
+func callHandler(handler func(Event), event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			common.Log.Errorf("EZSP event handler for %s panic: %v", frameIDToName(event.FrameID()), r)
+		}
+	}()
+	handler(event)
+}
+
+// EventBus 返回c的事件总线，c的 EzspCallbackDispatch 只在这里发布事件，
+// 每个Client一条总线，订阅者收到的事件都来自这个Client
+func (c *Client) EventBus() *EventBus {
+	return c.bus
+}
+
+// Subscribe 订阅c的事件总线上满足filter的事件
+func (c *Client) Subscribe(filter EventFilter, handler func(Event)) *Subscription {
+	return c.bus.Subscribe(filter, handler)
+}
+
+// Subscribe 订阅 DefaultEventBus 上满足filter的事件
+func Subscribe(filter EventFilter, handler func(Event)) *Subscription {
+	return DefaultEventBus.Subscribe(filter, handler)
+}
@@ -0,0 +1,57 @@
+package ezsp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEventFilter(t *testing.T) {
+	incoming := &IncomingMessageEvent{ApsFrame: EmberApsFrame{ProfileId: 0x0104, ClusterId: 0x0006}}
+	status := &StackStatusEvent{}
+	cases := []struct {
+		filter   EventFilter
+		incoming bool
+		status   bool
+	}{
+		{EventFilter{}, true, true},
+		{EventFilter{FrameIDs: []uint16{EZSP_STACK_STATUS_HANDLER}}, false, true},
+		{EventFilter{FrameIDs: []uint16{EZSP_INCOMING_MESSAGE_HANDLER, EZSP_STACK_STATUS_HANDLER}}, true, true},
+		{EventFilter{ProfileIds: []uint16{0x0104}}, true, false},
+		{EventFilter{ProfileIds: []uint16{0x0104}, ClusterIds: []uint16{0x0008}}, false, false},
+		{EventFilter{ClusterIds: []uint16{0x0008, 0x0006}}, true, false},
+	}
+	for _, c := range cases {
+		if c.filter.Match(incoming) != c.incoming || c.filter.Match(status) != c.status {
+			t.Errorf("%+v Match(incoming) = %v, Match(status) = %v", c.filter, c.filter.Match(incoming), c.filter.Match(status))
+		}
+	}
+}
+
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus()
+	var calls []string
+	bus.Subscribe(EventFilter{}, func(Event) { panic("handler panic") })
+	first := bus.Subscribe(EventFilter{}, func(Event) { calls = append(calls, "first") })
+	var self *Subscription
+	self = bus.Subscribe(EventFilter{}, func(Event) {
+		calls = append(calls, "self")
+		self.Unsubscribe() // 在handler里取消订阅
+	})
+	bus.Subscribe(EventFilter{FrameIDs: []uint16{EZSP_STACK_STATUS_HANDLER}}, func(Event) { calls = append(calls, "status") })
+
+	// 前面的订阅者panic不影响后面的，按订阅顺序调用
+	bus.Publish(&TimerEvent{})
+	bus.Publish(&StackStatusEvent{})
+	want := []string{"first", "self", "first", "status"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+
+	first.Unsubscribe()
+	first.Unsubscribe()
+	calls = nil
+	bus.Publish(&StackStatusEvent{})
+	if !reflect.DeepEqual(calls, []string{"status"}) {
+		t.Fatalf("calls after Unsubscribe = %v", calls)
+	}
+}
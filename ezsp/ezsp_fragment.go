@@ -0,0 +1,227 @@
+package ezsp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/conthing/utils/common"
+)
+
+var FragmentTraceOn bool
+
+func fragmentTrace(format string, v ...interface{}) {
+	if FragmentTraceOn {
+		common.Log.Debugf(format, v...)
+	}
+}
+
+// MAX_UNFRAGMENTED_LENGTH 去掉目的EUI64选项后单帧能发送的最大长度，超过的消息要分片发送
+const MAX_UNFRAGMENTED_LENGTH = 74
+
+const (
+	defaultFragmentSize              = 64
+	defaultFragmentWindow            = byte(DEFAULT_EZSP_CONFIG_FRAGMENT_WINDOW_SIZE)
+	defaultFragmentReassemblyTimeout = time.Second * 10
+	fragmentCountMax                 = 255
+)
+
+// StFragmentSettings APS分片设置，为0的字段使用默认值
+type StFragmentSettings struct {
+	FragmentSize      int           // 每个分片的最大载荷，不能超过 MAX_UNFRAGMENTED_LENGTH，默认64
+	WindowSize        byte          // 连续发送不等确认的分片数，要和对方的EZSP_CONFIG_FRAGMENT_WINDOW_SIZE一致，1-8，默认1
+	ReassemblyTimeout time.Duration // 收到第一个分片后多久没有收齐就丢弃，默认10秒
+}
+
+// stFragmentKey 同一条消息的分片有相同的发送者和APS sequence
+type stFragmentKey struct {
+	sender   uint16
+	sequence byte
+}
+
+// stRxFragments 正在重组的消息
+type stRxFragments struct {
+	count    int // 分片总数，收到第0个分片后才知道
+	blocks   [][]byte
+	received int
+	first    *IncomingMessageEvent
+	deadline time.Time
+}
+
+// FragmentSet 修改APS分片设置
+func (c *Client) FragmentSet(settings *StFragmentSettings) error {
+	s := StFragmentSettings{FragmentSize: defaultFragmentSize, WindowSize: defaultFragmentWindow, ReassemblyTimeout: defaultFragmentReassemblyTimeout}
+	if settings.WindowSize > 8 {
+		return fmt.Errorf("fragment window size %d out of range 1-8", settings.WindowSize)
+	}
+	if settings.FragmentSize > MAX_UNFRAGMENTED_LENGTH {
+		return fmt.Errorf("fragment size %d out of range 1-%d", settings.FragmentSize, MAX_UNFRAGMENTED_LENGTH)
+	}
+	if settings.FragmentSize > 0 {
+		s.FragmentSize = settings.FragmentSize
+	}
+	if settings.WindowSize != 0 {
+		s.WindowSize = settings.WindowSize
+	}
+	if settings.ReassemblyTimeout > 0 {
+		s.ReassemblyTimeout = settings.ReassemblyTimeout
+	}
+	c.fragmentMutex.Lock()
+	c.fragmentSettings = s
+	c.fragmentMutex.Unlock()
+	return nil
+}
+
+// FragmentSettings 返回当前的APS分片设置
+func (c *Client) FragmentSettings() StFragmentSettings {
+	c.fragmentMutex.Lock()
+	defer c.fragmentMutex.Unlock()
+	return c.fragmentSettings
+}
+
+// MaxMessageLength 分片发送时消息的最大长度
+func (c *Client) MaxMessageLength() int {
+	return c.FragmentSettings().FragmentSize * fragmentCountMax
+}
+
+// EzspSendUnicastFragmentedContext 发送单播并等待APS确认，消息超过 MAX_UNFRAGMENTED_LENGTH 时按 FragmentSize 分片发送，
+// 每发一个窗口的分片等全部确认后再发下一个窗口。分片的GroupId低字节是分片序号，
+// 第0个分片的高字节是分片总数，后续分片使用第0个分片的APS sequence
+func (c *Client) EzspSendUnicastFragmentedContext(ctx context.Context, outgoingMessageType byte, indexOrDestination uint16, apsFrame *EmberApsFrame, message []byte) error {
+	settings := c.FragmentSettings()
+	if len(message) <= MAX_UNFRAGMENTED_LENGTH {
+		h, err := c.EzspSendUnicastConfirmedContext(ctx, outgoingMessageType, indexOrDestination, apsFrame, message)
+		if err != nil {
+			return err
+		}
+		return h.WaitContext(ctx)
+	}
+
+	size := settings.FragmentSize
+	count := (len(message) + size - 1) / size
+	if count > fragmentCountMax {
+		return fmt.Errorf("message length %d to 0x%04x needs %d fragments, max %d", len(message), indexOrDestination, count, fragmentCountMax)
+	}
+	window := int(settings.WindowSize)
+	frame := *apsFrame
+	frame.Options |= EMBER_APS_OPTION_FRAGMENT | EMBER_APS_OPTION_RETRY
+	for base := 0; base < count; base += window {
+		var handles []*SendHandle
+		for i := base; i < base+window && i < count; i++ {
+			frame.GroupId = uint16(i)
+			if i == 0 {
+				frame.GroupId |= uint16(count) << 8
+			}
+			end := (i + 1) * size
+			if end > len(message) {
+				end = len(message)
+			}
+			h, err := c.EzspSendUnicastConfirmedContext(ctx, outgoingMessageType, indexOrDestination, &frame, message[i*size:end])
+			if err != nil {
+				return fmt.Errorf("fragment %d/%d to 0x%04x: %w", i, count, indexOrDestination, err)
+			}
+			if i == 0 {
+				frame.Sequence = h.sequence
+			}
+			handles = append(handles, h)
+		}
+		for i, h := range handles {
+			if err := h.WaitContext(ctx); err != nil {
+				return fmt.Errorf("fragment %d/%d to 0x%04x: %w", base+i, count, indexOrDestination, err)
+			}
+		}
+		fragmentTrace("fragment %d-%d/%d to 0x%04x acked", base, base+len(handles)-1, count, indexOrDestination)
+	}
+	return nil
+}
+
+func (c *Client) EzspSendUnicastFragmented(outgoingMessageType byte, indexOrDestination uint16, apsFrame *EmberApsFrame, message []byte) error {
+	return c.EzspSendUnicastFragmentedContext(context.Background(), outgoingMessageType, indexOrDestination, apsFrame, message)
+}
+
+// fragmentReceive 收到一个分片，回复确认，收齐后返回重组好的消息
+func (c *Client) fragmentReceive(e *IncomingMessageEvent) (*IncomingMessageEvent, bool) {
+	index := int(byte(e.ApsFrame.GroupId))
+	key := stFragmentKey{sender: e.Sender, sequence: e.ApsFrame.Sequence}
+	now := time.Now()
+
+	c.fragmentMutex.Lock()
+	settings := c.fragmentSettings
+	for k, rx := range c.rxFragments {
+		if now.After(rx.deadline) {
+			common.Log.Warnf("fragments from 0x%04x seq %d timeout, received %d/%d", k.sender, k.sequence, rx.received, rx.count)
+			delete(c.rxFragments, k)
+		}
+	}
+	rx, ok := c.rxFragments[key]
+	if !ok {
+		rx = &stRxFragments{blocks: make([][]byte, fragmentCountMax+1), deadline: now.Add(settings.ReassemblyTimeout)}
+		c.rxFragments[key] = rx
+	}
+	if index == 0 {
+		rx.count = int(e.ApsFrame.GroupId >> 8)
+		rx.first = e
+	}
+	if rx.blocks[index] == nil {
+		rx.blocks[index] = append([]byte{}, e.Message...)
+		rx.received++
+	}
+	// 确认所在窗口里已经收到的分片，窗口外和超出总数的位置1
+	window := int(settings.WindowSize)
+	base := index - index%window
+	mask := byte(0xff << uint(window))
+	for i := 0; i < window; i++ {
+		if base+i > fragmentCountMax || rx.blocks[base+i] != nil || (rx.count != 0 && base+i >= rx.count) {
+			mask |= 1 << uint(i)
+		}
+	}
+	count := rx.count
+	complete := count != 0 && rx.received >= count
+	for _, b := range rx.blocks[:count] {
+		if b == nil {
+			complete = false
+		}
+	}
+	if complete {
+		delete(c.rxFragments, key)
+	}
+	c.fragmentMutex.Unlock()
+
+	ack := e.ApsFrame
+	ack.GroupId = uint16(mask)<<8 | uint16(base)
+	if err := c.EzspSendReply(e.Sender, &ack, nil); err != nil {
+		common.Log.Errorf("fragment %d ack to 0x%04x failed: %v", index, e.Sender, err)
+	}
+	fragmentTrace("fragment %d/%d from 0x%04x seq %d, ack 0x%04x", index, count, e.Sender, key.sequence, ack.GroupId)
+	if !complete {
+		return nil, false
+	}
+
+	var message []byte
+	for _, b := range rx.blocks[:count] {
+		message = append(message, b...)
+	}
+	full := *rx.first
+	full.ApsFrame.Options &^= EMBER_APS_OPTION_FRAGMENT
+	full.ApsFrame.GroupId = 0
+	full.Message = message
+	return &full, true
+}
+
+// FragmentSet 修改DefaultClient的APS分片设置
+func FragmentSet(settings *StFragmentSettings) error {
+	return DefaultClient.FragmentSet(settings)
+}
+
+// MaxMessageLength DefaultClient分片发送时消息的最大长度
+func MaxMessageLength() int {
+	return DefaultClient.MaxMessageLength()
+}
+
+func EzspSendUnicastFragmented(outgoingMessageType byte, indexOrDestination uint16, apsFrame *EmberApsFrame, message []byte) error {
+	return DefaultClient.EzspSendUnicastFragmented(outgoingMessageType, indexOrDestination, apsFrame, message)
+}
+
+func EzspSendUnicastFragmentedContext(ctx context.Context, outgoingMessageType byte, indexOrDestination uint16, apsFrame *EmberApsFrame, message []byte) error {
+	return DefaultClient.EzspSendUnicastFragmentedContext(ctx, outgoingMessageType, indexOrDestination, apsFrame, message)
+}
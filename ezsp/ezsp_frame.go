@@ -1,8 +1,12 @@
 package ezsp
 
 import (
+	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/conthing/ezsp/ash"
@@ -13,20 +17,68 @@ import (
 type EzspFrame struct {
 	Sequence byte
 	Callback byte // 0-not callback, 1-synchronous callback, 2-asynchronous callback
-	FrameID  byte
+	FrameID  uint16
 	Data     []byte
 }
 
-var mutex sync.Mutex
-var sequence byte
-var seqMutex sync.Mutex
+// Client 绑定在一条ASH链路上的EZSP客户端，拥有独立的sequence空间和callback通道
+type Client struct {
+	link *ash.Link
 
-// callback 发送到这个ch
-var CallbackCh = make(chan []*EzspFrame, 1)
-var callbacks []*EzspFrame
+	sendLock chan struct{} // 同时只有一条命令在等待回复，用chan是为了等待时也能被ctx取消
+	sequence byte
+	seqMutex sync.Mutex
 
-// 用sequence做key的数组，存放收到的response时发往的ch
-var responseChMap [256]chan *EzspFrame
+	// versionInfo EzspVersion协商成功的版本，决定使用传统帧头还是扩展帧头，NCP复位后清0
+	versionInfo StVersionInfo
+
+	// callback 按收到的顺序发送到这个ch，满了以后按callbackPolicy处理
+	CallbackCh     chan *EzspFrame
+	callbackPolicy CallbackPolicy
+
+	// 用sequence做key的数组，存放收到的response时发往的ch，pending记录等待中的命令
+	responseChMap [256]chan *EzspFrame
+	pending       [256]stPending
+	resetCh       chan struct{} // NCP复位时关闭并换成新的，等待中的命令立即返回 ErrNcpReset
+	respMutex     sync.Mutex
+	waiting       int32 // 等待sendLock的发送者数量
+
+	// Timeout ctx没有deadline时等待回复的超时时间
+	Timeout time.Duration
+
+	// 确认发送的句柄，用messageTag做key，SendConfirmTimeout为0时使用 SEND_CONFIRM_TIMEOUT
+	SendConfirmTimeout time.Duration
+	sentHandles        map[byte]*SendHandle
+	sentTag            byte
+	sentMutex          sync.Mutex
+
+	// APS分片的设置和正在重组的消息
+	fragmentSettings StFragmentSettings
+	rxFragments      map[stFragmentKey]*stRxFragments
+	fragmentMutex    sync.Mutex
+
+	stats      StEzspStats
+	statsMutex sync.Mutex
+
+	// bus 这个Client的callback事件只发布到这里
+	bus *EventBus
+
+	// 由callback更新的网络状态、源路由表和组网扫描状态，每个Client一份
+	meshStatusUp bool
+	meshInfo     StMeshInfo
+	meshMutex    sync.Mutex
+	sourceRoutes stSourceRouteTable
+	formAndJoin  stFormAndJoin
+}
+
+// ErrNcpReset 等待回复时NCP复位了，命令可能没有执行，用 errors.Is 判断
+var ErrNcpReset = errors.New("NCP reset")
+
+// DefaultClient 包级函数使用的默认客户端，绑定在 ash.DefaultLink 上
+var DefaultClient = NewClient(ash.DefaultLink)
+
+// CallbackCh DefaultClient的callback通道
+var CallbackCh = DefaultClient.CallbackCh
 
 var EzspFrameTraceOn bool
 
@@ -36,6 +88,45 @@ func ezspFrameTrace(format string, v ...interface{}) {
 	}
 }
 
+// NewClient 创建绑定在link上的EZSP客户端，link的接收回调要设置成 Client.AshRecvImp，
+// link自动复位NCP后会调用 Client.EzspFrameInitVariables
+func NewClient(link *ash.Link) *Client {
+	c := &Client{link: link,
+		sendLock:    make(chan struct{}, 1),
+		resetCh:     make(chan struct{}),
+		CallbackCh:  make(chan *EzspFrame, defaultCallbackQueueSize),
+		Timeout:     time.Millisecond * 15000,
+		sentHandles: make(map[byte]*SendHandle),
+		rxFragments: make(map[stFragmentKey]*stRxFragments),
+		stats:       StEzspStats{Since: time.Now()},
+		bus:         NewEventBus()}
+	c.sourceRoutes.newestIndex = NULL_INDEX
+	c.FragmentSet(&StFragmentSettings{})
+	link.OnReset(c.EzspFrameInitVariables)
+	return c
+}
+
+// Link 返回客户端绑定的ASH链路
+func (c *Client) Link() *ash.Link {
+	return c.link
+}
+
+// ProtocolVersion 返回协商好的EZSP协议版本，还没有协商时返回0
+func (c *Client) ProtocolVersion() byte {
+	return c.VersionInfo().ProtocolVersion
+}
+
+func (c *Client) setVersionInfo(info StVersionInfo) {
+	c.seqMutex.Lock()
+	c.versionInfo = info
+	c.seqMutex.Unlock()
+}
+
+// extendedFrame 协议版本8以上使用扩展帧头
+func (c *Client) extendedFrame() bool {
+	return c.Supports(CAPABILITY_EXTENDED_FRAME)
+}
+
 func (ezspFrame EzspFrame) String() (s string) {
 	s = frameIDToName(ezspFrame.FrameID)
 	if ezspFrame.Callback == 2 {
@@ -47,57 +138,93 @@ func (ezspFrame EzspFrame) String() (s string) {
 	return
 }
 
-func responseChMapClear(i byte) {
-	ch := responseChMap[i]
-	if ch != nil {
-		select {
-		case <-ch:
-		default:
-		}
-		close(ch)
-		responseChMap[i] = nil
-	}
+// responseChMapClear 释放sequence对应的槽位，之后收到的迟到回复直接丢弃
+func (c *Client) responseChMapClear(i byte) {
+	c.respMutex.Lock()
+	c.responseChMap[i] = nil
+	c.respMutex.Unlock()
 }
 
-func sendToCallbackChannel(frame *EzspFrame) {
-	callbacks = append(callbacks, frame)
+// responseChMapPut 把回复交给等待中的发送函数，没有人等待时返回false
+func (c *Client) responseChMapPut(i byte, response *EzspFrame) bool {
+	c.respMutex.Lock()
+	defer c.respMutex.Unlock()
+	ch := c.responseChMap[i]
+	if ch == nil {
+		return false
+	}
 	select {
-	case CallbackCh <- callbacks:
-		callbacks = nil
-	default:
-
+	case ch <- response:
+	default: // ch有1个缓存，满了说明是重复的回复
 	}
+	return true
 }
 
 // EzspFrameInitVariables 初始化ezsp frame的一些变量，有些会在ASH的接收处理中用到，
-// 应该在 AshReset 成功后再次被调用
-func EzspFrameInitVariables() {
-	sequence = 0
+// 应该在 AshReset 成功后再次被调用。等待回复的命令返回 ErrNcpReset
+func (c *Client) EzspFrameInitVariables() {
+	c.seqMutex.Lock()
+	c.sequence = 0
+	c.versionInfo = StVersionInfo{} // NCP复位后要重新协商版本，先用传统帧头
+	c.seqMutex.Unlock()
 
-	// 清空 CallbackCh
-	select {
-	case <-CallbackCh:
-	default:
+	// 清空 CallbackCh，复位前的callback已经没有意义
+	for len(c.CallbackCh) != 0 {
+		select {
+		case <-c.CallbackCh:
+		default:
+		}
 	}
 
-	for i := range responseChMap {
-		responseChMapClear(byte(i))
+	c.respMutex.Lock()
+	for i := range c.responseChMap {
+		c.responseChMap[i] = nil
+		c.pending[i] = stPending{}
 	}
+	close(c.resetCh) // 正在等待的发送函数立刻返回
+	c.resetCh = make(chan struct{})
+	c.respMutex.Unlock()
+	c.sentHandlesAbort()
+
+	c.fragmentMutex.Lock()
+	c.rxFragments = make(map[stFragmentKey]*stRxFragments)
+	c.fragmentMutex.Unlock()
 }
 
-func getSequence() byte {
-	seqMutex.Lock()
-	seq := sequence
-	sequence++
-	seqMutex.Unlock()
+func (c *Client) getSequence() byte {
+	c.seqMutex.Lock()
+	seq := c.sequence
+	c.sequence++
+	c.seqMutex.Unlock()
 	return seq
 }
 
-func ezspFrameParse(data []byte) (*EzspFrame, error) {
+// ezspFrameParse 解析EZSP帧
+// 传统帧头: sequence, frame control, frame ID
+// 扩展帧头: sequence, frame control低字节, frame control高字节, frame ID(小端16位)
+func (c *Client) ezspFrameParse(data []byte) (*EzspFrame, error) {
+	if len(data) < 3 {
+		return nil, fmt.Errorf("EZSP frame too short 0x%x", data)
+	}
 	seq := data[0]
 	frmCtrl := data[1]
-	frmID := data[2]
+	frmID := uint16(data[2])
+	headerLen := 3
+	// version命令的回复总是传统帧头，扩展帧头的frame control高字节不会是0
+	if c.extendedFrame() && !(data[2] == byte(EZSP_VERSION) && (frmCtrl&0x18) == 0) {
+		if len(data) < 5 {
+			return nil, fmt.Errorf("EZSP extended frame too short 0x%x", data)
+		}
+		if (data[2] & 0x3) != EZSP_EXTENDED_FRAME_FORMAT_VERSION {
+			return nil, fmt.Errorf("EZSP extended frame unsupported frame format version %d", data[2]&0x3)
+		}
+		frmID = binary.LittleEndian.Uint16(data[3:])
+		headerLen = 5
+	}
 
+	c.seqMutex.Lock()
+	sequence := c.sequence
+	c.seqMutex.Unlock()
 	if seq-sequence <= 0x80 { /* seq >= sequence */
 		return nil, fmt.Errorf("EZSP frame out of sequence recvseq=%d, sequence=%d", seq, sequence)
 	}
@@ -119,91 +246,136 @@ func ezspFrameParse(data []byte) (*EzspFrame, error) {
 		return nil, fmt.Errorf("EZSP frame unsupported callback ")
 	}
 
-	//检查frmID 和 callback是否匹配
+	//检查frmID 和 callback是否匹配，不认识的ID作为callback时照常接收，由 EzspCallbackDispatch 原样发布
 	isCallbackID := isValidCallbackID(frmID)
+	_, isKnownID := frameIDNameMap[frmID]
 	if isCallbackID && callback == 0 {
 		return nil, fmt.Errorf("EZSP frame callback==%d while ID=%s", callback, frameIDToName(frmID))
-	} else if !isCallbackID && callback != 0 {
+	} else if !isCallbackID && isKnownID && callback != 0 {
 		return nil, fmt.Errorf("EZSP frame callback==%d while ID=%s", callback, frameIDToName(frmID))
 	}
 
-	return &EzspFrame{Sequence: seq, Callback: callback, FrameID: frmID, Data: data[3:]}, nil
+	return &EzspFrame{Sequence: seq, Callback: callback, FrameID: frmID, Data: data[headerLen:]}, nil
 }
 
 // AshRecvImp ASH串口接收处理，运行在串口收发线程中
-func AshRecvImp(data []byte) error {
-	ash.TransceiverStep = 31
-	ezspFrame, err := ezspFrameParse(data)
+func (c *Client) AshRecvImp(data []byte) error {
+	ezspFrame, err := c.ezspFrameParse(data)
 	if err != nil {
+		c.statsUpdate(func(s *StEzspStats) { s.ParseErrors++ })
 		return fmt.Errorf("EZSP frame parse error: %v", err)
 	}
-	ash.TransceiverStep = 32
 	ezspFrameTrace("EZSP recv < %s", ezspFrame)
+	if ezspFrame.Callback != 0 {
+		c.statsUpdate(func(s *StEzspStats) { s.Callbacks++ })
+		if ezspFrame.FrameID == EZSP_MESSAGE_SENT_HANDLER { // 确认发送的结果不用等应用层处理callback
+			if event, err := callbackDecode(ezspFrame); err == nil {
+				c.messageSentResolve(event.(*MessageSentEvent))
+			}
+		}
+	} else {
+		c.statsUpdate(func(s *StEzspStats) { s.Responses++ })
+	}
 	if ezspFrame.Callback == 2 { // async callback 给 CallbackCh
-		sendToCallbackChannel(ezspFrame)
-		return nil
+		return c.enqueueCallback(ezspFrame)
 	}
-	ash.TransceiverStep = 33
 	if ezspFrame.Callback == 1 { // sync callback 也给 CallbackCh，另外发个nil给堵塞的发送函数
-		sendToCallbackChannel(ezspFrame)
-	}
-	ash.TransceiverStep = 34
-	ch := responseChMap[ezspFrame.Sequence]
-	if ch != nil {
-		if ezspFrame.Callback == 1 {
-			ch <- nil
-			return nil
-		}
-		ash.TransceiverStep = 35
-		ch <- ezspFrame
+		err = c.enqueueCallback(ezspFrame)
+		c.responseChMapPut(ezspFrame.Sequence, nil)
+		return err
 	}
-	ash.TransceiverStep = 36
+	c.responseChMapPut(ezspFrame.Sequence, ezspFrame)
 	return nil
 }
 
-var SendStep = 0
+// EzspFrameSendContext 发送命令并等待回复，ctx取消或超时后停止等待并释放sequence槽位，
+// 返回的error包裹了 ctx.Err()，可以用 errors.Is(err, context.DeadlineExceeded) 判断。
+// ctx没有deadline时使用 c.Timeout
+func (c *Client) EzspFrameSendContext(ctx context.Context, frmID uint16, data []byte) (*EzspFrame, error) {
+	if _, ok := ctx.Deadline(); !ok && c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	if err := c.CheckCommand(frmID); err != nil {
+		return nil, err
+	}
 
-func EzspFrameSend(frmID byte, data []byte) (*EzspFrame, error) {
-	SendStep = 1
-	mutex.Lock()
+	atomic.AddInt32(&c.waiting, 1)
+	select {
+	case c.sendLock <- struct{}{}:
+		atomic.AddInt32(&c.waiting, -1)
+	case <-ctx.Done():
+		atomic.AddInt32(&c.waiting, -1)
+		return nil, fmt.Errorf("EZSP send %s canceled while waiting for previous command: %w", frameIDToName(frmID), ctx.Err())
+	}
 	defer func() {
-		mutex.Unlock()
-		SendStep = 0
+		<-c.sendLock
 	}()
-	seq := getSequence()
-	ashFrm := []byte{seq, 0, frmID}
+	seq := c.getSequence()
+	var ashFrm []byte
+	if c.extendedFrame() && frmID != EZSP_VERSION { // version命令总是用传统帧头发送
+		ashFrm = []byte{seq, 0, EZSP_EXTENDED_FRAME_FORMAT_VERSION, byte(frmID), byte(frmID >> 8)}
+	} else {
+		ashFrm = []byte{seq, 0, byte(frmID)}
+	}
 	if data != nil {
 		ashFrm = append(ashFrm, data...)
 	}
 
-	SendStep = 2
 	// 创建接收回复的ch
-	responseChMapClear(seq) //如果上一轮sequence发送时超时，有可能没有close
-	SendStep = 3
-	responseChMap[seq] = make(chan *EzspFrame, 1)
-	if responseChMap[seq] == nil {
-		return nil, fmt.Errorf("EZSP send %s(seq=%d) failed: make chan failed", frameIDToName(frmID), seq)
-	}
-	SendStep = 4
+	c.responseChMapClear(seq) //如果上一轮sequence发送时超时，有可能没有close
+	responseCh := make(chan *EzspFrame, 1)
+	c.respMutex.Lock()
+	c.responseChMap[seq] = responseCh
+	c.pending[seq] = stPending{frameID: frmID, since: time.Now()}
+	reset := c.resetCh
+	c.respMutex.Unlock()
 
-	err := ash.AshSend(ashFrm)
+	err := c.link.Send(ashFrm)
 	if err != nil {
-		responseChMapClear(seq)
+		c.responseChMapClear(seq)
 		return nil, fmt.Errorf("EZSP send %s(seq=%d) failed: ash send failed: %v", frameIDToName(frmID), seq, err)
 	}
 	ezspFrameTrace("EZSP send > %s 0x%x", frameIDToName(frmID), data)
-	SendStep = 5
+	c.statsUpdate(func(s *StEzspStats) { s.CommandsSent++ })
 
 	select {
-	case response := <-responseChMap[seq]:
-		SendStep = 6
-		close(responseChMap[seq])
-		responseChMap[seq] = nil
+	case response := <-responseCh:
+		c.responseChMapClear(seq)
 		return response, nil
-	case <-time.After(time.Millisecond * 15000):
-		SendStep = 7
-		responseChMapClear(seq)
-		SendStep = 8
-		return nil, fmt.Errorf("EZSP send %s timeout. ASH env \n%s", frameIDToName(frmID), ash.SprintVariables())
+	case <-reset: // 槽位已经在 EzspFrameInitVariables 里清掉了
+		return nil, fmt.Errorf("EZSP send %s(seq=%d) aborted: %w", frameIDToName(frmID), seq, ErrNcpReset)
+	case <-ctx.Done():
+		c.responseChMapClear(seq)
+		if ctx.Err() == context.DeadlineExceeded {
+			c.statsUpdate(func(s *StEzspStats) { s.Timeouts++ })
+			return nil, fmt.Errorf("EZSP send %s(seq=%d) timeout: %w. ASH %s", frameIDToName(frmID), seq, ctx.Err(), c.link.State())
+		}
+		c.statsUpdate(func(s *StEzspStats) { s.Canceled++ })
+		return nil, fmt.Errorf("EZSP send %s(seq=%d) canceled: %w", frameIDToName(frmID), seq, ctx.Err())
 	}
 }
+
+func (c *Client) EzspFrameSend(frmID uint16, data []byte) (*EzspFrame, error) {
+	return c.EzspFrameSendContext(context.Background(), frmID, data)
+}
+
+// EzspFrameInitVariables 初始化DefaultClient的变量，应该在 AshReset 成功后再次被调用
+func EzspFrameInitVariables() {
+	DefaultClient.EzspFrameInitVariables()
+}
+
+// AshRecvImp DefaultClient的ASH接收处理，交给 ash.AshStartTransceiver
+func AshRecvImp(data []byte) error {
+	return DefaultClient.AshRecvImp(data)
+}
+
+func EzspFrameSend(frmID uint16, data []byte) (*EzspFrame, error) {
+	return DefaultClient.EzspFrameSend(frmID, data)
+}
+
+func EzspFrameSendContext(ctx context.Context, frmID uint16, data []byte) (*EzspFrame, error) {
+	return DefaultClient.EzspFrameSendContext(ctx, frmID, data)
+}
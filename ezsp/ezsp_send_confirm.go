@@ -0,0 +1,200 @@
+package ezsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SEND_CONFIRM_TIMEOUT 等待messageSentHandler的默认时间，包括路由发现和APS重发
+const SEND_CONFIRM_TIMEOUT = time.Second * 30
+
+// messageTag的划分：0表示应用层不关心结果，1到 MESSAGE_TAG_APP_MAX 由应用层自己分配，
+// MESSAGE_TAG_CONFIRMED_MIN 以上由确认发送自动分配，两段不会冲突
+const (
+	MESSAGE_TAG_APP_MAX       = byte(0x7f)
+	MESSAGE_TAG_CONFIRMED_MIN = byte(0x80)
+)
+
+// ErrConfirmTimeout 在 SendConfirmTimeout 内没有收到messageSentHandler，用 errors.Is 判断
+var ErrConfirmTimeout = errors.New("confirm timeout")
+
+// IsConfirmedTag tag是否由确认发送分配，应用层处理messageSentHandler时应该跳过
+func IsConfirmedTag(tag byte) bool {
+	return tag >= MESSAGE_TAG_CONFIRMED_MIN
+}
+
+// SendHandle 确认发送的句柄，收到tag匹配的messageSentHandler或者超时后完成
+type SendHandle struct {
+	Tag         byte
+	Destination uint16
+
+	sequence byte // NCP分配的APS sequence，发送成功后才有效
+	sent     bool
+	timer    *time.Timer
+
+	done        chan struct{}
+	once        sync.Once
+	emberStatus byte
+	err         error
+}
+
+func newSendHandle(tag byte, destination uint16) *SendHandle {
+	return &SendHandle{Tag: tag, Destination: destination, done: make(chan struct{})}
+}
+
+// Done 完成时被close
+func (h *SendHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Wait 等待发送结果，APS ACK成功返回nil，失败返回 EmberError，超时或NCP复位返回其他错误
+func (h *SendHandle) Wait() error {
+	<-h.done
+	return h.err
+}
+
+// WaitContext 和 Wait 一样，ctx取消时不再等待，但句柄仍然有效
+func (h *SendHandle) WaitContext(ctx context.Context) error {
+	select {
+	case <-h.done:
+		return h.err
+	case <-ctx.Done():
+		return fmt.Errorf("wait message tag %d canceled: %w", h.Tag, ctx.Err())
+	}
+}
+
+// EmberStatus messageSentHandler里的状态，完成之前或者超时时为0
+func (h *SendHandle) EmberStatus() byte {
+	<-h.done
+	return h.emberStatus
+}
+
+func (h *SendHandle) resolve(emberStatus byte, err error) {
+	h.once.Do(func() {
+		h.emberStatus = emberStatus
+		h.err = err
+		close(h.done)
+	})
+}
+
+// allocTag 在 MESSAGE_TAG_CONFIRMED_MIN 到0xff之间分配一个没有在等待确认的tag
+func (c *Client) allocTag() (byte, error) {
+	for i := 0; i <= int(^MESSAGE_TAG_CONFIRMED_MIN); i++ {
+		c.sentTag++
+		if c.sentTag < MESSAGE_TAG_CONFIRMED_MIN {
+			c.sentTag = MESSAGE_TAG_CONFIRMED_MIN
+		}
+		if _, ok := c.sentHandles[c.sentTag]; !ok {
+			return c.sentTag, nil
+		}
+	}
+	return 0, fmt.Errorf("no free message tag, %d sends in flight", len(c.sentHandles))
+}
+
+func (c *Client) sentHandleRemove(h *SendHandle) {
+	c.sentMutex.Lock()
+	if c.sentHandles[h.Tag] == h {
+		delete(c.sentHandles, h.Tag)
+	}
+	c.sentMutex.Unlock()
+}
+
+// messageSentResolve 收到messageSentHandler时完成tag和APS sequence都匹配的句柄，运行在ASH接收线程中
+func (c *Client) messageSentResolve(e *MessageSentEvent) {
+	c.sentMutex.Lock()
+	h, ok := c.sentHandles[e.MessageTag]
+	if !ok || (h.sent && h.sequence != e.ApsFrame.Sequence) {
+		c.sentMutex.Unlock()
+		return
+	}
+	delete(c.sentHandles, e.MessageTag)
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+	c.sentMutex.Unlock()
+
+	if e.EmberStatus != EMBER_SUCCESS {
+		h.resolve(e.EmberStatus, EmberError{e.EmberStatus, fmt.Sprintf("message tag %d to 0x%04x", h.Tag, h.Destination)})
+	} else {
+		h.resolve(e.EmberStatus, nil)
+	}
+}
+
+// sentHandlesAbort NCP复位后等待中的确认不会再来了
+func (c *Client) sentHandlesAbort() {
+	c.sentMutex.Lock()
+	handles := c.sentHandles
+	c.sentHandles = make(map[byte]*SendHandle)
+	for _, h := range handles {
+		if h.timer != nil {
+			h.timer.Stop()
+		}
+	}
+	c.sentMutex.Unlock()
+	for _, h := range handles {
+		h.resolve(0, fmt.Errorf("message tag %d to 0x%04x aborted by NCP reset", h.Tag, h.Destination))
+	}
+}
+
+// SendsInFlight 正在等待messageSentHandler的确认发送数量
+func (c *Client) SendsInFlight() int {
+	c.sentMutex.Lock()
+	defer c.sentMutex.Unlock()
+	return len(c.sentHandles)
+}
+
+// EzspSendUnicastConfirmedContext 自动分配messageTag发送单播，NCP接受后返回句柄，
+// 句柄在收到对应的messageSentHandler（APS ACK的结果）或者 SendConfirmTimeout 后完成
+func (c *Client) EzspSendUnicastConfirmedContext(ctx context.Context, outgoingMessageType byte, indexOrDestination uint16, apsFrame *EmberApsFrame, message []byte) (*SendHandle, error) {
+	c.sentMutex.Lock()
+	tag, err := c.allocTag()
+	if err != nil {
+		c.sentMutex.Unlock()
+		return nil, err
+	}
+	h := newSendHandle(tag, indexOrDestination)
+	c.sentHandles[tag] = h // 先登记，messageSentHandler可能紧跟着回复到达
+	c.sentMutex.Unlock()
+
+	sequence, err := c.EzspSendUnicastContext(ctx, outgoingMessageType, indexOrDestination, apsFrame, tag, message)
+	if err != nil {
+		c.sentHandleRemove(h)
+		return nil, err
+	}
+
+	timeout := c.SendConfirmTimeout
+	if timeout <= 0 {
+		timeout = SEND_CONFIRM_TIMEOUT
+	}
+	c.sentMutex.Lock()
+	h.sequence = sequence
+	h.sent = true
+	if c.sentHandles[tag] == h { // 没有在回复之前就收到确认或被复位
+		h.timer = time.AfterFunc(timeout, func() {
+			c.sentHandleRemove(h)
+			h.resolve(0, fmt.Errorf("message tag %d to 0x%04x %w after %v", h.Tag, h.Destination, ErrConfirmTimeout, timeout))
+		})
+	}
+	c.sentMutex.Unlock()
+	return h, nil
+}
+
+func (c *Client) EzspSendUnicastConfirmed(outgoingMessageType byte, indexOrDestination uint16, apsFrame *EmberApsFrame, message []byte) (*SendHandle, error) {
+	return c.EzspSendUnicastConfirmedContext(context.Background(), outgoingMessageType, indexOrDestination, apsFrame, message)
+}
+
+func EzspSendUnicastConfirmed(outgoingMessageType byte, indexOrDestination uint16, apsFrame *EmberApsFrame, message []byte) (*SendHandle, error) {
+	return DefaultClient.EzspSendUnicastConfirmed(outgoingMessageType, indexOrDestination, apsFrame, message)
+}
+
+func EzspSendUnicastConfirmedContext(ctx context.Context, outgoingMessageType byte, indexOrDestination uint16, apsFrame *EmberApsFrame, message []byte) (*SendHandle, error) {
+	return DefaultClient.EzspSendUnicastConfirmedContext(ctx, outgoingMessageType, indexOrDestination, apsFrame, message)
+}
+
+// SendsInFlight DefaultClient正在等待确认的发送数量
+func SendsInFlight() int {
+	return DefaultClient.SendsInFlight()
+}
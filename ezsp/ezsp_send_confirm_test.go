@@ -0,0 +1,70 @@
+package ezsp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/conthing/ezsp/ash"
+)
+
+func sentEvent(tag byte, sequence byte, emberStatus byte) *MessageSentEvent {
+	return &MessageSentEvent{ApsFrame: EmberApsFrame{Sequence: sequence}, MessageTag: tag, EmberStatus: emberStatus}
+}
+
+func isDone(h *SendHandle) bool {
+	select {
+	case <-h.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// TestMessageSentResolve messageSentHandler要tag和APS sequence都匹配才完成句柄
+func TestMessageSentResolve(t *testing.T) {
+	c := NewClient(ash.NewLink())
+	h := newSendHandle(MESSAGE_TAG_CONFIRMED_MIN, 0x1001)
+	h.sequence, h.sent = 5, true
+	c.sentHandles[h.Tag] = h
+
+	// tag复用前的旧确认和应用层自己的tag都不匹配
+	c.messageSentResolve(sentEvent(h.Tag, 4, EMBER_SUCCESS))
+	c.messageSentResolve(sentEvent(0x01, 5, EMBER_SUCCESS))
+	if isDone(h) || c.SendsInFlight() != 1 {
+		t.Fatalf("resolved by mismatched confirm, in flight %d", c.SendsInFlight())
+	}
+
+	c.messageSentResolve(sentEvent(h.Tag, 5, EMBER_DELIVERY_FAILED))
+	var emberErr EmberError
+	if err := h.Wait(); !errors.As(err, &emberErr) || emberErr.EmberStatus != EMBER_DELIVERY_FAILED {
+		t.Fatalf("Wait = %v, want EMBER_DELIVERY_FAILED", err)
+	}
+	if h.EmberStatus() != EMBER_DELIVERY_FAILED || c.SendsInFlight() != 0 {
+		t.Fatalf("EmberStatus = 0x%x, in flight %d", h.EmberStatus(), c.SendsInFlight())
+	}
+
+	// sendUnicast的回复还没到时还不知道sequence，只按tag匹配
+	h = newSendHandle(MESSAGE_TAG_CONFIRMED_MIN+1, 0x1001)
+	c.sentHandles[h.Tag] = h
+	c.messageSentResolve(sentEvent(h.Tag, 9, EMBER_SUCCESS))
+	if !isDone(h) || h.Wait() != nil {
+		t.Fatalf("unsent handle not resolved, err %v", h.err)
+	}
+}
+
+// TestAllocTag 确认发送的tag在 MESSAGE_TAG_CONFIRMED_MIN 以上循环分配，跳过还在等待的tag
+func TestAllocTag(t *testing.T) {
+	c := NewClient(ash.NewLink())
+	c.sentHandles[MESSAGE_TAG_CONFIRMED_MIN] = newSendHandle(MESSAGE_TAG_CONFIRMED_MIN, 0)
+	c.sentTag = 0xff
+	if tag, err := c.allocTag(); err != nil || tag != MESSAGE_TAG_CONFIRMED_MIN+1 || !IsConfirmedTag(tag) {
+		t.Fatalf("allocTag = 0x%x, %v", tag, err)
+	}
+
+	for tag := int(MESSAGE_TAG_CONFIRMED_MIN); tag <= 0xff; tag++ {
+		c.sentHandles[byte(tag)] = newSendHandle(byte(tag), 0)
+	}
+	if tag, err := c.allocTag(); err == nil {
+		t.Fatalf("allocTag = 0x%x with all tags in flight", tag)
+	}
+}
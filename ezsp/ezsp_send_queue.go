@@ -0,0 +1,220 @@
+package ezsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/conthing/utils/common"
+)
+
+var SendQueueTraceOn bool
+
+func sendQueueTrace(format string, v ...interface{}) {
+	if SendQueueTraceOn {
+		common.Log.Debugf(format, v...)
+	}
+}
+
+const (
+	defaultSendQueueSize  = 64
+	defaultSendAttempts   = 3
+	defaultSendRetryDelay = time.Millisecond * 500
+)
+
+// StSendQueueSettings 发送队列设置，为0的字段使用默认值
+type StSendQueueSettings struct {
+	Size        int           // 队列里最多的消息数，满了以后发送者等待，默认64
+	MaxAttempts int           // 每条消息最多发送次数，默认3
+	RetryDelay  time.Duration // 第一次重发前等待的时间，之后每次加倍，默认0.5秒
+	// RouteRefresh 每次发送前调用，用来设置最新的源路由
+	RouteRefresh func(destination uint16) error
+}
+
+// OutgoingMessage 放进发送队列的消息，发送成功或者最终失败后完成
+type OutgoingMessage struct {
+	OutgoingMessageType byte
+	Destination         uint16
+	ApsFrame            EmberApsFrame
+	Message             []byte
+
+	ctx      context.Context
+	attempts int
+	handle   *SendHandle
+}
+
+// Done 完成时被close
+func (m *OutgoingMessage) Done() <-chan struct{} {
+	return m.handle.Done()
+}
+
+// Wait 等待发送结果，收到APS ACK返回nil
+func (m *OutgoingMessage) Wait() error {
+	return m.handle.Wait()
+}
+
+// Handle 返回消息完成时一起完成的句柄，重发和分片都结束后才有结果，Tag总是0
+func (m *OutgoingMessage) Handle() *SendHandle {
+	return m.handle
+}
+
+// Attempts 等待完成，返回实际的发送次数
+func (m *OutgoingMessage) Attempts() int {
+	<-m.handle.Done()
+	return m.attempts
+}
+
+// SendQueue 发送队列，同一个目的地址的消息按顺序逐条发送，前一条确认后才发下一条，
+// 不同目的地址之间互不影响。失败的消息按 StSendQueueSettings 重发，重发时强制路由发现
+type SendQueue struct {
+	client   *Client
+	settings StSendQueueSettings
+
+	slots        chan struct{} // 队列里每条消息占一个，满了以后 SendContext 等待
+	destinations map[uint16][]*OutgoingMessage
+	mutex        sync.Mutex
+}
+
+// DefaultSendQueue 使用DefaultClient发送，发送前设置主机维护的源路由
+var DefaultSendQueue = NewSendQueue(DefaultClient, &StSendQueueSettings{RouteRefresh: NcpSetSourceRoute})
+
+// NewSendQueue 创建在c上发送的队列，settings为nil时全部使用默认值
+func NewSendQueue(c *Client, settings *StSendQueueSettings) *SendQueue {
+	s := StSendQueueSettings{Size: defaultSendQueueSize, MaxAttempts: defaultSendAttempts, RetryDelay: defaultSendRetryDelay}
+	if settings != nil {
+		if settings.Size > 0 {
+			s.Size = settings.Size
+		}
+		if settings.MaxAttempts > 0 {
+			s.MaxAttempts = settings.MaxAttempts
+		}
+		if settings.RetryDelay > 0 {
+			s.RetryDelay = settings.RetryDelay
+		}
+		s.RouteRefresh = settings.RouteRefresh
+	}
+	return &SendQueue{client: c,
+		settings:     s,
+		slots:        make(chan struct{}, s.Size),
+		destinations: make(map[uint16][]*OutgoingMessage)}
+}
+
+// Len 队列里还没有完成的消息数量
+func (q *SendQueue) Len() int {
+	return len(q.slots)
+}
+
+// SendContext 把单播消息放进队列，队列满时等待（背压），ctx取消后返回错误。
+// ctx同时控制排队和发送，消息发送过程中ctx取消时以ctx的错误完成
+func (q *SendQueue) SendContext(ctx context.Context, outgoingMessageType byte, indexOrDestination uint16, apsFrame *EmberApsFrame, message []byte) (*OutgoingMessage, error) {
+	select {
+	case q.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("send queue full(%d) for 0x%04x: %w", cap(q.slots), indexOrDestination, ctx.Err())
+	}
+	m := &OutgoingMessage{OutgoingMessageType: outgoingMessageType,
+		Destination: indexOrDestination,
+		ApsFrame:    *apsFrame,
+		Message:     append([]byte(nil), message...),
+		ctx:         ctx,
+		handle:      newSendHandle(0, indexOrDestination)}
+
+	q.mutex.Lock()
+	list, running := q.destinations[indexOrDestination]
+	q.destinations[indexOrDestination] = append(list, m)
+	q.mutex.Unlock()
+	if !running {
+		go q.run(indexOrDestination)
+	}
+	return m, nil
+}
+
+func (q *SendQueue) Send(outgoingMessageType byte, indexOrDestination uint16, apsFrame *EmberApsFrame, message []byte) (*OutgoingMessage, error) {
+	return q.SendContext(context.Background(), outgoingMessageType, indexOrDestination, apsFrame, message)
+}
+
+// run 按顺序发送一个目的地址的消息，队列空了以后退出
+func (q *SendQueue) run(destination uint16) {
+	for {
+		q.mutex.Lock()
+		list := q.destinations[destination]
+		if len(list) == 0 {
+			delete(q.destinations, destination)
+			q.mutex.Unlock()
+			return
+		}
+		m := list[0]
+		q.destinations[destination] = list[1:]
+		q.mutex.Unlock()
+
+		err := q.deliver(m)
+		var e EmberError
+		if errors.As(err, &e) {
+			m.handle.resolve(e.EmberStatus, err)
+		} else {
+			m.handle.resolve(0, err)
+		}
+		<-q.slots
+	}
+}
+
+// sendRetryable 发送失败后是否值得重发，只重发超时和暂时性的Ember状态，
+// 其他错误（NCP不支持、ASH没有复位好、消息太长、取消等）重发也不会成功
+func sendRetryable(err error) bool {
+	var e EmberError
+	if !errors.As(err, &e) {
+		return errors.Is(err, ErrConfirmTimeout) || errors.Is(err, context.DeadlineExceeded)
+	}
+	switch e.EmberStatus {
+	case EMBER_DELIVERY_FAILED, EMBER_NO_BUFFERS, EMBER_MAX_MESSAGE_LIMIT_REACHED, EMBER_NETWORK_BUSY,
+		EMBER_SOURCE_ROUTE_FAILURE, EMBER_MAC_NO_ACK_RECEIVED, EMBER_MAC_INDIRECT_TIMEOUT:
+		return true
+	}
+	return false
+}
+
+func (q *SendQueue) deliver(m *OutgoingMessage) (err error) {
+	delay := q.settings.RetryDelay
+	for attempt := 1; attempt <= q.settings.MaxAttempts; attempt++ {
+		apsFrame := m.ApsFrame
+		if attempt > 1 {
+			select {
+			case <-time.After(delay):
+			case <-m.ctx.Done():
+				return fmt.Errorf("send to 0x%04x canceled before attempt %d: %w", m.Destination, attempt, m.ctx.Err())
+			}
+			delay *= 2
+			// 上次失败可能是路由失效，重新发现路由
+			apsFrame.Options |= EMBER_APS_OPTION_ENABLE_ROUTE_DISCOVERY | EMBER_APS_OPTION_FORCE_ROUTE_DISCOVERY
+		}
+		m.attempts = attempt
+		if q.settings.RouteRefresh != nil {
+			if e := q.settings.RouteRefresh(m.Destination); e != nil {
+				common.Log.Warnf("send to 0x%04x refresh route failed: %v", m.Destination, e)
+			}
+		}
+
+		err = q.client.EzspSendUnicastFragmentedContext(m.ctx, m.OutgoingMessageType, m.Destination, &apsFrame, m.Message)
+		if err == nil {
+			sendQueueTrace("send to 0x%04x success at attempt %d", m.Destination, attempt)
+			return nil
+		}
+		if m.ctx.Err() != nil || !sendRetryable(err) {
+			return err
+		}
+		sendQueueTrace("send to 0x%04x attempt %d failed: %v", m.Destination, attempt, err)
+	}
+	return fmt.Errorf("send to 0x%04x failed after %d attempts: %w", m.Destination, m.attempts, err)
+}
+
+// SendQueued 通过 DefaultSendQueue 发送单播
+func SendQueued(outgoingMessageType byte, indexOrDestination uint16, apsFrame *EmberApsFrame, message []byte) (*OutgoingMessage, error) {
+	return DefaultSendQueue.Send(outgoingMessageType, indexOrDestination, apsFrame, message)
+}
+
+// SendQueuedContext 通过 DefaultSendQueue 发送单播
+func SendQueuedContext(ctx context.Context, outgoingMessageType byte, indexOrDestination uint16, apsFrame *EmberApsFrame, message []byte) (*OutgoingMessage, error) {
+	return DefaultSendQueue.SendContext(ctx, outgoingMessageType, indexOrDestination, apsFrame, message)
+}
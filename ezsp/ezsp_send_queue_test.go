@@ -0,0 +1,30 @@
+package ezsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSendRetryable(t *testing.T) {
+	cases := []struct {
+		err       error
+		retryable bool
+	}{
+		{EmberError{EMBER_DELIVERY_FAILED, ""}, true},
+		{fmt.Errorf("wrapped: %w", EmberError{EMBER_MAC_NO_ACK_RECEIVED, ""}), true},
+		{EmberError{EMBER_NETWORK_BUSY, ""}, true},
+		{EmberError{EMBER_NETWORK_DOWN, ""}, false},
+		{EmberError{EMBER_MESSAGE_TOO_LONG, ""}, false},
+		{fmt.Errorf("tag 128: %w", ErrConfirmTimeout), true},
+		{context.DeadlineExceeded, true},
+		{context.Canceled, false},
+		{errors.New("ash not connected"), false},
+	}
+	for _, c := range cases {
+		if sendRetryable(c.err) != c.retryable {
+			t.Errorf("sendRetryable(%v) = %v", c.err, !c.retryable)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package ezsp
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/conthing/ezsp/ash"
+)
+
+// StPendingCommand 已发送还在等待回复的命令
+type StPendingCommand struct {
+	Sequence byte          `json:"sequence"`
+	FrameID  uint16        `json:"frameid"`
+	Name     string        `json:"name"`
+	Age      time.Duration `json:"age"` // 发送到现在的时间
+}
+
+// StClientState EZSP客户端状态的快照，包括下面的ASH链路
+type StClientState struct {
+	ProtocolVersion byte               `json:"protocolversion"`
+	Waiting         int                `json:"waiting"` // 等待前一条命令完成的发送者
+	Pending         []StPendingCommand `json:"pending"`
+	CallbackBacklog int                `json:"callbackbacklog"` // CallbackCh里还没有被取走的callback数量
+	CallbackQueue   int                `json:"callbackqueue"`   // CallbackCh的长度
+	SendsInFlight   int                `json:"sendsinflight"`   // 等待messageSentHandler的确认发送
+	Link            ash.StLinkState    `json:"link"`
+}
+
+// stPending 等待回复的命令，用sequence做下标
+type stPending struct {
+	frameID uint16
+	since   time.Time
+}
+
+// State 返回客户端状态的快照，可以在任何线程调用
+func (c *Client) State() StClientState {
+	state := StClientState{
+		ProtocolVersion: c.ProtocolVersion(),
+		Waiting:         int(atomic.LoadInt32(&c.waiting)),
+		Link:            c.link.State(),
+	}
+	now := time.Now()
+	c.respMutex.Lock()
+	for seq, ch := range c.responseChMap {
+		if ch != nil {
+			p := c.pending[seq]
+			state.Pending = append(state.Pending, StPendingCommand{
+				Sequence: byte(seq),
+				FrameID:  p.frameID,
+				Name:     frameIDToName(p.frameID),
+				Age:      now.Sub(p.since),
+			})
+		}
+	}
+	c.respMutex.Unlock()
+
+	state.CallbackBacklog = len(c.CallbackCh)
+	state.CallbackQueue = cap(c.CallbackCh)
+	state.SendsInFlight = c.SendsInFlight()
+	return state
+}
+
+// State DefaultClient的状态快照
+func State() StClientState {
+	return DefaultClient.State()
+}
@@ -0,0 +1,53 @@
+package ezsp
+
+import (
+	"time"
+)
+
+// StEzspStats EZSP层的统计计数
+type StEzspStats struct {
+	CommandsSent        uint64        `json:"commandssent"`        // 交给ASH发送的命令
+	Responses           uint64        `json:"responses"`           // 收到的回复
+	Callbacks           uint64        `json:"callbacks"`           // 收到的callback，包括同步和异步
+	Timeouts            uint64        `json:"timeouts"`            // 等待回复超时
+	Canceled            uint64        `json:"canceled"`            // 等待回复时ctx被取消
+	ParseErrors         uint64        `json:"parseerrors"`         // 无法解析的EZSP帧
+	CallbackOverflows   uint64        `json:"callbackoverflows"`   // callback队列满的次数
+	CallbackDropped     uint64        `json:"callbackdropped"`     // 队列满被丢弃的callback
+	CallbackBlocked     uint64        `json:"callbackblocked"`     // 队列满等待消费者的次数
+	CallbackBlockedTime time.Duration `json:"callbackblockedtime"` // 等待消费者的总时间
+	CallbackQueueMax    int           `json:"callbackqueuemax"`    // 队列的最大深度
+	Since               time.Time     `json:"since"`               // 开始计数的时间
+}
+
+func (c *Client) statsUpdate(update func(s *StEzspStats)) {
+	c.statsMutex.Lock()
+	update(&c.stats)
+	c.statsMutex.Unlock()
+}
+
+// Stats 返回统计计数的快照
+func (c *Client) Stats() StEzspStats {
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+	return c.stats
+}
+
+// ResetStats 计数清0，返回清0之前的快照
+func (c *Client) ResetStats() StEzspStats {
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+	stats := c.stats
+	c.stats = StEzspStats{Since: time.Now()}
+	return stats
+}
+
+// Stats DefaultClient的统计计数
+func Stats() StEzspStats {
+	return DefaultClient.Stats()
+}
+
+// ResetStats DefaultClient的计数清0，返回清0之前的快照
+func ResetStats() StEzspStats {
+	return DefaultClient.ResetStats()
+}
@@ -0,0 +1,168 @@
+package ezsp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	// 本库支持的EZSP协议版本范围
+	EZSP_MIN_PROTOCOL_VERSION = byte(0x04)
+	EZSP_MAX_PROTOCOL_VERSION = byte(0x08)
+)
+
+// Capability NCP固件支持的功能，由协商好的协议版本和协议栈类型决定
+type Capability uint32
+
+const (
+	// CAPABILITY_EXTENDED_FRAME 扩展帧头，2字节frame control和16位frame ID，协议版本8以上
+	CAPABILITY_EXTENDED_FRAME = Capability(1 << iota)
+	// CAPABILITY_HOST_SOURCE_ROUTE host用setSourceRoute指定源路由，协议版本8开始这个命令被去掉，源路由由NCP自己维护
+	CAPABILITY_HOST_SOURCE_ROUTE
+	// CAPABILITY_MESH_STACK 协议栈类型是mesh
+	CAPABILITY_MESH_STACK
+	// CAPABILITY_NCP_SOURCE_ROUTE_TABLE NCP维护源路由表，可以用getSourceRouteTableEntry读取，协议版本8以上
+	CAPABILITY_NCP_SOURCE_ROUTE_TABLE
+)
+
+var capabilityNameMap = map[Capability]string{
+	CAPABILITY_EXTENDED_FRAME:         "EXTENDED_FRAME",
+	CAPABILITY_HOST_SOURCE_ROUTE:      "HOST_SOURCE_ROUTE",
+	CAPABILITY_MESH_STACK:             "MESH_STACK",
+	CAPABILITY_NCP_SOURCE_ROUTE_TABLE: "NCP_SOURCE_ROUTE_TABLE",
+}
+
+// commandCapabilityMap 需要特定功能才能使用的命令
+var commandCapabilityMap = map[uint16]Capability{
+	EZSP_SET_SOURCE_ROUTE:                   CAPABILITY_HOST_SOURCE_ROUTE,
+	EZSP_GET_SOURCE_ROUTE_TABLE_TOTAL_SIZE:  CAPABILITY_NCP_SOURCE_ROUTE_TABLE,
+	EZSP_GET_SOURCE_ROUTE_TABLE_FILLED_SIZE: CAPABILITY_NCP_SOURCE_ROUTE_TABLE,
+	EZSP_GET_SOURCE_ROUTE_TABLE_ENTRY:       CAPABILITY_NCP_SOURCE_ROUTE_TABLE,
+}
+
+func (caps Capability) String() string {
+	var names []string
+	for bit := Capability(1); bit != 0; bit <<= 1 {
+		if caps&bit != 0 {
+			name, ok := capabilityNameMap[bit]
+			if !ok {
+				name = fmt.Sprintf("UNKNOWN_CAPABILITY_%X", uint32(bit))
+			}
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// StVersionInfo EZSP版本协商的结果
+type StVersionInfo struct {
+	ProtocolVersion byte
+	StackType       byte
+	StackVersion    uint16
+	Capabilities    Capability
+}
+
+func newVersionInfo(protocolVersion byte, stackType byte, stackVersion uint16) StVersionInfo {
+	info := StVersionInfo{ProtocolVersion: protocolVersion, StackType: stackType, StackVersion: stackVersion}
+	if protocolVersion >= EZSP_EXTENDED_FRAME_PROTOCOL_VERSION {
+		info.Capabilities |= CAPABILITY_EXTENDED_FRAME | CAPABILITY_NCP_SOURCE_ROUTE_TABLE
+	} else {
+		info.Capabilities |= CAPABILITY_HOST_SOURCE_ROUTE
+	}
+	if stackType == EZSP_STACK_TYPE_MESH {
+		info.Capabilities |= CAPABILITY_MESH_STACK
+	}
+	return info
+}
+
+// CapabilityError NCP固件不支持要使用的功能
+type CapabilityError struct {
+	Capability      Capability
+	ProtocolVersion byte
+	OccurAt         string
+}
+
+func (e CapabilityError) Error() string {
+	return fmt.Sprintf("%s not supported: NCP protocolVersion(%d) has no capability %s", e.OccurAt, e.ProtocolVersion, e.Capability)
+}
+
+// VersionInfo 返回协商好的版本信息，还没有协商时ProtocolVersion为0
+func (c *Client) VersionInfo() StVersionInfo {
+	c.seqMutex.Lock()
+	defer c.seqMutex.Unlock()
+	return c.versionInfo
+}
+
+// Supports 判断NCP是否支持caps里的所有功能，还没有协商版本时返回false
+func (c *Client) Supports(caps Capability) bool {
+	return c.VersionInfo().Capabilities&caps == caps
+}
+
+// CheckCapability NCP不支持caps时返回 CapabilityError
+func (c *Client) CheckCapability(caps Capability) error {
+	info := c.VersionInfo()
+	if info.Capabilities&caps != caps {
+		return CapabilityError{Capability: caps &^ info.Capabilities, ProtocolVersion: info.ProtocolVersion, OccurAt: "CheckCapability"}
+	}
+	return nil
+}
+
+// CheckCommand 检查NCP是否支持命令frmID，还没有协商版本时不做检查
+func (c *Client) CheckCommand(frmID uint16) error {
+	info := c.VersionInfo()
+	if info.ProtocolVersion == 0 {
+		return nil
+	}
+	caps, ok := commandCapabilityMap[frmID]
+	if ok && info.Capabilities&caps != caps {
+		return CapabilityError{Capability: caps &^ info.Capabilities, ProtocolVersion: info.ProtocolVersion, OccurAt: frameIDToName(frmID)}
+	}
+	return nil
+}
+
+// NegotiateVersionContext 用desiredProtocolVersion发送version命令，NCP返回了不同的版本并且本库支持时，
+// 用NCP的版本再协商一次。成功后记录版本信息，之后的命令按协商的版本选择帧头和检查功能
+func (c *Client) NegotiateVersionContext(ctx context.Context, desiredProtocolVersion byte) (info StVersionInfo, err error) {
+	protocolVersion, stackType, stackVersion, err := c.EzspVersionContext(ctx, desiredProtocolVersion)
+	if err != nil && protocolVersion != 0 && protocolVersion != desiredProtocolVersion {
+		if protocolVersion < EZSP_MIN_PROTOCOL_VERSION || protocolVersion > EZSP_MAX_PROTOCOL_VERSION {
+			return info, fmt.Errorf("NCP protocolVersion(%d) not supported, expect %d-%d", protocolVersion, EZSP_MIN_PROTOCOL_VERSION, EZSP_MAX_PROTOCOL_VERSION)
+		}
+		ezspApiTrace("NCP protocolVersion(%d) != desired(%d), negotiate again", protocolVersion, desiredProtocolVersion)
+		protocolVersion, stackType, stackVersion, err = c.EzspVersionContext(ctx, protocolVersion)
+	}
+	if err != nil {
+		return info, err
+	}
+	info = c.VersionInfo()
+	ezspApiTrace("NegotiateVersion protocolVersion(%d) stackType(%d) stackVersion(0x%04x) capabilities(%s)", protocolVersion, stackType, stackVersion, info.Capabilities)
+	return info, nil
+}
+
+func (c *Client) NegotiateVersion(desiredProtocolVersion byte) (StVersionInfo, error) {
+	return c.NegotiateVersionContext(context.Background(), desiredProtocolVersion)
+}
+
+func NegotiateVersion(desiredProtocolVersion byte) (StVersionInfo, error) {
+	return DefaultClient.NegotiateVersion(desiredProtocolVersion)
+}
+
+func NegotiateVersionContext(ctx context.Context, desiredProtocolVersion byte) (StVersionInfo, error) {
+	return DefaultClient.NegotiateVersionContext(ctx, desiredProtocolVersion)
+}
+
+// VersionInfo DefaultClient协商好的版本信息
+func VersionInfo() StVersionInfo {
+	return DefaultClient.VersionInfo()
+}
+
+// Supports 判断DefaultClient连接的NCP是否支持caps
+func Supports(caps Capability) bool {
+	return DefaultClient.Supports(caps)
+}
+
+// CheckCapability DefaultClient连接的NCP不支持caps时返回 CapabilityError
+func CheckCapability(caps Capability) error {
+	return DefaultClient.CheckCapability(caps)
+}
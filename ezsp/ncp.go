@@ -16,6 +16,9 @@ func ncpTrace(format string, v ...interface{}) {
 	}
 }
 
+// StNcpCallbacks 以前的callback接口，现在由 DefaultEventBus（DefaultClient的事件总线）上的订阅转发过来
+//
+// Deprecated: 使用 Subscribe 订阅事件
 type StNcpCallbacks struct {
 	NcpMessageSentHandler         func(outgoingMessageType byte, indexOrDestination uint16, apsFrame *EmberApsFrame, messageTag byte, emberStatus byte, message []byte)
 	NcpIncomingSenderEui64Handler func(senderEui64 uint64)
@@ -23,12 +26,46 @@ type StNcpCallbacks struct {
 	NcpTrustCenterJoinHandler     func(newNodeId uint16, newNodeEui64 uint64, deviceUpdateStatus byte, joinDecision byte, parentOfNewNode uint16)
 }
 
+// NcpCallbacks 不为nil的handler在 DefaultEventBus 发布对应事件时被调用
+//
+// Deprecated: 使用 Subscribe 订阅事件
+var NcpCallbacks StNcpCallbacks
+
+var ncpCallbacksSubscription = DefaultEventBus.Subscribe(EventFilter{FrameIDs: []uint16{
+	EZSP_MESSAGE_SENT_HANDLER,
+	EZSP_INCOMING_SENDER_EUI64_HANDLER,
+	EZSP_INCOMING_MESSAGE_HANDLER,
+	EZSP_TRUST_CENTER_JOIN_HANDLER}}, ncpCallbacksForward)
+
+// ncpCallbacksForward 把事件转发给 NcpCallbacks
+func ncpCallbacksForward(event Event) {
+	switch e := event.(type) {
+	case *MessageSentEvent:
+		if NcpCallbacks.NcpMessageSentHandler != nil {
+			NcpCallbacks.NcpMessageSentHandler(e.OutgoingMessageType, e.IndexOrDestination, &e.ApsFrame, e.MessageTag, e.EmberStatus, e.Message)
+		}
+	case *IncomingSenderEui64Event:
+		if NcpCallbacks.NcpIncomingSenderEui64Handler != nil {
+			NcpCallbacks.NcpIncomingSenderEui64Handler(e.SenderEui64)
+		}
+	case *IncomingMessageEvent:
+		if NcpCallbacks.NcpIncomingMessageHandler != nil {
+			NcpCallbacks.NcpIncomingMessageHandler(e.IncomingMessageType, &e.ApsFrame, e.LastHopLqi, e.LastHopRssi, e.Sender, e.BindingIndex, e.AddressIndex, e.Message)
+		}
+	case *TrustCenterJoinEvent:
+		if NcpCallbacks.NcpTrustCenterJoinHandler != nil {
+			NcpCallbacks.NcpTrustCenterJoinHandler(e.NewNodeId, e.NewNodeEui64, e.DeviceUpdateStatus, e.JoinDecision, e.ParentOfNewNode)
+		}
+	}
+}
+
 // StModuleInfo
 type StModuleInfo struct {
 	ModuleType      string `json:"moduletype"`
 	ProtocolVersion byte   `json:"protocolversion"`
 	StackType       byte   `json:"stacktype"`
 	StackVersion    string `json:"stackversion"`
+	Capabilities    string `json:"capabilities"`
 }
 
 // StMeshInfo
@@ -39,16 +76,47 @@ type StMeshInfo struct {
 }
 
 var ModuleInfo = StModuleInfo{ModuleType: "EM357"}
-var MeshInfo StMeshInfo
-var MeshStatusUp bool
-var NcpCallbacks StNcpCallbacks
 
+// MeshStatusUp 网络是否已经建立，由c收到的stack status callback更新
+func (c *Client) MeshStatusUp() bool {
+	c.meshMutex.Lock()
+	defer c.meshMutex.Unlock()
+	return c.meshStatusUp
+}
+
+// MeshInfo 网络建立时从NCP读到的网络参数
+func (c *Client) MeshInfo() StMeshInfo {
+	c.meshMutex.Lock()
+	defer c.meshMutex.Unlock()
+	return c.meshInfo
+}
+
+// MeshStatusUp DefaultClient的网络是否已经建立
+func MeshStatusUp() bool {
+	return DefaultClient.MeshStatusUp()
+}
+
+// MeshInfo DefaultClient的网络参数
+func MeshInfo() StMeshInfo {
+	return DefaultClient.MeshInfo()
+}
+
+func (c *Client) setMeshStatusUp(up bool) {
+	c.meshMutex.Lock()
+	c.meshStatusUp = up
+	c.meshMutex.Unlock()
+}
+
+// NcpGetVersion 和NCP协商EZSP协议版本，结果记录在 ModuleInfo 里
 func NcpGetVersion() (err error) {
-	var stackVersion uint16
-	ModuleInfo.ProtocolVersion, ModuleInfo.StackType, stackVersion, err = EzspVersion(EZSP_PROTOCOL_VERSION)
+	info, err := NegotiateVersion(EZSP_PROTOCOL_VERSION)
 	if err != nil {
-		return fmt.Errorf("EzspVersion failed: %v", err)
+		return fmt.Errorf("NegotiateVersion failed: %v", err)
 	}
+	ModuleInfo.ProtocolVersion = info.ProtocolVersion
+	ModuleInfo.StackType = info.StackType
+	ModuleInfo.Capabilities = info.Capabilities.String()
+	stackVersion := info.StackVersion
 
 	emberVersion, err := EzspGetValue_VERSION_INFO()
 	if err != nil {
@@ -60,7 +128,7 @@ func NcpGetVersion() (err error) {
 
 	//common.Log.Infof("%v", stackVersion)
 
-	ncpTrace("NcpGetVersion: protocolVersion(%d) stackType(%d) stackVersion(%s)", ModuleInfo.ProtocolVersion, ModuleInfo.StackType, ModuleInfo.StackVersion)
+	ncpTrace("NcpGetVersion: protocolVersion(%d) stackType(%d) stackVersion(%s) capabilities(%s)", ModuleInfo.ProtocolVersion, ModuleInfo.StackType, ModuleInfo.StackVersion, ModuleInfo.Capabilities)
 	return nil
 }
 
@@ -229,18 +297,20 @@ func NcpPrintAddressTable() {
 
 // Called when the stack status changes, usually as a result of an
 // attempt to form, join, or leave a network.
-func EzspStackStatusHandler(emberStatus byte) {
+func (c *Client) EzspStackStatusHandler(emberStatus byte) {
 	switch emberStatus {
 	case EMBER_NETWORK_UP, EMBER_TRUST_CENTER_EUI_HAS_CHANGED, EMBER_CHANNEL_CHANGED: // also means NETWORK_UP
-		MeshStatusUp = true
+		c.setMeshStatusUp(true)
 
-		nodeType, parameters, err := EzspGetNetworkParameters()
+		nodeType, parameters, err := c.EzspGetNetworkParameters()
 		if err != nil {
 			common.Log.Errorf("EzspGetNetworkParameters failed: %v", err)
 		} else {
-			MeshInfo.PANID = parameters.PanId
-			MeshInfo.Channel = parameters.RadioChannel
-			MeshInfo.ExPANID = parameters.ExtendedPanId
+			c.meshMutex.Lock()
+			c.meshInfo.PANID = parameters.PanId
+			c.meshInfo.Channel = parameters.RadioChannel
+			c.meshInfo.ExPANID = parameters.ExtendedPanId
+			c.meshMutex.Unlock()
 
 			ncpTrace("EMBER_NETWORK_UP NodeType = %d channels = %d panId = 0x%04x expanid = %016x",
 				nodeType,
@@ -250,7 +320,7 @@ func EzspStackStatusHandler(emberStatus byte) {
 		}
 
 	case EMBER_NETWORK_DOWN, EMBER_RECEIVED_KEY_IN_THE_CLEAR, EMBER_NO_NETWORK_KEY_RECEIVED, EMBER_NO_LINK_KEY_RECEIVED, EMBER_PRECONFIGURED_KEY_REQUIRED, EMBER_MOVE_FAILED, EMBER_JOIN_FAILED, EMBER_NO_BEACONS, EMBER_CANNOT_JOIN_AS_ROUTER:
-		MeshStatusUp = false
+		c.setMeshStatusUp(false)
 		ncpTrace("EMBER_NETWORK_DOWN")
 
 	default:
@@ -258,6 +328,10 @@ func EzspStackStatusHandler(emberStatus byte) {
 	}
 }
 
+func EzspStackStatusHandler(emberStatus byte) {
+	DefaultClient.EzspStackStatusHandler(emberStatus)
+}
+
 func EzspMessageSentHandler(outgoingMessageType byte,
 	indexOrDestination uint16,
 	apsFrame *EmberApsFrame,
@@ -266,21 +340,10 @@ func EzspMessageSentHandler(outgoingMessageType byte,
 	message []byte) {
 	ncpTrace("%s message sent(%s) to 0x%04x, Profile 0x%04x, Cluster 0x%04x: 0x%x",
 		outgoingMessageTypeToString(outgoingMessageType), emberStatusToString(emberStatus), indexOrDestination, apsFrame.ProfileId, apsFrame.ClusterId, message)
-	if NcpCallbacks.NcpMessageSentHandler != nil {
-		NcpCallbacks.NcpMessageSentHandler(outgoingMessageType,
-			indexOrDestination,
-			apsFrame,
-			messageTag,
-			emberStatus,
-			message)
-	}
 }
 
 func EzspIncomingSenderEui64Handler(senderEui64 uint64) {
 	ncpTrace("Incoming sender EUI64 %016x", senderEui64)
-	if NcpCallbacks.NcpIncomingSenderEui64Handler != nil {
-		NcpCallbacks.NcpIncomingSenderEui64Handler(senderEui64)
-	}
 }
 
 func EzspIncomingMessageHandler(incomingMessageType byte,
@@ -293,21 +356,15 @@ func EzspIncomingMessageHandler(incomingMessageType byte,
 	message []byte) {
 
 	ncpTrace("Incoming %s message from 0x%04x, Profile 0x%04x, Cluster 0x%04x: 0x%x", incomingMessageTypeToString(incomingMessageType), sender, apsFrame.ProfileId, apsFrame.ClusterId, message)
-	if NcpCallbacks.NcpIncomingMessageHandler != nil {
-		NcpCallbacks.NcpIncomingMessageHandler(incomingMessageType,
-			apsFrame,
-			lastHopLqi,
-			lastHopRssi,
-			sender,
-			bindingIndex,
-			addressIndex,
-			message)
-	}
 }
 
-func EzspIncomingRouteErrorHandler(emberStatus byte, target uint16) {
+func (c *Client) EzspIncomingRouteErrorHandler(emberStatus byte, target uint16) {
 	ncpTrace("Incoming route error %s for 0x%04x", emberStatusToString(emberStatus), target)
-	NcpSendMTORR()
+	c.NcpSendMTORR()
+}
+
+func EzspIncomingRouteErrorHandler(emberStatus byte, target uint16) {
+	DefaultClient.EzspIncomingRouteErrorHandler(emberStatus, target)
 }
 
 func EzspTrustCenterJoinHandler(newNodeId uint16,
@@ -316,16 +373,18 @@ func EzspTrustCenterJoinHandler(newNodeId uint16,
 	joinDecision byte,
 	parentOfNewNode uint16) {
 	ncpTrace("Trust center has 0x%04x(%016x) joined(%d)", newNodeId, newNodeEui64, deviceUpdateStatus)
-	if NcpCallbacks.NcpTrustCenterJoinHandler != nil {
-		NcpCallbacks.NcpTrustCenterJoinHandler(newNodeId, newNodeEui64, deviceUpdateStatus, joinDecision, parentOfNewNode)
-	}
 }
 
-func NcpSendMTORR() {
-	if MeshStatusUp {
-		err := EzspSendManyToOneRouteRequest(EMBER_HIGH_RAM_CONCENTRATOR, 0)
+// NcpSendMTORR 网络建立后发送many-to-one route request，让设备回复route record
+func (c *Client) NcpSendMTORR() {
+	if c.MeshStatusUp() {
+		err := c.EzspSendManyToOneRouteRequest(EMBER_HIGH_RAM_CONCENTRATOR, 0)
 		if err != nil {
 			common.Log.Errorf("EzspSendManyToOneRouteRequest failed: %v", err)
 		}
 	}
 }
+
+func NcpSendMTORR() {
+	DefaultClient.NcpSendMTORR()
+}
@@ -3,6 +3,7 @@ package ezsp
 import (
 	"fmt"
 	"math/rand"
+	"sync"
 	"time"
 
 	"encoding/binary"
@@ -18,8 +19,8 @@ func ncpFormTrace(format string, v ...interface{}) {
 	}
 }
 
-// NcpFormNetwork radioChannel=0xff时自动根据能量扫描选择channel
-func NcpFormNetwork(radioChannel byte, tcEnable bool) (err error) {
+// NcpFormNetwork radioChannel=0xff时自动根据能量扫描选择channel，扫描结果要由c的 EzspCallbackDispatch 处理
+func (c *Client) NcpFormNetwork(radioChannel byte, tcEnable bool) (err error) {
 	var channelMask uint32
 	if radioChannel == 0xff {
 		channelMask = EMBER_RECOMMENDED_802_15_4_CHANNELS_MASK
@@ -29,7 +30,7 @@ func NcpFormNetwork(radioChannel byte, tcEnable bool) (err error) {
 		return fmt.Errorf("unsupported channel %d", radioChannel)
 	}
 	if tcEnable {
-		err = ncpTrustCenterInit()
+		err = c.ncpTrustCenterInit()
 		if err != nil {
 			common.Log.Errorf("TrustCenterInit failed %v", err)
 			return
@@ -37,10 +38,15 @@ func NcpFormNetwork(radioChannel byte, tcEnable bool) (err error) {
 	}
 	ncpFormTrace("Start Energy Scan")
 	rand.Seed(time.Now().UnixNano())
-	return ncpStartScan(channelMask)
+	return c.ncpStartScan(channelMask)
+}
+
+// NcpFormNetwork 在DefaultClient上组网
+func NcpFormNetwork(radioChannel byte, tcEnable bool) (err error) {
+	return DefaultClient.NcpFormNetwork(radioChannel, tcEnable)
 }
 
-func ncpTrustCenterInit() (err error) {
+func (c *Client) ncpTrustCenterInit() (err error) {
 	emberInitialSecurityState := EmberInitialSecurityState{}
 	emberInitialSecurityState.bitmask |= EMBER_TRUST_CENTER_GLOBAL_LINK_KEY
 	emberInitialSecurityState.bitmask |= EMBER_HAVE_PRECONFIGURED_KEY
@@ -54,28 +60,28 @@ func ncpTrustCenterInit() (err error) {
 	binary.LittleEndian.PutUint64(emberInitialSecurityState.networkKey[:], rand.Uint64())
 	binary.LittleEndian.PutUint64(emberInitialSecurityState.networkKey[8:], rand.Uint64())
 
-	err = EzspSetInitialSecurityState(&emberInitialSecurityState)
+	err = c.EzspSetInitialSecurityState(&emberInitialSecurityState)
 	if err != nil {
 		return fmt.Errorf("EzspSetInitialSecurityState failed: %v", err)
 	}
 
 	extended := EMBER_JOINER_GLOBAL_LINK_KEY
-	err = EzspSetValue_EXTENDED_SECURITY_BITMASK(extended)
+	err = c.EzspSetValue_EXTENDED_SECURITY_BITMASK(extended)
 	if err != nil {
 		return fmt.Errorf("EzspSetValue_EXTENDED_SECURITY_BITMASK failed: %v", err)
 	}
 
-	err = EzspSetPolicy(EZSP_TC_KEY_REQUEST_POLICY, EZSP_DENY_TC_KEY_REQUESTS)
+	err = c.EzspSetPolicy(EZSP_TC_KEY_REQUEST_POLICY, EZSP_DENY_TC_KEY_REQUESTS)
 	if err != nil {
 		return fmt.Errorf("EzspSetPolicy EZSP_DENY_TC_KEY_REQUESTS failed: %v", err)
 	}
 
-	err = EzspSetPolicy(EZSP_APP_KEY_REQUEST_POLICY, EZSP_ALLOW_APP_KEY_REQUESTS)
+	err = c.EzspSetPolicy(EZSP_APP_KEY_REQUEST_POLICY, EZSP_ALLOW_APP_KEY_REQUESTS)
 	if err != nil {
 		return fmt.Errorf("EzspSetPolicy EZSP_ALLOW_APP_KEY_REQUESTS failed: %v", err)
 	}
 
-	err = EzspSetPolicy(EZSP_TRUST_CENTER_POLICY, EZSP_ALLOW_PRECONFIGURED_KEY_JOINS)
+	err = c.EzspSetPolicy(EZSP_TRUST_CENTER_POLICY, EZSP_ALLOW_PRECONFIGURED_KEY_JOINS)
 	if err != nil {
 		return fmt.Errorf("EzspSetPolicy EZSP_ALLOW_PRECONFIGURED_KEY_JOINS failed: %v", err)
 	}
@@ -105,39 +111,60 @@ const (
 	ENERGY_SCAN_DURATION = byte(5)
 )
 
-var formAndJoinScanType = FORM_AND_JOIN_NOT_SCANNING
-var networkCount byte
-var channelEnergies [EMBER_NUM_802_15_4_CHANNELS]byte
-var panIdCandidates [NUM_PAN_ID_CANDIDATES]uint16
-var channelCache byte
+// stFormAndJoin 组网扫描的状态，每个Client一份，NcpFormNetwork 和扫描的callback都会用到，
+// 调用EZSP命令时不持有mutex
+type stFormAndJoin struct {
+	mutex           sync.Mutex
+	scanType        byte
+	networkCount    byte
+	channelEnergies [EMBER_NUM_802_15_4_CHANNELS]byte
+	panIdCandidates [NUM_PAN_ID_CANDIDATES]uint16
+	channelCache    byte
+}
 
-func ncpStartScan(channelMask uint32) (err error) {
-	if isScanning() {
+func (c *Client) ncpStartScan(channelMask uint32) (err error) {
+	f := &c.formAndJoin
+	f.mutex.Lock()
+	if f.isScanning() {
+		f.mutex.Unlock()
 		return fmt.Errorf("already in scan")
 	}
-	formAndJoinScanType = FORM_AND_JOIN_ENERGY_SCAN
-	networkCount = 0
-	for i := range channelEnergies {
-		channelEnergies[i] = byte(0xff)
+	f.scanType = FORM_AND_JOIN_ENERGY_SCAN
+	f.networkCount = 0
+	for i := range f.channelEnergies {
+		f.channelEnergies[i] = byte(0xff)
 	}
-	err = startScan(EZSP_ENERGY_SCAN, channelMask, ENERGY_SCAN_DURATION)
+	f.mutex.Unlock()
+	err = c.startScan(EZSP_ENERGY_SCAN, channelMask, ENERGY_SCAN_DURATION)
 	return
 }
 
-func EzspEnergyScanResultHandler(channel byte, maxRssiValue int8) {
+func (c *Client) EzspEnergyScanResultHandler(channel byte, maxRssiValue int8) {
 	ncpFormTrace("EzspEnergyScanResultHandler found energy %d dBm on channel %d", maxRssiValue, channel)
-	if isScanning() {
-		channelEnergies[channel-EMBER_MIN_802_15_4_CHANNEL_NUMBER] = byte(maxRssiValue) //todo 这里应该用有符号
+	f := &c.formAndJoin
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.isScanning() {
+		f.channelEnergies[channel-EMBER_MIN_802_15_4_CHANNEL_NUMBER] = byte(maxRssiValue) //todo 这里应该用有符号
 	}
 }
 
-func EzspScanCompleteHandler(channel byte, emberStatus byte) {
-	if !isScanning() {
+func EzspEnergyScanResultHandler(channel byte, maxRssiValue int8) {
+	DefaultClient.EzspEnergyScanResultHandler(channel, maxRssiValue)
+}
+
+func (c *Client) EzspScanCompleteHandler(channel byte, emberStatus byte) {
+	f := &c.formAndJoin
+	f.mutex.Lock()
+	scanning := f.isScanning()
+	scanType := f.scanType
+	f.mutex.Unlock()
+	if !scanning {
 		common.Log.Error("unexpected EzspScanCompleteHandler, not in scaning")
 		return
 	}
 
-	if FORM_AND_JOIN_ENERGY_SCAN != formAndJoinScanType {
+	if FORM_AND_JOIN_ENERGY_SCAN != scanType {
 		// This scan is an Active Scan.
 		// Active Scans potentially report transmit failures through this callback.
 		if EMBER_SUCCESS != emberStatus {
@@ -151,37 +178,55 @@ func EzspScanCompleteHandler(channel byte, emberStatus byte) {
 		}
 	}
 
-	switch formAndJoinScanType {
+	switch scanType {
 	case FORM_AND_JOIN_ENERGY_SCAN:
 		ncpFormTrace("Energy Scan CompleteHandler")
-		energyScanComplete()
+		c.energyScanComplete()
 	case FORM_AND_JOIN_PAN_ID_SCAN:
 		ncpFormTrace("PANID Scan CompleteHandler")
-		panIdScanComplete()
+		c.panIdScanComplete()
 	default:
-		common.Log.Errorf("unexpected EzspScanCompleteHandler formAndJoinScanType=%d", formAndJoinScanType)
+		common.Log.Errorf("unexpected EzspScanCompleteHandler formAndJoinScanType=%d", scanType)
 	}
 }
 
-func EzspNetworkFoundHandler(networkFound *EmberZigbeeNetwork, lqi byte, rssi int8) {
+func EzspScanCompleteHandler(channel byte, emberStatus byte) {
+	DefaultClient.EzspScanCompleteHandler(channel, emberStatus)
+}
+
+func (c *Client) EzspNetworkFoundHandler(networkFound *EmberZigbeeNetwork, lqi byte, rssi int8) {
 	ncpFormTrace("SCAN: found %+v, lqi %d, rssi: %d", networkFound, lqi, rssi)
+	f := &c.formAndJoin
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
 
-	switch formAndJoinScanType {
+	switch f.scanType {
 
 	case FORM_AND_JOIN_PAN_ID_SCAN:
 		for i := 0; i < NUM_PAN_ID_CANDIDATES; i++ {
-			if panIdCandidates[i] == networkFound.PanId {
-				panIdCandidates[i] = uint16(0xFFFF)
+			if f.panIdCandidates[i] == networkFound.PanId {
+				f.panIdCandidates[i] = uint16(0xFFFF)
 			}
 		}
 
 	default:
-		common.Log.Error("unknown scan  ", formAndJoinScanType)
+		common.Log.Error("unknown scan  ", f.scanType)
 	}
 }
 
-func isScanning() bool {
-	return formAndJoinScanType >= FORM_AND_JOIN_ENERGY_SCAN
+func EzspNetworkFoundHandler(networkFound *EmberZigbeeNetwork, lqi byte, rssi int8) {
+	DefaultClient.EzspNetworkFoundHandler(networkFound, lqi, rssi)
+}
+
+// isScanning 调用时要持有mutex
+func (f *stFormAndJoin) isScanning() bool {
+	return f.scanType >= FORM_AND_JOIN_ENERGY_SCAN
+}
+
+func (c *Client) energyScanComplete() {
+	if c.formAndJoin.selectChannel() {
+		c.startPanIdScan()
+	}
 }
 
 // Pick a channel from among those with the lowest energy and then look for
@@ -191,7 +236,9 @@ func isScanning() bool {
 // them for very long, so we add in some slop to the measurements and then pick
 // a random channel from the least noisy ones.  This avoids having several
 // coordinators pick the same slightly quieter channel.
-func energyScanComplete() {
+func (f *stFormAndJoin) selectChannel() bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
 	cutoff := byte(0xFF)
 	candidateCount := byte(0)
 	var channelIndex byte
@@ -199,8 +246,8 @@ func energyScanComplete() {
 
 	// cutoff = min energy + ENERGY_SCAN_FUZZ
 	for i = 0; i < EMBER_NUM_802_15_4_CHANNELS; i++ {
-		if channelEnergies[i] < cutoff-ENERGY_SCAN_FUZZ {
-			cutoff = channelEnergies[i] + ENERGY_SCAN_FUZZ
+		if f.channelEnergies[i] < cutoff-ENERGY_SCAN_FUZZ {
+			cutoff = f.channelEnergies[i] + ENERGY_SCAN_FUZZ
 		}
 	}
 
@@ -208,7 +255,7 @@ func energyScanComplete() {
 	// so there will be at least one candidate.
 	// 能量低于cutoff的频道比较适合创建新的网络
 	for i = 0; i < EMBER_NUM_802_15_4_CHANNELS; i++ {
-		if channelEnergies[i] < cutoff {
+		if f.channelEnergies[i] < cutoff {
 			candidateCount++
 		}
 	}
@@ -217,9 +264,9 @@ func energyScanComplete() {
 	// then our candidateCount will be 0.  We want to avoid that case and
 	// bail out (since we will do a divide by 0 below)
 	if candidateCount == 0 {
-		formAndJoinScanType = FORM_AND_JOIN_NOT_SCANNING
+		f.scanType = FORM_AND_JOIN_NOT_SCANNING
 		common.Log.Error("never got any energy scan results")
-		return
+		return false
 	}
 
 	// 在这些candidate中随机取第channelIndex个
@@ -227,29 +274,25 @@ func energyScanComplete() {
 	ncpFormTrace("cutoff=%d rand select %d", cutoff, channelIndex)
 
 	for i = 0; i < EMBER_NUM_802_15_4_CHANNELS; i++ {
-		if channelEnergies[i] < cutoff {
+		if f.channelEnergies[i] < cutoff {
 			if channelIndex == 0 {
-				channelCache = byte(EMBER_MIN_802_15_4_CHANNEL_NUMBER + i)
+				f.channelCache = byte(EMBER_MIN_802_15_4_CHANNEL_NUMBER + i)
 				break
 			}
 			channelIndex--
 		}
 	}
 
-	ncpFormTrace("select channel %d, Start PANID Scan", channelCache)
-	startPanIdScan()
+	ncpFormTrace("select channel %d, Start PANID Scan", f.channelCache)
+	return true
 }
 
 // Form a network using one of the unused PAN IDs.  If we got unlucky we
 // pick some more and try again.
-func panIdScanComplete() {
-
-	for i := 0; i < NUM_PAN_ID_CANDIDATES; i++ {
-		if panIdCandidates[i] != 0xFFFF {
-			unusedPanIdFoundHandler(panIdCandidates[i], channelCache)
-			formAndJoinScanType = FORM_AND_JOIN_NOT_SCANNING
-			return
-		}
+func (c *Client) panIdScanComplete() {
+	if panId, channel, ok := c.formAndJoin.unusedPanId(); ok {
+		c.unusedPanIdFoundHandler(panId, channel)
+		return
 	}
 
 	// XXX: Do we care this could keep happening forever?
@@ -259,43 +302,61 @@ func panIdScanComplete() {
 	// (more likely due to a bug) and we could hear the same set of
 	// PAN IDs that conflict with our random set.
 
-	startPanIdScan() // Start over with new candidates.
+	c.startPanIdScan() // Start over with new candidates.
 }
 
-func startPanIdScan() {
+// unusedPanId 找到没有被使用的PAN ID后结束扫描
+func (f *stFormAndJoin) unusedPanId() (panId uint16, channel byte, ok bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for i := 0; i < NUM_PAN_ID_CANDIDATES; i++ {
+		if f.panIdCandidates[i] != 0xFFFF {
+			f.scanType = FORM_AND_JOIN_NOT_SCANNING
+			return f.panIdCandidates[i], f.channelCache, true
+		}
+	}
+	return 0, 0, false
+}
 
+func (c *Client) startPanIdScan() {
+	f := &c.formAndJoin
+	f.mutex.Lock()
 	// PAN IDs can be 0..0xFFFE.  We pick some trial candidates and then do a scan
 	// to find one that is not in use.
 	for i := 0; i < NUM_PAN_ID_CANDIDATES; {
 		panId := uint16(rand.Uint32())
 		if panId != 0xFFFF {
-			panIdCandidates[i] = panId
+			f.panIdCandidates[i] = panId
 			i++
 		}
 	}
 
-	formAndJoinScanType = FORM_AND_JOIN_PAN_ID_SCAN
-	err := startScan(EZSP_ACTIVE_SCAN, uint32(1)<<channelCache, ACTIVE_SCAN_DURATION)
+	f.scanType = FORM_AND_JOIN_PAN_ID_SCAN
+	channel := f.channelCache
+	f.mutex.Unlock()
+	err := c.startScan(EZSP_ACTIVE_SCAN, uint32(1)<<channel, ACTIVE_SCAN_DURATION)
 	if err != nil {
 		common.Log.Errorf("startScan EZSP_ACTIVE_SCAN failed %v", err)
 	}
 }
 
-func startScan(scanType byte, channelMask uint32, duration byte) (err error) {
-	err = EzspStartScan(scanType, channelMask, duration)
+func (c *Client) startScan(scanType byte, channelMask uint32, duration byte) (err error) {
+	err = c.EzspStartScan(scanType, channelMask, duration)
 	if err != nil {
-		formAndJoinScanType = FORM_AND_JOIN_NOT_SCANNING
+		c.formAndJoin.mutex.Lock()
+		c.formAndJoin.scanType = FORM_AND_JOIN_NOT_SCANNING
+		c.formAndJoin.mutex.Unlock()
 	}
 	return
 }
 
-func unusedPanIdFoundHandler(panId uint16, channel byte) {
+func (c *Client) unusedPanIdFoundHandler(panId uint16, channel byte) {
 	networkParams := EmberNetworkParameters{}
 	networkParams.RadioChannel = channel
 	networkParams.PanId = panId
 	networkParams.RadioTxPower = -1
 
-	err := EzspFormNetwork(&networkParams)
+	err := c.EzspFormNetwork(&networkParams)
 	if err != nil {
 		common.Log.Errorf("ezsp form error: %v", err)
 	}
@@ -1,7 +1,7 @@
 package ezsp
 
 import (
-	"github.com/conthing/ezsp/ash"
+	"sync"
 
 	"github.com/conthing/utils/common"
 )
@@ -29,13 +29,17 @@ const (
 	EZSP_HOST_SOURCE_ROUTE_TABLE_SIZE = 64
 )
 
-var sourceRouteTable [EZSP_HOST_SOURCE_ROUTE_TABLE_SIZE]StSourceRouteTableEntry
+// stSourceRouteTable 主机端的源路由表，每个Client一张，route record callback 写入，发送前读取
+type stSourceRouteTable struct {
+	mutex   sync.Mutex
+	entries [EZSP_HOST_SOURCE_ROUTE_TABLE_SIZE]StSourceRouteTableEntry
 
-// The number of entries in use.
-var entryCount = 0
+	// The number of entries in use.
+	entryCount int
 
-// The index of the most recently added entry.
-var newestIndex = NULL_INDEX
+	// The index of the most recently added entry.
+	newestIndex byte
+}
 
 var NcpSourceRouteTraceOn bool
 
@@ -45,9 +49,9 @@ func ncpSourceRouteTrace(format string, v ...interface{}) {
 	}
 }
 
-func sourceRouteFindIndex(id uint16) byte {
-	for i := 0; i < entryCount; i++ {
-		if sourceRouteTable[i].destination == id {
+func (t *stSourceRouteTable) findIndex(id uint16) byte {
+	for i := 0; i < t.entryCount; i++ {
+		if t.entries[i].destination == id {
 			return byte(i)
 		}
 	}
@@ -56,66 +60,76 @@ func sourceRouteFindIndex(id uint16) byte {
 
 // Create an entry with the given id or update an existing entry. furtherIndex
 // is the entry one hop further from the gateway.
-func sourceRouteAddEntry(id uint16, furtherIndex byte) byte {
+func (t *stSourceRouteTable) addEntry(id uint16, furtherIndex byte) byte {
 	// See if the id already exists in the table.
-	index := sourceRouteFindIndex(id)
+	index := t.findIndex(id)
 
 	if index == NULL_INDEX {
-		if entryCount < EZSP_HOST_SOURCE_ROUTE_TABLE_SIZE {
+		if t.entryCount < EZSP_HOST_SOURCE_ROUTE_TABLE_SIZE {
 			// No existing entry. Table is not full. Add new entry.
-			index = byte(entryCount)
-			entryCount++
+			index = byte(t.entryCount)
+			t.entryCount++
 		} else {
 			// No existing entry. Table is full. Replace oldest entry.
-			index = newestIndex
-			for sourceRouteTable[index].olderIndex != NULL_INDEX {
-				index = sourceRouteTable[index].olderIndex
+			index = t.newestIndex
+			for t.entries[index].olderIndex != NULL_INDEX {
+				index = t.entries[index].olderIndex
 			}
 		}
 	}
 
 	// Update the pointers (only) if something has changed.
-	if index != newestIndex {
-		for i := 0; i < entryCount; i++ {
-			if sourceRouteTable[i].olderIndex == index {
-				sourceRouteTable[i].olderIndex = sourceRouteTable[index].olderIndex
+	if index != t.newestIndex {
+		for i := 0; i < t.entryCount; i++ {
+			if t.entries[i].olderIndex == index {
+				t.entries[i].olderIndex = t.entries[index].olderIndex
 				break
 			}
 		}
-		sourceRouteTable[index].olderIndex = newestIndex
-		newestIndex = index
+		t.entries[index].olderIndex = t.newestIndex
+		t.newestIndex = index
 	}
 
 	// Add the entry.
-	sourceRouteTable[index].destination = id
-	sourceRouteTable[index].closerIndex = NULL_INDEX
+	t.entries[index].destination = id
+	t.entries[index].closerIndex = NULL_INDEX
 
 	// The current index is one hop closer to the gateway than furtherIndex.
 	if furtherIndex != NULL_INDEX {
-		sourceRouteTable[furtherIndex].closerIndex = index
+		t.entries[furtherIndex].closerIndex = index
 	}
 
 	// Return the current index to save the caller having to look it up.
 	return index
 }
 
-func EzspIncomingRouteRecordHandler(source uint16, sourceEui uint64, lastHopLqi byte, lastHopRssi int8, relay []uint16) {
+// EzspIncomingRouteRecordHandler 把route record记到c的源路由表
+func (c *Client) EzspIncomingRouteRecordHandler(source uint16, sourceEui uint64, lastHopLqi byte, lastHopRssi int8, relay []uint16) {
 	ncpSourceRouteTrace("NCP get source route for 0x%04x, %v", source, relay)
+	t := &c.sourceRoutes
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	// The source of the route record is furthest from the gateway. We start there
 	// and work closer.
-	previous := sourceRouteAddEntry(source, NULL_INDEX)
+	previous := t.addEntry(source, NULL_INDEX)
 
 	// Go through the relay list and add them to the source route table.
 	for _, id := range relay {
 		// We pass the index of the previous entry to link the route together.
-		previous = sourceRouteAddEntry(id, previous)
+		previous = t.addEntry(id, previous)
 	}
 }
 
+func EzspIncomingRouteRecordHandler(source uint16, sourceEui uint64, lastHopLqi byte, lastHopRssi int8, relay []uint16) {
+	DefaultClient.EzspIncomingRouteRecordHandler(source, sourceEui, lastHopLqi, lastHopRssi, relay)
+}
+
 // Note: We assume that the given relayList location is big enough to handle the
 // longest source route.
-func ncpFindSourceRoute(destination uint16) (exist bool, relayList []uint16) {
-	index := sourceRouteFindIndex(destination)
+func (t *stSourceRouteTable) find(destination uint16) (exist bool, relayList []uint16) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	index := t.findIndex(destination)
 
 	if index == NULL_INDEX {
 		exist = false
@@ -124,21 +138,29 @@ func ncpFindSourceRoute(destination uint16) (exist bool, relayList []uint16) {
 
 	// Fill in the relay list. The first relay in the list is the closest to the
 	// destination (furthest from the gateway).
-	for sourceRouteTable[index].closerIndex != NULL_INDEX {
-		index = sourceRouteTable[index].closerIndex
-		relayList = append(relayList, sourceRouteTable[index].destination)
+	for t.entries[index].closerIndex != NULL_INDEX {
+		index = t.entries[index].closerIndex
+		relayList = append(relayList, t.entries[index].destination)
 	}
 	exist = true
 	return
 }
 
-func NcpSetSourceRoute(id uint16) (err error) {
-	exist, relayList := ncpFindSourceRoute(id)
+// NcpSetSourceRoute 按c的源路由表给下一条发往id的消息设置源路由
+func (c *Client) NcpSetSourceRoute(id uint16) (err error) {
+	if !c.Supports(CAPABILITY_HOST_SOURCE_ROUTE) {
+		return nil // 协议版本8以上源路由由NCP维护
+	}
+	exist, relayList := c.sourceRoutes.find(id)
 	if !exist {
 		ncpSourceRouteTrace("NCP cannot find source route for 0x%04x, send directly", id)
 		return nil //不存在没有错，直接发送
 	}
-	ncpSourceRouteTrace("NCP set source route for 0x%04x, %v, \nTranceiverStep=%d SendStep=%d", id, relayList, ash.TransceiverStep, SendStep)
-	err = EzspSetSourceRoute(id, relayList)
+	ncpSourceRouteTrace("NCP set source route for 0x%04x, %v", id, relayList)
+	err = c.EzspSetSourceRoute(id, relayList)
 	return
 }
+
+func NcpSetSourceRoute(id uint16) (err error) {
+	return DefaultClient.NcpSetSourceRoute(id)
+}
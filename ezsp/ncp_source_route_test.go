@@ -0,0 +1,37 @@
+package ezsp
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/conthing/ezsp/ash"
+)
+
+// TestSourceRoutePerClient route record只记到收到它的Client的源路由表
+func TestSourceRoutePerClient(t *testing.T) {
+	a := NewClient(ash.NewLink())
+	b := NewClient(ash.NewLink())
+	a.EzspIncomingRouteRecordHandler(0x3003, 0, 0xff, -40, []uint16{0x2002, 0x1001})
+
+	exist, relays := a.sourceRoutes.find(0x3003)
+	if !exist || !reflect.DeepEqual(relays, []uint16{0x2002, 0x1001}) {
+		t.Fatalf("find(0x3003) = %v, %04x", exist, relays)
+	}
+	if exist, relays = a.sourceRoutes.find(0x2002); !exist || !reflect.DeepEqual(relays, []uint16{0x1001}) {
+		t.Fatalf("find(0x2002) = %v, %04x", exist, relays)
+	}
+	if exist, _ = b.sourceRoutes.find(0x3003); exist {
+		t.Fatal("route record leaked to another client")
+	}
+
+	// 表满后替换最早的项
+	for id := uint16(1); id <= EZSP_HOST_SOURCE_ROUTE_TABLE_SIZE; id++ {
+		a.EzspIncomingRouteRecordHandler(0x4000+id, 0, 0xff, -40, nil)
+	}
+	if exist, _ = a.sourceRoutes.find(0x3003); exist {
+		t.Fatal("oldest entry not replaced")
+	}
+	if exist, _ = a.sourceRoutes.find(0x4000 + EZSP_HOST_SOURCE_ROUTE_TABLE_SIZE); !exist {
+		t.Fatal("newest entry missing")
+	}
+}
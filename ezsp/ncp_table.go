@@ -0,0 +1,148 @@
+package ezsp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/conthing/utils/common"
+)
+
+// StSourceRoute 一条源路由，Relays从离目的地址最近的中继开始
+type StSourceRoute struct {
+	Destination uint16
+	Relays      []uint16
+}
+
+// StNcpTables NCP的邻居表、路由表、子节点表和源路由表
+type StNcpTables struct {
+	Neighbors    []EmberNeighborTableEntry
+	Routes       []EmberRouteTableEntry // 只包括在用的项
+	Children     []EmberChildData
+	SourceRoutes []StSourceRoute // 协议版本8以上是NCP的源路由表，之前是host维护的源路由表
+}
+
+// sourceRoutesFollow 按closer索引把源路由表的项串成中继列表
+func sourceRoutesFollow(destinations []uint16, closer []byte) []StSourceRoute {
+	routes := make([]StSourceRoute, 0, len(destinations))
+	for i, d := range destinations {
+		r := StSourceRoute{Destination: d}
+		for index := closer[i]; index != NULL_INDEX && int(index) < len(destinations); index = closer[index] {
+			if len(r.Relays) >= len(destinations) { // 表损坏形成了环
+				break
+			}
+			r.Relays = append(r.Relays, destinations[index])
+		}
+		routes = append(routes, r)
+	}
+	return routes
+}
+
+func (c *Client) ncpGetSourceRoutes() ([]StSourceRoute, error) {
+	var destinations []uint16
+	var closer []byte
+	if c.Supports(CAPABILITY_NCP_SOURCE_ROUTE_TABLE) {
+		filled, err := c.EzspGetSourceRouteTableFilledSize()
+		if err != nil {
+			return nil, err
+		}
+		for i := byte(0); i < filled; i++ {
+			destination, closerIndex, err := c.EzspGetSourceRouteTableEntry(i)
+			if err != nil {
+				return nil, err
+			}
+			destinations = append(destinations, destination)
+			closer = append(closer, closerIndex)
+		}
+	} else {
+		t := &c.sourceRoutes
+		t.mutex.Lock()
+		for _, e := range t.entries[:t.entryCount] {
+			destinations = append(destinations, e.destination)
+			closer = append(closer, e.closerIndex)
+		}
+		t.mutex.Unlock()
+	}
+	return sourceRoutesFollow(destinations, closer), nil
+}
+
+// NcpGetTables 读取NCP路由相关的全部表，读取失败时返回错误，已经读到的部分仍然返回
+func (c *Client) NcpGetTables() (tables *StNcpTables, err error) {
+	tables = &StNcpTables{}
+
+	count, err := c.EzspNeighborCount()
+	if err != nil {
+		return tables, fmt.Errorf("neighbor count: %v", err)
+	}
+	for i := byte(0); i < count; i++ {
+		neighbor, err := c.EzspGetNeighbor(i)
+		if err != nil {
+			return tables, fmt.Errorf("neighbor %d: %v", i, err)
+		}
+		tables.Neighbors = append(tables.Neighbors, neighbor)
+	}
+
+	size, err := c.EzspGetConfigurationValue(EZSP_CONFIG_ROUTE_TABLE_SIZE)
+	if err != nil {
+		return tables, fmt.Errorf("route table size: %v", err)
+	}
+	for i := 0; i < int(size) && i <= 0xff; i++ {
+		route, err := c.EzspGetRouteTableEntry(byte(i))
+		if err != nil {
+			return tables, fmt.Errorf("route table %d: %v", i, err)
+		}
+		if route.Status != EMBER_ROUTE_UNUSED {
+			tables.Routes = append(tables.Routes, route)
+		}
+	}
+
+	childCount, _, _, err := c.EzspGetParentChildParameters()
+	if err != nil {
+		return tables, fmt.Errorf("child count: %v", err)
+	}
+	maxChildren, err := c.EzspGetConfigurationValue(EZSP_CONFIG_MAX_END_DEVICE_CHILDREN)
+	if err != nil {
+		return tables, fmt.Errorf("max children: %v", err)
+	}
+	for i := 0; i < int(maxChildren) && i <= 0xff && len(tables.Children) < int(childCount); i++ {
+		child, err := c.EzspGetChildData(byte(i))
+		var e EmberError
+		if errors.As(err, &e) && e.EmberStatus == EMBER_NOT_JOINED {
+			continue // 空的位置
+		}
+		if err != nil {
+			return tables, fmt.Errorf("child %d: %v", i, err)
+		}
+		tables.Children = append(tables.Children, child)
+	}
+
+	tables.SourceRoutes, err = c.ncpGetSourceRoutes()
+	if err != nil {
+		return tables, fmt.Errorf("source route table: %v", err)
+	}
+	return tables, nil
+}
+
+// NcpGetTables 读取DefaultClient所在NCP的表
+func NcpGetTables() (tables *StNcpTables, err error) {
+	return DefaultClient.NcpGetTables()
+}
+
+// NcpPrintTables 像 NcpPrintAddressTable 一样把邻居表、路由表、子节点表和源路由表打印到日志
+func NcpPrintTables() {
+	tables, err := NcpGetTables()
+	if err != nil {
+		common.Log.Errorf("NcpGetTables failed: %v", err)
+	}
+	for _, n := range tables.Neighbors {
+		common.Log.Infof("neighbor 0x%04x EUI64=%016x lqi=%d inCost=%d outCost=%d age=%d", n.ShortId, n.LongId, n.AverageLqi, n.InCost, n.OutCost, n.Age)
+	}
+	for _, r := range tables.Routes {
+		common.Log.Infof("route 0x%04x nextHop=0x%04x status=%s age=%d", r.Destination, r.NextHop, routeStatusToString(r.Status), r.Age)
+	}
+	for _, c := range tables.Children {
+		common.Log.Infof("child 0x%04x EUI64=%016x type=%d", c.Id, c.Eui64, c.Type)
+	}
+	for _, s := range tables.SourceRoutes {
+		common.Log.Infof("source route 0x%04x relays=%04x", s.Destination, s.Relays)
+	}
+}
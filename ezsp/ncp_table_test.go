@@ -0,0 +1,29 @@
+package ezsp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSourceRoutesFollow(t *testing.T) {
+	// 0x3003经过0x2002、0x1001，0x2002经过0x1001，0x1001直达
+	routes := sourceRoutesFollow([]uint16{0x3003, 0x2002, 0x1001}, []byte{1, 2, NULL_INDEX})
+	want := []StSourceRoute{
+		{Destination: 0x3003, Relays: []uint16{0x2002, 0x1001}},
+		{Destination: 0x2002, Relays: []uint16{0x1001}},
+		{Destination: 0x1001},
+	}
+	if !reflect.DeepEqual(routes, want) {
+		t.Fatalf("routes = %+v, want %+v", routes, want)
+	}
+
+	// 越界的索引当作结束，表损坏形成的环不会死循环
+	routes = sourceRoutesFollow([]uint16{0x1001, 0x2002}, []byte{5, 0})
+	if len(routes[0].Relays) != 0 || !reflect.DeepEqual(routes[1].Relays, []uint16{0x1001}) {
+		t.Fatalf("routes with index out of range = %+v", routes)
+	}
+	routes = sourceRoutesFollow([]uint16{0x1001, 0x2002}, []byte{1, 0})
+	if len(routes[0].Relays) != 2 || len(routes[1].Relays) != 2 {
+		t.Fatalf("routes of looped table = %+v", routes)
+	}
+}
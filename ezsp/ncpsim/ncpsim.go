@@ -0,0 +1,403 @@
+// Package ncpsim 在内存中模拟一个EmberZNet NCP，实现 ash.Transport，
+// NCP一侧的ASH帧（RST/RSTACK、DATA/ACK/NAK、数据伪随机化）和常用的EZSP命令都在这里应答，
+// 另外可以用脚本化的设备产生入网、上报等callback，用来在没有硬件的情况下跑通整个协议栈
+package ncpsim
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/conthing/ezsp/ash"
+
+	"github.com/conthing/utils/common"
+	"github.com/conthing/utils/crc16"
+)
+
+const (
+	ashControlData   = byte(0x00)
+	ashControlAck    = byte(0x80)
+	ashControlNak    = byte(0xA0)
+	ashControlRst    = byte(0xC0)
+	ashControlRstack = byte(0xC1)
+	ashControlError  = byte(0xC2)
+	ashControlRetx   = byte(0x08)
+
+	ashXon  = byte(0x11)
+	ashXoff = byte(0x13)
+	ashSub  = byte(0x18)
+	ashCan  = byte(0x1A)
+	ashFlag = byte(0x7E)
+	ashEsc  = byte(0x7D)
+	ashFlip = byte(0x20)
+
+	ashVersion = byte(0x02)
+
+	// RSTACK和ERROR帧里的reset code
+	RESET_UNKNOWN_REASON                     = byte(0x00)
+	RESET_EXTERNAL                           = byte(0x01)
+	RESET_POWER_ON                           = byte(0x02)
+	RESET_WATCHDOG                           = byte(0x03)
+	RESET_SOFTWARE                           = byte(0x0B)
+	ERROR_EXCEEDED_MAXIMUM_ACK_TIMEOUT_COUNT = byte(0x51)
+)
+
+// Simulator 模拟NCP，host把它当作 ash.Transport 使用
+type Simulator struct {
+	mutex sync.Mutex
+
+	out    []byte        // 发给host的字节
+	notify chan struct{} // out有新数据
+	closed bool
+
+	// NCP一侧的帧解析状态
+	rxBuffer     []byte
+	rxEsc        bool
+	rxSubstitute bool
+
+	// NCP一侧的ASH状态
+	connected bool
+	frmNumRx  byte      // 期望收到的host frmNum，也就是发出去的ackNum
+	frmNumTx  byte      // 下一个发给host的frmNum
+	ackNumTx  byte      // host确认过的frmNum，也就是host发来的ackNum
+	txHistory [8][]byte // 发出去的DATA帧内容，收到NAK时重发
+	txQueue   [][]byte  // 等待发送窗口的EZSP帧
+
+	// 软件流控，xoff期间收到的host字节数记在rxDuringXoff
+	xoff         bool
+	rxDuringXoff int
+
+	// 模拟线路出错，丢弃或者NAK接下来收到的host DATA帧
+	dropData int
+	nakData  int
+
+	ezsp ncpState
+
+	// ReadTimeout Read 没有数据时等待的时间，超时返回io.EOF
+	ReadTimeout time.Duration
+	// TxWindow 未被host确认的DATA帧的最大数量
+	TxWindow byte
+}
+
+var TraceOn bool
+
+func simTrace(format string, v ...interface{}) {
+	if TraceOn {
+		common.Log.Debugf(format, v...)
+	}
+}
+
+// New 创建一个模拟NCP，初始状态和刚上电的EM357模块一致：没有网络，等待RST
+func New() *Simulator {
+	s := &Simulator{
+		notify:      make(chan struct{}, 1),
+		ReadTimeout: time.Millisecond * 20,
+		TxWindow:    1,
+	}
+	s.ezsp.init()
+	return s
+}
+
+// Read host从NCP读取字节，没有数据时等待 ReadTimeout 后返回io.EOF，和串口的行为一致
+func (s *Simulator) Read(p []byte) (int, error) {
+	timeout := time.After(s.ReadTimeout)
+	for {
+		s.mutex.Lock()
+		if s.closed {
+			s.mutex.Unlock()
+			return 0, io.ErrClosedPipe
+		}
+		if len(s.out) != 0 {
+			n := copy(p, s.out)
+			s.out = s.out[n:]
+			s.mutex.Unlock()
+			return n, nil
+		}
+		s.mutex.Unlock()
+
+		select {
+		case <-s.notify:
+		case <-timeout:
+			return 0, io.EOF
+		}
+	}
+}
+
+// Write host写给NCP的字节，在调用者的线程里同步处理，回复放到发送缓存里
+func (s *Simulator) Write(p []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if s.xoff {
+		s.rxDuringXoff += len(p)
+	}
+	for _, b := range p {
+		s.rxByte(b)
+	}
+	return len(p), nil
+}
+
+func (s *Simulator) Close() error {
+	s.mutex.Lock()
+	s.closed = true
+	s.mutex.Unlock()
+	return nil
+}
+
+func dataFrmPseudoRandom(data []byte) {
+	rand := byte(0x42)
+	for i := range data {
+		data[i] ^= rand
+		if (rand & 1) == 0 {
+			rand = byte((rand >> 1) & 0x7F)
+		} else {
+			rand = byte(((rand >> 1) & 0x7F) ^ 0xB8)
+		}
+	}
+}
+
+func (s *Simulator) rxByte(b byte) {
+	if s.rxSubstitute {
+		if b == ashFlag {
+			s.rxSubstitute = false
+			s.rxBuffer = s.rxBuffer[:0]
+		}
+		return
+	}
+	if s.rxEsc {
+		s.rxBuffer = append(s.rxBuffer, b^ashFlip)
+		s.rxEsc = false
+		return
+	}
+	switch b {
+	case ashEsc:
+		s.rxEsc = true
+	case ashXon, ashXoff:
+	case ashSub:
+		s.rxSubstitute = true
+		s.rxBuffer = s.rxBuffer[:0]
+	case ashCan:
+		s.rxBuffer = s.rxBuffer[:0]
+	case ashFlag:
+		frame := s.rxBuffer
+		s.rxBuffer = nil
+		if len(frame) <= 2 {
+			return
+		}
+		if crc16.CRC16CCITTFalse(frame) != 0 {
+			simTrace("NCPSIM rx crc error < 0x%x", frame)
+			if s.connected {
+				s.sendFrame([]byte{ashControlNak | s.frmNumRx})
+			}
+			return
+		}
+		s.rxFrame(frame[:len(frame)-2])
+	default:
+		s.rxBuffer = append(s.rxBuffer, b)
+	}
+}
+
+func (s *Simulator) rxFrame(frame []byte) {
+	control := frame[0]
+	switch {
+	case control == ashControlRst:
+		simTrace("NCPSIM rx RST")
+		s.connected = true
+		s.frmNumRx = 0
+		s.frmNumTx = 0
+		s.ackNumTx = 0
+		for i := range s.txHistory {
+			s.txHistory[i] = nil
+		}
+		s.txQueue = nil
+		s.ezsp.reset()
+		s.out = append(s.out, ashCan)
+		s.sendFrame([]byte{ashControlRstack, ashVersion, RESET_SOFTWARE})
+	case !s.connected:
+		simTrace("NCPSIM rx frame before RST < 0x%x", frame)
+	case control&0x80 == ashControlData:
+		frmNum := (control >> 4) & 7
+		reTx := control&ashControlRetx != 0
+		if s.dropData > 0 {
+			s.dropData--
+			simTrace("NCPSIM drop DATA frmNum(%d)", frmNum)
+			return
+		}
+		if s.nakData > 0 && frmNum == s.frmNumRx {
+			s.nakData--
+			simTrace("NCPSIM NAK DATA frmNum(%d)", frmNum)
+			s.sendFrame([]byte{ashControlNak | s.frmNumRx})
+			return
+		}
+		if frmNum != s.frmNumRx {
+			if reTx {
+				s.sendFrame([]byte{ashControlAck | s.frmNumRx}) // 重发的报文已经处理过，再ACK一次
+			} else {
+				s.sendFrame([]byte{ashControlNak | s.frmNumRx})
+			}
+			return
+		}
+		s.frmNumRx = (s.frmNumRx + 1) & 7
+		s.ackNumProcess(control & 7)
+		data := append([]byte(nil), frame[1:]...)
+		dataFrmPseudoRandom(data)
+		simTrace("NCPSIM rx DATA < 0x%x", data)
+		s.sendFrame([]byte{ashControlAck | s.frmNumRx})
+		s.ezsp.command(s, data)
+	case control&0xE0 == ashControlAck:
+		s.ackNumProcess(control & 7)
+	case control&0xE0 == ashControlNak:
+		ackNum := control & 7
+		simTrace("NCPSIM rx NAK(%d)", ackNum)
+		s.ackNumProcess(ackNum)
+		for i := ackNum; i != s.frmNumTx; i = (i + 1) & 7 {
+			if s.txHistory[i] != nil {
+				s.sendDataFrame(i, s.txHistory[i], true)
+			}
+		}
+	default:
+		simTrace("NCPSIM rx unknown frame < 0x%x", frame)
+	}
+}
+
+// sendFrame 给帧加上CRC、转义和FLAG，放进发送缓存
+func (s *Simulator) sendFrame(frame []byte) {
+	crc := crc16.CRC16CCITTFalse(frame)
+	frmWithCrc := append(append([]byte(nil), frame...), byte(crc>>8), byte(crc))
+	for _, b := range frmWithCrc {
+		if b == ashXon || b == ashXoff || b == ashSub || b == ashCan || b == ashEsc || b == ashFlag {
+			s.out = append(s.out, ashEsc, b^ashFlip)
+		} else {
+			s.out = append(s.out, b)
+		}
+	}
+	s.out = append(s.out, ashFlag)
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Simulator) sendDataFrame(frmNum byte, data []byte, reTx bool) {
+	control := ashControlData | frmNum<<4 | s.frmNumRx
+	if reTx {
+		control |= ashControlRetx
+	}
+	frame := append([]byte{control}, data...)
+	dataFrmPseudoRandom(frame[1:])
+	s.sendFrame(frame)
+}
+
+// ackNumProcess host确认了ackNum之前的帧，窗口空出来后继续发送排队的帧
+func (s *Simulator) ackNumProcess(ackNum byte) {
+	if (s.frmNumTx-ackNum)&7 > (s.frmNumTx-s.ackNumTx)&7 {
+		return // 不在窗口内的ackNum
+	}
+	for s.ackNumTx != ackNum {
+		s.txHistory[s.ackNumTx] = nil
+		s.ackNumTx = (s.ackNumTx + 1) & 7
+	}
+	s.txProcess()
+}
+
+func (s *Simulator) txProcess() {
+	for len(s.txQueue) != 0 && (s.frmNumTx-s.ackNumTx)&7 < s.TxWindow {
+		data := s.txQueue[0]
+		s.txQueue = s.txQueue[1:]
+		simTrace("NCPSIM tx DATA > 0x%x", data)
+		s.txHistory[s.frmNumTx] = data
+		s.sendDataFrame(s.frmNumTx, data, false)
+		s.frmNumTx = (s.frmNumTx + 1) & 7
+	}
+}
+
+// sendData 发送一个EZSP帧给host，超出发送窗口时排队
+func (s *Simulator) sendData(data []byte) {
+	s.txQueue = append(s.txQueue, data)
+	s.txProcess()
+}
+
+// SendError 模拟NCP出错，发送ERROR帧后NCP等待host重新RST
+func (s *Simulator) SendError(code byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.connected = false
+	s.sendFrame([]byte{ashControlError, ashVersion, code})
+}
+
+// PowerOnReset 模拟NCP自己复位，没有收到RST就发出RSTACK
+func (s *Simulator) PowerOnReset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.connected = false
+	s.ezsp.reset()
+	s.out = append(s.out, ashCan)
+	s.sendFrame([]byte{ashControlRstack, ashVersion, RESET_POWER_ON})
+}
+
+// DropData 丢弃接下来收到的n个host DATA帧，模拟帧丢失，host要超时重发
+func (s *Simulator) DropData(n int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.dropData = n
+}
+
+// NakData 对接下来收到的n个host DATA帧回复NAK，模拟帧出错，host要立即重发
+func (s *Simulator) NakData(n int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.nakData = n
+}
+
+// FlowControl 模拟的NCP使用XON/XOFF软件流控
+func (s *Simulator) FlowControl() ash.FlowControl {
+	return ash.FLOW_CONTROL_SOFTWARE
+}
+
+// Xoff 模拟NCP接收缓存满，发出XOFF让host暂停发送
+func (s *Simulator) Xoff() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.xoff = true
+	s.out = append(s.out, ashXoff)
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Xon 发出XON让host恢复发送
+func (s *Simulator) Xon() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.xoff = false
+	s.out = append(s.out, ashXon)
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// SetVersion 设置NCP的EZSP协议版本和协议栈版本，协议版本8以上模拟EmberZNet 6.7之后的EFR32模块
+func (s *Simulator) SetVersion(protocolVersion byte, stackVersion uint16) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.ezsp.ProtocolVersion = protocolVersion
+	s.ezsp.StackVersion = stackVersion
+}
+
+// Connected host是否已经通过RST建立了ASH连接
+func (s *Simulator) Connected() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.connected
+}
+
+func (s *Simulator) String() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return fmt.Sprintf("ncpsim connected=%v frmNumRx=%d frmNumTx=%d xoff=%v rxDuringXoff=%d", s.connected, s.frmNumRx, s.frmNumTx, s.xoff, s.rxDuringXoff)
+}
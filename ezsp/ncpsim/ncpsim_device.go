@@ -0,0 +1,101 @@
+package ncpsim
+
+import (
+	"github.com/conthing/ezsp/ezsp"
+)
+
+// Device 脚本化的ZigBee设备，通过 Join 加入模拟的网络
+type Device struct {
+	NodeID      uint16
+	Eui64       uint64
+	Unreachable bool // 为true时发给它的单播messageSent回复DELIVERY_FAILED
+	EndDevice   bool // 为true时是协调器的子节点，否则是协调器的邻居路由器
+	Silent      bool // 为true时发给它的单播不回复messageSent，host等到确认超时
+
+	// OnMessage 设备收到host的单播，返回非nil时作为回复消息上报给host，
+	// 回复的profile/cluster和收到的一致（ZDO的回复cluster加上0x8000），源和目的endpoint对调
+	OnMessage func(apsFrame *ezsp.EmberApsFrame, message []byte) []byte
+
+	fragmentSeq byte // 设备正在发送的分片消息的APS sequence
+}
+
+// Join 设备入网，NCP发出trustCenterJoinHandler，并把设备加入地址表
+func (s *Simulator) Join(dev *Device) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.ezsp.devices[dev.NodeID] = dev
+	for i, d := range s.ezsp.addressTable {
+		if d == nil || d.Eui64 == dev.Eui64 {
+			s.ezsp.addressTable[i] = dev
+			break
+		}
+	}
+	data := append(u16(dev.NodeID), u64(dev.Eui64)...)
+	data = append(data, ezsp.EMBER_STANDARD_SECURITY_UNSECURED_JOIN, ezsp.EMBER_USE_PRECONFIGURED_KEY)
+	s.callback(ezsp.EZSP_TRUST_CENTER_JOIN_HANDLER, append(data, u16(0)...))
+}
+
+// Announce 设备发出ZDO Device_annce
+func (s *Simulator) Announce(dev *Device) {
+	payload := append([]byte{0}, u16(dev.NodeID)...)
+	payload = append(payload, u64(dev.Eui64)...)
+	payload = append(payload, 0x8E) // capability: mains powered, rx on when idle, allocate address
+	s.Incoming(dev, &ezsp.EmberApsFrame{ClusterId: 0x0013}, ezsp.EMBER_INCOMING_BROADCAST, payload)
+}
+
+// Incoming 设备主动发给host的消息，NCP先上报incomingSenderEui64再上报incomingMessage
+func (s *Simulator) Incoming(dev *Device, apsFrame *ezsp.EmberApsFrame, incomingMessageType byte, message []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.incoming(dev, apsFrame, incomingMessageType, message)
+}
+
+// Leave 设备离网，NCP发出DEVICE_LEFT的trustCenterJoinHandler
+func (s *Simulator) Leave(dev *Device) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.ezsp.devices, dev.NodeID)
+	for i, d := range s.ezsp.addressTable {
+		if d == dev {
+			s.ezsp.addressTable[i] = nil
+		}
+	}
+	data := append(u16(dev.NodeID), u64(dev.Eui64)...)
+	data = append(data, ezsp.EMBER_DEVICE_LEFT, ezsp.EMBER_NO_ACTION)
+	s.callback(ezsp.EZSP_TRUST_CENTER_JOIN_HANDLER, append(data, u16(0)...))
+}
+
+func (s *Simulator) incoming(dev *Device, apsFrame *ezsp.EmberApsFrame, incomingMessageType byte, message []byte) {
+	// 同一条消息的后续分片使用这个设备第0个分片的APS sequence
+	if apsFrame.Options&ezsp.EMBER_APS_OPTION_FRAGMENT == 0 || byte(apsFrame.GroupId) == 0 {
+		s.ezsp.apsSeq++
+		dev.fragmentSeq = s.ezsp.apsSeq
+		apsFrame.Sequence = s.ezsp.apsSeq
+	} else {
+		apsFrame.Sequence = dev.fragmentSeq
+	}
+	s.callback(ezsp.EZSP_INCOMING_SENDER_EUI64_HANDLER, u64(dev.Eui64))
+
+	data := append([]byte{incomingMessageType}, apsFramePack(apsFrame)...)
+	data = append(data, 0xff, 0xd8) // lastHopLqi, lastHopRssi -40dBm
+	data = append(data, u16(dev.NodeID)...)
+	data = append(data, 0xff, 0xff, byte(len(message))) // bindingIndex, addressIndex
+	s.callback(ezsp.EZSP_INCOMING_MESSAGE_HANDLER, append(data, message...))
+}
+
+// deliver host发给设备的单播送达，在锁内调用
+func (s *Simulator) deliver(dev *Device, apsFrame *ezsp.EmberApsFrame, message []byte) {
+	if dev.OnMessage == nil {
+		return
+	}
+	reply := dev.OnMessage(apsFrame, message)
+	if reply == nil {
+		return
+	}
+	replyFrame := *apsFrame
+	replyFrame.SourceEndpoint, replyFrame.DestinationEndpoint = apsFrame.DestinationEndpoint, apsFrame.SourceEndpoint
+	if apsFrame.ProfileId == 0 { // ZDO
+		replyFrame.ClusterId |= 0x8000
+	}
+	s.incoming(dev, &replyFrame, ezsp.EMBER_INCOMING_UNICAST, reply)
+}
@@ -0,0 +1,549 @@
+package ncpsim
+
+import (
+	"encoding/binary"
+
+	"github.com/conthing/ezsp/ezsp"
+)
+
+const (
+	frameControlResponse      = byte(0x80)
+	frameControlSyncCallback  = byte(0x88)
+	frameControlAsyncCallback = byte(0x90)
+)
+
+// ncpState NCP的EZSP层状态
+type ncpState struct {
+	ProtocolVersion byte
+	StackType       byte
+	StackVersion    uint16
+	Eui64           uint64
+
+	lastSeq  byte // 最近一条命令的sequence，callback使用
+	extended bool // 协商了版本8以上，使用扩展帧头
+
+	configurations map[byte]uint16
+	values         map[byte][]byte
+	policies       map[byte]byte
+	tokens         map[byte][]byte
+	mfgTokens      map[byte][]byte
+
+	networkUp  bool
+	nodeType   byte
+	parameters ezsp.EmberNetworkParameters
+	permitJoin byte
+	apsSeq     byte
+
+	devices      map[uint16]*Device
+	addressTable [64]*Device
+
+	shortReplies map[uint16]uint16 // 测试用，这些命令只回复不带参数的帧头，帧ID换成map的值，模拟回复太短的NCP
+}
+
+func (n *ncpState) init() {
+	n.ProtocolVersion = ezsp.EZSP_PROTOCOL_VERSION
+	n.StackType = ezsp.EZSP_STACK_TYPE_MESH
+	n.StackVersion = 0x5A00
+	n.Eui64 = 0x000d6f0000000001
+	n.configurations = make(map[byte]uint16)
+	n.values = map[byte][]byte{
+		ezsp.EZSP_VALUE_VERSION_INFO: {0x5c, 0x00, 5, 10, 1, 0, 0xaa}, // 5.10.1.0 build 92
+	}
+	n.policies = make(map[byte]byte)
+	n.tokens = make(map[byte][]byte)
+	n.mfgTokens = map[byte][]byte{
+		ezsp.EZSP_MFG_PHY_CONFIG: {0xff, 0xff},
+	}
+	n.devices = make(map[uint16]*Device)
+	n.reset()
+}
+
+// reset NCP复位后网络处于down状态，配置恢复默认，token和网络参数保留
+func (n *ncpState) reset() {
+	n.lastSeq = 0xff
+	n.extended = false
+	n.networkUp = false
+	n.permitJoin = 0
+	for k := range n.configurations {
+		delete(n.configurations, k)
+	}
+	n.configurations[ezsp.EZSP_CONFIG_SECURITY_LEVEL] = 5
+	n.configurations[ezsp.EZSP_CONFIG_ROUTE_TABLE_SIZE] = ezsp.DEFAULT_EZSP_CONFIG_ROUTE_TABLE_SIZE
+	n.configurations[ezsp.EZSP_CONFIG_MAX_END_DEVICE_CHILDREN] = ezsp.DEFAULT_EZSP_CONFIG_MAX_END_DEVICE_CHILDREN
+}
+
+// tableDevices 地址表里的路由器和子节点，按地址表的顺序
+func (n *ncpState) tableDevices(endDevice bool) []*Device {
+	var devices []*Device
+	for _, d := range n.addressTable {
+		if d != nil && d.EndDevice == endDevice {
+			devices = append(devices, d)
+		}
+	}
+	return devices
+}
+
+func u16(v uint16) []byte {
+	return []byte{byte(v), byte(v >> 8)}
+}
+
+func u64(v uint64) []byte {
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint64(data, v)
+	return data
+}
+
+func apsFrameParse(data []byte) (apsFrame ezsp.EmberApsFrame) {
+	apsFrame.ProfileId = binary.LittleEndian.Uint16(data)
+	apsFrame.ClusterId = binary.LittleEndian.Uint16(data[2:])
+	apsFrame.SourceEndpoint = data[4]
+	apsFrame.DestinationEndpoint = data[5]
+	apsFrame.Options = binary.LittleEndian.Uint16(data[6:])
+	apsFrame.GroupId = binary.LittleEndian.Uint16(data[8:])
+	apsFrame.Sequence = data[10]
+	return
+}
+
+func apsFramePack(apsFrame *ezsp.EmberApsFrame) []byte {
+	data := u16(apsFrame.ProfileId)
+	data = append(data, u16(apsFrame.ClusterId)...)
+	data = append(data, apsFrame.SourceEndpoint, apsFrame.DestinationEndpoint)
+	data = append(data, u16(apsFrame.Options)...)
+	data = append(data, u16(apsFrame.GroupId)...)
+	return append(data, apsFrame.Sequence)
+}
+
+func networkParametersPack(p *ezsp.EmberNetworkParameters) []byte {
+	data := u64(p.ExtendedPanId)
+	data = append(data, u16(p.PanId)...)
+	data = append(data, byte(p.RadioTxPower), p.RadioChannel, p.JoinMethod)
+	data = append(data, u16(p.NwkManagerId)...)
+	data = append(data, p.NwkUpdateId)
+	return append(data, byte(p.Channels), byte(p.Channels>>8), byte(p.Channels>>16), byte(p.Channels>>24))
+}
+
+// header 生成EZSP帧头
+func header(seq byte, frameControl byte, frameID uint16, extended bool) []byte {
+	if extended {
+		return []byte{seq, frameControl, ezsp.EZSP_EXTENDED_FRAME_FORMAT_VERSION, byte(frameID), byte(frameID >> 8)}
+	}
+	return []byte{seq, frameControl, byte(frameID)}
+}
+
+// callback 在锁内调用，异步callback发给host
+func (s *Simulator) callback(frameID uint16, parameters []byte) {
+	if !s.connected {
+		return
+	}
+	data := header(s.ezsp.lastSeq, frameControlAsyncCallback, frameID, s.ezsp.extended)
+	s.sendData(append(data, parameters...))
+}
+
+func (s *Simulator) stackStatus(emberStatus byte) {
+	s.callback(ezsp.EZSP_STACK_STATUS_HANDLER, []byte{emberStatus})
+}
+
+// command 处理一条EZSP命令，先发出回复，再发出命令引起的callback
+func (n *ncpState) command(s *Simulator, data []byte) {
+	var after []func()
+	response := n.execute(s, data, &after)
+	if response != nil {
+		s.sendData(response)
+	}
+	for _, f := range after {
+		f()
+	}
+}
+
+// execute 执行EZSP命令，返回回复的EZSP帧，回复之后要发出的callback放到after里
+func (n *ncpState) execute(s *Simulator, data []byte, after *[]func()) []byte {
+	if len(data) < 3 {
+		return nil
+	}
+	seq := data[0]
+	frameID := uint16(data[2])
+	p := data[3:]
+	// version命令总是传统帧头，其它命令在协商了扩展帧头后frame control高字节是帧格式版本
+	extended := n.extended && len(data) >= 5 && data[2]&0x3 == ezsp.EZSP_EXTENDED_FRAME_FORMAT_VERSION
+	if extended {
+		frameID = binary.LittleEndian.Uint16(data[3:])
+		p = data[5:]
+	}
+	n.lastSeq = seq
+	if replyID, ok := n.shortReplies[frameID]; ok {
+		return header(seq, frameControlResponse, replyID, extended)
+	}
+
+	resp := func(parameters ...byte) []byte {
+		return append(header(seq, frameControlResponse, frameID, extended), parameters...)
+	}
+	invalid := func(ezspStatus byte) []byte {
+		return append(header(seq, frameControlResponse, ezsp.EZSP_INVALID_COMMAND, extended), ezspStatus)
+	}
+	need := func(l int) bool {
+		return len(p) >= l
+	}
+
+	switch frameID {
+	case ezsp.EZSP_VERSION:
+		if !need(1) {
+			return invalid(ezsp.EZSP_ERROR_WRONG_DIRECTION)
+		}
+		r := resp(append([]byte{n.ProtocolVersion, n.StackType}, u16(n.StackVersion)...)...)
+		if p[0] == n.ProtocolVersion {
+			n.extended = n.ProtocolVersion >= ezsp.EZSP_EXTENDED_FRAME_PROTOCOL_VERSION
+		}
+		return r
+
+	case ezsp.EZSP_GET_VALUE:
+		if !need(1) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		value, ok := n.values[p[0]]
+		if !ok {
+			return resp(ezsp.EZSP_ERROR_INVALID_ID, 0)
+		}
+		return resp(append([]byte{ezsp.EZSP_SUCCESS, byte(len(value))}, value...)...)
+
+	case ezsp.EZSP_SET_VALUE:
+		if !need(2) || !need(2+int(p[1])) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		n.values[p[0]] = append([]byte(nil), p[2:2+int(p[1])]...)
+		return resp(ezsp.EZSP_SUCCESS)
+
+	case ezsp.EZSP_GET_CONFIGURATION_VALUE:
+		if !need(1) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		return resp(append([]byte{ezsp.EZSP_SUCCESS}, u16(n.configurations[p[0]])...)...)
+
+	case ezsp.EZSP_SET_CONFIGURATION_VALUE:
+		if !need(3) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		if n.networkUp {
+			return resp(ezsp.EZSP_ERROR_INVALID_CALL)
+		}
+		n.configurations[p[0]] = binary.LittleEndian.Uint16(p[1:])
+		return resp(ezsp.EZSP_SUCCESS)
+
+	case ezsp.EZSP_SET_POLICY:
+		if !need(2) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		n.policies[p[0]] = p[1]
+		return resp(ezsp.EZSP_SUCCESS)
+
+	case ezsp.EZSP_GET_POLICY:
+		if !need(1) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		return resp(ezsp.EZSP_SUCCESS, n.policies[p[0]])
+
+	case ezsp.EZSP_GET_EUI64:
+		return resp(u64(n.Eui64)...)
+
+	case ezsp.EZSP_NOP:
+		return resp()
+
+	case ezsp.EZSP_NETWORK_STATE:
+		if n.networkUp {
+			return resp(ezsp.EMBER_JOINED_NETWORK)
+		}
+		return resp(ezsp.EMBER_NO_NETWORK)
+
+	case ezsp.EZSP_GET_NODE_ID:
+		if n.networkUp {
+			return resp(u16(0x0000)...) // 只模拟协调器
+		}
+		return resp(u16(ezsp.EMBER_NULL_NODE_ID)...)
+
+	case ezsp.EZSP_SET_GPIO_CURRENT_CONFIGURATION, ezsp.EZSP_ADD_ENDPOINT:
+		return resp(ezsp.EZSP_SUCCESS)
+
+	case ezsp.EZSP_SET_RADIO_POWER, ezsp.EZSP_SET_INITIAL_SECURITY_STATE, ezsp.EZSP_SET_SOURCE_ROUTE, ezsp.EZSP_REMOVE_DEVICE:
+		return resp(ezsp.EMBER_SUCCESS)
+
+	case ezsp.EZSP_GET_MFG_TOKEN:
+		if !need(1) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		token := n.mfgTokens[p[0]]
+		return resp(append([]byte{byte(len(token))}, token...)...)
+
+	case ezsp.EZSP_SET_MFG_TOKEN:
+		if !need(2) || !need(2+int(p[1])) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		n.mfgTokens[p[0]] = append([]byte(nil), p[2:2+int(p[1])]...)
+		return resp(ezsp.EMBER_SUCCESS)
+
+	case ezsp.EZSP_GET_TOKEN:
+		if !need(1) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		token, ok := n.tokens[p[0]]
+		if !ok {
+			token = make([]byte, 8)
+		}
+		return resp(append([]byte{ezsp.EMBER_SUCCESS}, token...)...)
+
+	case ezsp.EZSP_SET_TOKEN:
+		if !need(9) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		n.tokens[p[0]] = append([]byte(nil), p[1:9]...)
+		return resp(ezsp.EMBER_SUCCESS)
+
+	case ezsp.EZSP_GET_NETWORK_PARAMETERS:
+		status := ezsp.EMBER_SUCCESS
+		if !n.networkUp {
+			status = ezsp.EMBER_NOT_JOINED
+		}
+		return resp(append([]byte{status, n.nodeType}, networkParametersPack(&n.parameters)...)...)
+
+	case ezsp.EZSP_NETWORK_INIT:
+		if n.parameters.PanId == 0 {
+			return resp(ezsp.EMBER_NOT_JOINED)
+		}
+		n.networkUp = true
+		*after = append(*after, func() { s.stackStatus(ezsp.EMBER_NETWORK_UP) })
+		return resp(ezsp.EMBER_SUCCESS)
+
+	case ezsp.EZSP_FORM_NETWORK:
+		if !need(20) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		if n.networkUp {
+			return resp(ezsp.EMBER_INVALID_CALL)
+		}
+		n.parameters.ExtendedPanId = binary.LittleEndian.Uint64(p)
+		if n.parameters.ExtendedPanId == 0 {
+			n.parameters.ExtendedPanId = n.Eui64
+		}
+		n.parameters.PanId = binary.LittleEndian.Uint16(p[8:])
+		n.parameters.RadioTxPower = int8(p[10])
+		n.parameters.RadioChannel = p[11]
+		n.parameters.JoinMethod = p[12]
+		n.parameters.NwkManagerId = binary.LittleEndian.Uint16(p[13:])
+		n.parameters.NwkUpdateId = p[15]
+		n.parameters.Channels = binary.LittleEndian.Uint32(p[16:])
+		n.nodeType = ezsp.EMBER_COORDINATOR
+		n.networkUp = true
+		*after = append(*after, func() { s.stackStatus(ezsp.EMBER_NETWORK_UP) })
+		return resp(ezsp.EMBER_SUCCESS)
+
+	case ezsp.EZSP_LEAVE_NETWORK:
+		if !n.networkUp {
+			return resp(ezsp.EMBER_INVALID_CALL)
+		}
+		n.networkUp = false
+		n.parameters = ezsp.EmberNetworkParameters{}
+		*after = append(*after, func() { s.stackStatus(ezsp.EMBER_NETWORK_DOWN) })
+		return resp(ezsp.EMBER_SUCCESS)
+
+	case ezsp.EZSP_START_SCAN:
+		if !need(6) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		scanType := p[0]
+		channelMask := binary.LittleEndian.Uint32(p[1:])
+		*after = append(*after, func() {
+			for ch := byte(ezsp.EMBER_MIN_802_15_4_CHANNEL_NUMBER); ch <= ezsp.EMBER_MAX_802_15_4_CHANNEL_NUMBER; ch++ {
+				if channelMask&(1<<ch) != 0 && scanType == ezsp.EZSP_ENERGY_SCAN {
+					s.callback(ezsp.EZSP_ENERGY_SCAN_RESULT_HANDLER, []byte{ch, byte(int8(-90 + int(ch)))})
+				}
+			}
+			s.callback(ezsp.EZSP_SCAN_COMPLETE_HANDLER, []byte{0, ezsp.EMBER_SUCCESS})
+		})
+		return resp(ezsp.EMBER_SUCCESS)
+
+	case ezsp.EZSP_SET_RADIO_CHANNEL:
+		if !need(1) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		n.parameters.RadioChannel = p[0]
+		return resp(ezsp.EMBER_SUCCESS)
+
+	case ezsp.EZSP_PERMIT_JOINING:
+		if !need(1) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		n.permitJoin = p[0]
+		return resp(ezsp.EMBER_SUCCESS)
+
+	case ezsp.EZSP_SEND_MANY_TO_ONE_ROUTE_REQUEST:
+		if !n.networkUp {
+			return resp(ezsp.EMBER_NETWORK_DOWN)
+		}
+		return resp(ezsp.EMBER_SUCCESS)
+
+	case ezsp.EZSP_LOOKUP_EUI64_BY_NODE_ID:
+		if !need(2) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		dev, ok := n.devices[binary.LittleEndian.Uint16(p)]
+		if !ok {
+			return resp(append([]byte{ezsp.EMBER_ERR_FATAL}, u64(0)...)...)
+		}
+		return resp(append([]byte{ezsp.EMBER_SUCCESS}, u64(dev.Eui64)...)...)
+
+	case ezsp.EZSP_LOOKUP_NODE_ID_BY_EUI64:
+		if !need(8) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		eui64 := binary.LittleEndian.Uint64(p)
+		for _, dev := range n.devices {
+			if dev.Eui64 == eui64 {
+				return resp(u16(dev.NodeID)...)
+			}
+		}
+		return resp(u16(ezsp.EMBER_NULL_NODE_ID)...)
+
+	case ezsp.EZSP_ADDRESS_TABLE_ENTRY_IS_ACTIVE, ezsp.EZSP_GET_ADDRESS_TABLE_REMOTE_EUI64, ezsp.EZSP_GET_ADDRESS_TABLE_REMOTE_NODE_ID:
+		if !need(1) || int(p[0]) >= len(n.addressTable) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		dev := n.addressTable[p[0]]
+		switch frameID {
+		case ezsp.EZSP_ADDRESS_TABLE_ENTRY_IS_ACTIVE:
+			if dev != nil {
+				return resp(1)
+			}
+			return resp(0)
+		case ezsp.EZSP_GET_ADDRESS_TABLE_REMOTE_EUI64:
+			if dev != nil {
+				return resp(u64(dev.Eui64)...)
+			}
+			return resp(u64(0)...)
+		default:
+			if dev != nil {
+				return resp(u16(dev.NodeID)...)
+			}
+			return resp(u16(ezsp.EMBER_NULL_NODE_ID)...)
+		}
+
+	case ezsp.EZSP_NEIGHBOR_COUNT:
+		return resp(byte(len(n.tableDevices(false))))
+
+	case ezsp.EZSP_GET_NEIGHBOR:
+		if !need(1) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		routers := n.tableDevices(false)
+		if int(p[0]) >= len(routers) {
+			return resp(append([]byte{ezsp.EMBER_ERR_FATAL}, make([]byte, 14)...)...)
+		}
+		dev := routers[p[0]]
+		r := append([]byte{ezsp.EMBER_SUCCESS}, u16(dev.NodeID)...)
+		r = append(r, 255, 1, 1, 3) // averageLqi, inCost, outCost, age
+		return resp(append(r, u64(dev.Eui64)...)...)
+
+	case ezsp.EZSP_GET_ROUTE_TABLE_ENTRY:
+		if !need(1) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		if int(p[0]) >= int(n.configurations[ezsp.EZSP_CONFIG_ROUTE_TABLE_SIZE]) {
+			return resp(append([]byte{ezsp.EMBER_ERR_FATAL}, make([]byte, 8)...)...)
+		}
+		// 邻居路由器都是一跳直达
+		routers := n.tableDevices(false)
+		if int(p[0]) >= len(routers) {
+			r := append([]byte{ezsp.EMBER_SUCCESS}, u16(ezsp.EMBER_NULL_NODE_ID)...)
+			r = append(r, u16(ezsp.EMBER_NULL_NODE_ID)...)
+			return resp(append(r, ezsp.EMBER_ROUTE_UNUSED, 0, 0, 0)...)
+		}
+		dev := routers[p[0]]
+		r := append([]byte{ezsp.EMBER_SUCCESS}, u16(dev.NodeID)...)
+		r = append(r, u16(dev.NodeID)...)
+		return resp(append(r, ezsp.EMBER_ROUTE_ACTIVE, 1, 0, 0)...)
+
+	case ezsp.EZSP_GET_PARENT_CHILD_PARAMETERS:
+		r := []byte{byte(len(n.tableDevices(true)))}
+		r = append(r, u64(0)...)
+		return resp(append(r, u16(ezsp.EMBER_NULL_NODE_ID)...)...)
+
+	case ezsp.EZSP_GET_CHILD_DATA:
+		if !need(1) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		children := n.tableDevices(true)
+		var dev Device
+		status := ezsp.EMBER_NOT_JOINED
+		if int(p[0]) < len(children) {
+			dev, status = *children[p[0]], ezsp.EMBER_SUCCESS
+		}
+		// 协议版本8开始回复EmberChildData结构
+		if n.extended {
+			r := append([]byte{status}, u64(dev.Eui64)...)
+			r = append(r, ezsp.EMBER_SLEEPY_END_DEVICE)
+			r = append(r, u16(dev.NodeID)...)
+			return resp(append(r, 0, 0, 0)...)
+		}
+		r := append([]byte{status}, u16(dev.NodeID)...)
+		r = append(r, u64(dev.Eui64)...)
+		return resp(append(r, ezsp.EMBER_SLEEPY_END_DEVICE)...)
+
+	case ezsp.EZSP_GET_SOURCE_ROUTE_TABLE_TOTAL_SIZE:
+		return resp(byte(n.configurations[ezsp.EZSP_CONFIG_SOURCE_ROUTE_TABLE_SIZE]))
+
+	case ezsp.EZSP_GET_SOURCE_ROUTE_TABLE_FILLED_SIZE:
+		return resp(0) // 模拟的网络没有多跳的设备
+
+	case ezsp.EZSP_SEND_UNICAST:
+		if !need(16) || !need(16+int(p[15])) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		if !n.networkUp {
+			return resp(ezsp.EMBER_NETWORK_DOWN, 0)
+		}
+		outgoingMessageType := p[0]
+		destination := binary.LittleEndian.Uint16(p[1:])
+		apsFrame := apsFrameParse(p[3:])
+		messageTag := p[14]
+		message := append([]byte(nil), p[16:16+int(p[15])]...)
+		// 后续分片沿用host带下来的第0个分片的APS sequence
+		if apsFrame.Options&ezsp.EMBER_APS_OPTION_FRAGMENT == 0 || byte(apsFrame.GroupId) == 0 {
+			n.apsSeq++
+			apsFrame.Sequence = n.apsSeq
+		}
+		dev := n.devices[destination]
+		if dev != nil && dev.Silent {
+			return resp(ezsp.EMBER_SUCCESS, apsFrame.Sequence)
+		}
+		*after = append(*after, func() {
+			status := ezsp.EMBER_DELIVERY_FAILED
+			if dev != nil && !dev.Unreachable {
+				status = ezsp.EMBER_SUCCESS
+			}
+			cb := append([]byte{outgoingMessageType}, u16(destination)...)
+			cb = append(cb, apsFramePack(&apsFrame)...)
+			cb = append(cb, messageTag, status, byte(len(message)))
+			s.callback(ezsp.EZSP_MESSAGE_SENT_HANDLER, append(cb, message...))
+			if status == ezsp.EMBER_SUCCESS {
+				s.deliver(dev, &apsFrame, message)
+			}
+		})
+		return resp(ezsp.EMBER_SUCCESS, apsFrame.Sequence)
+
+	case ezsp.EZSP_SEND_BROADCAST:
+		if !need(16) || !need(16+int(p[15])) {
+			return invalid(ezsp.EZSP_ERROR_INVALID_VALUE)
+		}
+		if !n.networkUp {
+			return resp(ezsp.EMBER_NETWORK_DOWN, 0)
+		}
+		n.apsSeq++
+		return resp(ezsp.EMBER_SUCCESS, n.apsSeq)
+
+	case ezsp.EZSP_SEND_REPLY:
+		return resp(ezsp.EMBER_SUCCESS)
+
+	case ezsp.EZSP_CALLBACK:
+		return header(seq, frameControlSyncCallback, ezsp.EZSP_NO_CALLBACKS, extended)
+
+	default:
+		return invalid(ezsp.EZSP_ERROR_INVALID_FRAME_ID)
+	}
+}
@@ -0,0 +1,158 @@
+package ncpsim
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/conthing/ezsp/ezsp"
+)
+
+func testMessage(n int) []byte {
+	message := make([]byte, n)
+	for i := range message {
+		message[i] = byte(i)
+	}
+	return message
+}
+
+// incomingFragment 设备发出第index个分片，第0个分片带分片总数
+func incomingFragment(sim *Simulator, dev *Device, message []byte, size int, index int) {
+	count := (len(message) + size - 1) / size
+	apsFrame := ezsp.EmberApsFrame{ProfileId: 0x0104, ClusterId: 0x0019, SourceEndpoint: 1, DestinationEndpoint: 1,
+		Options: ezsp.EMBER_APS_OPTION_FRAGMENT, GroupId: uint16(index)}
+	if index == 0 {
+		apsFrame.GroupId |= uint16(count) << 8
+	}
+	end := (index + 1) * size
+	if end > len(message) {
+		end = len(message)
+	}
+	sim.Incoming(dev, &apsFrame, ezsp.EMBER_INCOMING_UNICAST, message[index*size:end])
+}
+
+// dispatchAll 分发收到的callback，直到一段时间内没有新的callback，返回发布的incomingMessage事件
+func dispatchAll(client *ezsp.Client) []*ezsp.IncomingMessageEvent {
+	var messages []*ezsp.IncomingMessageEvent
+	subscription := client.Subscribe(ezsp.EventFilter{FrameIDs: []uint16{ezsp.EZSP_INCOMING_MESSAGE_HANDLER}}, func(event ezsp.Event) {
+		messages = append(messages, event.(*ezsp.IncomingMessageEvent))
+	})
+	defer subscription.Unsubscribe()
+	for {
+		select {
+		case cb := <-client.CallbackCh:
+			client.EzspCallbackDispatch(cb)
+		case <-time.After(time.Millisecond * 200):
+			return messages
+		}
+	}
+}
+
+func TestFragmentSend(t *testing.T) {
+	sim := New()
+	link, client := startClient(t, sim, nil)
+	defer link.Close()
+	formNetwork(t, client)
+	if err := client.FragmentSet(&ezsp.StFragmentSettings{FragmentSize: 50, WindowSize: 2}); err != nil {
+		t.Fatalf("FragmentSet: %v", err)
+	}
+
+	var fragments []ezsp.EmberApsFrame
+	var received []byte
+	dev := &Device{NodeID: 0x5001, Eui64: 0x000d6f0000005001,
+		OnMessage: func(apsFrame *ezsp.EmberApsFrame, message []byte) []byte {
+			fragments = append(fragments, *apsFrame)
+			received = append(received, message...)
+			return nil
+		}}
+	sim.Join(dev)
+
+	message := testMessage(180)
+	apsFrame := ezsp.EmberApsFrame{ProfileId: 0x0104, ClusterId: 0x0019, SourceEndpoint: 1, DestinationEndpoint: 1}
+	if err := client.EzspSendUnicastFragmented(ezsp.EMBER_OUTGOING_DIRECT, dev.NodeID, &apsFrame, message); err != nil {
+		t.Fatalf("EzspSendUnicastFragmented: %v", err)
+	}
+	if !bytes.Equal(received, message) || len(fragments) != 4 {
+		t.Fatalf("device received %d fragments 0x%x", len(fragments), received)
+	}
+	for i, f := range fragments {
+		want := uint16(i)
+		if i == 0 {
+			want |= 4 << 8
+		}
+		if f.GroupId != want || f.Options&ezsp.EMBER_APS_OPTION_FRAGMENT == 0 || f.Sequence != fragments[0].Sequence {
+			t.Fatalf("fragment %d apsFrame = %+v", i, f)
+		}
+	}
+
+	if err := client.EzspSendUnicastFragmented(ezsp.EMBER_OUTGOING_DIRECT, dev.NodeID, &apsFrame, testMessage(client.MaxMessageLength()+1)); err == nil {
+		t.Fatal("message longer than MaxMessageLength accepted")
+	}
+	if err := client.FragmentSet(&ezsp.StFragmentSettings{FragmentSize: ezsp.MAX_UNFRAGMENTED_LENGTH + 1}); err == nil {
+		t.Fatal("fragment size larger than MAX_UNFRAGMENTED_LENGTH accepted")
+	}
+}
+
+func TestFragmentReassembly(t *testing.T) {
+	sim := New()
+	link, client := startClient(t, sim, nil)
+	defer link.Close()
+	formNetwork(t, client)
+
+	dev1 := &Device{NodeID: 0x5002, Eui64: 0x000d6f0000005002}
+	dev2 := &Device{NodeID: 0x5003, Eui64: 0x000d6f0000005003}
+	sim.Join(dev1)
+	sim.Join(dev2)
+
+	// 两个设备的分片交错、乱序、重复到达，按发送者分别重组
+	message1 := testMessage(150)
+	message2 := testMessage(100)
+	incomingFragment(sim, dev1, message1, 64, 0)
+	incomingFragment(sim, dev2, message2, 64, 0)
+	incomingFragment(sim, dev1, message1, 64, 2)
+	incomingFragment(sim, dev1, message1, 64, 2)
+	incomingFragment(sim, dev2, message2, 64, 1)
+	incomingFragment(sim, dev1, message1, 64, 1)
+
+	messages := dispatchAll(client)
+	if len(messages) != 2 {
+		t.Fatalf("published %d messages, want 2", len(messages))
+	}
+	for i, want := range []struct {
+		sender  uint16
+		message []byte
+	}{{dev2.NodeID, message2}, {dev1.NodeID, message1}} {
+		m := messages[i]
+		if m.Sender != want.sender || !bytes.Equal(m.Message, want.message) {
+			t.Fatalf("message %d from 0x%04x = 0x%x", i, m.Sender, m.Message)
+		}
+		if m.ApsFrame.Options&ezsp.EMBER_APS_OPTION_FRAGMENT != 0 || m.ApsFrame.GroupId != 0 {
+			t.Fatalf("message %d apsFrame = %+v", i, m.ApsFrame)
+		}
+	}
+}
+
+func TestFragmentReassemblyTimeout(t *testing.T) {
+	sim := New()
+	link, client := startClient(t, sim, nil)
+	defer link.Close()
+	formNetwork(t, client)
+	if err := client.FragmentSet(&ezsp.StFragmentSettings{ReassemblyTimeout: time.Millisecond * 100}); err != nil {
+		t.Fatalf("FragmentSet: %v", err)
+	}
+
+	dev := &Device{NodeID: 0x5004, Eui64: 0x000d6f0000005004}
+	sim.Join(dev)
+	message := testMessage(100)
+	incomingFragment(sim, dev, message, 64, 0)
+	if messages := dispatchAll(client); len(messages) != 0 {
+		t.Fatalf("incomplete message published: %+v", messages[0])
+	}
+
+	// 超时后第0个分片被丢弃，后面的分片不能再组成完整的消息
+	time.Sleep(time.Millisecond * 100)
+	incomingFragment(sim, dev, message, 64, 1)
+	if messages := dispatchAll(client); len(messages) != 0 {
+		t.Fatalf("message published after reassembly timeout: %+v", messages[0])
+	}
+}
@@ -0,0 +1,757 @@
+package ncpsim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/conthing/ezsp/ash"
+	"github.com/conthing/ezsp/ezsp"
+)
+
+// startClient 在模拟NCP上建立ASH链路和EZSP客户端，完成RST和版本协商
+func startClient(t *testing.T, sim *Simulator, settings *ash.StAshSettings) (*ash.Link, *ezsp.Client) {
+	link := ash.NewLink()
+	if settings != nil {
+		if err := link.Set(settings); err != nil {
+			t.Fatalf("link.Set: %v", err)
+		}
+	}
+	client := ezsp.NewClient(link)
+	client.Timeout = time.Second * 5
+	link.StartTransceiver(sim, client.AshRecvImp, make(chan error, 1))
+	time.Sleep(sim.ReadTimeout * 2) // 收发线程启动时会清空transport，等它清空后再复位，否则RSTACK会被丢掉
+	if err := link.Reset(); err != nil {
+		link.Close()
+		t.Fatalf("Reset: %v", err)
+	}
+	client.EzspFrameInitVariables()
+	link.InitVariables()
+	if _, err := client.NegotiateVersion(ezsp.EZSP_PROTOCOL_VERSION); err != nil {
+		link.Close()
+		t.Fatalf("NegotiateVersion: %v", err)
+	}
+	return link, client
+}
+
+func formNetwork(t *testing.T, client *ezsp.Client) {
+	err := client.EzspFormNetwork(&ezsp.EmberNetworkParameters{PanId: 0x1234, RadioChannel: 15, Channels: 1 << 15})
+	if err != nil {
+		t.Fatalf("EzspFormNetwork: %v", err)
+	}
+	waitCallback(t, client, ezsp.EZSP_STACK_STATUS_HANDLER)
+}
+
+// waitCallback 等待frameID的callback，跳过其它callback
+func waitCallback(t *testing.T, client *ezsp.Client, frameID uint16) *ezsp.EzspFrame {
+	timeout := time.After(time.Second * 2)
+	for {
+		select {
+		case cb := <-client.CallbackCh:
+			if cb.FrameID == frameID {
+				return cb
+			}
+		case <-timeout:
+			t.Fatalf("callback 0x%x not received", frameID)
+			return nil
+		}
+	}
+}
+
+// waitEvent 分发收到的callback，直到frameID的事件发布到client的事件总线
+func waitEvent(t *testing.T, client *ezsp.Client, frameID uint16) ezsp.Event {
+	events := make(chan ezsp.Event, 1)
+	subscription := client.Subscribe(ezsp.EventFilter{FrameIDs: []uint16{frameID}}, func(event ezsp.Event) {
+		select {
+		case events <- event:
+		default:
+		}
+	})
+	defer subscription.Unsubscribe()
+	timeout := time.After(time.Second * 2)
+	for {
+		select {
+		case cb := <-client.CallbackCh:
+			client.EzspCallbackDispatch(cb)
+		case event := <-events:
+			return event
+		case <-timeout:
+			t.Fatalf("event 0x%x not published", frameID)
+			return nil
+		}
+	}
+}
+
+func TestBoot(t *testing.T) {
+	sim := New()
+	link, client := startClient(t, sim, nil)
+	defer link.Close()
+
+	if !sim.Connected() {
+		t.Fatal("simulator not connected after RST")
+	}
+	info := client.VersionInfo()
+	if info.ProtocolVersion != ezsp.EZSP_PROTOCOL_VERSION || info.StackType != ezsp.EZSP_STACK_TYPE_MESH {
+		t.Fatalf("VersionInfo = %+v", info)
+	}
+	eui64, err := client.EzspGetEUI64()
+	if err != nil {
+		t.Fatalf("EzspGetEUI64: %v", err)
+	}
+	if eui64 != sim.ezsp.Eui64 {
+		t.Fatalf("EzspGetEUI64 = %016x, want %016x", eui64, sim.ezsp.Eui64)
+	}
+	if err = client.EzspSetConfigurationValue(ezsp.EZSP_CONFIG_SECURITY_LEVEL, 0); err != nil {
+		t.Fatalf("EzspSetConfigurationValue: %v", err)
+	}
+	level, err := client.EzspGetConfigurationValue(ezsp.EZSP_CONFIG_SECURITY_LEVEL)
+	if err != nil || level != 0 {
+		t.Fatalf("EzspGetConfigurationValue = %d, %v", level, err)
+	}
+}
+
+func TestFormNetwork(t *testing.T) {
+	sim := New()
+	link, client := startClient(t, sim, nil)
+	defer link.Close()
+
+	err := client.EzspNetworkInit()
+	var emberErr ezsp.EmberError
+	if !errors.As(err, &emberErr) || emberErr.EmberStatus != ezsp.EMBER_NOT_JOINED {
+		t.Fatalf("EzspNetworkInit before form = %v, want EMBER_NOT_JOINED", err)
+	}
+
+	formNetwork(t, client)
+	state, err := client.EzspNetworkState()
+	if err != nil || state != ezsp.EMBER_JOINED_NETWORK {
+		t.Fatalf("EzspNetworkState = %d, %v", state, err)
+	}
+	nodeType, parameters, err := client.EzspGetNetworkParameters()
+	if err != nil {
+		t.Fatalf("EzspGetNetworkParameters: %v", err)
+	}
+	if nodeType != ezsp.EMBER_COORDINATOR || parameters.PanId != 0x1234 || parameters.RadioChannel != 15 {
+		t.Fatalf("EzspGetNetworkParameters = %d %+v", nodeType, parameters)
+	}
+
+	// NCP复位后用保存的网络参数恢复网络
+	if err = link.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	client.EzspFrameInitVariables()
+	link.InitVariables()
+	if _, err = client.NegotiateVersion(ezsp.EZSP_PROTOCOL_VERSION); err != nil {
+		t.Fatalf("NegotiateVersion: %v", err)
+	}
+	if err = client.EzspNetworkInit(); err != nil {
+		t.Fatalf("EzspNetworkInit after reset: %v", err)
+	}
+	cb := waitCallback(t, client, ezsp.EZSP_STACK_STATUS_HANDLER)
+	if cb.Data[0] != ezsp.EMBER_NETWORK_UP {
+		t.Fatalf("stackStatus = 0x%x, want EMBER_NETWORK_UP", cb.Data[0])
+	}
+}
+
+func TestJoinAndUnicast(t *testing.T) {
+	sim := New()
+	link, client := startClient(t, sim, nil)
+	defer link.Close()
+	formNetwork(t, client)
+
+	var received []byte
+	dev := &Device{NodeID: 0x1001, Eui64: 0x000d6f0000001001,
+		OnMessage: func(apsFrame *ezsp.EmberApsFrame, message []byte) []byte {
+			received = message
+			return []byte{0x18, message[1], 0x0b, message[2], 0x00}
+		}}
+	sim.Join(dev)
+	event := waitEvent(t, client, ezsp.EZSP_TRUST_CENTER_JOIN_HANDLER)
+	join, ok := event.(*ezsp.TrustCenterJoinEvent)
+	if !ok || join.NewNodeId != dev.NodeID || join.NewNodeEui64 != dev.Eui64 {
+		t.Fatalf("trustCenterJoin event = %+v", event)
+	}
+	nodeID, err := client.EzspLookupNodeIdByEui64(dev.Eui64)
+	if err != nil || nodeID != dev.NodeID {
+		t.Fatalf("EzspLookupNodeIdByEui64 = 0x%04x, %v", nodeID, err)
+	}
+
+	apsFrame := ezsp.EmberApsFrame{ProfileId: 0x0104, ClusterId: 0x0006, SourceEndpoint: 1, DestinationEndpoint: 1}
+	h, err := client.EzspSendUnicastConfirmed(ezsp.EMBER_OUTGOING_DIRECT, dev.NodeID, &apsFrame, []byte{0x01, 0x42, 0x01})
+	if err != nil {
+		t.Fatalf("EzspSendUnicastConfirmed: %v", err)
+	}
+	if err = h.Wait(); err != nil {
+		t.Fatalf("send to reachable device: %v", err)
+	}
+	if string(received) != string([]byte{0x01, 0x42, 0x01}) {
+		t.Fatalf("device received 0x%x", received)
+	}
+	if client.SendsInFlight() != 0 {
+		t.Fatalf("SendsInFlight = %d after confirm", client.SendsInFlight())
+	}
+
+	// 设备的回复先上报incomingSenderEui64再上报incomingMessage
+	event = waitEvent(t, client, ezsp.EZSP_INCOMING_MESSAGE_HANDLER)
+	incoming, ok := event.(*ezsp.IncomingMessageEvent)
+	if !ok || incoming.Sender != dev.NodeID || incoming.ApsFrame.ClusterId != 0x0006 || incoming.Message[1] != 0x42 {
+		t.Fatalf("incomingMessage event = %+v", event)
+	}
+
+	dev.Unreachable = true
+	h, err = client.EzspSendUnicastConfirmed(ezsp.EMBER_OUTGOING_DIRECT, dev.NodeID, &apsFrame, []byte{0x01, 0x43, 0x00})
+	if err != nil {
+		t.Fatalf("EzspSendUnicastConfirmed: %v", err)
+	}
+	var emberErr ezsp.EmberError
+	if err = h.Wait(); !errors.As(err, &emberErr) || emberErr.EmberStatus != ezsp.EMBER_DELIVERY_FAILED {
+		t.Fatalf("send to unreachable device = %v, want EMBER_DELIVERY_FAILED", err)
+	}
+}
+
+func TestCallbackDelivery(t *testing.T) {
+	sim := New()
+	link, client := startClient(t, sim, nil)
+	defer link.Close()
+	formNetwork(t, client)
+
+	events := make(chan ezsp.Event, 8)
+	subscription := client.Subscribe(ezsp.EventFilter{FrameIDs: []uint16{ezsp.EZSP_INCOMING_MESSAGE_HANDLER}, ProfileIds: []uint16{0x0000}},
+		func(event ezsp.Event) { events <- event })
+	defer subscription.Unsubscribe()
+
+	dev := &Device{NodeID: 0x1002, Eui64: 0x000d6f0000001002}
+	sim.Join(dev)
+	sim.Announce(dev)
+	sim.Incoming(dev, &ezsp.EmberApsFrame{ProfileId: 0x0104, ClusterId: 0x0006, SourceEndpoint: 1, DestinationEndpoint: 1},
+		ezsp.EMBER_INCOMING_UNICAST, []byte{0x18, 0x01, 0x0a, 0x00, 0x00, 0x10, 0x01})
+
+	// 按NCP发出的顺序分发，只有ZDO的incomingMessage满足订阅条件
+	var frameIDs []uint16
+	timeout := time.After(time.Second * 2)
+	for len(frameIDs) < 5 {
+		select {
+		case cb := <-client.CallbackCh:
+			frameIDs = append(frameIDs, cb.FrameID)
+			client.EzspCallbackDispatch(cb)
+		case <-timeout:
+			t.Fatalf("callbacks received %x", frameIDs)
+		}
+	}
+	want := []uint16{ezsp.EZSP_TRUST_CENTER_JOIN_HANDLER,
+		ezsp.EZSP_INCOMING_SENDER_EUI64_HANDLER, ezsp.EZSP_INCOMING_MESSAGE_HANDLER,
+		ezsp.EZSP_INCOMING_SENDER_EUI64_HANDLER, ezsp.EZSP_INCOMING_MESSAGE_HANDLER}
+	for i := range want {
+		if frameIDs[i] != want[i] {
+			t.Fatalf("callback order %x, want %x", frameIDs, want)
+		}
+	}
+
+	select {
+	case event := <-events:
+		incoming := event.(*ezsp.IncomingMessageEvent)
+		if incoming.ApsFrame.ClusterId != 0x0013 || incoming.Sender != dev.NodeID {
+			t.Fatalf("announce event = %+v", incoming)
+		}
+	default:
+		t.Fatal("announce not published on the event bus")
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event %+v", event)
+	default:
+	}
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	for _, protocolVersion := range []byte{ezsp.EZSP_PROTOCOL_VERSION, ezsp.EZSP_EXTENDED_FRAME_PROTOCOL_VERSION} {
+		sim := New()
+		sim.SetVersion(protocolVersion, 0x6700)
+		link, client := startClient(t, sim, nil)
+
+		// NCP的版本和期望的不同时用NCP的版本再协商一次
+		info := client.VersionInfo()
+		extended := protocolVersion >= ezsp.EZSP_EXTENDED_FRAME_PROTOCOL_VERSION
+		if info.ProtocolVersion != protocolVersion || info.StackVersion != 0x6700 || client.Supports(ezsp.CAPABILITY_EXTENDED_FRAME) != extended {
+			t.Fatalf("protocolVersion(%d) VersionInfo = %+v", protocolVersion, info)
+		}
+
+		// 扩展帧头下的命令和按版本解码的回复
+		formNetwork(t, client)
+		child := &Device{NodeID: 0x4001, Eui64: 0x000d6f0000004001, EndDevice: true}
+		sim.Join(child)
+		childData, err := client.EzspGetChildData(0)
+		if err != nil || childData.Id != child.NodeID || childData.Eui64 != child.Eui64 || childData.Type != ezsp.EMBER_SLEEPY_END_DEVICE {
+			t.Fatalf("protocolVersion(%d) EzspGetChildData = %+v, %v", protocolVersion, childData, err)
+		}
+
+		// 协议版本8去掉了setSourceRoute，不发给NCP直接返回 CapabilityError
+		err = client.EzspSetSourceRoute(child.NodeID, nil)
+		_, isCapabilityError := err.(ezsp.CapabilityError)
+		if isCapabilityError != extended {
+			t.Fatalf("protocolVersion(%d) EzspSetSourceRoute = %v", protocolVersion, err)
+		}
+		_, err = client.EzspGetSourceRouteTableTotalSize()
+		_, isCapabilityError = err.(ezsp.CapabilityError)
+		if isCapabilityError == extended {
+			t.Fatalf("protocolVersion(%d) EzspGetSourceRouteTableTotalSize = %v", protocolVersion, err)
+		}
+		link.Close()
+	}
+}
+
+func TestNegotiateVersionUnsupported(t *testing.T) {
+	sim := New()
+	sim.SetVersion(ezsp.EZSP_MIN_PROTOCOL_VERSION-1, 0x4000)
+	link := ash.NewLink()
+	client := ezsp.NewClient(link)
+	link.StartTransceiver(sim, client.AshRecvImp, make(chan error, 1))
+	defer link.Close()
+	time.Sleep(sim.ReadTimeout * 2)
+	if err := link.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	client.EzspFrameInitVariables()
+	link.InitVariables()
+
+	if _, err := client.NegotiateVersion(ezsp.EZSP_PROTOCOL_VERSION); err == nil {
+		t.Fatal("NegotiateVersion with unsupported NCP succeeded")
+	}
+	if client.ProtocolVersion() != 0 {
+		t.Fatalf("ProtocolVersion = %d after failed negotiation", client.ProtocolVersion())
+	}
+}
+
+func TestRetransmit(t *testing.T) {
+	sim := New()
+	link, client := startClient(t, sim, &ash.StAshSettings{AckTimeInit: time.Millisecond * 100, AckTimeMin: time.Millisecond * 50})
+	defer link.Close()
+	link.ResetStats()
+
+	// NAK立即重发，不改变t_rx_ack
+	sim.NakData(1)
+	if err := client.EzspNop(); err != nil {
+		t.Fatalf("EzspNop after NAK: %v", err)
+	}
+	stats := link.Stats()
+	if stats.NakReceived != 1 || stats.Retransmits != 1 || stats.AckTimeouts != 0 {
+		t.Fatalf("stats after NAK = %+v", stats)
+	}
+
+	// 丢失的帧等ACK超时后重发
+	sim.DropData(1)
+	if err := client.EzspNop(); err != nil {
+		t.Fatalf("EzspNop after drop: %v", err)
+	}
+	stats = link.Stats()
+	if stats.Retransmits != 2 || stats.AckTimeouts != 1 {
+		t.Fatalf("stats after drop = %+v", stats)
+	}
+}
+
+// TestTwoLinks 两个NCP各自的链路和客户端在同一个进程里并发收发，用 go test -race 检查
+func TestTwoLinks(t *testing.T) {
+	sims := []*Simulator{New(), New()}
+	sims[1].ezsp.Eui64 = sims[0].ezsp.Eui64 + 1
+	var clients []*ezsp.Client
+	for _, sim := range sims {
+		link, client := startClient(t, sim, &ash.StAshSettings{WindowSize: 3})
+		defer link.Close()
+		clients = append(clients, client)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i, client := range clients {
+		want := sims[i].ezsp.Eui64
+		for j := 0; j < 4; j++ {
+			wg.Add(1)
+			go func(client *ezsp.Client) {
+				defer wg.Done()
+				for k := 0; k < 10; k++ {
+					eui64, err := client.EzspGetEUI64()
+					if err == nil && eui64 != want {
+						err = fmt.Errorf("EzspGetEUI64 = %016x, want %016x", eui64, want)
+					}
+					if err != nil {
+						errs <- err
+						return
+					}
+				}
+			}(client)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
+// TestNcpResetAbortsPending NCP在命令等待回复时复位，命令返回ErrNcpReset，槽位被清掉
+func TestNcpResetAbortsPending(t *testing.T) {
+	sim := New()
+	link, client := startClient(t, sim, nil)
+	defer link.Close()
+
+	sim.DropData(100)
+	done := make(chan error, 1)
+	go func() { done <- client.EzspNop() }()
+	time.Sleep(time.Millisecond * 100)
+	if pending := client.State().Pending; len(pending) != 1 {
+		t.Fatalf("Pending = %+v, want the NOP", pending)
+	}
+
+	sim.DropData(0)
+	sim.PowerOnReset()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ezsp.ErrNcpReset) {
+			t.Fatalf("EzspNop = %v, want ErrNcpReset", err)
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatal("EzspNop not aborted by NCP reset")
+	}
+	select {
+	case event := <-link.Events():
+		if event.Type != ash.LINK_EVENT_RESET {
+			t.Fatalf("link event = %s", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("LINK_EVENT_RESET not sent")
+	}
+	if pending := client.State().Pending; len(pending) != 0 {
+		t.Fatalf("Pending after reset = %+v", pending)
+	}
+
+	// 复位后序号从0开始，重新协商版本后可以继续使用
+	if _, err := client.NegotiateVersion(ezsp.EZSP_PROTOCOL_VERSION); err != nil {
+		t.Fatalf("NegotiateVersion after reset: %v", err)
+	}
+	if err := client.EzspNop(); err != nil {
+		t.Fatalf("EzspNop after reset: %v", err)
+	}
+}
+
+// TestCommandCanceled ctx取消后命令返回包裹了context.Canceled的错误，释放sequence槽位
+func TestCommandCanceled(t *testing.T) {
+	sim := New()
+	link, client := startClient(t, sim, nil)
+	defer link.Close()
+
+	sim.DropData(100)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- client.EzspNopContext(ctx) }()
+	time.Sleep(time.Millisecond * 100)
+	if pending := client.State().Pending; len(pending) != 1 {
+		t.Fatalf("Pending = %+v, want the NOP", pending)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("EzspNopContext = %v, want context.Canceled", err)
+	}
+	if pending := client.State().Pending; len(pending) != 0 {
+		t.Fatalf("Pending after cancel = %+v", pending)
+	}
+	if stats := client.Stats(); stats.Canceled != 1 || stats.Timeouts != 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	sim.DropData(0)
+	if err := client.EzspNop(); err != nil {
+		t.Fatalf("EzspNop after cancel: %v", err)
+	}
+}
+
+// TestCallbackShortReply EZSP_CALLBACK的INVALID_COMMAND回复没有状态字节时返回错误，不能越界
+func TestCallbackShortReply(t *testing.T) {
+	sim := New()
+	link, client := startClient(t, sim, nil)
+	defer link.Close()
+
+	sim.mutex.Lock()
+	sim.ezsp.shortReplies = map[uint16]uint16{ezsp.EZSP_CALLBACK: ezsp.EZSP_INVALID_COMMAND}
+	sim.mutex.Unlock()
+	if err := client.EzspCallback(); err == nil {
+		t.Fatal("EzspCallback accepted INVALID_COMMAND without status")
+	}
+}
+
+// TestErrorFrameRecovery NCP发出ERROR帧后host重新RST，发出LINK_EVENT_RESET，带着ERROR帧里的错误码
+func TestErrorFrameRecovery(t *testing.T) {
+	sim := New()
+	link, client := startClient(t, sim, nil)
+	defer link.Close()
+	link.ResetStats()
+
+	sim.SendError(ash.RESET_ASSERT)
+	select {
+	case event := <-link.Events():
+		if event.Type != ash.LINK_EVENT_RESET || event.ResetCode != ash.RESET_ASSERT {
+			t.Fatalf("link event = %s", event)
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatal("LINK_EVENT_RESET not sent after ERROR frame")
+	}
+	if !sim.Connected() {
+		t.Fatal("simulator not connected after re-reset")
+	}
+	if stats := link.Stats(); stats.Resets != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if _, err := client.NegotiateVersion(ezsp.EZSP_PROTOCOL_VERSION); err != nil {
+		t.Fatalf("NegotiateVersion after re-reset: %v", err)
+	}
+	if err := client.EzspNop(); err != nil {
+		t.Fatalf("EzspNop after re-reset: %v", err)
+	}
+}
+
+// TestStats ASH和EZSP的计数随收发增加，ResetStats返回清0之前的快照
+func TestStats(t *testing.T) {
+	sim := New()
+	link, client := startClient(t, sim, nil)
+	defer link.Close()
+	ashSince := link.ResetStats().Since
+	ezspSince := client.ResetStats().Since
+
+	for i := 0; i < 3; i++ {
+		if err := client.EzspNop(); err != nil {
+			t.Fatalf("EzspNop: %v", err)
+		}
+	}
+	formNetwork(t, client)
+
+	ashStats := link.Stats()
+	if ashStats.TxData != 4 || ashStats.RxData < 5 || ashStats.TxFrames < ashStats.TxData || ashStats.RxFrames < ashStats.RxData ||
+		ashStats.Retransmits != 0 || ashStats.CrcErrors != 0 || !ashStats.Since.After(ashSince) {
+		t.Fatalf("ASH stats = %+v", ashStats)
+	}
+	ezspStats := client.Stats()
+	if ezspStats.CommandsSent != 4 || ezspStats.Responses != 4 || ezspStats.Callbacks != 1 || ezspStats.Timeouts != 0 ||
+		!ezspStats.Since.After(ezspSince) {
+		t.Fatalf("EZSP stats = %+v", ezspStats)
+	}
+
+	if stats := client.ResetStats(); stats.CommandsSent != 4 {
+		t.Fatalf("EZSP ResetStats = %+v", stats)
+	}
+	if stats := link.ResetStats(); stats.TxData != 4 {
+		t.Fatalf("ASH ResetStats = %+v", stats)
+	}
+	if stats := client.Stats(); stats.CommandsSent != 0 || stats.Responses != 0 || stats.Callbacks != 0 {
+		t.Fatalf("EZSP stats after reset = %+v", stats)
+	}
+	if stats := link.Stats(); stats.TxData != 0 || stats.RxData != 0 || stats.TxFrames != 0 {
+		t.Fatalf("ASH stats after reset = %+v", stats)
+	}
+}
+
+// TestClientEventBus 每个Client的事件只发布到自己的事件总线，网络状态也各自记录
+func TestClientEventBus(t *testing.T) {
+	sims := []*Simulator{New(), New()}
+	var clients []*ezsp.Client
+	var published [2][]ezsp.Event
+	for i, sim := range sims {
+		link, client := startClient(t, sim, nil)
+		defer link.Close()
+		clients = append(clients, client)
+		i := i
+		subscription := client.Subscribe(ezsp.EventFilter{}, func(event ezsp.Event) { published[i] = append(published[i], event) })
+		defer subscription.Unsubscribe()
+	}
+	onDefault := 0
+	subscription := ezsp.Subscribe(ezsp.EventFilter{}, func(ezsp.Event) { onDefault++ })
+	defer subscription.Unsubscribe()
+
+	err := clients[0].EzspFormNetwork(&ezsp.EmberNetworkParameters{PanId: 0x1234, RadioChannel: 15, Channels: 1 << 15})
+	if err != nil {
+		t.Fatalf("EzspFormNetwork: %v", err)
+	}
+	waitEvent(t, clients[0], ezsp.EZSP_STACK_STATUS_HANDLER)
+	clients[1].EzspCallbackDispatchPending()
+
+	if len(published[0]) != 1 || len(published[1]) != 0 || onDefault != 0 {
+		t.Fatalf("published %d, %d events, %d on DefaultEventBus", len(published[0]), len(published[1]), onDefault)
+	}
+	if !clients[0].MeshStatusUp() || clients[1].MeshStatusUp() || ezsp.MeshStatusUp() {
+		t.Fatalf("MeshStatusUp = %v, %v, default %v", clients[0].MeshStatusUp(), clients[1].MeshStatusUp(), ezsp.MeshStatusUp())
+	}
+	if info := clients[0].MeshInfo(); info.PANID != 0x1234 || info.Channel != 15 {
+		t.Fatalf("MeshInfo = %+v", info)
+	}
+}
+
+// TestSendConfirmTimeout 设备不回复messageSent，确认发送在SendConfirmTimeout后返回ErrConfirmTimeout
+func TestSendConfirmTimeout(t *testing.T) {
+	sim := New()
+	link, client := startClient(t, sim, nil)
+	defer link.Close()
+	formNetwork(t, client)
+	client.SendConfirmTimeout = time.Millisecond * 100
+
+	dev := &Device{NodeID: 0x1001, Eui64: 0x000d6f0000001001, Silent: true}
+	sim.Join(dev)
+	apsFrame := ezsp.EmberApsFrame{ProfileId: 0x0104, ClusterId: 0x0006, SourceEndpoint: 1, DestinationEndpoint: 1}
+	start := time.Now()
+	h, err := client.EzspSendUnicastConfirmed(ezsp.EMBER_OUTGOING_DIRECT, dev.NodeID, &apsFrame, []byte{0x01, 0x44, 0x01})
+	if err != nil {
+		t.Fatalf("EzspSendUnicastConfirmed: %v", err)
+	}
+	if err = h.Wait(); !errors.Is(err, ezsp.ErrConfirmTimeout) {
+		t.Fatalf("Wait = %v, want ErrConfirmTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed < client.SendConfirmTimeout {
+		t.Fatalf("timed out after %v", elapsed)
+	}
+	if client.SendsInFlight() != 0 {
+		t.Fatalf("SendsInFlight = %d after timeout", client.SendsInFlight())
+	}
+
+	// 超时后tag释放，设备恢复后同一个client的确认发送正常完成
+	sim.mutex.Lock()
+	dev.Silent = false
+	sim.mutex.Unlock()
+	h, err = client.EzspSendUnicastConfirmed(ezsp.EMBER_OUTGOING_DIRECT, dev.NodeID, &apsFrame, []byte{0x01, 0x45, 0x01})
+	if err != nil {
+		t.Fatalf("EzspSendUnicastConfirmed: %v", err)
+	}
+	if err = h.Wait(); err != nil {
+		t.Fatalf("Wait after device recovered: %v", err)
+	}
+}
+
+// TestSendQueue 同一个目的地址按顺序发送，不同目的地址互不等待，只重发暂时性的失败
+func TestSendQueue(t *testing.T) {
+	sim := New()
+	link, client := startClient(t, sim, nil)
+	defer link.Close()
+	formNetwork(t, client)
+	go func() { // 发送队列等待messageSent，callback需要有人取走
+		for range client.CallbackCh {
+		}
+	}()
+	client.SendConfirmTimeout = time.Millisecond * 300
+
+	var mutex sync.Mutex
+	received := make(map[uint16][]byte)
+	record := func(nodeID uint16) func(*ezsp.EmberApsFrame, []byte) []byte {
+		return func(apsFrame *ezsp.EmberApsFrame, message []byte) []byte {
+			mutex.Lock()
+			received[nodeID] = append(received[nodeID], message[1])
+			mutex.Unlock()
+			return nil
+		}
+	}
+	a := &Device{NodeID: 0x1001, Eui64: 0x000d6f0000001001}
+	a.OnMessage = record(a.NodeID)
+	b := &Device{NodeID: 0x1002, Eui64: 0x000d6f0000001002}
+	b.OnMessage = record(b.NodeID)
+	silent := &Device{NodeID: 0x1003, Eui64: 0x000d6f0000001003, Silent: true}
+	unreachable := &Device{NodeID: 0x1004, Eui64: 0x000d6f0000001004, Unreachable: true}
+	for _, dev := range []*Device{a, b, silent, unreachable} {
+		sim.Join(dev)
+	}
+
+	q := ezsp.NewSendQueue(client, &ezsp.StSendQueueSettings{MaxAttempts: 3, RetryDelay: time.Millisecond * 10})
+	apsFrame := ezsp.EmberApsFrame{ProfileId: 0x0104, ClusterId: 0x0006, SourceEndpoint: 1, DestinationEndpoint: 1}
+	send := func(dev *Device, seq byte) *ezsp.OutgoingMessage {
+		m, err := q.Send(ezsp.EMBER_OUTGOING_DIRECT, dev.NodeID, &apsFrame, []byte{0x01, seq, 0x01})
+		if err != nil {
+			t.Fatalf("Send to 0x%04x: %v", dev.NodeID, err)
+		}
+		return m
+	}
+
+	// 不回复的设备一直在等确认超时，其它设备的消息照常发送
+	blocked := send(silent, 0)
+	var last []*ezsp.OutgoingMessage
+	for seq := byte(1); seq <= 10; seq++ {
+		last = append(last, send(a, seq), send(b, seq+0x10))
+	}
+	for _, m := range last {
+		if err := m.Wait(); err != nil {
+			t.Fatalf("send to 0x%04x: %v", m.Destination, err)
+		}
+	}
+	select {
+	case <-blocked.Done():
+		t.Fatal("send to silent device finished before other destinations")
+	default:
+	}
+	mutex.Lock()
+	for i := byte(0); i < 10; i++ {
+		if received[a.NodeID][i] != i+1 || received[b.NodeID][i] != i+0x11 {
+			t.Fatalf("received out of order: a 0x%x, b 0x%x", received[a.NodeID], received[b.NodeID])
+		}
+	}
+	mutex.Unlock()
+
+	// DELIVERY_FAILED和确认超时是暂时性的，重发到MaxAttempts次
+	var emberErr ezsp.EmberError
+	m := send(unreachable, 0x20)
+	if err := m.Wait(); !errors.As(err, &emberErr) || emberErr.EmberStatus != ezsp.EMBER_DELIVERY_FAILED || m.Attempts() != 3 {
+		t.Fatalf("send to unreachable device = %v after %d attempts", err, m.Attempts())
+	}
+	if err := blocked.Wait(); !errors.Is(err, ezsp.ErrConfirmTimeout) || blocked.Attempts() != 3 {
+		t.Fatalf("send to silent device = %v after %d attempts", err, blocked.Attempts())
+	}
+
+	// 网络断开重发也不会成功，只发一次
+	if err := client.EzspLeaveNetwork(); err != nil {
+		t.Fatalf("EzspLeaveNetwork: %v", err)
+	}
+	m = send(a, 0x30)
+	if err := m.Wait(); !errors.As(err, &emberErr) || emberErr.EmberStatus != ezsp.EMBER_NETWORK_DOWN || m.Attempts() != 1 {
+		t.Fatalf("send with network down = %v after %d attempts", err, m.Attempts())
+	}
+	if q.Len() != 0 {
+		t.Fatalf("Len = %d after all sends finished", q.Len())
+	}
+}
+
+// TestNcpGetTables 邻居表、在用的路由表、子节点表，源路由表在协议版本8以前来自host收到的route record
+func TestNcpGetTables(t *testing.T) {
+	for _, protocolVersion := range []byte{ezsp.EZSP_PROTOCOL_VERSION, ezsp.EZSP_EXTENDED_FRAME_PROTOCOL_VERSION} {
+		sim := New()
+		sim.SetVersion(protocolVersion, 0x6700)
+		link, client := startClient(t, sim, nil)
+		formNetwork(t, client)
+
+		routers := []*Device{{NodeID: 0x1001, Eui64: 0x000d6f0000001001}, {NodeID: 0x1002, Eui64: 0x000d6f0000001002}}
+		children := []*Device{{NodeID: 0x4001, Eui64: 0x000d6f0000004001, EndDevice: true}}
+		for _, dev := range append(routers, children...) {
+			sim.Join(dev)
+		}
+		client.EzspIncomingRouteRecordHandler(0x3003, 0x000d6f0000003003, 0xff, -40, []uint16{0x1001})
+
+		tables, err := client.NcpGetTables()
+		if err != nil {
+			t.Fatalf("protocolVersion(%d) NcpGetTables: %v", protocolVersion, err)
+		}
+		if len(tables.Neighbors) != len(routers) || len(tables.Routes) != len(routers) || len(tables.Children) != len(children) {
+			t.Fatalf("protocolVersion(%d) tables = %+v", protocolVersion, tables)
+		}
+		for i, dev := range routers {
+			n, r := tables.Neighbors[i], tables.Routes[i]
+			if n.ShortId != dev.NodeID || n.LongId != dev.Eui64 || r.Destination != dev.NodeID || r.NextHop != dev.NodeID || r.Status != ezsp.EMBER_ROUTE_ACTIVE {
+				t.Fatalf("protocolVersion(%d) neighbor %+v, route %+v", protocolVersion, n, r)
+			}
+		}
+		if c := tables.Children[0]; c.Id != children[0].NodeID || c.Eui64 != children[0].Eui64 {
+			t.Fatalf("protocolVersion(%d) child %+v", protocolVersion, c)
+		}
+		// 协议版本8以上读NCP的源路由表，模拟的NCP里是空的
+		if protocolVersion >= ezsp.EZSP_EXTENDED_FRAME_PROTOCOL_VERSION {
+			if len(tables.SourceRoutes) != 0 {
+				t.Fatalf("protocolVersion(%d) source routes = %+v", protocolVersion, tables.SourceRoutes)
+			}
+		} else if len(tables.SourceRoutes) != 2 || tables.SourceRoutes[0].Destination != 0x3003 || len(tables.SourceRoutes[0].Relays) != 1 ||
+			tables.SourceRoutes[0].Relays[0] != 0x1001 || tables.SourceRoutes[1].Destination != 0x1001 {
+			t.Fatalf("protocolVersion(%d) source routes = %+v", protocolVersion, tables.SourceRoutes)
+		}
+		link.Close()
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/conthing/ezsp
 
-go 1.12
+go 1.13
 
 require (
 	github.com/conthing/utils v0.0.0-20190911082154-fe34ef8e7b4e
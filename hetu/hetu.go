@@ -7,8 +7,8 @@ import (
 	"time"
 
 	"github.com/conthing/ezsp/ezsp"
-
-	"encoding/binary"
+	"github.com/conthing/ezsp/interview"
+	"github.com/conthing/ezsp/zdo"
 
 	"github.com/conthing/utils/common"
 	"github.com/conthing/utils/crc16"
@@ -41,6 +41,8 @@ type StNode struct {
 	State        byte
 	Addr         byte
 	LastRecvTime time.Time
+
+	Interview *interview.StDevice // interview的结果，没有interview或者还没有结束时为nil
 }
 
 //eui64 要转成 16进制 mac
@@ -54,6 +56,18 @@ var HetuCallbacks StHetuCallbacks
 
 var Nodes sync.Map
 
+// interviewCh interview的结果在它自己的goroutine里发布，放到这里交给HetuTick，和callback在同一个线程修改Nodes
+var interviewCh = make(chan *interview.StDevice, 16)
+
+func queueInterview(e *interview.InterviewEvent) {
+	device := e.Device
+	select {
+	case interviewCh <- &device:
+	default:
+		common.Log.Errorf("interview result of 0x%016x dropped", device.Eui64)
+	}
+}
+
 // LoadNodesMap 加载 Map
 func LoadNodesMap(m map[uint64]StNode) {
 	for _, node := range m {
@@ -94,10 +108,11 @@ var lastMtorrTime = int64(0)
 func HetuTick() {
 	var err error
 	select {
-	case cbs := <-ezsp.CallbackCh:
-		for _, cb := range cbs {
-			ezsp.EzspCallbackDispatch(cb)
-		}
+	case cb := <-ezsp.CallbackCh:
+		ezsp.EzspCallbackDispatch(cb)
+		ezsp.EzspCallbackDispatchPending()
+	case device := <-interviewCh:
+		InterviewHandler(device)
 	case <-time.After(time.Millisecond * 500):
 
 	}
@@ -110,7 +125,7 @@ func HetuTick() {
 			}
 			return true
 		})
-		if ezsp.MeshStatusUp {
+		if ezsp.MeshStatusUp() {
 			if now-lastMtorrTime >= 300 {
 				lastMtorrTime = now
 				common.Log.Debugf("MTORR ...")
@@ -193,9 +208,39 @@ func SetPermission(duration byte) (err error) {
 	return
 }
 
+var subscription *ezsp.Subscription
+
 func Init() {
-	ezsp.NcpCallbacks.NcpMessageSentHandler = MessageSentHandler
-	ezsp.NcpCallbacks.NcpIncomingMessageHandler = IncomingMessageHandler
+	subscription.Unsubscribe()
+	subscription = ezsp.Subscribe(ezsp.EventFilter{FrameIDs: []uint16{ezsp.EZSP_MESSAGE_SENT_HANDLER, ezsp.EZSP_INCOMING_MESSAGE_HANDLER, interview.INTERVIEW_EVENT_ID}}, eventHandler)
+}
+
+func eventHandler(event ezsp.Event) {
+	switch e := event.(type) {
+	case *ezsp.MessageSentEvent:
+		MessageSentHandler(e.OutgoingMessageType, e.IndexOrDestination, &e.ApsFrame, e.MessageTag, e.EmberStatus, e.Message)
+	case *ezsp.IncomingMessageEvent:
+		IncomingMessageHandler(e.IncomingMessageType, &e.ApsFrame, e.LastHopLqi, e.LastHopRssi, e.Sender, e.BindingIndex, e.AddressIndex, e.Message)
+	case *interview.InterviewEvent:
+		queueInterview(e)
+	}
+}
+
+// InterviewHandler 把interview的结果写到节点记录里，在HetuTick的线程里调用
+func InterviewHandler(device *interview.StDevice) {
+	nodeID := findNodeIDbyEui64(device.Eui64)
+	value, ok := Nodes.Load(nodeID) // 从map中加载
+	if !ok {
+		common.Log.Debugf("interview result of 0x%016x, node not found", device.Eui64)
+		return
+	}
+	node, ok := value.(StNode)
+	if !ok {
+		common.Log.Errorf("Nodes map unsupported type")
+		return
+	}
+	node.Interview = device
+	Nodes.Store(node.NodeID, node) // map中存储
 }
 
 var hndl_cnt byte
@@ -221,7 +266,7 @@ func MessageSentHandler(outgoingMessageType byte,
 	if apsFrame.ProfileId == ZDO_PROFILE {
 		return
 	}
-	if messageTag == 0 { //应用层不关心时tag==0
+	if messageTag == 0 || ezsp.IsConfirmedTag(messageTag) { //应用层不关心时tag==0，确认发送的结果由句柄返回
 		return
 	}
 	var node StNode
@@ -251,22 +296,31 @@ func IncomingMessageHandler(incomingMessageType byte,
 
 	now := time.Now()
 	if apsFrame.ProfileId == ZDO_PROFILE {
-		if apsFrame.ClusterId == 0x0013 { //device announce
-			nodeID := binary.LittleEndian.Uint16(message[1:])
-			eui64 := binary.LittleEndian.Uint64(message[3:])
-			node := StNode{NodeID: nodeID, Eui64: eui64, LastRecvTime: AnnouceFlagTime}
+		if len(message) < 1 {
+			return
+		}
+		seq := message[0]
+		if apsFrame.ClusterId == zdo.ZDO_DEVICE_ANNCE {
+			announce, err := zdo.ZdoParseDeviceAnnounce(message[1:])
+			if err != nil {
+				common.Log.Errorf("zdo announce: %v", err)
+				return
+			}
+			node := StNode{NodeID: announce.NodeID, Eui64: announce.Eui64, LastRecvTime: AnnouceFlagTime}
 			StoreNode(&node)
 			node.RefreshHandle(false)
-			common.Log.Debugf("2 zdo announce: 0x%04x,%016x", nodeID, eui64)
-		} else if apsFrame.ClusterId == 0x0006 { //match desc req
-			seq := message[0]
-			nwkAddrOfInterest := binary.LittleEndian.Uint16(message[1:])
-			profileID := binary.LittleEndian.Uint16(message[3:])
+			common.Log.Debugf("2 zdo announce: 0x%04x,%016x", announce.NodeID, announce.Eui64)
+		} else if apsFrame.ClusterId == zdo.ZDO_MATCH_DESC_REQ {
+			nwkAddrOfInterest, profileID, _, _, err := zdo.ZdoParseMatchDescReq(message[1:])
+			if err != nil {
+				common.Log.Errorf("match desc req: %v", err)
+				return
+			}
 			common.Log.Debugf("match desc req: 0x%04x, nwkAddrOfInterest:0x%04x, profile:0x%04x", sender, nwkAddrOfInterest, profileID)
 
 			var apsFrame ezsp.EmberApsFrame
-			apsFrame.ProfileId = 0
-			apsFrame.ClusterId = 0x8006
+			apsFrame.ProfileId = zdo.ZDO_PROFILE_ID
+			apsFrame.ClusterId = zdo.ZDO_MATCH_DESC_RSP
 			apsFrame.SourceEndpoint = 0
 			apsFrame.DestinationEndpoint = 0
 			apsFrame.Options = ezsp.EMBER_APS_OPTION_NONE
@@ -274,7 +328,8 @@ func IncomingMessageHandler(incomingMessageType byte,
 
 			// todo 设置路由表
 			_ = ezsp.NcpSetSourceRoute(sender)
-			_, err := ezsp.EzspSendUnicast(ezsp.EMBER_OUTGOING_DIRECT, sender, &apsFrame, tag, []byte{seq, 0, 0, 0, 1, 2})
+			rsp := append([]byte{seq}, zdo.ZdoPackMatchDescRsp(zdo.ZDO_SUCCESS, 0x0000, []byte{2})...) // 协调器的endpoint 2
+			_, err = ezsp.EzspSendUnicast(ezsp.EMBER_OUTGOING_DIRECT, sender, &apsFrame, tag, rsp)
 			if err != nil {
 				common.Log.Errorf("send match desc resp failed: %v", err)
 			}
@@ -327,42 +382,63 @@ func IncomingMessageHandler(incomingMessageType byte,
 	}
 }
 
-var unicastTagSequence = byte(0)
-
-func nextSequence() byte {
-	unicastTagSequence++
-	if unicastTagSequence == 0 || unicastTagSequence == 0xff {
-		unicastTagSequence = 1
-	}
-	return unicastTagSequence
-}
-
-func SendUnicast(eui64 uint64, message []byte) (err error) {
-	common.Log.Debugf("SendUnicast %016x ...", eui64)
-
-	nodeID := findNodeIDbyEui64(eui64)
+func unicastApsFrame(eui64 uint64, message []byte) (nodeID uint16, apsFrame ezsp.EmberApsFrame, err error) {
+	nodeID = findNodeIDbyEui64(eui64)
 	if nodeID == ezsp.EMBER_NULL_NODE_ID {
-		return fmt.Errorf("unknow EUI64 %016x", eui64)
+		err = fmt.Errorf("unknow EUI64 %016x", eui64)
+		return
 	}
-	var apsFrame ezsp.EmberApsFrame
 	apsFrame.ProfileId = 0xabcd
 	apsFrame.ClusterId = 0xabde
 	apsFrame.SourceEndpoint = 2
 	apsFrame.DestinationEndpoint = 2
-	apsFrame.Options = getSendOptions(nodeID, apsFrame.ProfileId, apsFrame.ClusterId, byte(len(message)))
-	tag := byte(0)
-	needConfirm := false
-	if needConfirm {
-		tag = nextSequence()
+	apsFrame.Options, err = getSendOptions(nodeID, apsFrame.ProfileId, apsFrame.ClusterId, len(message))
+	return
+}
+
+// sendQueued 放进 ezsp.DefaultSendQueue，同一个节点的消息按顺序发送，失败后重发，发送前设置源路由
+func sendQueued(eui64 uint64, message []byte) (*ezsp.OutgoingMessage, error) {
+	nodeID, apsFrame, err := unicastApsFrame(eui64, message)
+	if err != nil {
+		return nil, err
 	}
+	return ezsp.SendQueued(ezsp.EMBER_OUTGOING_DIRECT, nodeID, &apsFrame, message)
+}
+
+// SendUnicast 把单播放进发送队列后返回，发送失败只记录日志
+func SendUnicast(eui64 uint64, message []byte) (err error) {
+	common.Log.Debugf("SendUnicast %016x ...", eui64)
 
-	// todo 设置路由表
-	_ = ezsp.NcpSetSourceRoute(nodeID)
-	_, err = ezsp.EzspSendUnicast(ezsp.EMBER_OUTGOING_DIRECT, nodeID, &apsFrame, tag, message)
+	m, err := sendQueued(eui64, message)
+	if err != nil {
+		return
+	}
+	go func() {
+		if err := m.Wait(); err != nil {
+			common.Log.Errorf("SendUnicast %016x failed: %v", eui64, err)
+		}
+	}()
 	return
 }
 
-func getSendOptions(destination uint16, profileId uint16, clusterId uint16, messageLength byte) (options uint16) {
+// SendUnicastConfirmed 把单播放进发送队列，返回的句柄在收到APS ACK的结果、重发用完或者超时后完成，
+// 结果只通过句柄返回，不会再通过 HetuMessageSentHandler 上报
+func SendUnicastConfirmed(eui64 uint64, message []byte) (*ezsp.SendHandle, error) {
+	common.Log.Debugf("SendUnicastConfirmed %016x ...", eui64)
+
+	m, err := sendQueued(eui64, message)
+	if err != nil {
+		return nil, err
+	}
+	return m.Handle(), nil
+}
+
+// getSendOptions 按消息长度选择APS选项，单帧放不下时去掉EUI64选项，超过分片能发送的长度时返回错误
+func getSendOptions(destination uint16, profileId uint16, clusterId uint16, messageLength int) (options uint16, err error) {
+	if messageLength > ezsp.MaxMessageLength() {
+		err = fmt.Errorf("message length %d exceeds max %d", messageLength, ezsp.MaxMessageLength())
+		return
+	}
 	if profileId == 0xc25d && clusterId == 0x0001 {
 		if destination >= ezsp.EMBER_BROADCAST_ADDRESS {
 			options = /*ezsp.EMBER_APS_OPTION_RETRY |*/ ezsp.EMBER_APS_OPTION_SOURCE_EUI64
@@ -373,7 +449,7 @@ func getSendOptions(destination uint16, profileId uint16, clusterId uint16, mess
 
 		if messageLength <= 66 { /*66不溢*/
 			options = /*ezsp.EMBER_APS_OPTION_RETRY | */ ezsp.EMBER_APS_OPTION_SOURCE_EUI64 | ezsp.EMBER_APS_OPTION_DESTINATION_EUI64
-		} else if messageLength <= 74 { /*67~74*/
+		} else if messageLength <= ezsp.MAX_UNFRAGMENTED_LENGTH { /*67~74*/
 			options = /*ezsp.EMBER_APS_OPTION_RETRY | */ ezsp.EMBER_APS_OPTION_SOURCE_EUI64
 		} else {
 			options = ezsp.EMBER_APS_OPTION_NONE
@@ -397,7 +473,7 @@ func RemoveDevice(eui64 uint64) (err error) {
 
 func RemoveNetwork() (err error) {
 	common.Log.Debugf("RemoveNetwork()")
-	if !ezsp.MeshStatusUp {
+	if !ezsp.MeshStatusUp() {
 		return ErrMeshNotExist
 	}
 
@@ -419,7 +495,7 @@ func SetRadioChannel(channel byte) (err error) {
 
 func FormNetwork(radioChannel byte) (err error) {
 	common.Log.Debugf("FormNetwork(%d)", radioChannel)
-	if ezsp.MeshStatusUp {
+	if ezsp.MeshStatusUp() {
 		return ErrMeshAlreadyExist
 	} else {
 		return ezsp.NcpFormNetwork(radioChannel, false)
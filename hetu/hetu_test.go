@@ -0,0 +1,145 @@
+package hetu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/conthing/ezsp/ash"
+	"github.com/conthing/ezsp/ezsp"
+	"github.com/conthing/ezsp/ezsp/ncpsim"
+
+	"github.com/conthing/utils/crc16"
+)
+
+// startNcp 在模拟NCP上启动 ash.DefaultLink 和 ezsp.DefaultClient，组网后用 HetuTick 处理callback
+func startNcp(t *testing.T) (*ncpsim.Simulator, chan struct{}) {
+	sim := ncpsim.New()
+	ash.AshStartTransceiver(sim, ezsp.AshRecvImp, make(chan error, 1))
+	time.Sleep(sim.ReadTimeout * 2) // 收发线程启动时会清空transport，等它清空后再复位
+	if err := ash.AshReset(); err != nil {
+		t.Fatalf("AshReset: %v", err)
+	}
+	ezsp.EzspFrameInitVariables()
+	ash.InitVariables()
+	if err := ezsp.NcpGetVersion(); err != nil {
+		t.Fatalf("NcpGetVersion: %v", err)
+	}
+
+	Init()
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				HetuTick()
+			}
+		}
+	}()
+
+	err := ezsp.EzspFormNetwork(&ezsp.EmberNetworkParameters{PanId: 0x4321, RadioChannel: 20, Channels: 1 << 20})
+	if err != nil {
+		t.Fatalf("EzspFormNetwork: %v", err)
+	}
+	deadline := time.Now().Add(time.Second * 2)
+	for !ezsp.MeshStatusUp() {
+		if time.Now().After(deadline) {
+			t.Fatal("network not up")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	return sim, stop
+}
+
+// hetuMessage 38字节的hetu报文，第5字节是数字地址，最后2字节是CRC16/MODBUS
+func hetuMessage(addr byte) []byte {
+	message := make([]byte, 36)
+	message[0] = 0x55
+	message[5] = addr
+	crc := crc16.CRC16MODBUS(message)
+	return append(message, byte(crc), byte(crc>>8))
+}
+
+func loadNode(nodeID uint16) (StNode, bool) {
+	value, ok := Nodes.Load(nodeID)
+	if !ok {
+		return StNode{}, false
+	}
+	node, ok := value.(StNode)
+	return node, ok
+}
+
+func TestHetuNetwork(t *testing.T) {
+	sim, stop := startNcp(t)
+	defer close(stop)
+
+	type stIncoming struct {
+		eui64   uint64
+		message []byte
+	}
+	incoming := make(chan stIncoming, 4)
+	statuses := make(chan byte, 4)
+	HetuCallbacks.HetuIncomingMessageHandler = func(eui64 uint64, message []byte, recvTime time.Time) {
+		incoming <- stIncoming{eui64, message}
+	}
+	HetuCallbacks.HetuNodeStatusHandler = func(eui64 uint64, nodeID uint16, status byte, addr byte) {
+		statuses <- status
+	}
+	defer func() { HetuCallbacks = StHetuCallbacks{} }()
+
+	var received [][]byte
+	dev := &ncpsim.Device{NodeID: 0x3001, Eui64: 0x000d6f0000003001,
+		OnMessage: func(apsFrame *ezsp.EmberApsFrame, message []byte) []byte {
+			received = append(received, message)
+			return nil
+		}}
+	sim.Join(dev)
+	sim.Announce(dev)
+
+	deadline := time.Now().Add(time.Second * 2)
+	for {
+		node, ok := loadNode(dev.NodeID)
+		if ok && node.Eui64 == dev.Eui64 && node.State == C4_STATE_CONNECTING {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("announced device not connecting: %+v", node)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	message := hetuMessage(7)
+	sim.Incoming(dev, &ezsp.EmberApsFrame{ProfileId: 0xabcd, ClusterId: 0xabde, SourceEndpoint: 2, DestinationEndpoint: 2},
+		ezsp.EMBER_INCOMING_UNICAST, message)
+	select {
+	case m := <-incoming:
+		if m.eui64 != dev.Eui64 || string(m.message) != string(message) {
+			t.Fatalf("HetuIncomingMessageHandler(%016x, %x)", m.eui64, m.message)
+		}
+	case <-time.After(time.Second * 2):
+		t.Fatal("hetu message not delivered")
+	}
+	select {
+	case status := <-statuses:
+		if status != C4_STATE_ONLINE {
+			t.Fatalf("HetuNodeStatusHandler status %d, want online", status)
+		}
+	case <-time.After(time.Second * 2):
+		t.Fatal("node status not reported")
+	}
+	if node, _ := loadNode(dev.NodeID); node.Addr != 7 || node.State != C4_STATE_ONLINE {
+		t.Fatalf("node after message = %+v", node)
+	}
+
+	h, err := SendUnicastConfirmed(dev.Eui64, []byte{0x78, 0x87, 0x01})
+	if err != nil {
+		t.Fatalf("SendUnicastConfirmed: %v", err)
+	}
+	if err = h.Wait(); err != nil {
+		t.Fatalf("SendUnicastConfirmed wait: %v", err)
+	}
+	if len(received) != 1 || string(received[0]) != string([]byte{0x78, 0x87, 0x01}) {
+		t.Fatalf("device received %x", received)
+	}
+}
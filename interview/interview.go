@@ -0,0 +1,559 @@
+package interview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/conthing/ezsp/ezsp"
+	"github.com/conthing/ezsp/zcl"
+	"github.com/conthing/ezsp/zdo"
+	"github.com/conthing/utils/common"
+)
+
+// 设备入网（trustCenterJoinHandler）或者广播Device_annce后，依次读取节点描述符、active endpoints、
+// 每个endpoint的简单描述符，再从带Basic cluster的endpoint读取厂家、型号、软件版本和电源。
+// 每一步失败后按设置重试，已经读到的结果保留，重新开始时从失败的那一步继续
+
+var InterviewTraceOn bool
+
+func interviewTrace(format string, v ...interface{}) {
+	if InterviewTraceOn {
+		common.Log.Debugf(format, v...)
+	}
+}
+
+type InterviewState byte
+
+const (
+	INTERVIEW_STATE_RUNNING = InterviewState(1) // 正在进行或者等待重试
+	INTERVIEW_STATE_DONE    = InterviewState(2)
+	INTERVIEW_STATE_FAILED  = InterviewState(3) // 重试次数用完，设备再次入网或者调用Start时继续
+)
+
+var interviewStateNameMap = map[InterviewState]string{
+	INTERVIEW_STATE_RUNNING: "RUNNING",
+	INTERVIEW_STATE_DONE:    "DONE",
+	INTERVIEW_STATE_FAILED:  "FAILED",
+}
+
+func (s InterviewState) String() string {
+	name, ok := interviewStateNameMap[s]
+	if !ok {
+		name = fmt.Sprintf("UNKNOWN_STATE_%d", s)
+	}
+	return name
+}
+
+var basicAttribIds = []uint16{zcl.AttrManufacturerName, zcl.AttrModelIdentifier, zcl.AttrSWBuildID, zcl.AttrPowerSource}
+
+// INTERVIEW_EVENT_ID InterviewEvent 的ID，订阅时放在 ezsp.EventFilter 的FrameIDs里
+const INTERVIEW_EVENT_ID = ezsp.HOST_EVENT_ID_MIN
+
+// InterviewEvent interview完成或者失败，Device.State是 INTERVIEW_STATE_DONE 或 INTERVIEW_STATE_FAILED。
+// 在interview的goroutine里发布到 New 的bus上
+type InterviewEvent struct {
+	Device StDevice
+}
+
+func (e *InterviewEvent) FrameID() uint16 { return INTERVIEW_EVENT_ID }
+
+// StDevice 一个节点的interview记录，没有读到的字段为零值
+type StDevice struct {
+	Eui64      uint64
+	NodeID     uint16
+	Capability byte // Device_annce或者节点描述符里的MAC capability，还不知道时当作一直接收
+	State      InterviewState
+	Err        error // 失败的原因
+	UpdateTime time.Time
+
+	NodeDescriptor   *zdo.StNodeDescriptor
+	ActiveEndpoints  []byte
+	Endpoints        []*zdo.StSimpleDescriptor // 和ActiveEndpoints一一对应
+	BasicRead        bool                      // Basic cluster的属性已经读取，没有Basic cluster的设备不读
+	ManufacturerName string
+	ModelIdentifier  string
+	SWBuildID        string
+	PowerSource      byte
+}
+
+// Sleepy 不是一直接收的设备，请求只能在它poll的时候送达
+func (d *StDevice) Sleepy() bool {
+	return d.Capability&zdo.ZDO_CAPABILITY_RX_ON_WHEN_IDLE == 0
+}
+
+// basicEndpoint 返回第一个带Basic server cluster的endpoint
+func (d *StDevice) basicEndpoint() *zdo.StSimpleDescriptor {
+	for _, ep := range d.Endpoints {
+		for _, c := range ep.InClusters {
+			if c == zcl.ClustBasic {
+				return ep
+			}
+		}
+	}
+	return nil
+}
+
+const (
+	defaultInterviewAttempts         = 3
+	defaultInterviewRetryDelay       = time.Second * 2
+	defaultInterviewSleepyAttempts   = 10
+	defaultInterviewSleepyRetryDelay = time.Second * 30
+	defaultInterviewTimeout          = time.Second * 10
+	defaultInterviewLocalEndpoint    = 1
+)
+
+// StInterviewSettings interview设置，为0的字段使用默认值
+type StInterviewSettings struct {
+	Attempts         int           // 每一步最多请求次数，默认3
+	RetryDelay       time.Duration // 重试前等待的时间，默认2秒
+	SleepyAttempts   int           // 休眠设备每一步最多请求次数，默认10
+	SleepyRetryDelay time.Duration // 休眠设备重试前等待的时间，默认30秒，期间收到设备的消息马上重试
+	Timeout          time.Duration // 每个请求等待回复的时间，默认10秒
+	LocalEndpoint    byte          // 读取Basic属性时本地的endpoint，默认1
+	// RouteRefresh 读取Basic属性前调用，用来设置最新的源路由
+	RouteRefresh func(destination uint16) error
+}
+
+type stRecord struct {
+	device StDevice
+	wake   chan struct{} // 收到设备的消息时唤醒等待重试的请求
+	cancel context.CancelFunc
+}
+
+func (r *stRecord) wakeUp() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// stZclPending 等待Read Attributes Response的请求，按ZCL序号索引，序号和c4、hetu一样从 zcl.NextSendSequence 取
+type stZclPending struct {
+	nodeID    uint16
+	clusterId uint16
+	rsp       chan []*zcl.StAttrib
+}
+
+// Interviewer 在设备入网后自动interview，结果按EUI64缓存，完成或者失败时发布 InterviewEvent
+type Interviewer struct {
+	ezsp     *ezsp.Client
+	zdo      *zdo.Client
+	bus      *ezsp.EventBus
+	settings StInterviewSettings
+	sub      *ezsp.Subscription
+
+	mutex      sync.Mutex
+	devices    map[uint64]*stRecord
+	zclPending map[byte]*stZclPending
+}
+
+// New 创建在c和z上发送请求、从bus上接收入网事件和回复并发布结果的Interviewer，settings为nil时全部使用默认值
+func New(c *ezsp.Client, z *zdo.Client, bus *ezsp.EventBus, settings *StInterviewSettings) *Interviewer {
+	s := StInterviewSettings{Attempts: defaultInterviewAttempts,
+		RetryDelay:       defaultInterviewRetryDelay,
+		SleepyAttempts:   defaultInterviewSleepyAttempts,
+		SleepyRetryDelay: defaultInterviewSleepyRetryDelay,
+		Timeout:          defaultInterviewTimeout,
+		LocalEndpoint:    defaultInterviewLocalEndpoint}
+	if settings != nil {
+		if settings.Attempts > 0 {
+			s.Attempts = settings.Attempts
+		}
+		if settings.RetryDelay > 0 {
+			s.RetryDelay = settings.RetryDelay
+		}
+		if settings.SleepyAttempts > 0 {
+			s.SleepyAttempts = settings.SleepyAttempts
+		}
+		if settings.SleepyRetryDelay > 0 {
+			s.SleepyRetryDelay = settings.SleepyRetryDelay
+		}
+		if settings.Timeout > 0 {
+			s.Timeout = settings.Timeout
+		}
+		if settings.LocalEndpoint > 0 {
+			s.LocalEndpoint = settings.LocalEndpoint
+		}
+		s.RouteRefresh = settings.RouteRefresh
+	}
+	i := &Interviewer{ezsp: c, zdo: z, bus: bus, settings: s,
+		devices:    make(map[uint64]*stRecord),
+		zclPending: make(map[byte]*stZclPending)}
+	i.sub = bus.Subscribe(ezsp.EventFilter{FrameIDs: []uint16{
+		ezsp.EZSP_TRUST_CENTER_JOIN_HANDLER,
+		ezsp.EZSP_INCOMING_MESSAGE_HANDLER}}, i.eventHandler)
+	return i
+}
+
+// Close 取消订阅并停止所有进行中的interview，缓存的结果仍然可以读取
+func (i *Interviewer) Close() {
+	i.sub.Unsubscribe()
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	for _, r := range i.devices {
+		if r.cancel != nil {
+			r.cancel()
+		}
+	}
+}
+
+// eventHandler 在 EzspCallbackDispatch 的线程里执行，不能等待
+func (i *Interviewer) eventHandler(event ezsp.Event) {
+	switch e := event.(type) {
+	case *ezsp.TrustCenterJoinEvent:
+		if e.DeviceUpdateStatus == ezsp.EMBER_DEVICE_LEFT {
+			i.Forget(e.NewNodeEui64)
+		} else {
+			i.start(e.NewNodeId, e.NewNodeEui64, nil)
+		}
+	case *ezsp.IncomingMessageEvent:
+		i.wakeNode(e.Sender)
+		if e.ApsFrame.ProfileId == zdo.ZDO_PROFILE_ID {
+			if e.ApsFrame.ClusterId == zdo.ZDO_DEVICE_ANNCE && len(e.Message) > 0 {
+				announce, err := zdo.ZdoParseDeviceAnnounce(e.Message[1:])
+				if err != nil {
+					common.Log.Errorf("interview: %v", err)
+					return
+				}
+				i.start(announce.NodeID, announce.Eui64, announce)
+			}
+		} else {
+			i.zclReceive(e)
+		}
+	}
+}
+
+// wakeNode 收到nodeID的消息，说明它醒着，等待重试的请求马上重试
+func (i *Interviewer) wakeNode(nodeID uint16) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	for _, r := range i.devices {
+		if r.device.NodeID == nodeID && r.device.State == INTERVIEW_STATE_RUNNING {
+			r.wakeUp()
+		}
+	}
+}
+
+// Start 开始interview，已经完成的不再进行，失败的从失败的那一步继续
+func (i *Interviewer) Start(nodeID uint16, eui64 uint64) {
+	i.start(nodeID, eui64, nil)
+}
+
+func (i *Interviewer) start(nodeID uint16, eui64 uint64, announce *zdo.StDeviceAnnounce) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	r, ok := i.devices[eui64]
+	if !ok {
+		r = &stRecord{device: StDevice{Eui64: eui64, Capability: zdo.ZDO_CAPABILITY_RX_ON_WHEN_IDLE}, wake: make(chan struct{}, 1)}
+		i.devices[eui64] = r
+	}
+	r.device.NodeID = nodeID
+	if announce != nil && r.device.NodeDescriptor == nil {
+		r.device.Capability = announce.Capability
+	}
+	if r.device.State == INTERVIEW_STATE_RUNNING || r.device.State == INTERVIEW_STATE_DONE {
+		r.wakeUp()
+		return
+	}
+	interviewTrace("interview %016x(0x%04x) start", eui64, nodeID)
+	r.device.State = INTERVIEW_STATE_RUNNING
+	r.device.Err = nil
+	r.device.UpdateTime = time.Now()
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	go i.run(ctx, r)
+}
+
+// Forget 删除设备的记录，进行中的interview停止，不发布 InterviewEvent
+func (i *Interviewer) Forget(eui64 uint64) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if r, ok := i.devices[eui64]; ok {
+		if r.cancel != nil {
+			r.cancel()
+		}
+		delete(i.devices, eui64)
+	}
+}
+
+// Device 返回设备的interview记录
+func (i *Interviewer) Device(eui64 uint64) (StDevice, bool) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	r, ok := i.devices[eui64]
+	if !ok {
+		return StDevice{}, false
+	}
+	return r.device, true
+}
+
+// Devices 返回所有设备的interview记录
+func (i *Interviewer) Devices() []StDevice {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	devices := make([]StDevice, 0, len(i.devices))
+	for _, r := range i.devices {
+		devices = append(devices, r.device)
+	}
+	return devices
+}
+
+func (i *Interviewer) run(ctx context.Context, r *stRecord) {
+	err := i.interview(ctx, r)
+
+	i.mutex.Lock()
+	if i.devices[r.device.Eui64] != r || ctx.Err() != nil { // 已经被Forget或者Close
+		i.mutex.Unlock()
+		return
+	}
+	r.cancel()
+	r.cancel = nil
+	r.device.State = INTERVIEW_STATE_DONE
+	if err != nil {
+		r.device.State = INTERVIEW_STATE_FAILED
+	}
+	r.device.Err = err
+	r.device.UpdateTime = time.Now()
+	device := r.device
+	i.mutex.Unlock()
+
+	if err != nil {
+		common.Log.Errorf("interview %016x(0x%04x) failed: %v", device.Eui64, device.NodeID, err)
+	} else {
+		common.Log.Infof("interview %016x(0x%04x) done: manufacturer(%s) model(%s) build(%s) endpoints%v",
+			device.Eui64, device.NodeID, device.ManufacturerName, device.ModelIdentifier, device.SWBuildID, device.ActiveEndpoints)
+	}
+	i.bus.Publish(&InterviewEvent{Device: device})
+}
+
+// update 在锁内修改记录
+func (i *Interviewer) update(r *stRecord, f func(d *StDevice)) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	f(&r.device)
+	r.device.UpdateTime = time.Now()
+}
+
+func (i *Interviewer) interview(ctx context.Context, r *stRecord) error {
+	i.mutex.Lock()
+	d := r.device
+	i.mutex.Unlock()
+
+	if d.NodeDescriptor == nil {
+		err := i.step(ctx, r, "node descriptor", func(ctx context.Context, nodeID uint16) error {
+			desc, err := i.zdo.NodeDescReqContext(ctx, nodeID, nodeID)
+			if err == nil {
+				i.update(r, func(d *StDevice) {
+					d.NodeDescriptor = desc
+					d.Capability = desc.MacCapability
+				})
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if d.ActiveEndpoints == nil {
+		err := i.step(ctx, r, "active endpoints", func(ctx context.Context, nodeID uint16) error {
+			endpoints, err := i.zdo.ActiveEpReqContext(ctx, nodeID, nodeID)
+			if err == nil {
+				if endpoints == nil {
+					endpoints = []byte{} // 没有endpoint也算读到了
+				}
+				i.update(r, func(d *StDevice) {
+					d.ActiveEndpoints = endpoints
+					d.Endpoints = make([]*zdo.StSimpleDescriptor, len(endpoints))
+				})
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	i.mutex.Lock()
+	d = r.device
+	i.mutex.Unlock()
+	for n, endpoint := range d.ActiveEndpoints {
+		if d.Endpoints[n] != nil {
+			continue
+		}
+		n, endpoint := n, endpoint
+		err := i.step(ctx, r, fmt.Sprintf("simple descriptor of endpoint %d", endpoint), func(ctx context.Context, nodeID uint16) error {
+			desc, err := i.zdo.SimpleDescReqContext(ctx, nodeID, nodeID, endpoint)
+			if err == nil {
+				i.update(r, func(d *StDevice) {
+					// 复制一份，Device 返回的记录和这里不共用
+					d.Endpoints = append([]*zdo.StSimpleDescriptor(nil), d.Endpoints...)
+					d.Endpoints[n] = desc
+				})
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	i.mutex.Lock()
+	d = r.device
+	i.mutex.Unlock()
+	basic := d.basicEndpoint()
+	if basic == nil || d.BasicRead {
+		return nil
+	}
+	return i.step(ctx, r, "basic attributes", func(ctx context.Context, nodeID uint16) error {
+		attribs, err := i.zclReadAttribs(ctx, nodeID, basic, zcl.ClustBasic, basicAttribIds)
+		if err == nil {
+			i.update(r, func(d *StDevice) {
+				d.BasicRead = true
+				for _, a := range attribs {
+					switch a.AttributeIdentifier {
+					case zcl.AttrManufacturerName:
+						d.ManufacturerName, _ = a.AttributeData.(string)
+					case zcl.AttrModelIdentifier:
+						d.ModelIdentifier, _ = a.AttributeData.(string)
+					case zcl.AttrSWBuildID:
+						d.SWBuildID, _ = a.AttributeData.(string)
+					case zcl.AttrPowerSource:
+						d.PowerSource, _ = a.AttributeData.(byte)
+					}
+				}
+			})
+		}
+		return err
+	})
+}
+
+// step 执行interview的一步，失败后等待重试，休眠设备的重试次数和间隔不同。
+// 设备回复了不成功的ZDO状态时不再重试
+func (i *Interviewer) step(ctx context.Context, r *stRecord, name string, f func(ctx context.Context, nodeID uint16) error) error {
+	for attempt := 1; ; attempt++ {
+		i.mutex.Lock()
+		nodeID := r.device.NodeID
+		sleepy := r.device.Sleepy()
+		i.mutex.Unlock()
+
+		reqCtx, cancel := context.WithTimeout(ctx, i.settings.Timeout)
+		err := f(reqCtx, nodeID)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		var ze zdo.ZdoError
+		if errors.As(err, &ze) {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+
+		attempts, delay := i.settings.Attempts, i.settings.RetryDelay
+		if sleepy {
+			attempts, delay = i.settings.SleepyAttempts, i.settings.SleepyRetryDelay
+		}
+		if attempt >= attempts || ctx.Err() != nil {
+			return fmt.Errorf("%s failed after %d attempts: %v", name, attempt, err)
+		}
+		interviewTrace("interview 0x%04x %s attempt %d failed: %v, retry in %v", nodeID, name, attempt, err, delay)
+
+		select {
+		case <-r.wake: // 设备醒了，或者重新入网
+		default:
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-r.wake:
+		case <-ctx.Done():
+		}
+		timer.Stop()
+	}
+}
+
+// zclReadAttribs 读取属性，只返回设备支持的属性
+func (i *Interviewer) zclReadAttribs(ctx context.Context, nodeID uint16, desc *zdo.StSimpleDescriptor, clusterId uint16, attribIds []uint16) ([]*zcl.StAttrib, error) {
+	p := &stZclPending{nodeID: nodeID, clusterId: clusterId, rsp: make(chan []*zcl.StAttrib, 1)}
+	seq := zcl.NextSendSequence()
+	i.mutex.Lock()
+	i.zclPending[seq] = p
+	i.mutex.Unlock()
+	defer func() {
+		i.mutex.Lock()
+		if i.zclPending[seq] == p {
+			delete(i.zclPending, seq)
+		}
+		i.mutex.Unlock()
+	}()
+
+	apsFrame := ezsp.EmberApsFrame{ProfileId: desc.ProfileId,
+		ClusterId:           clusterId,
+		SourceEndpoint:      i.settings.LocalEndpoint,
+		DestinationEndpoint: desc.Endpoint,
+		Options:             ezsp.EMBER_APS_OPTION_RETRY | ezsp.EMBER_APS_OPTION_ENABLE_ROUTE_DISCOVERY}
+	if i.settings.RouteRefresh != nil {
+		_ = i.settings.RouteRefresh(nodeID)
+	}
+	_, err := i.ezsp.EzspSendUnicastContext(ctx, ezsp.EMBER_OUTGOING_DIRECT, nodeID, &apsFrame, 0, zcl.ZclPackReadAttribs(seq, attribIds))
+	if err != nil {
+		return nil, fmt.Errorf("send read attributes to 0x%04x failed: %w", nodeID, err)
+	}
+	select {
+	case attribs := <-p.rsp:
+		return attribs, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("read attributes of cluster 0x%04x from 0x%04x: %w", clusterId, nodeID, ctx.Err())
+	}
+}
+
+// zclReceive 把Read Attributes Response交给等待的请求
+func (i *Interviewer) zclReceive(e *ezsp.IncomingMessageEvent) {
+	seq, attribs, err := zcl.ZclParseReadAttribResponse(e.Message)
+	if err == zcl.ErrUnsupportGeneralCommand {
+		return
+	}
+	i.mutex.Lock()
+	p, ok := i.zclPending[seq]
+	if ok && p.nodeID == e.Sender && p.clusterId == e.ApsFrame.ClusterId {
+		delete(i.zclPending, seq)
+	} else {
+		ok = false
+	}
+	i.mutex.Unlock()
+	if !ok {
+		return
+	}
+	if err != nil {
+		common.Log.Errorf("interview 0x%04x read attributes response(%x): %v", e.Sender, e.Message, err)
+	}
+	p.rsp <- attribs
+}
+
+// DefaultInterviewer Init 之后在 ezsp.DefaultClient 上自动interview新入网的设备
+var DefaultInterviewer *Interviewer
+
+// Init 创建 DefaultInterviewer，重复调用时替换原来的，缓存的结果丢失
+func Init(settings *StInterviewSettings) {
+	s := StInterviewSettings{RouteRefresh: ezsp.NcpSetSourceRoute}
+	if settings != nil {
+		s = *settings
+		if s.RouteRefresh == nil {
+			s.RouteRefresh = ezsp.NcpSetSourceRoute
+		}
+	}
+	if DefaultInterviewer != nil {
+		DefaultInterviewer.Close()
+	}
+	DefaultInterviewer = New(ezsp.DefaultClient, zdo.DefaultClient, ezsp.DefaultEventBus, &s)
+}
+
+// Device 返回 DefaultInterviewer 里设备的interview记录，没有Init时返回false
+func Device(eui64 uint64) (StDevice, bool) {
+	if DefaultInterviewer == nil {
+		return StDevice{}, false
+	}
+	return DefaultInterviewer.Device(eui64)
+}
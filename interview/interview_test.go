@@ -0,0 +1,247 @@
+package interview
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/conthing/ezsp/ash"
+	"github.com/conthing/ezsp/ezsp"
+	"github.com/conthing/ezsp/ezsp/ncpsim"
+	"github.com/conthing/ezsp/zcl"
+	"github.com/conthing/ezsp/zdo"
+)
+
+// testDevice 回复interview请求的设备，asleep时不回复，requests记录收到的请求cluster
+type testDevice struct {
+	ncpsim.Device
+	capability byte
+
+	mutex    sync.Mutex
+	asleep   bool
+	requests []uint16
+}
+
+func newTestDevice(nodeID uint16, eui64 uint64, capability byte) *testDevice {
+	d := &testDevice{capability: capability}
+	d.NodeID, d.Eui64 = nodeID, eui64
+	d.OnMessage = d.reply
+	return d
+}
+
+func u16(v uint16) []byte {
+	return []byte{byte(v), byte(v >> 8)}
+}
+
+func (d *testDevice) reply(apsFrame *ezsp.EmberApsFrame, message []byte) []byte {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.requests = append(d.requests, apsFrame.ClusterId)
+	if d.asleep {
+		return nil
+	}
+	seq := message[0]
+	if apsFrame.ProfileId != zdo.ZDO_PROFILE_ID {
+		seq = message[1]
+		rsp := []byte{0x18, seq, zcl.CmdReadAttribResponse}
+		rsp = append(append(rsp, u16(zcl.AttrManufacturerName)...), zcl.Success, zcl.TypeCharString, 4, 'A', 'c', 'm', 'e')
+		rsp = append(append(rsp, u16(zcl.AttrModelIdentifier)...), zcl.Success, zcl.TypeCharString, 3, 'S', '0', '1')
+		rsp = append(append(rsp, u16(zcl.AttrSWBuildID)...), 0x86) // UNSUPPORTED_ATTRIBUTE
+		return append(append(rsp, u16(zcl.AttrPowerSource)...), zcl.Success, zcl.TypeEnum8, 0x03)
+	}
+	rsp := append([]byte{seq, zdo.ZDO_SUCCESS}, u16(d.NodeID)...)
+	switch apsFrame.ClusterId {
+	case zdo.ZDO_NODE_DESC_REQ:
+		rsp = append(rsp, zdo.ZDO_LOGICAL_TYPE_END_DEVICE, 0x40, d.capability, 0x02, 0x10, 0x52, 0x80, 0x00, 0x00, 0x00, 0x80, 0x00, 0x00)
+	case zdo.ZDO_ACTIVE_EP_REQ:
+		rsp = append(rsp, 1, 1)
+	case zdo.ZDO_SIMPLE_DESC_REQ:
+		rsp = append(rsp, 12, 1, 0x04, 0x01, 0x02, 0x04, 0x00, 2)
+		rsp = append(append(append(rsp, u16(zcl.ClustBasic)...), u16(0x0500)...), 0)
+	default:
+		return nil
+	}
+	return rsp
+}
+
+func (d *testDevice) setAsleep(asleep bool) {
+	d.mutex.Lock()
+	d.asleep = asleep
+	d.mutex.Unlock()
+}
+
+func (d *testDevice) requestCount(clusterId uint16) (n int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for _, c := range d.requests {
+		if c == clusterId {
+			n++
+		}
+	}
+	return
+}
+
+// startInterviewer 在模拟NCP上组网，callback在单独的goroutine里分发，interview结果放到返回的channel
+func startInterviewer(t *testing.T, sim *ncpsim.Simulator, settings *StInterviewSettings) (*Interviewer, <-chan StDevice, func()) {
+	link := ash.NewLink()
+	client := ezsp.NewClient(link)
+	client.Timeout = time.Second * 5
+	link.StartTransceiver(sim, client.AshRecvImp, make(chan error, 1))
+	time.Sleep(sim.ReadTimeout * 2) // 收发线程启动时会清空transport
+	if err := link.Reset(); err != nil {
+		link.Close()
+		t.Fatalf("Reset: %v", err)
+	}
+	client.EzspFrameInitVariables()
+	link.InitVariables()
+	if _, err := client.NegotiateVersion(ezsp.EZSP_PROTOCOL_VERSION); err != nil {
+		link.Close()
+		t.Fatalf("NegotiateVersion: %v", err)
+	}
+	if err := client.EzspFormNetwork(&ezsp.EmberNetworkParameters{PanId: 0x1234, RadioChannel: 15, Channels: 1 << 15}); err != nil {
+		link.Close()
+		t.Fatalf("EzspFormNetwork: %v", err)
+	}
+
+	z := zdo.NewClient(client, client.EventBus(), &zdo.StClientSettings{Timeout: settings.Timeout})
+	i := New(client, z, client.EventBus(), settings)
+	results := make(chan StDevice, 4)
+	client.Subscribe(ezsp.EventFilter{FrameIDs: []uint16{INTERVIEW_EVENT_ID}}, func(event ezsp.Event) {
+		results <- event.(*InterviewEvent).Device
+	})
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case cb := <-client.CallbackCh:
+				client.EzspCallbackDispatch(cb)
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return i, results, func() {
+		i.Close()
+		z.Close()
+		close(stop)
+		<-stopped
+		link.Close()
+	}
+}
+
+func waitInterview(t *testing.T, results <-chan StDevice, timeout time.Duration) StDevice {
+	select {
+	case device := <-results:
+		return device
+	case <-time.After(timeout):
+		t.Fatalf("InterviewEvent not published in %v", timeout)
+		return StDevice{}
+	}
+}
+
+// announce 设备用自己的capability广播Device_annce
+func announce(sim *ncpsim.Simulator, d *testDevice) {
+	payload := zdo.ZdoPackDeviceAnnounce(&zdo.StDeviceAnnounce{NodeID: d.NodeID, Eui64: d.Eui64, Capability: d.capability})
+	sim.Incoming(&d.Device, &ezsp.EmberApsFrame{ClusterId: zdo.ZDO_DEVICE_ANNCE}, ezsp.EMBER_INCOMING_BROADCAST, append([]byte{0}, payload...))
+}
+
+// TestInterview 设备入网后读取描述符和Basic属性，完成后发布InterviewEvent
+func TestInterview(t *testing.T) {
+	sim := ncpsim.New()
+	i, results, stop := startInterviewer(t, sim, &StInterviewSettings{Timeout: time.Millisecond * 500})
+	defer stop()
+
+	dev := newTestDevice(0x1001, 0x000d6f0000001001, 0x8e)
+	sim.Join(&dev.Device)
+	device := waitInterview(t, results, time.Second*5)
+	if device.State != INTERVIEW_STATE_DONE || device.Err != nil || device.Eui64 != dev.Eui64 || device.NodeID != dev.NodeID {
+		t.Fatalf("InterviewEvent device = %+v", device)
+	}
+	if device.NodeDescriptor == nil || device.Sleepy() || len(device.ActiveEndpoints) != 1 || device.Endpoints[0].ProfileId != 0x0104 {
+		t.Fatalf("descriptors = %+v, endpoints %+v", device.NodeDescriptor, device.Endpoints)
+	}
+	if !device.BasicRead || device.ManufacturerName != "Acme" || device.ModelIdentifier != "S01" || device.SWBuildID != "" || device.PowerSource != 0x03 {
+		t.Fatalf("basic attributes = %+v", device)
+	}
+	if cached, ok := i.Device(dev.Eui64); !ok || cached.State != INTERVIEW_STATE_DONE || cached.ManufacturerName != "Acme" {
+		t.Fatalf("Device = %+v, %v", cached, ok)
+	}
+
+	// 已经完成的设备再次入网不重新interview
+	sim.Join(&dev.Device)
+	time.Sleep(time.Millisecond * 200)
+	if n := dev.requestCount(zdo.ZDO_NODE_DESC_REQ); n != 1 {
+		t.Fatalf("Node_Desc_req sent %d times", n)
+	}
+}
+
+// TestInterviewSleepyWakeUp 休眠设备等待重试期间发来消息，马上重试，不等SleepyRetryDelay
+func TestInterviewSleepyWakeUp(t *testing.T) {
+	// 连接之前入网，不上报trustCenterJoin，interview从Device_annce开始，一开始就知道是休眠设备
+	sim := ncpsim.New()
+	dev := newTestDevice(0x4001, 0x000d6f0000004001, 0x80) // 不是一直接收
+	dev.EndDevice = true
+	dev.setAsleep(true)
+	sim.Join(&dev.Device)
+	i, results, stop := startInterviewer(t, sim, &StInterviewSettings{Attempts: 1, SleepyAttempts: 3,
+		SleepyRetryDelay: time.Second * 30, Timeout: time.Millisecond * 200})
+	defer stop()
+	announce(sim, dev)
+
+	deadline := time.Now().Add(time.Second * 2)
+	for dev.requestCount(zdo.ZDO_NODE_DESC_REQ) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Node_Desc_req to sleepy device not sent")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	time.Sleep(time.Millisecond * 300) // 请求超时，开始等待重试
+	if device, _ := i.Device(dev.Eui64); device.State != INTERVIEW_STATE_RUNNING || !device.Sleepy() {
+		t.Fatalf("Device while asleep = %+v", device)
+	}
+
+	dev.setAsleep(false)
+	sim.Incoming(&dev.Device, &ezsp.EmberApsFrame{ProfileId: 0x0104, ClusterId: 0x0500, SourceEndpoint: 1, DestinationEndpoint: 1},
+		ezsp.EMBER_INCOMING_UNICAST, []byte{0x18, 0x01, 0x0a})
+	device := waitInterview(t, results, time.Second*5)
+	if device.State != INTERVIEW_STATE_DONE || !device.Sleepy() || device.ManufacturerName != "Acme" {
+		t.Fatalf("InterviewEvent device = %+v", device)
+	}
+	if n := dev.requestCount(zdo.ZDO_NODE_DESC_REQ); n != 2 {
+		t.Fatalf("Node_Desc_req sent %d times", n)
+	}
+}
+
+// TestInterviewSleepyRetry 休眠设备按SleepyAttempts重试，用完后发布失败的InterviewEvent，再次入网时继续
+func TestInterviewSleepyRetry(t *testing.T) {
+	sim := ncpsim.New()
+	dev := newTestDevice(0x4002, 0x000d6f0000004002, 0x80)
+	dev.EndDevice = true
+	dev.setAsleep(true)
+	sim.Join(&dev.Device)
+	i, results, stop := startInterviewer(t, sim, &StInterviewSettings{Attempts: 1, SleepyAttempts: 3,
+		SleepyRetryDelay: time.Millisecond * 50, Timeout: time.Millisecond * 100})
+	defer stop()
+	announce(sim, dev)
+
+	device := waitInterview(t, results, time.Second*5)
+	if device.State != INTERVIEW_STATE_FAILED || device.Err == nil || device.NodeDescriptor != nil {
+		t.Fatalf("InterviewEvent device = %+v", device)
+	}
+	if n := dev.requestCount(zdo.ZDO_NODE_DESC_REQ); n != 3 {
+		t.Fatalf("Node_Desc_req sent %d times, want SleepyAttempts", n)
+	}
+
+	// 醒着重新入网，从失败的那一步继续
+	dev.setAsleep(false)
+	announce(sim, dev)
+	device = waitInterview(t, results, time.Second*5)
+	if device.State != INTERVIEW_STATE_DONE || device.Err != nil || !device.BasicRead {
+		t.Fatalf("InterviewEvent after rejoin = %+v", device)
+	}
+	if cached, _ := i.Device(dev.Eui64); cached.State != INTERVIEW_STATE_DONE {
+		t.Fatalf("Device after rejoin = %+v", cached)
+	}
+}
@@ -3,6 +3,7 @@ package zcl
 import (
 	"encoding/binary"
 	"errors"
+	"sync"
 
 	"github.com/conthing/utils/common"
 )
@@ -31,6 +32,7 @@ const (
 	AttrModelIdentifier    uint16 = 0x0005
 	AttrDateCode           uint16 = 0x0006
 	AttrPowerSource        uint16 = 0x0007
+	AttrSWBuildID          uint16 = 0x4000
 )
 
 const (
@@ -133,6 +135,16 @@ type ZclContext struct {
 }
 
 var SendSequence byte
+var sendSequenceMutex sync.Mutex
+
+//NextSendSequence return SendSequence for the next request and increase it, shared by all ZCL senders
+func NextSendSequence() (seq byte) {
+	sendSequenceMutex.Lock()
+	seq = SendSequence
+	SendSequence++
+	sendSequenceMutex.Unlock()
+	return
+}
 
 type ZclGlobalHandle interface {
 	AttribReportedHandle(*ZclContext, uint16, []*StAttrib) error
@@ -214,29 +226,25 @@ func zclPackFrame(clusterSpecific bool, fromServer bool, disableDefaultResponse
 
 //ZclPackOnoffClusterCommandOff pack a OnOff cluster OFF command
 func ZclPackOnoffClusterCommandOff() (data []byte) {
-	data = zclPackFrame(true, false, false, SendSequence, 0, nil)
-	SendSequence++
+	data = zclPackFrame(true, false, false, NextSendSequence(), 0, nil)
 	return
 }
 
 //ZclPackOnoffClusterCommandOn pack a OnOff cluster ON command
 func ZclPackOnoffClusterCommandOn() (data []byte) {
-	data = zclPackFrame(true, false, false, SendSequence, 1, nil)
-	SendSequence++
+	data = zclPackFrame(true, false, false, NextSendSequence(), 1, nil)
 	return
 }
 
 //ZclPackOnoffClusterCommandToggle pack a OnOff cluster TOGGLE command
 func ZclPackOnoffClusterCommandToggle() (data []byte) {
-	data = zclPackFrame(true, false, false, SendSequence, 2, nil)
-	SendSequence++
+	data = zclPackFrame(true, false, false, NextSendSequence(), 2, nil)
 	return
 }
 
 //ZclPackBasicClusterCommandResetToFactory pack a Basic cluster Reset to factory command
 func ZclPackBasicClusterCommandResetToFactory() (data []byte) {
-	data = zclPackFrame(true, false, false, SendSequence, 2, nil)
-	SendSequence++
+	data = zclPackFrame(true, false, false, NextSendSequence(), 2, nil)
 	return
 }
 
@@ -246,8 +254,7 @@ func ZclPackLevelCommandMoveToLevel(level uint8, time uint16) (data []byte) {
 	payload[0] = level
 	payload[1] = byte(time & 0xff)
 	payload[2] = byte((time >> 8) & 0xff)
-	data = zclPackFrame(true, false, false, SendSequence, 0, payload)
-	SendSequence++
+	data = zclPackFrame(true, false, false, NextSendSequence(), 0, payload)
 	return
 }
 
@@ -256,8 +263,7 @@ func ZclPackLevelCommandMove(mode uint8, rate uint8) (data []byte) {
 	payload := make([]byte, 2)
 	payload[0] = mode
 	payload[1] = rate
-	data = zclPackFrame(true, false, false, SendSequence, 1, payload)
-	SendSequence++
+	data = zclPackFrame(true, false, false, NextSendSequence(), 1, payload)
 	return
 }
 
@@ -268,15 +274,13 @@ func ZclPackLevelCommandStep(mode uint8, size uint8, time uint16) (data []byte)
 	payload[1] = size
 	payload[2] = byte(time & 0xff)
 	payload[3] = byte((time >> 8) & 0xff)
-	data = zclPackFrame(true, false, false, SendSequence, 2, payload)
-	SendSequence++
+	data = zclPackFrame(true, false, false, NextSendSequence(), 2, payload)
 	return
 }
 
 //ZclPackLevelCommandStop pack a Level cluster Stop command
 func ZclPackLevelCommandStop() (data []byte) {
-	data = zclPackFrame(true, false, false, SendSequence, 3, nil) //3或者7,怎么处理
-	SendSequence++
+	data = zclPackFrame(true, false, false, NextSendSequence(), 3, nil) //3或者7,怎么处理
 	return
 }
 
@@ -286,8 +290,7 @@ func ZclPackLevelCommandMoveToLevelWithOnOff(level uint8, time uint16) (data []b
 	payload[0] = level
 	payload[1] = byte(time & 0xff)
 	payload[2] = byte((time >> 8) & 0xff)
-	data = zclPackFrame(true, false, false, SendSequence, 4, payload)
-	SendSequence++
+	data = zclPackFrame(true, false, false, NextSendSequence(), 4, payload)
 	return
 }
 
@@ -296,8 +299,7 @@ func ZclPackLevelCommandMoveWithOnOff(mode uint8, rate uint8) (data []byte) {
 	payload := make([]byte, 2)
 	payload[0] = mode
 	payload[1] = rate
-	data = zclPackFrame(true, false, false, SendSequence, 5, payload)
-	SendSequence++
+	data = zclPackFrame(true, false, false, NextSendSequence(), 5, payload)
 	return
 }
 
@@ -307,8 +309,7 @@ func ZclPackLevelCommandStepWithOnOff(mode uint8, size uint8, time uint16) (data
 	payload[0] = mode
 	payload[1] = size
 	payload[2] = byte(time & 0xff)
-	data = zclPackFrame(true, false, false, SendSequence, 6, nil)
-	SendSequence++
+	data = zclPackFrame(true, false, false, NextSendSequence(), 6, nil)
 	return
 }
 
@@ -316,8 +317,121 @@ func ZclPackLevelCommandStepWithOnOff(mode uint8, size uint8, time uint16) (data
 func ZclPackReadAttr(attrId []byte) (data []byte) {
 	payload := make([]byte, 1)
 	payload = append(payload, attrId...)
-	data = zclPackFrame(false, false, false, SendSequence, 0, payload)
-	SendSequence++
+	data = zclPackFrame(false, false, false, NextSendSequence(), 0, payload)
+	return
+}
+
+//ZclPackReadAttribs pack a Read Attributes command with the given sequence number
+func ZclPackReadAttribs(sequenceNumber byte, attribIds []uint16) (data []byte) {
+	payload := make([]byte, 0, 2*len(attribIds))
+	for _, id := range attribIds {
+		payload = append(payload, byte(id), byte(id>>8))
+	}
+	return zclPackFrame(false, false, false, sequenceNumber, CmdReadAttrib, payload)
+}
+
+//ZclParseReadAttribResponse parse a Read Attributes Response frame, unsupported attributes are skipped
+func ZclParseReadAttribResponse(data []byte) (sequenceNumber byte, attribs []*StAttrib, err error) {
+	if len(data) < 3 {
+		return 0, nil, ErrFailToAnalysis
+	}
+	offset := 1
+	if data[0]&0x04 != 0 { // manufacturer code
+		offset += 2
+	}
+	if data[0]&0x03 != 0 || len(data) < offset+2 || data[offset+1] != CmdReadAttribResponse {
+		return 0, nil, ErrUnsupportGeneralCommand
+	}
+	sequenceNumber = data[offset]
+	payload := data[offset+2:]
+	for i := 0; i < len(payload); {
+		if i+3 > len(payload) {
+			return sequenceNumber, attribs, ErrFailToAnalysis
+		}
+		id := binary.LittleEndian.Uint16(payload[i:])
+		if payload[i+2] != Success {
+			i += 3
+			continue
+		}
+		if i+4 > len(payload) {
+			return sequenceNumber, attribs, ErrFailToAnalysis
+		}
+		datatype := payload[i+3]
+		val, length, err := readAttribValue(datatype, payload[i+4:])
+		if err != nil {
+			return sequenceNumber, attribs, err
+		}
+		attribs = append(attribs, &StAttrib{AttributeIdentifier: id, AttributeDataType: datatype, AttributeData: val})
+		i += 4 + length
+	}
+	return
+}
+
+// readAttribValue 解析一个属性值，返回值的类型：bool、byte、uint16、uint32、int8、int16、int32、string、[]byte
+func readAttribValue(datatype byte, payload []byte) (val interface{}, length int, err error) {
+	switch datatype {
+	case TypeBool:
+		length = 1
+	case Type8Bit, Type8BitMap, TypeU8, TypeEnum8, TypeS8:
+		length = 1
+	case Type16Bit, Type16BitMap, TypeU16, TypeEnum16, TypeS16, TypeClustId, TypeAttribId:
+		length = 2
+	case Type24Bit, Type24BitMap, TypeU24:
+		length = 3
+	case Type32Bit, Type32BitMap, TypeU32, TypeS32, TypeUtcTime:
+		length = 4
+	case TypeCharString, TypeByteArray:
+		if len(payload) < 1 {
+			return nil, 0, ErrFailToAnalysis
+		}
+		length = 1 + int(payload[0])
+		if payload[0] == 0xff { // 无效值
+			length = 1
+		}
+	case TypeLongCharString, TypeLongByteArray:
+		if len(payload) < 2 {
+			return nil, 0, ErrFailToAnalysis
+		}
+		length = 2 + int(binary.LittleEndian.Uint16(payload))
+		if length == 2+0xffff {
+			length = 2
+		}
+	default:
+		return nil, 0, ErrorDataTypeNotSupport
+	}
+	if len(payload) < length {
+		return nil, 0, ErrFailToAnalysis
+	}
+	b := payload[:length]
+	switch datatype {
+	case TypeBool:
+		val = b[0] != 0
+	case TypeS8:
+		val = int8(b[0])
+	case TypeS16:
+		val = int16(binary.LittleEndian.Uint16(b))
+	case TypeS32:
+		val = int32(binary.LittleEndian.Uint32(b))
+	case TypeCharString:
+		val = string(b[1:])
+	case TypeByteArray:
+		val = append([]byte(nil), b[1:]...)
+	case TypeLongCharString:
+		val = string(b[2:])
+	case TypeLongByteArray:
+		val = append([]byte(nil), b[2:]...)
+	default:
+		switch length {
+		case 1:
+			val = b[0]
+		case 2:
+			val = binary.LittleEndian.Uint16(b)
+		case 3:
+			val = uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+		case 4:
+			val = binary.LittleEndian.Uint32(b)
+		}
+	}
 	return
 }
 
@@ -325,8 +439,7 @@ func ZclPackReadAttr(attrId []byte) (data []byte) {
 func ZclPackWriteAttr(attrId []byte, attrDataType byte, attrData []byte) (data []byte) {
 	payload := make([]byte, 1)
 	//payload = append(payload,attrId...,attrDataType,attrData...)
-	data = zclPackFrame(false, false, false, SendSequence, 2, payload)
-	SendSequence++
+	data = zclPackFrame(false, false, false, NextSendSequence(), 2, payload)
 	return
 }
 
@@ -0,0 +1,92 @@
+package zcl
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestPackReadAttribs(t *testing.T) {
+	data := ZclPackReadAttribs(0x35, []uint16{0x0004, 0x0005})
+	want := []byte{0x00, 0x35, CmdReadAttrib, 0x04, 0x00, 0x05, 0x00}
+	if !bytes.Equal(data, want) {
+		t.Fatalf("ZclPackReadAttribs = 0x%x, want 0x%x", data, want)
+	}
+}
+
+func TestParseReadAttribResponse(t *testing.T) {
+	data := []byte{0x18, 0x35, CmdReadAttribResponse,
+		0x04, 0x00, Success, TypeCharString, 4, 'A', 'C', 'M', 'E',
+		0x05, 0x00, 0x86, // UNSUPPORTED_ATTRIBUTE 被跳过
+		0x07, 0x00, Success, TypeEnum8, 0x01,
+		0x00, 0x40, Success, TypeU24, 0x01, 0x02, 0x03,
+		0x00, 0x00, Success, TypeS16, 0xfb, 0xff,
+		0x00, 0x00, Success, TypeBool, 0x01,
+		0x10, 0x00, Success, TypeCharString, 0xff, // 无效的字符串
+	}
+	want := []*StAttrib{
+		{0x0004, TypeCharString, "ACME"},
+		{0x0007, TypeEnum8, byte(0x01)},
+		{0x4000, TypeU24, uint32(0x030201)},
+		{0x0000, TypeS16, int16(-5)},
+		{0x0000, TypeBool, true},
+		{0x0010, TypeCharString, ""},
+	}
+	seq, attribs, err := ZclParseReadAttribResponse(data)
+	if err != nil || seq != 0x35 {
+		t.Fatalf("ZclParseReadAttribResponse seq = 0x%02x, %v", seq, err)
+	}
+	if !reflect.DeepEqual(attribs, want) {
+		t.Fatalf("ZclParseReadAttribResponse = %+v, want %+v", attribs, want)
+	}
+
+	// 厂商自定义的回复带有厂商代码
+	seq, attribs, err = ZclParseReadAttribResponse([]byte{0x1c, 0x02, 0x10, 0x36, CmdReadAttribResponse, 0x00, 0x00, Success, TypeU8, 0x07})
+	if err != nil || seq != 0x36 || len(attribs) != 1 || attribs[0].AttributeData != byte(0x07) {
+		t.Fatalf("manufacturer specific response = 0x%02x %+v, %v", seq, attribs, err)
+	}
+}
+
+func TestParseReadAttribResponseErrors(t *testing.T) {
+	cases := []struct {
+		data []byte
+		err  error
+	}{
+		{[]byte{0x18, 0x35}, ErrFailToAnalysis},
+		{[]byte{0x19, 0x35, CmdReadAttribResponse}, ErrUnsupportGeneralCommand},
+		{[]byte{0x18, 0x35, CmdReadAttrib, 0x04, 0x00}, ErrUnsupportGeneralCommand},
+		{[]byte{0x18, 0x35, CmdReadAttribResponse, 0x04, 0x00}, ErrFailToAnalysis},
+		{[]byte{0x18, 0x35, CmdReadAttribResponse, 0x04, 0x00, Success}, ErrFailToAnalysis},
+		{[]byte{0x18, 0x35, CmdReadAttribResponse, 0x04, 0x00, Success, TypeCharString, 4, 'A'}, ErrFailToAnalysis},
+		{[]byte{0x18, 0x35, CmdReadAttribResponse, 0x04, 0x00, Success, TypeS16, 0x01}, ErrFailToAnalysis},
+		{[]byte{0x18, 0x35, CmdReadAttribResponse, 0x04, 0x00, Success, TypeLongByteArray, 0x01}, ErrFailToAnalysis},
+		{[]byte{0x18, 0x35, CmdReadAttribResponse, 0x04, 0x00, Success, 0xff, 0x00}, ErrorDataTypeNotSupport},
+	}
+	for _, c := range cases {
+		if _, _, err := ZclParseReadAttribResponse(c.data); err != c.err {
+			t.Errorf("ZclParseReadAttribResponse(0x%x) = %v, want %v", c.data, err, c.err)
+		}
+	}
+}
+
+// TestNextSendSequence 多个goroutine同时取序号，256次正好每个序号取到一次
+func TestNextSendSequence(t *testing.T) {
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	seen := make(map[byte]int)
+	for i := 0; i < 256; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			seq := NextSendSequence()
+			mutex.Lock()
+			seen[seq]++
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+	if len(seen) != 256 {
+		t.Fatalf("got %d distinct sequences, want 256", len(seen))
+	}
+}
@@ -0,0 +1,669 @@
+package zdo
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// ZDO帧的第一个字节是序号，本包的 ZdoPackXxx 和 ZdoParseXxx 处理的payload都不包括序号
+
+const ZDO_PROFILE_ID = uint16(0x0000)
+
+const (
+	ZDO_NWK_ADDR_REQ            = uint16(0x0000)
+	ZDO_IEEE_ADDR_REQ           = uint16(0x0001)
+	ZDO_NODE_DESC_REQ           = uint16(0x0002)
+	ZDO_POWER_DESC_REQ          = uint16(0x0003)
+	ZDO_SIMPLE_DESC_REQ         = uint16(0x0004)
+	ZDO_ACTIVE_EP_REQ           = uint16(0x0005)
+	ZDO_MATCH_DESC_REQ          = uint16(0x0006)
+	ZDO_DEVICE_ANNCE            = uint16(0x0013)
+	ZDO_BIND_REQ                = uint16(0x0021)
+	ZDO_UNBIND_REQ              = uint16(0x0022)
+	ZDO_MGMT_LQI_REQ            = uint16(0x0031)
+	ZDO_MGMT_RTG_REQ            = uint16(0x0032)
+	ZDO_MGMT_BIND_REQ           = uint16(0x0033)
+	ZDO_MGMT_LEAVE_REQ          = uint16(0x0034)
+	ZDO_MGMT_PERMIT_JOINING_REQ = uint16(0x0036)
+
+	ZDO_RESPONSE_BIT = uint16(0x8000) // 回复的cluster是请求的cluster加上这一位
+
+	ZDO_NWK_ADDR_RSP            = ZDO_NWK_ADDR_REQ | ZDO_RESPONSE_BIT
+	ZDO_IEEE_ADDR_RSP           = ZDO_IEEE_ADDR_REQ | ZDO_RESPONSE_BIT
+	ZDO_NODE_DESC_RSP           = ZDO_NODE_DESC_REQ | ZDO_RESPONSE_BIT
+	ZDO_POWER_DESC_RSP          = ZDO_POWER_DESC_REQ | ZDO_RESPONSE_BIT
+	ZDO_SIMPLE_DESC_RSP         = ZDO_SIMPLE_DESC_REQ | ZDO_RESPONSE_BIT
+	ZDO_ACTIVE_EP_RSP           = ZDO_ACTIVE_EP_REQ | ZDO_RESPONSE_BIT
+	ZDO_MATCH_DESC_RSP          = ZDO_MATCH_DESC_REQ | ZDO_RESPONSE_BIT
+	ZDO_BIND_RSP                = ZDO_BIND_REQ | ZDO_RESPONSE_BIT
+	ZDO_UNBIND_RSP              = ZDO_UNBIND_REQ | ZDO_RESPONSE_BIT
+	ZDO_MGMT_LQI_RSP            = ZDO_MGMT_LQI_REQ | ZDO_RESPONSE_BIT
+	ZDO_MGMT_RTG_RSP            = ZDO_MGMT_RTG_REQ | ZDO_RESPONSE_BIT
+	ZDO_MGMT_BIND_RSP           = ZDO_MGMT_BIND_REQ | ZDO_RESPONSE_BIT
+	ZDO_MGMT_LEAVE_RSP          = ZDO_MGMT_LEAVE_REQ | ZDO_RESPONSE_BIT
+	ZDO_MGMT_PERMIT_JOINING_RSP = ZDO_MGMT_PERMIT_JOINING_REQ | ZDO_RESPONSE_BIT
+)
+
+var clusterNameMap = map[uint16]string{
+	ZDO_NWK_ADDR_REQ:            "NWK_addr_req",
+	ZDO_IEEE_ADDR_REQ:           "IEEE_addr_req",
+	ZDO_NODE_DESC_REQ:           "Node_Desc_req",
+	ZDO_POWER_DESC_REQ:          "Power_Desc_req",
+	ZDO_SIMPLE_DESC_REQ:         "Simple_Desc_req",
+	ZDO_ACTIVE_EP_REQ:           "Active_EP_req",
+	ZDO_MATCH_DESC_REQ:          "Match_Desc_req",
+	ZDO_DEVICE_ANNCE:            "Device_annce",
+	ZDO_BIND_REQ:                "Bind_req",
+	ZDO_UNBIND_REQ:              "Unbind_req",
+	ZDO_MGMT_LQI_REQ:            "Mgmt_Lqi_req",
+	ZDO_MGMT_RTG_REQ:            "Mgmt_Rtg_req",
+	ZDO_MGMT_BIND_REQ:           "Mgmt_Bind_req",
+	ZDO_MGMT_LEAVE_REQ:          "Mgmt_Leave_req",
+	ZDO_MGMT_PERMIT_JOINING_REQ: "Mgmt_Permit_Joining_req",
+
+	ZDO_NWK_ADDR_RSP:            "NWK_addr_rsp",
+	ZDO_IEEE_ADDR_RSP:           "IEEE_addr_rsp",
+	ZDO_NODE_DESC_RSP:           "Node_Desc_rsp",
+	ZDO_POWER_DESC_RSP:          "Power_Desc_rsp",
+	ZDO_SIMPLE_DESC_RSP:         "Simple_Desc_rsp",
+	ZDO_ACTIVE_EP_RSP:           "Active_EP_rsp",
+	ZDO_MATCH_DESC_RSP:          "Match_Desc_rsp",
+	ZDO_BIND_RSP:                "Bind_rsp",
+	ZDO_UNBIND_RSP:              "Unbind_rsp",
+	ZDO_MGMT_LQI_RSP:            "Mgmt_Lqi_rsp",
+	ZDO_MGMT_RTG_RSP:            "Mgmt_Rtg_rsp",
+	ZDO_MGMT_BIND_RSP:           "Mgmt_Bind_rsp",
+	ZDO_MGMT_LEAVE_RSP:          "Mgmt_Leave_rsp",
+	ZDO_MGMT_PERMIT_JOINING_RSP: "Mgmt_Permit_Joining_rsp",
+}
+
+func clusterToString(clusterId uint16) string {
+	name, ok := clusterNameMap[clusterId]
+	if !ok {
+		name = fmt.Sprintf("ZDO_CLUSTER_0x%04X", clusterId)
+	}
+	return name
+}
+
+const (
+	ZDO_SUCCESS            = byte(0x00)
+	ZDO_INV_REQUESTTYPE    = byte(0x80)
+	ZDO_DEVICE_NOT_FOUND   = byte(0x81)
+	ZDO_INVALID_EP         = byte(0x82)
+	ZDO_NOT_ACTIVE         = byte(0x83)
+	ZDO_NOT_SUPPORTED      = byte(0x84)
+	ZDO_TIMEOUT            = byte(0x85)
+	ZDO_NO_MATCH           = byte(0x86)
+	ZDO_NO_ENTRY           = byte(0x88)
+	ZDO_NO_DESCRIPTOR      = byte(0x89)
+	ZDO_INSUFFICIENT_SPACE = byte(0x8a)
+	ZDO_NOT_PERMITTED      = byte(0x8b)
+	ZDO_TABLE_FULL         = byte(0x8c)
+	ZDO_NOT_AUTHORIZED     = byte(0x8d)
+)
+
+var statusNameMap = map[byte]string{
+	ZDO_SUCCESS:            "SUCCESS",
+	ZDO_INV_REQUESTTYPE:    "INV_REQUESTTYPE",
+	ZDO_DEVICE_NOT_FOUND:   "DEVICE_NOT_FOUND",
+	ZDO_INVALID_EP:         "INVALID_EP",
+	ZDO_NOT_ACTIVE:         "NOT_ACTIVE",
+	ZDO_NOT_SUPPORTED:      "NOT_SUPPORTED",
+	ZDO_TIMEOUT:            "TIMEOUT",
+	ZDO_NO_MATCH:           "NO_MATCH",
+	ZDO_NO_ENTRY:           "NO_ENTRY",
+	ZDO_NO_DESCRIPTOR:      "NO_DESCRIPTOR",
+	ZDO_INSUFFICIENT_SPACE: "INSUFFICIENT_SPACE",
+	ZDO_NOT_PERMITTED:      "NOT_PERMITTED",
+	ZDO_TABLE_FULL:         "TABLE_FULL",
+	ZDO_NOT_AUTHORIZED:     "NOT_AUTHORIZED",
+}
+
+func statusToString(status byte) string {
+	name, ok := statusNameMap[status]
+	if !ok {
+		name = fmt.Sprintf("UNKNOWN_ZDO_STATUS_%02X", status)
+	}
+	return name
+}
+
+// 节点描述符和邻居表里的逻辑类型
+const (
+	ZDO_LOGICAL_TYPE_COORDINATOR = byte(0)
+	ZDO_LOGICAL_TYPE_ROUTER      = byte(1)
+	ZDO_LOGICAL_TYPE_END_DEVICE  = byte(2)
+)
+
+// MAC capability，在节点描述符和Device_annce里
+const (
+	ZDO_CAPABILITY_ALTERNATE_PAN_COORDINATOR = byte(0x01)
+	ZDO_CAPABILITY_FULL_FUNCTION_DEVICE      = byte(0x02)
+	ZDO_CAPABILITY_MAINS_POWERED             = byte(0x04)
+	ZDO_CAPABILITY_RX_ON_WHEN_IDLE           = byte(0x08)
+	ZDO_CAPABILITY_SECURITY                  = byte(0x40)
+	ZDO_CAPABILITY_ALLOCATE_ADDRESS          = byte(0x80)
+)
+
+// 绑定的目的地址模式
+const (
+	ZDO_ADDR_MODE_GROUP = byte(0x01)
+	ZDO_ADDR_MODE_IEEE  = byte(0x03)
+)
+
+// ZdoError ZDO回复的状态不是SUCCESS
+type ZdoError struct {
+	Status  byte
+	OccurAt string
+}
+
+func (e ZdoError) Error() string {
+	return fmt.Sprintf("%s get error zdoStatus(%s)", e.OccurAt, statusToString(e.Status))
+}
+
+// StNodeDescriptor 节点描述符
+type StNodeDescriptor struct {
+	LogicalType                byte
+	ComplexDescriptorAvailable bool
+	UserDescriptorAvailable    bool
+	ApsFlags                   byte
+	FrequencyBand              byte
+	MacCapability              byte
+	ManufacturerCode           uint16
+	MaxBufferSize              byte
+	MaxIncomingTransferSize    uint16
+	ServerMask                 uint16
+	MaxOutgoingTransferSize    uint16
+	DescriptorCapability       byte
+}
+
+// RxOnWhenIdle 为false的是休眠设备，只有在它poll的时候才能收到消息
+func (d *StNodeDescriptor) RxOnWhenIdle() bool {
+	return d.MacCapability&ZDO_CAPABILITY_RX_ON_WHEN_IDLE != 0
+}
+
+// StPowerDescriptor 电源描述符，各字段都是4位
+type StPowerDescriptor struct {
+	CurrentPowerMode        byte
+	AvailablePowerSources   byte
+	CurrentPowerSource      byte
+	CurrentPowerSourceLevel byte
+}
+
+// StSimpleDescriptor 一个endpoint的简单描述符
+type StSimpleDescriptor struct {
+	Endpoint      byte
+	ProfileId     uint16
+	DeviceId      uint16
+	DeviceVersion byte
+	InClusters    []uint16
+	OutClusters   []uint16
+}
+
+// StAddrRsp NWK_addr_rsp和IEEE_addr_rsp，扩展请求时带有关联设备（子节点）列表
+type StAddrRsp struct {
+	Eui64        uint64
+	NodeID       uint16
+	StartIndex   byte
+	AssocDevices []uint16
+}
+
+// StDeviceAnnounce 设备入网或者地址变化后广播的Device_annce
+type StDeviceAnnounce struct {
+	NodeID     uint16
+	Eui64      uint64
+	Capability byte
+}
+
+// StBinding 绑定表的一项，DstAddrMode为 ZDO_ADDR_MODE_GROUP 时用DstGroup，否则用DstEui64和DstEndpoint
+type StBinding struct {
+	SrcEui64    uint64
+	SrcEndpoint byte
+	ClusterId   uint16
+	DstAddrMode byte
+	DstGroup    uint16
+	DstEui64    uint64
+	DstEndpoint byte
+}
+
+// StNeighbor Mgmt_Lqi_rsp里的邻居表项
+type StNeighbor struct {
+	ExtendedPanId uint64
+	Eui64         uint64
+	NodeID        uint16
+	DeviceType    byte // ZDO_LOGICAL_TYPE_XXX
+	RxOnWhenIdle  byte // 0:否 1:是 2:未知
+	Relationship  byte // 0:父节点 1:子节点 2:兄弟 3:无 4:以前的子节点
+	PermitJoining byte // 0:否 1:是 2:未知
+	Depth         byte
+	Lqi           byte
+}
+
+// StRoute Mgmt_Rtg_rsp里的路由表项
+type StRoute struct {
+	Destination         uint16
+	Status              byte // 0:ACTIVE 1:DISCOVERY_UNDERWAY 2:DISCOVERY_FAILED 3:INACTIVE 4:VALIDATION_UNDERWAY
+	MemoryConstrained   bool
+	ManyToOne           bool
+	RouteRecordRequired bool
+	NextHop             uint16
+}
+
+// StMgmtLqiRsp 邻居表的一段，Total是表的总项数，需要从StartIndex+len(Neighbors)继续读
+type StMgmtLqiRsp struct {
+	Total      byte
+	StartIndex byte
+	Neighbors  []StNeighbor
+}
+
+// StMgmtRtgRsp 路由表的一段
+type StMgmtRtgRsp struct {
+	Total      byte
+	StartIndex byte
+	Routes     []StRoute
+}
+
+// StMgmtBindRsp 绑定表的一段
+type StMgmtBindRsp struct {
+	Total      byte
+	StartIndex byte
+	Bindings   []StBinding
+}
+
+// reader 顺序读取小端数据，数据不够时记录错误，之后的读取都返回nil或0，
+// 解析表项的循环在r.err不为nil时停止，不能把读到的0当成表项
+type reader struct {
+	data []byte
+	off  int
+	err  error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if r.off+n > len(r.data) {
+		r.err = fmt.Errorf("need %d bytes at offset %d, data length %d", n, r.off, len(r.data))
+		return nil
+	}
+	b := r.data[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *reader) u8() byte {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) u16() uint16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(b)
+}
+
+func (r *reader) u64() uint64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+func (r *reader) u16List(n int) []uint16 {
+	list := make([]uint16, 0, n)
+	for i := 0; i < n; i++ {
+		v := r.u16()
+		if r.err != nil {
+			return nil
+		}
+		list = append(list, v)
+	}
+	return list
+}
+
+// done 检查数据是否足够，name是报文的名字
+func (r *reader) done(name string) error {
+	if r.err != nil {
+		return fmt.Errorf("invalid %s(%x): %v", name, r.data, r.err)
+	}
+	return nil
+}
+
+// status 读取回复的状态字节，不成功时返回 ZdoError
+func (r *reader) status(name string) error {
+	status := r.u8()
+	if err := r.done(name); err != nil {
+		return err
+	}
+	if status != ZDO_SUCCESS {
+		return ZdoError{status, name}
+	}
+	return nil
+}
+
+func putU16(data []byte, v uint16) []byte {
+	return append(data, byte(v), byte(v>>8))
+}
+
+func putU64(data []byte, v uint64) []byte {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	return append(data, b[:]...)
+}
+
+func putU16List(data []byte, list []uint16) []byte {
+	data = append(data, byte(len(list)))
+	for _, v := range list {
+		data = putU16(data, v)
+	}
+	return data
+}
+
+func boolBit(b bool, bit byte) byte {
+	if b {
+		return bit
+	}
+	return 0
+}
+
+func ZdoPackNwkAddrReq(eui64 uint64, extended bool, startIndex byte) (data []byte) {
+	data = putU64(nil, eui64)
+	return append(data, boolBit(extended, 1), startIndex)
+}
+
+func ZdoPackIeeeAddrReq(nodeID uint16, extended bool, startIndex byte) (data []byte) {
+	data = putU16(nil, nodeID)
+	return append(data, boolBit(extended, 1), startIndex)
+}
+
+func ZdoPackNodeDescReq(nodeID uint16) (data []byte) {
+	return putU16(nil, nodeID)
+}
+
+func ZdoPackPowerDescReq(nodeID uint16) (data []byte) {
+	return putU16(nil, nodeID)
+}
+
+func ZdoPackSimpleDescReq(nodeID uint16, endpoint byte) (data []byte) {
+	return append(putU16(nil, nodeID), endpoint)
+}
+
+func ZdoPackActiveEpReq(nodeID uint16) (data []byte) {
+	return putU16(nil, nodeID)
+}
+
+func ZdoPackMatchDescReq(nodeID uint16, profileId uint16, inClusters []uint16, outClusters []uint16) (data []byte) {
+	data = putU16(putU16(nil, nodeID), profileId)
+	data = putU16List(data, inClusters)
+	return putU16List(data, outClusters)
+}
+
+func ZdoPackMatchDescRsp(status byte, nodeID uint16, endpoints []byte) (data []byte) {
+	data = putU16([]byte{status}, nodeID)
+	data = append(data, byte(len(endpoints)))
+	return append(data, endpoints...)
+}
+
+func ZdoPackDeviceAnnounce(announce *StDeviceAnnounce) (data []byte) {
+	data = putU64(putU16(nil, announce.NodeID), announce.Eui64)
+	return append(data, announce.Capability)
+}
+
+func zdoPackBinding(binding *StBinding) (data []byte) {
+	data = putU64(nil, binding.SrcEui64)
+	data = append(data, binding.SrcEndpoint)
+	data = putU16(data, binding.ClusterId)
+	data = append(data, binding.DstAddrMode)
+	if binding.DstAddrMode == ZDO_ADDR_MODE_GROUP {
+		return putU16(data, binding.DstGroup)
+	}
+	return append(putU64(data, binding.DstEui64), binding.DstEndpoint)
+}
+
+func ZdoPackBindReq(binding *StBinding) (data []byte) {
+	return zdoPackBinding(binding)
+}
+
+func ZdoPackUnbindReq(binding *StBinding) (data []byte) {
+	return zdoPackBinding(binding)
+}
+
+func ZdoPackMgmtLqiReq(startIndex byte) (data []byte) {
+	return []byte{startIndex}
+}
+
+func ZdoPackMgmtRtgReq(startIndex byte) (data []byte) {
+	return []byte{startIndex}
+}
+
+func ZdoPackMgmtBindReq(startIndex byte) (data []byte) {
+	return []byte{startIndex}
+}
+
+// ZdoPackMgmtLeaveReq eui64为0时让收到请求的设备自己离网
+func ZdoPackMgmtLeaveReq(eui64 uint64, removeChildren bool, rejoin bool) (data []byte) {
+	return append(putU64(nil, eui64), boolBit(removeChildren, 0x40)|boolBit(rejoin, 0x80))
+}
+
+// ZdoPackMgmtPermitJoiningReq duration单位秒，0关闭，0xff一直允许
+func ZdoPackMgmtPermitJoiningReq(duration byte, tcSignificance bool) (data []byte) {
+	return []byte{duration, boolBit(tcSignificance, 1)}
+}
+
+// ZdoParseAddrRsp 解析NWK_addr_rsp或IEEE_addr_rsp
+func ZdoParseAddrRsp(payload []byte) (*StAddrRsp, error) {
+	r := &reader{data: payload}
+	if err := r.status("addr_rsp"); err != nil {
+		return nil, err
+	}
+	rsp := &StAddrRsp{Eui64: r.u64(), NodeID: r.u16()}
+	if r.off < len(r.data) { // 扩展回复
+		count := int(r.u8())
+		rsp.StartIndex = r.u8()
+		rsp.AssocDevices = r.u16List(count)
+	}
+	if err := r.done("addr_rsp"); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+func ZdoParseNodeDescRsp(payload []byte) (*StNodeDescriptor, error) {
+	r := &reader{data: payload}
+	if err := r.status("Node_Desc_rsp"); err != nil {
+		return nil, err
+	}
+	r.u16() // nwkAddrOfInterest
+	b0, b1 := r.u8(), r.u8()
+	d := &StNodeDescriptor{LogicalType: b0 & 0x07,
+		ComplexDescriptorAvailable: b0&0x08 != 0,
+		UserDescriptorAvailable:    b0&0x10 != 0,
+		ApsFlags:                   b1 & 0x07,
+		FrequencyBand:              b1 >> 3}
+	d.MacCapability = r.u8()
+	d.ManufacturerCode = r.u16()
+	d.MaxBufferSize = r.u8()
+	d.MaxIncomingTransferSize = r.u16()
+	d.ServerMask = r.u16()
+	d.MaxOutgoingTransferSize = r.u16()
+	d.DescriptorCapability = r.u8()
+	if err := r.done("Node_Desc_rsp"); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func ZdoParsePowerDescRsp(payload []byte) (*StPowerDescriptor, error) {
+	r := &reader{data: payload}
+	if err := r.status("Power_Desc_rsp"); err != nil {
+		return nil, err
+	}
+	r.u16() // nwkAddrOfInterest
+	b0, b1 := r.u8(), r.u8()
+	d := &StPowerDescriptor{CurrentPowerMode: b0 & 0x0f,
+		AvailablePowerSources:   b0 >> 4,
+		CurrentPowerSource:      b1 & 0x0f,
+		CurrentPowerSourceLevel: b1 >> 4}
+	if err := r.done("Power_Desc_rsp"); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func ZdoParseSimpleDescRsp(payload []byte) (*StSimpleDescriptor, error) {
+	r := &reader{data: payload}
+	if err := r.status("Simple_Desc_rsp"); err != nil {
+		return nil, err
+	}
+	r.u16() // nwkAddrOfInterest
+	r.u8()  // length
+	d := &StSimpleDescriptor{Endpoint: r.u8(), ProfileId: r.u16(), DeviceId: r.u16(), DeviceVersion: r.u8() & 0x0f}
+	d.InClusters = r.u16List(int(r.u8()))
+	d.OutClusters = r.u16List(int(r.u8()))
+	if err := r.done("Simple_Desc_rsp"); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// zdoParseEndpointList 解析Active_EP_rsp和Match_Desc_rsp
+func zdoParseEndpointList(payload []byte, name string) ([]byte, error) {
+	r := &reader{data: payload}
+	if err := r.status(name); err != nil {
+		return nil, err
+	}
+	r.u16() // nwkAddrOfInterest
+	endpoints := append([]byte(nil), r.next(int(r.u8()))...)
+	if err := r.done(name); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+func ZdoParseActiveEpRsp(payload []byte) ([]byte, error) {
+	return zdoParseEndpointList(payload, "Active_EP_rsp")
+}
+
+func ZdoParseMatchDescRsp(payload []byte) ([]byte, error) {
+	return zdoParseEndpointList(payload, "Match_Desc_rsp")
+}
+
+// ZdoParseMatchDescReq 解析设备发来的Match_Desc_req
+func ZdoParseMatchDescReq(payload []byte) (nodeID uint16, profileId uint16, inClusters []uint16, outClusters []uint16, err error) {
+	r := &reader{data: payload}
+	nodeID = r.u16()
+	profileId = r.u16()
+	inClusters = r.u16List(int(r.u8()))
+	outClusters = r.u16List(int(r.u8()))
+	if err = r.done("Match_Desc_req"); err != nil {
+		return 0, 0, nil, nil, err
+	}
+	return
+}
+
+func ZdoParseDeviceAnnounce(payload []byte) (*StDeviceAnnounce, error) {
+	r := &reader{data: payload}
+	announce := &StDeviceAnnounce{NodeID: r.u16(), Eui64: r.u64(), Capability: r.u8()}
+	if err := r.done("Device_annce"); err != nil {
+		return nil, err
+	}
+	return announce, nil
+}
+
+// ZdoParseStatusRsp 解析只有状态的回复，比如Bind_rsp、Unbind_rsp、Mgmt_Leave_rsp、Mgmt_Permit_Joining_rsp
+func ZdoParseStatusRsp(clusterId uint16, payload []byte) error {
+	r := &reader{data: payload}
+	return r.status(clusterToString(clusterId))
+}
+
+func zdoParseBinding(r *reader) (binding StBinding) {
+	binding.SrcEui64 = r.u64()
+	binding.SrcEndpoint = r.u8()
+	binding.ClusterId = r.u16()
+	binding.DstAddrMode = r.u8()
+	if binding.DstAddrMode == ZDO_ADDR_MODE_GROUP {
+		binding.DstGroup = r.u16()
+	} else {
+		binding.DstEui64 = r.u64()
+		binding.DstEndpoint = r.u8()
+	}
+	return
+}
+
+func ZdoParseMgmtLqiRsp(payload []byte) (*StMgmtLqiRsp, error) {
+	r := &reader{data: payload}
+	if err := r.status("Mgmt_Lqi_rsp"); err != nil {
+		return nil, err
+	}
+	rsp := &StMgmtLqiRsp{Total: r.u8(), StartIndex: r.u8()}
+	count := int(r.u8())
+	for i := 0; i < count; i++ {
+		n := StNeighbor{ExtendedPanId: r.u64(), Eui64: r.u64(), NodeID: r.u16()}
+		b := r.u8()
+		n.DeviceType = b & 0x03
+		n.RxOnWhenIdle = (b >> 2) & 0x03
+		n.Relationship = (b >> 4) & 0x07
+		n.PermitJoining = r.u8() & 0x03
+		n.Depth = r.u8()
+		n.Lqi = r.u8()
+		if r.err != nil {
+			break
+		}
+		rsp.Neighbors = append(rsp.Neighbors, n)
+	}
+	if err := r.done("Mgmt_Lqi_rsp"); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+func ZdoParseMgmtRtgRsp(payload []byte) (*StMgmtRtgRsp, error) {
+	r := &reader{data: payload}
+	if err := r.status("Mgmt_Rtg_rsp"); err != nil {
+		return nil, err
+	}
+	rsp := &StMgmtRtgRsp{Total: r.u8(), StartIndex: r.u8()}
+	count := int(r.u8())
+	for i := 0; i < count; i++ {
+		route := StRoute{Destination: r.u16()}
+		b := r.u8()
+		route.Status = b & 0x07
+		route.MemoryConstrained = b&0x08 != 0
+		route.ManyToOne = b&0x10 != 0
+		route.RouteRecordRequired = b&0x20 != 0
+		route.NextHop = r.u16()
+		if r.err != nil {
+			break
+		}
+		rsp.Routes = append(rsp.Routes, route)
+	}
+	if err := r.done("Mgmt_Rtg_rsp"); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+func ZdoParseMgmtBindRsp(payload []byte) (*StMgmtBindRsp, error) {
+	r := &reader{data: payload}
+	if err := r.status("Mgmt_Bind_rsp"); err != nil {
+		return nil, err
+	}
+	rsp := &StMgmtBindRsp{Total: r.u8(), StartIndex: r.u8()}
+	count := int(r.u8())
+	for i := 0; i < count; i++ {
+		binding := zdoParseBinding(r)
+		if r.err != nil {
+			break
+		}
+		rsp.Bindings = append(rsp.Bindings, binding)
+	}
+	if err := r.done("Mgmt_Bind_rsp"); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
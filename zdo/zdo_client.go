@@ -0,0 +1,304 @@
+package zdo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/conthing/ezsp/ezsp"
+	"github.com/conthing/utils/common"
+)
+
+var ZdoTraceOn bool
+
+func zdoTrace(format string, v ...interface{}) {
+	if ZdoTraceOn {
+		common.Log.Debugf(format, v...)
+	}
+}
+
+const defaultZdoTimeout = time.Second * 10
+
+// StClientSettings ZDO客户端设置，为0的字段使用默认值
+type StClientSettings struct {
+	Timeout time.Duration // 等待回复的时间，默认10秒
+	Radius  byte          // 广播请求的半径，0是最大半径
+	// RouteRefresh 单播请求发送前调用，用来设置最新的源路由
+	RouteRefresh func(destination uint16) error
+}
+
+type stPending struct {
+	destination uint16
+	clusterId   uint16 // 期待的回复cluster
+	rsp         chan []byte
+}
+
+// Client ZDO客户端，请求通过EZSP单播或者广播发出，回复按ZDO序号、cluster和发送者和请求对应
+type Client struct {
+	ezsp     *ezsp.Client
+	settings StClientSettings
+	sub      *ezsp.Subscription
+
+	mutex    sync.Mutex
+	sequence byte
+	pending  map[byte]*stPending
+}
+
+// DefaultClient 包级函数使用的ZDO客户端，在 ezsp.DefaultClient 上发送，发送前设置主机维护的源路由
+var DefaultClient = NewClient(ezsp.DefaultClient, ezsp.DefaultEventBus, &StClientSettings{RouteRefresh: ezsp.NcpSetSourceRoute})
+
+// NewClient 创建在c上发送请求、从bus上接收回复的ZDO客户端，settings为nil时全部使用默认值
+func NewClient(c *ezsp.Client, bus *ezsp.EventBus, settings *StClientSettings) *Client {
+	s := StClientSettings{Timeout: defaultZdoTimeout}
+	if settings != nil {
+		if settings.Timeout > 0 {
+			s.Timeout = settings.Timeout
+		}
+		s.Radius = settings.Radius
+		s.RouteRefresh = settings.RouteRefresh
+	}
+	z := &Client{ezsp: c, settings: s, pending: make(map[byte]*stPending)}
+	z.sub = bus.Subscribe(ezsp.EventFilter{FrameIDs: []uint16{ezsp.EZSP_INCOMING_MESSAGE_HANDLER}, ProfileIds: []uint16{ZDO_PROFILE_ID}}, z.incoming)
+	return z
+}
+
+// Close 取消订阅，之后等待中的请求都会超时
+func (c *Client) Close() {
+	c.sub.Unsubscribe()
+}
+
+// Pending 还在等待回复的请求数量
+func (c *Client) Pending() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.pending)
+}
+
+// incoming 在 EzspCallbackDispatch 的线程里执行，只把回复交给等待的请求
+func (c *Client) incoming(event ezsp.Event) {
+	e, ok := event.(*ezsp.IncomingMessageEvent)
+	if !ok || e.ApsFrame.ClusterId&ZDO_RESPONSE_BIT == 0 || len(e.Message) < 1 {
+		return
+	}
+	seq := e.Message[0]
+	c.mutex.Lock()
+	p, ok := c.pending[seq]
+	if ok && p.clusterId == e.ApsFrame.ClusterId && (p.destination >= ezsp.EMBER_BROADCAST_ADDRESS || p.destination == e.Sender) {
+		delete(c.pending, seq)
+	} else {
+		ok = false
+	}
+	c.mutex.Unlock()
+	if !ok {
+		zdoTrace("ZDO %s from 0x%04x seq %d not requested", clusterToString(e.ApsFrame.ClusterId), e.Sender, seq)
+		return
+	}
+	zdoTrace("ZDO %s from 0x%04x seq %d: %x", clusterToString(e.ApsFrame.ClusterId), e.Sender, seq, e.Message[1:])
+	p.rsp <- append([]byte(nil), e.Message[1:]...)
+}
+
+// allocSequence 分配一个没有在等待回复的序号，在锁内调用
+func (c *Client) allocSequence() (byte, error) {
+	for i := 0; i < 0x100; i++ {
+		c.sequence++
+		if _, ok := c.pending[c.sequence]; !ok {
+			return c.sequence, nil
+		}
+	}
+	return 0, fmt.Errorf("too many ZDO requests(%d) in flight", len(c.pending))
+}
+
+func (c *Client) send(ctx context.Context, destination uint16, clusterId uint16, seq byte, payload []byte) (err error) {
+	apsFrame := ezsp.EmberApsFrame{ProfileId: ZDO_PROFILE_ID, ClusterId: clusterId}
+	message := append([]byte{seq}, payload...)
+	zdoTrace("ZDO %s to 0x%04x seq %d: %x", clusterToString(clusterId), destination, seq, payload)
+	if destination >= ezsp.EMBER_BROADCAST_ADDRESS {
+		_, err = c.ezsp.EzspSendBroadcastContext(ctx, destination, &apsFrame, c.settings.Radius, 0, message)
+	} else {
+		apsFrame.Options = ezsp.EMBER_APS_OPTION_RETRY | ezsp.EMBER_APS_OPTION_ENABLE_ROUTE_DISCOVERY
+		if c.settings.RouteRefresh != nil {
+			_ = c.settings.RouteRefresh(destination)
+		}
+		_, err = c.ezsp.EzspSendUnicastContext(ctx, ezsp.EMBER_OUTGOING_DIRECT, destination, &apsFrame, 0, message)
+	}
+	if err != nil {
+		return fmt.Errorf("send ZDO %s to 0x%04x failed: %w", clusterToString(clusterId), destination, err)
+	}
+	return nil
+}
+
+// SendContext 发送不需要回复的ZDO请求，比如广播的Mgmt_Permit_Joining_req
+func (c *Client) SendContext(ctx context.Context, destination uint16, clusterId uint16, payload []byte) error {
+	c.mutex.Lock()
+	seq, err := c.allocSequence()
+	c.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	return c.send(ctx, destination, clusterId, seq, payload)
+}
+
+// RequestContext 发送ZDO请求，等待同一个序号的回复，payload和返回的回复都不包括序号。
+// destination是广播地址时接受第一个回复
+func (c *Client) RequestContext(ctx context.Context, destination uint16, clusterId uint16, payload []byte) ([]byte, error) {
+	p := &stPending{destination: destination, clusterId: clusterId | ZDO_RESPONSE_BIT, rsp: make(chan []byte, 1)}
+	c.mutex.Lock()
+	seq, err := c.allocSequence()
+	if err == nil {
+		c.pending[seq] = p
+	}
+	c.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		c.mutex.Lock()
+		if c.pending[seq] == p {
+			delete(c.pending, seq)
+		}
+		c.mutex.Unlock()
+	}()
+
+	if err = c.send(ctx, destination, clusterId, seq, payload); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(c.settings.Timeout)
+	defer timer.Stop()
+	select {
+	case rsp := <-p.rsp:
+		return rsp, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("ZDO %s to 0x%04x seq %d no response in %v", clusterToString(clusterId), destination, seq, c.settings.Timeout)
+	case <-ctx.Done():
+		return nil, fmt.Errorf("ZDO %s to 0x%04x seq %d canceled: %w", clusterToString(clusterId), destination, seq, ctx.Err())
+	}
+}
+
+func (c *Client) Request(destination uint16, clusterId uint16, payload []byte) ([]byte, error) {
+	return c.RequestContext(context.Background(), destination, clusterId, payload)
+}
+
+// NwkAddrReqContext 按EUI64查询短地址，destination一般是 ezsp.EMBER_RX_ON_WHEN_IDLE_BROADCAST_ADDRESS
+func (c *Client) NwkAddrReqContext(ctx context.Context, destination uint16, eui64 uint64, extended bool, startIndex byte) (*StAddrRsp, error) {
+	rsp, err := c.RequestContext(ctx, destination, ZDO_NWK_ADDR_REQ, ZdoPackNwkAddrReq(eui64, extended, startIndex))
+	if err != nil {
+		return nil, err
+	}
+	return ZdoParseAddrRsp(rsp)
+}
+
+// IeeeAddrReqContext 查询nodeID的EUI64，extended为true时同时返回它的子节点
+func (c *Client) IeeeAddrReqContext(ctx context.Context, destination uint16, nodeID uint16, extended bool, startIndex byte) (*StAddrRsp, error) {
+	rsp, err := c.RequestContext(ctx, destination, ZDO_IEEE_ADDR_REQ, ZdoPackIeeeAddrReq(nodeID, extended, startIndex))
+	if err != nil {
+		return nil, err
+	}
+	return ZdoParseAddrRsp(rsp)
+}
+
+// NodeDescReqContext 读取nodeID的节点描述符，destination一般就是nodeID，休眠设备可以问它的父节点
+func (c *Client) NodeDescReqContext(ctx context.Context, destination uint16, nodeID uint16) (*StNodeDescriptor, error) {
+	rsp, err := c.RequestContext(ctx, destination, ZDO_NODE_DESC_REQ, ZdoPackNodeDescReq(nodeID))
+	if err != nil {
+		return nil, err
+	}
+	return ZdoParseNodeDescRsp(rsp)
+}
+
+func (c *Client) PowerDescReqContext(ctx context.Context, destination uint16, nodeID uint16) (*StPowerDescriptor, error) {
+	rsp, err := c.RequestContext(ctx, destination, ZDO_POWER_DESC_REQ, ZdoPackPowerDescReq(nodeID))
+	if err != nil {
+		return nil, err
+	}
+	return ZdoParsePowerDescRsp(rsp)
+}
+
+func (c *Client) SimpleDescReqContext(ctx context.Context, destination uint16, nodeID uint16, endpoint byte) (*StSimpleDescriptor, error) {
+	rsp, err := c.RequestContext(ctx, destination, ZDO_SIMPLE_DESC_REQ, ZdoPackSimpleDescReq(nodeID, endpoint))
+	if err != nil {
+		return nil, err
+	}
+	return ZdoParseSimpleDescRsp(rsp)
+}
+
+func (c *Client) ActiveEpReqContext(ctx context.Context, destination uint16, nodeID uint16) ([]byte, error) {
+	rsp, err := c.RequestContext(ctx, destination, ZDO_ACTIVE_EP_REQ, ZdoPackActiveEpReq(nodeID))
+	if err != nil {
+		return nil, err
+	}
+	return ZdoParseActiveEpRsp(rsp)
+}
+
+// MatchDescReqContext 返回profile和cluster匹配的endpoint，destination是广播地址时只返回第一个回复
+func (c *Client) MatchDescReqContext(ctx context.Context, destination uint16, nodeID uint16, profileId uint16, inClusters []uint16, outClusters []uint16) ([]byte, error) {
+	rsp, err := c.RequestContext(ctx, destination, ZDO_MATCH_DESC_REQ, ZdoPackMatchDescReq(nodeID, profileId, inClusters, outClusters))
+	if err != nil {
+		return nil, err
+	}
+	return ZdoParseMatchDescRsp(rsp)
+}
+
+// BindReqContext 在destination（一般是绑定的源设备）上增加绑定
+func (c *Client) BindReqContext(ctx context.Context, destination uint16, binding *StBinding) error {
+	rsp, err := c.RequestContext(ctx, destination, ZDO_BIND_REQ, ZdoPackBindReq(binding))
+	if err != nil {
+		return err
+	}
+	return ZdoParseStatusRsp(ZDO_BIND_RSP, rsp)
+}
+
+func (c *Client) UnbindReqContext(ctx context.Context, destination uint16, binding *StBinding) error {
+	rsp, err := c.RequestContext(ctx, destination, ZDO_UNBIND_REQ, ZdoPackUnbindReq(binding))
+	if err != nil {
+		return err
+	}
+	return ZdoParseStatusRsp(ZDO_UNBIND_RSP, rsp)
+}
+
+// MgmtLqiReqContext 读取destination邻居表从startIndex开始的一段
+func (c *Client) MgmtLqiReqContext(ctx context.Context, destination uint16, startIndex byte) (*StMgmtLqiRsp, error) {
+	rsp, err := c.RequestContext(ctx, destination, ZDO_MGMT_LQI_REQ, ZdoPackMgmtLqiReq(startIndex))
+	if err != nil {
+		return nil, err
+	}
+	return ZdoParseMgmtLqiRsp(rsp)
+}
+
+func (c *Client) MgmtRtgReqContext(ctx context.Context, destination uint16, startIndex byte) (*StMgmtRtgRsp, error) {
+	rsp, err := c.RequestContext(ctx, destination, ZDO_MGMT_RTG_REQ, ZdoPackMgmtRtgReq(startIndex))
+	if err != nil {
+		return nil, err
+	}
+	return ZdoParseMgmtRtgRsp(rsp)
+}
+
+func (c *Client) MgmtBindReqContext(ctx context.Context, destination uint16, startIndex byte) (*StMgmtBindRsp, error) {
+	rsp, err := c.RequestContext(ctx, destination, ZDO_MGMT_BIND_REQ, ZdoPackMgmtBindReq(startIndex))
+	if err != nil {
+		return nil, err
+	}
+	return ZdoParseMgmtBindRsp(rsp)
+}
+
+// MgmtLeaveReqContext 让destination把eui64（为0时是它自己）移出网络
+func (c *Client) MgmtLeaveReqContext(ctx context.Context, destination uint16, eui64 uint64, removeChildren bool, rejoin bool) error {
+	rsp, err := c.RequestContext(ctx, destination, ZDO_MGMT_LEAVE_REQ, ZdoPackMgmtLeaveReq(eui64, removeChildren, rejoin))
+	if err != nil {
+		return err
+	}
+	return ZdoParseStatusRsp(ZDO_MGMT_LEAVE_RSP, rsp)
+}
+
+// MgmtPermitJoiningReqContext 允许入网duration秒，destination是广播地址时没有回复，发出后就返回
+func (c *Client) MgmtPermitJoiningReqContext(ctx context.Context, destination uint16, duration byte, tcSignificance bool) error {
+	payload := ZdoPackMgmtPermitJoiningReq(duration, tcSignificance)
+	if destination >= ezsp.EMBER_BROADCAST_ADDRESS {
+		return c.SendContext(ctx, destination, ZDO_MGMT_PERMIT_JOINING_REQ, payload)
+	}
+	rsp, err := c.RequestContext(ctx, destination, ZDO_MGMT_PERMIT_JOINING_REQ, payload)
+	if err != nil {
+		return err
+	}
+	return ZdoParseStatusRsp(ZDO_MGMT_PERMIT_JOINING_RSP, rsp)
+}
@@ -0,0 +1,133 @@
+package zdo
+
+import "context"
+
+// DefaultClient 上的ZDO请求
+
+func Send(destination uint16, clusterId uint16, payload []byte) error {
+	return DefaultClient.SendContext(context.Background(), destination, clusterId, payload)
+}
+
+func SendContext(ctx context.Context, destination uint16, clusterId uint16, payload []byte) error {
+	return DefaultClient.SendContext(ctx, destination, clusterId, payload)
+}
+
+func Request(destination uint16, clusterId uint16, payload []byte) ([]byte, error) {
+	return DefaultClient.RequestContext(context.Background(), destination, clusterId, payload)
+}
+
+func RequestContext(ctx context.Context, destination uint16, clusterId uint16, payload []byte) ([]byte, error) {
+	return DefaultClient.RequestContext(ctx, destination, clusterId, payload)
+}
+
+func NwkAddrReq(destination uint16, eui64 uint64, extended bool, startIndex byte) (*StAddrRsp, error) {
+	return DefaultClient.NwkAddrReqContext(context.Background(), destination, eui64, extended, startIndex)
+}
+
+func NwkAddrReqContext(ctx context.Context, destination uint16, eui64 uint64, extended bool, startIndex byte) (*StAddrRsp, error) {
+	return DefaultClient.NwkAddrReqContext(ctx, destination, eui64, extended, startIndex)
+}
+
+func IeeeAddrReq(destination uint16, nodeID uint16, extended bool, startIndex byte) (*StAddrRsp, error) {
+	return DefaultClient.IeeeAddrReqContext(context.Background(), destination, nodeID, extended, startIndex)
+}
+
+func IeeeAddrReqContext(ctx context.Context, destination uint16, nodeID uint16, extended bool, startIndex byte) (*StAddrRsp, error) {
+	return DefaultClient.IeeeAddrReqContext(ctx, destination, nodeID, extended, startIndex)
+}
+
+func NodeDescReq(destination uint16, nodeID uint16) (*StNodeDescriptor, error) {
+	return DefaultClient.NodeDescReqContext(context.Background(), destination, nodeID)
+}
+
+func NodeDescReqContext(ctx context.Context, destination uint16, nodeID uint16) (*StNodeDescriptor, error) {
+	return DefaultClient.NodeDescReqContext(ctx, destination, nodeID)
+}
+
+func PowerDescReq(destination uint16, nodeID uint16) (*StPowerDescriptor, error) {
+	return DefaultClient.PowerDescReqContext(context.Background(), destination, nodeID)
+}
+
+func PowerDescReqContext(ctx context.Context, destination uint16, nodeID uint16) (*StPowerDescriptor, error) {
+	return DefaultClient.PowerDescReqContext(ctx, destination, nodeID)
+}
+
+func SimpleDescReq(destination uint16, nodeID uint16, endpoint byte) (*StSimpleDescriptor, error) {
+	return DefaultClient.SimpleDescReqContext(context.Background(), destination, nodeID, endpoint)
+}
+
+func SimpleDescReqContext(ctx context.Context, destination uint16, nodeID uint16, endpoint byte) (*StSimpleDescriptor, error) {
+	return DefaultClient.SimpleDescReqContext(ctx, destination, nodeID, endpoint)
+}
+
+func ActiveEpReq(destination uint16, nodeID uint16) ([]byte, error) {
+	return DefaultClient.ActiveEpReqContext(context.Background(), destination, nodeID)
+}
+
+func ActiveEpReqContext(ctx context.Context, destination uint16, nodeID uint16) ([]byte, error) {
+	return DefaultClient.ActiveEpReqContext(ctx, destination, nodeID)
+}
+
+func MatchDescReq(destination uint16, nodeID uint16, profileId uint16, inClusters []uint16, outClusters []uint16) ([]byte, error) {
+	return DefaultClient.MatchDescReqContext(context.Background(), destination, nodeID, profileId, inClusters, outClusters)
+}
+
+func MatchDescReqContext(ctx context.Context, destination uint16, nodeID uint16, profileId uint16, inClusters []uint16, outClusters []uint16) ([]byte, error) {
+	return DefaultClient.MatchDescReqContext(ctx, destination, nodeID, profileId, inClusters, outClusters)
+}
+
+func BindReq(destination uint16, binding *StBinding) error {
+	return DefaultClient.BindReqContext(context.Background(), destination, binding)
+}
+
+func BindReqContext(ctx context.Context, destination uint16, binding *StBinding) error {
+	return DefaultClient.BindReqContext(ctx, destination, binding)
+}
+
+func UnbindReq(destination uint16, binding *StBinding) error {
+	return DefaultClient.UnbindReqContext(context.Background(), destination, binding)
+}
+
+func UnbindReqContext(ctx context.Context, destination uint16, binding *StBinding) error {
+	return DefaultClient.UnbindReqContext(ctx, destination, binding)
+}
+
+func MgmtLqiReq(destination uint16, startIndex byte) (*StMgmtLqiRsp, error) {
+	return DefaultClient.MgmtLqiReqContext(context.Background(), destination, startIndex)
+}
+
+func MgmtLqiReqContext(ctx context.Context, destination uint16, startIndex byte) (*StMgmtLqiRsp, error) {
+	return DefaultClient.MgmtLqiReqContext(ctx, destination, startIndex)
+}
+
+func MgmtRtgReq(destination uint16, startIndex byte) (*StMgmtRtgRsp, error) {
+	return DefaultClient.MgmtRtgReqContext(context.Background(), destination, startIndex)
+}
+
+func MgmtRtgReqContext(ctx context.Context, destination uint16, startIndex byte) (*StMgmtRtgRsp, error) {
+	return DefaultClient.MgmtRtgReqContext(ctx, destination, startIndex)
+}
+
+func MgmtBindReq(destination uint16, startIndex byte) (*StMgmtBindRsp, error) {
+	return DefaultClient.MgmtBindReqContext(context.Background(), destination, startIndex)
+}
+
+func MgmtBindReqContext(ctx context.Context, destination uint16, startIndex byte) (*StMgmtBindRsp, error) {
+	return DefaultClient.MgmtBindReqContext(ctx, destination, startIndex)
+}
+
+func MgmtLeaveReq(destination uint16, eui64 uint64, removeChildren bool, rejoin bool) error {
+	return DefaultClient.MgmtLeaveReqContext(context.Background(), destination, eui64, removeChildren, rejoin)
+}
+
+func MgmtLeaveReqContext(ctx context.Context, destination uint16, eui64 uint64, removeChildren bool, rejoin bool) error {
+	return DefaultClient.MgmtLeaveReqContext(ctx, destination, eui64, removeChildren, rejoin)
+}
+
+func MgmtPermitJoiningReq(destination uint16, duration byte, tcSignificance bool) error {
+	return DefaultClient.MgmtPermitJoiningReqContext(context.Background(), destination, duration, tcSignificance)
+}
+
+func MgmtPermitJoiningReqContext(ctx context.Context, destination uint16, duration byte, tcSignificance bool) error {
+	return DefaultClient.MgmtPermitJoiningReqContext(ctx, destination, duration, tcSignificance)
+}
@@ -0,0 +1,135 @@
+package zdo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func bytesOf(parts ...[]byte) (data []byte) {
+	for _, part := range parts {
+		data = append(data, part...)
+	}
+	return
+}
+
+const (
+	testEui64  = uint64(0x000d6f0001020304)
+	testExtPan = uint64(0x1122334455667788)
+)
+
+type parseCase struct {
+	name    string
+	payload []byte
+	parse   func([]byte) (interface{}, error)
+	want    interface{}
+	valid   []int // 截短到这些长度也是合法的报文
+}
+
+var parseCases = []parseCase{
+	{"addr_rsp", bytesOf([]byte{ZDO_SUCCESS}, putU64(nil, testEui64), putU16(nil, 0x1001), []byte{2, 0}, putU16(nil, 0x2001), putU16(nil, 0x2002)),
+		func(p []byte) (interface{}, error) { return ZdoParseAddrRsp(p) },
+		&StAddrRsp{Eui64: testEui64, NodeID: 0x1001, AssocDevices: []uint16{0x2001, 0x2002}}, []int{11}},
+	{"Node_Desc_rsp", bytesOf([]byte{ZDO_SUCCESS}, putU16(nil, 0x1001), []byte{0x12, 0x40, 0x80}, putU16(nil, 0x1002), []byte{0x52},
+		putU16(nil, 0x0080), putU16(nil, 0x0040), putU16(nil, 0x0080), []byte{0x00}),
+		func(p []byte) (interface{}, error) { return ZdoParseNodeDescRsp(p) },
+		&StNodeDescriptor{LogicalType: ZDO_LOGICAL_TYPE_END_DEVICE, UserDescriptorAvailable: true, FrequencyBand: 0x08, MacCapability: 0x80,
+			ManufacturerCode: 0x1002, MaxBufferSize: 0x52, MaxIncomingTransferSize: 0x0080, ServerMask: 0x0040, MaxOutgoingTransferSize: 0x0080}, nil},
+	{"Power_Desc_rsp", bytesOf([]byte{ZDO_SUCCESS}, putU16(nil, 0x1001), []byte{0x10, 0xc1}),
+		func(p []byte) (interface{}, error) { return ZdoParsePowerDescRsp(p) },
+		&StPowerDescriptor{AvailablePowerSources: 1, CurrentPowerSource: 1, CurrentPowerSourceLevel: 0x0c}, nil},
+	{"Simple_Desc_rsp", bytesOf([]byte{ZDO_SUCCESS}, putU16(nil, 0x1001), []byte{13, 1}, putU16(nil, 0x0104), putU16(nil, 0x0100), []byte{0x01},
+		putU16List(nil, []uint16{0x0000, 0x0006}), putU16List(nil, []uint16{0x0019})),
+		func(p []byte) (interface{}, error) { return ZdoParseSimpleDescRsp(p) },
+		&StSimpleDescriptor{Endpoint: 1, ProfileId: 0x0104, DeviceId: 0x0100, DeviceVersion: 1, InClusters: []uint16{0x0000, 0x0006}, OutClusters: []uint16{0x0019}}, nil},
+	{"Active_EP_rsp", bytesOf([]byte{ZDO_SUCCESS}, putU16(nil, 0x1001), []byte{2, 1, 2}),
+		func(p []byte) (interface{}, error) { return ZdoParseActiveEpRsp(p) },
+		[]byte{1, 2}, nil},
+	{"Device_annce", ZdoPackDeviceAnnounce(&StDeviceAnnounce{NodeID: 0x1001, Eui64: testEui64, Capability: 0x8e}),
+		func(p []byte) (interface{}, error) { return ZdoParseDeviceAnnounce(p) },
+		&StDeviceAnnounce{NodeID: 0x1001, Eui64: testEui64, Capability: 0x8e}, nil},
+	{"Mgmt_Lqi_rsp", bytesOf([]byte{ZDO_SUCCESS, 1, 0, 1}, putU64(nil, testExtPan), putU64(nil, testEui64), putU16(nil, 0x1001), []byte{0x25, 0x02, 1, 0xa0}),
+		func(p []byte) (interface{}, error) { return ZdoParseMgmtLqiRsp(p) },
+		&StMgmtLqiRsp{Total: 1, Neighbors: []StNeighbor{{ExtendedPanId: testExtPan, Eui64: testEui64, NodeID: 0x1001,
+			DeviceType: ZDO_LOGICAL_TYPE_ROUTER, RxOnWhenIdle: 1, Relationship: 2, PermitJoining: 2, Depth: 1, Lqi: 0xa0}}}, nil},
+	{"Mgmt_Rtg_rsp", bytesOf([]byte{ZDO_SUCCESS, 3, 2, 1}, putU16(nil, 0x1234), []byte{0x10}, putU16(nil, 0x1001)),
+		func(p []byte) (interface{}, error) { return ZdoParseMgmtRtgRsp(p) },
+		&StMgmtRtgRsp{Total: 3, StartIndex: 2, Routes: []StRoute{{Destination: 0x1234, ManyToOne: true, NextHop: 0x1001}}}, nil},
+	{"Mgmt_Bind_rsp", mgmtBindRsp(),
+		func(p []byte) (interface{}, error) { return ZdoParseMgmtBindRsp(p) },
+		&StMgmtBindRsp{Total: 2, Bindings: testBindings}, nil},
+}
+
+var testBindings = []StBinding{
+	{SrcEui64: testEui64, SrcEndpoint: 1, ClusterId: 0x0006, DstAddrMode: ZDO_ADDR_MODE_IEEE, DstEui64: testExtPan, DstEndpoint: 2},
+	{SrcEui64: testEui64, SrcEndpoint: 1, ClusterId: 0x0008, DstAddrMode: ZDO_ADDR_MODE_GROUP, DstGroup: 0x0101},
+}
+
+// mgmtBindRsp 单播绑定和组绑定各一项的Mgmt_Bind_rsp
+func mgmtBindRsp() []byte {
+	payload := []byte{ZDO_SUCCESS, 2, 0, 2}
+	for i := range testBindings {
+		payload = append(payload, zdoPackBinding(&testBindings[i])...)
+	}
+	return payload
+}
+
+func TestParse(t *testing.T) {
+	for _, c := range parseCases {
+		got, err := c.parse(c.payload)
+		if err != nil {
+			t.Fatalf("parse %s: %v", c.name, err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Fatalf("parse %s = %+v, want %+v", c.name, got, c.want)
+		}
+	}
+}
+
+// TestParseShort 截短的报文都要报错，不能把缺少的数据当成0
+func TestParseShort(t *testing.T) {
+	for _, c := range parseCases {
+	next:
+		for n := 0; n < len(c.payload); n++ {
+			for _, valid := range c.valid {
+				if n == valid {
+					continue next
+				}
+			}
+			if got, err := c.parse(c.payload[:n]); err == nil {
+				t.Fatalf("parse %s truncated to %d bytes = %+v", c.name, n, got)
+			}
+		}
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	_, err := ZdoParseNodeDescRsp([]byte{ZDO_DEVICE_NOT_FOUND, 0x01, 0x10})
+	if zdoErr, ok := err.(ZdoError); !ok || zdoErr.Status != ZDO_DEVICE_NOT_FOUND {
+		t.Fatalf("ZdoParseNodeDescRsp error = %v", err)
+	}
+	if err = ZdoParseStatusRsp(ZDO_BIND_RSP, []byte{ZDO_TABLE_FULL}); err == nil || !strings.Contains(err.Error(), "Bind_rsp") {
+		t.Fatalf("ZdoParseStatusRsp(TABLE_FULL) = %v", err)
+	}
+	if err = ZdoParseStatusRsp(ZDO_BIND_RSP, nil); err == nil {
+		t.Fatal("ZdoParseStatusRsp accepted empty payload")
+	}
+	if err = ZdoParseStatusRsp(ZDO_MGMT_LEAVE_RSP, []byte{ZDO_SUCCESS}); err != nil {
+		t.Fatalf("ZdoParseStatusRsp(SUCCESS) = %v", err)
+	}
+}
+
+func TestMatchDesc(t *testing.T) {
+	nodeID, profileId, inClusters, outClusters, err := ZdoParseMatchDescReq(ZdoPackMatchDescReq(0xfffd, 0x0104, []uint16{0x0019}, nil))
+	if err != nil || nodeID != 0xfffd || profileId != 0x0104 || !reflect.DeepEqual(inClusters, []uint16{0x0019}) || len(outClusters) != 0 {
+		t.Fatalf("ZdoParseMatchDescReq = 0x%04x 0x%04x %v %v, %v", nodeID, profileId, inClusters, outClusters, err)
+	}
+	if _, _, _, _, err = ZdoParseMatchDescReq([]byte{0xfd, 0xff, 0x04, 0x01, 2, 0x19, 0x00}); err == nil {
+		t.Fatal("ZdoParseMatchDescReq accepted short cluster list")
+	}
+
+	endpoints, err := ZdoParseMatchDescRsp(ZdoPackMatchDescRsp(ZDO_SUCCESS, 0x0000, []byte{1}))
+	if err != nil || !reflect.DeepEqual(endpoints, []byte{1}) {
+		t.Fatalf("ZdoParseMatchDescRsp = %v, %v", endpoints, err)
+	}
+}
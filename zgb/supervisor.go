@@ -0,0 +1,305 @@
+package zgb
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/conthing/ezsp/ash"
+	"github.com/conthing/ezsp/ezsp"
+
+	"github.com/conthing/utils/common"
+)
+
+type SupervisorState byte
+
+const (
+	SUPERVISOR_STATE_IDLE         = SupervisorState(0)
+	SUPERVISOR_STATE_RESETTING    = SupervisorState(1) // ASH复位NCP
+	SUPERVISOR_STATE_CONFIGURING  = SupervisorState(2) // 协商版本，配置NCP的config、policy和endpoint
+	SUPERVISOR_STATE_NETWORK_INIT = SupervisorState(3) // 恢复网络
+	SUPERVISOR_STATE_RUNNING      = SupervisorState(4)
+	SUPERVISOR_STATE_BACKOFF      = SupervisorState(5) // 失败后等待重试
+	SUPERVISOR_STATE_FAILED       = SupervisorState(6) // 连续失败超过最大次数
+	SUPERVISOR_STATE_LINK_DOWN    = SupervisorState(7) // 串口丢失，等待重连
+)
+
+var supervisorStateNameMap = map[SupervisorState]string{
+	SUPERVISOR_STATE_IDLE:         "IDLE",
+	SUPERVISOR_STATE_RESETTING:    "RESETTING",
+	SUPERVISOR_STATE_CONFIGURING:  "CONFIGURING",
+	SUPERVISOR_STATE_NETWORK_INIT: "NETWORK_INIT",
+	SUPERVISOR_STATE_RUNNING:      "RUNNING",
+	SUPERVISOR_STATE_BACKOFF:      "BACKOFF",
+	SUPERVISOR_STATE_FAILED:       "FAILED",
+	SUPERVISOR_STATE_LINK_DOWN:    "LINK_DOWN",
+}
+
+func (s SupervisorState) String() string {
+	name, ok := supervisorStateNameMap[s]
+	if !ok {
+		name = fmt.Sprintf("UNKNOWN_STATE_%d", s)
+	}
+	return name
+}
+
+// StSupervisorSettings NCP重建的重试设置
+type StSupervisorSettings struct {
+	MaxRetries int           // 连续失败的最大次数，超过后进入FAILED状态并报错退出，0表示不限制
+	BackoffMin time.Duration // 第一次失败后的等待时间，之后每次翻倍
+	BackoffMax time.Duration // 最长等待时间
+}
+
+// StSupervisorStatus NCP的健康状态
+type StSupervisorStatus struct {
+	State      string            `json:"state"`
+	Healthy    bool              `json:"healthy"`
+	Restarts   int               `json:"restarts"`  // 启动后NCP重建的次数
+	Failures   int               `json:"failures"`  // 连续失败次数
+	LinkDowns  int               `json:"linkdowns"` // 串口丢失的次数
+	LastError  string            `json:"lasterror"`
+	LastCause  string            `json:"lastcause"` // 最近一次重建的原因
+	Since      time.Time         `json:"since"`     // 进入当前状态的时间
+	ModuleInfo ezsp.StModuleInfo `json:"moduleinfo"`
+	AshStats   ash.StAshStats    `json:"ashstats"`
+	EzspStats  ezsp.StEzspStats  `json:"ezspstats"`
+}
+
+// StEndpoint NCP上的endpoint，每次NCP复位后重新添加
+type StEndpoint struct {
+	Endpoint          byte
+	ProfileId         uint16
+	DeviceId          uint16
+	DeviceVersion     byte
+	InputClusterList  []uint16
+	OutputClusterList []uint16
+}
+
+type stSupervisor struct {
+	mutex     sync.Mutex
+	settings  StSupervisorSettings
+	state     SupervisorState
+	status    StSupervisorStatus
+	endpoints []StEndpoint
+	booted    bool
+}
+
+var supervisor = stSupervisor{
+	settings: StSupervisorSettings{MaxRetries: 10, BackoffMin: time.Second, BackoffMax: time.Minute},
+}
+
+// SupervisorSet 修改重试设置，要在 TickRunning 之前调用，为0的字段使用默认值
+func SupervisorSet(settings *StSupervisorSettings) {
+	supervisor.mutex.Lock()
+	defer supervisor.mutex.Unlock()
+	supervisor.settings.MaxRetries = settings.MaxRetries
+	if settings.BackoffMin > 0 {
+		supervisor.settings.BackoffMin = settings.BackoffMin
+	}
+	if settings.BackoffMax > 0 {
+		supervisor.settings.BackoffMax = settings.BackoffMax
+	}
+}
+
+// EndpointAdd 登记一个endpoint，NCP每次复位后重新添加，要在 TickRunning 之前调用
+func EndpointAdd(endpoint StEndpoint) {
+	supervisor.mutex.Lock()
+	defer supervisor.mutex.Unlock()
+	supervisor.endpoints = append(supervisor.endpoints, endpoint)
+}
+
+// Status 返回NCP的健康状态
+func Status() StSupervisorStatus {
+	supervisor.mutex.Lock()
+	defer supervisor.mutex.Unlock()
+	status := supervisor.status
+	status.State = supervisor.state.String()
+	status.Healthy = supervisor.state == SUPERVISOR_STATE_RUNNING
+	status.AshStats = ash.AshStats()
+	status.EzspStats = ezsp.Stats()
+	return status
+}
+
+func (s *stSupervisor) setState(state SupervisorState) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.state != state {
+		common.Log.Infof("supervisor state %s -> %s", s.state, state)
+		s.state = state
+		s.status.Since = time.Now()
+	}
+}
+
+// setModuleInfo 版本协商后记录NCP的信息，Status 返回这份拷贝，不读 ezsp.ModuleInfo
+func (s *stSupervisor) setModuleInfo(info ezsp.StModuleInfo) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.status.ModuleInfo = info
+}
+
+func (s *stSupervisor) backoff(failures int) time.Duration {
+	d := s.settings.BackoffMin
+	for i := 1; i < failures && d < s.settings.BackoffMax; i++ {
+		d *= 2
+	}
+	if d > s.settings.BackoffMax {
+		d = s.settings.BackoffMax
+	}
+	return d
+}
+
+func (s *stSupervisor) applyEndpoints() error {
+	s.mutex.Lock()
+	endpoints := s.endpoints
+	s.mutex.Unlock()
+	for _, ep := range endpoints {
+		err := ezsp.EzspAddEndpoint(ep.Endpoint, ep.ProfileId, ep.DeviceId, ep.DeviceVersion, ep.InputClusterList, ep.OutputClusterList)
+		if err != nil {
+			return fmt.Errorf("EzspAddEndpoint(%d) failed: %v", ep.Endpoint, err)
+		}
+	}
+	return nil
+}
+
+// rebuild 按顺序重建NCP的状态，needReset为false时ASH链路已经复位好了
+func (s *stSupervisor) rebuild(needReset bool) error {
+	if needReset {
+		s.setState(SUPERVISOR_STATE_RESETTING)
+		err := ash.AshReset()
+		if err != nil {
+			return fmt.Errorf("AshReset failed: %v", err)
+		}
+		ezsp.EzspFrameInitVariables() // 有些变量在ASH的接收线程里会被使用
+		ash.InitVariables()           // 上层的变量初始化完成后，最后调用ASH的变量初始化
+		common.Log.Info("AshReset OK")
+	}
+
+	s.setState(SUPERVISOR_STATE_CONFIGURING)
+	err := ncpSetup()
+	if err != nil {
+		return err
+	}
+	err = s.applyEndpoints()
+	if err != nil {
+		return err
+	}
+
+	if !s.booted {
+		s.booted = true
+		bootInfoPrint()
+	}
+
+	s.setState(SUPERVISOR_STATE_NETWORK_INIT)
+	err = ezsp.EzspNetworkInit()
+	if e, ok := err.(ezsp.EmberError); ok && e.EmberStatus == ezsp.EMBER_NOT_JOINED {
+		common.Log.Infof("EzspNetworkInit: NCP not joined any network")
+	} else if err != nil {
+		return fmt.Errorf("EzspNetworkInit failed: %v", err)
+	} else {
+		common.Log.Infof("EzspNetworkInit OK")
+	}
+	return nil
+}
+
+// linkEvent 记录链路事件，返回是否需要重建NCP状态，串口丢失和恢复只记录状态
+func (s *stSupervisor) linkEvent(event ash.LinkEvent) bool {
+	common.Log.Warnf("ASH link event %s", event)
+	switch event.Type {
+	case ash.LINK_EVENT_DOWN:
+		s.mutex.Lock()
+		s.status.LinkDowns++
+		s.status.LastError = event.String()
+		s.mutex.Unlock()
+		s.setState(SUPERVISOR_STATE_LINK_DOWN)
+	case ash.LINK_EVENT_UP:
+		s.setState(SUPERVISOR_STATE_RESETTING) // 链路接着会自动复位NCP
+	case ash.LINK_EVENT_RESET, ash.LINK_EVENT_RESET_FAILED:
+		return true
+	}
+	return false
+}
+
+// waitLinkEvent 等待需要重建NCP状态的链路事件
+func (s *stSupervisor) waitLinkEvent() ash.LinkEvent {
+	for event := range ash.AshEvents() {
+		if s.linkEvent(event) {
+			return event
+		}
+	}
+	return ash.LinkEvent{}
+}
+
+// drainLinkEvents 重建成功后丢弃重建期间积压的复位事件，NCP已经按最新的状态重建过了，
+// 不丢弃的话进入RUNNING后马上又会重建一次
+func (s *stSupervisor) drainLinkEvents() {
+	for {
+		select {
+		case event := <-ash.AshEvents():
+			if s.linkEvent(event) {
+				common.Log.Infof("ignore stale ASH link event %s", event)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// afterLinkEvent 收到重建NCP状态的链路事件后记录原因，返回是否还需要复位
+func (s *stSupervisor) afterLinkEvent(event ash.LinkEvent) bool {
+	s.mutex.Lock()
+	s.status.Restarts++
+	s.status.LastCause = event.String()
+	s.mutex.Unlock()
+	return event.Type != ash.LINK_EVENT_RESET // 链路自己复位成功了就不用再复位
+}
+
+// run NCP状态机：复位 -> 配置 -> 恢复网络 -> 运行，链路复位后重新来过，失败后退避重试。
+// 第一次进入RUNNING时往started发true，在这之前进入FAILED时发false
+func (s *stSupervisor) run(errs chan error, started chan<- bool) {
+	needReset := true
+	s.mutex.Lock()
+	s.status.LastCause = "boot"
+	s.mutex.Unlock()
+	for {
+		err := s.rebuild(needReset)
+		if err == nil {
+			s.mutex.Lock()
+			s.status.Failures = 0
+			s.mutex.Unlock()
+			s.drainLinkEvents()
+			if ash.DefaultLink.Up() {
+				s.setState(SUPERVISOR_STATE_RUNNING)
+				if started != nil {
+					started <- true
+					started = nil
+				}
+			}
+
+			needReset = s.afterLinkEvent(s.waitLinkEvent())
+			continue
+		}
+
+		common.Log.Errorf("NCP rebuild failed: %v", err)
+		if !ash.DefaultLink.Up() { // 串口丢失引起的失败不计数，等重连后链路自己复位
+			needReset = s.afterLinkEvent(s.waitLinkEvent())
+			continue
+		}
+		s.mutex.Lock()
+		s.status.Failures++
+		s.status.LastError = err.Error()
+		failures := s.status.Failures
+		maxRetries := s.settings.MaxRetries
+		s.mutex.Unlock()
+		if maxRetries > 0 && failures >= maxRetries {
+			s.setState(SUPERVISOR_STATE_FAILED)
+			errs <- fmt.Errorf("NCP rebuild failed %d times: %v", failures, err)
+			if started != nil {
+				started <- false
+			}
+			return
+		}
+		s.setState(SUPERVISOR_STATE_BACKOFF)
+		time.Sleep(s.backoff(failures))
+		needReset = true
+	}
+}
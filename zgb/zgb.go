@@ -1,10 +1,14 @@
 package zgb
 
 import (
+	"fmt"
+
 	"github.com/conthing/ezsp/ash"
 	"github.com/conthing/ezsp/c4"
 	"github.com/conthing/ezsp/ezsp"
 	"github.com/conthing/ezsp/hetu"
+	"github.com/conthing/ezsp/interview"
+	"github.com/conthing/ezsp/zdo"
 
 	"github.com/conthing/utils/common"
 )
@@ -18,6 +22,8 @@ type StTraceSettings struct {
 	NcpTraceOn            bool
 	NcpFormTraceOn        bool
 	NcpSourceRouteTraceOn bool
+	ZdoTraceOn            bool
+	InterviewTraceOn      bool
 }
 
 func TraceSet(settings *StTraceSettings) {
@@ -69,19 +75,38 @@ func TraceSet(settings *StTraceSettings) {
 	} else {
 		ezsp.NcpSourceRouteTraceOn = false
 	}
+	if settings.ZdoTraceOn {
+		common.Log.Info("ZdoTraceOn")
+		zdo.ZdoTraceOn = true
+	} else {
+		zdo.ZdoTraceOn = false
+	}
+	if settings.InterviewTraceOn {
+		common.Log.Info("InterviewTraceOn")
+		interview.InterviewTraceOn = true
+	} else {
+		interview.InterviewTraceOn = false
+	}
 }
 
 type StNetworkSettings struct {
 	NetworkType   string
 	SecurityLevel uint16
+	Interview     bool // 新入网的设备自动读取描述符和Basic属性，结果写到c4/hetu的节点记录里，也可以用 interview.Device 查询
 }
 
 var networkSettings StNetworkSettings
+var transport ash.Transport
 
 func NetworkSet(settings *StNetworkSettings) {
 	networkSettings = *settings
 }
 
+// TransportSet 设置NCP所在的Transport，串口或TCP
+func TransportSet(t ash.Transport) {
+	transport = t
+}
+
 func networkInit() {
 	common.Log.Infof("network type %s", networkSettings.NetworkType)
 	if networkSettings.NetworkType == "hetu" {
@@ -89,44 +114,40 @@ func networkInit() {
 	} else {
 		c4.C4Init()
 	}
+	if networkSettings.Interview {
+		interview.Init(nil)
+	}
 }
 
-func networkSecurityLevelInit() {
+func networkSecurityLevelInit() error {
 	err := ezsp.EzspSetConfigurationValue(ezsp.EZSP_CONFIG_SECURITY_LEVEL, networkSettings.SecurityLevel)
 	if err != nil {
-		common.Log.Errorf("EZSP_CONFIG_SECURITY_LEVEL write %d failed: %v", networkSettings.SecurityLevel, err)
+		return fmt.Errorf("EZSP_CONFIG_SECURITY_LEVEL write %d failed: %v", networkSettings.SecurityLevel, err)
 	}
 	common.Log.Debugf("Set EZSP_CONFIG_SECURITY_LEVEL = %d", networkSettings.SecurityLevel)
+	return nil
 }
 
-//TickRunning 定时运行tick
-func TickRunning(errs chan error) {
-	ash.AshStartTransceiver(ezsp.AshRecvImp, errs)
-
-	err := ash.AshReset()
+// ncpSetup NCP复位后的配置，每次复位都要重新做
+func ncpSetup() error {
+	err := ezsp.NcpGetVersion()
 	if err != nil {
-		common.Log.Errorf("AshReset failed: %v", err)
-	} else {
-		networkInit()
-		ezsp.EzspFrameInitVariables() // 有些变量在ASH的接收线程里会被使用
-		ash.InitVariables()           // 上层的变量初始化完成后，最后调用ASH的变量初始化
-		common.Log.Info("AshReset OK")
-	}
-
-	err = ezsp.NcpGetVersion()
-	if err != nil {
-		common.Log.Errorf("NcpGetVersion failed: %v", err)
+		return fmt.Errorf("NcpGetVersion failed: %v", err)
 	}
+	supervisor.setModuleInfo(ezsp.ModuleInfo)
 
 	common.Log.Infof("NCP module info : %+v", ezsp.ModuleInfo)
 
 	err = ezsp.NcpConfig()
 	if err != nil {
-		common.Log.Errorf("NcpConfig failed: %v", err)
+		return fmt.Errorf("NcpConfig failed: %v", err)
 	}
 
-	networkSecurityLevelInit()
+	return networkSecurityLevelInit()
+}
 
+// bootInfoPrint 第一次启动成功后打印NCP的信息
+func bootInfoPrint() {
 	common.Log.Infof("Print All Configurations...")
 	ezsp.NcpPrintAllConfigurations()
 
@@ -141,6 +162,36 @@ func TickRunning(errs chan error) {
 		common.Log.Errorf("EzspGetEUI64 failed: %v", err)
 	}
 	common.Log.Infof("NCP EUI64 = %016x", eui64)
+}
+
+// boot 启动收发线程和supervisor，等NCP第一次配置完成进入RUNNING后返回true，
+// 启动失败时错误已经发到errs，返回false
+func boot(errs chan error) bool {
+	ash.AshStartTransceiver(transport, ezsp.AshRecvImp, errs)
+
+	networkInit()
+	started := make(chan bool, 1)
+	go supervisor.run(errs, started)
+	return <-started
+}
+
+// tickLoop 一直运行c4或hetu的tick
+func tickLoop() {
+	for {
+		if networkSettings.NetworkType == "hetu" {
+			hetu.HetuTick()
+		} else {
+			c4.C4Tick()
+		}
+	}
+}
+
+//TickRunning 定时运行tick，NCP的复位、配置和网络恢复由supervisor负责，
+//NCP第一次配置完成之前不运行tick，应用也不应该发送
+func TickRunning(errs chan error) {
+	if !boot(errs) {
+		return
+	}
 
 	//err = ezsp.NcpFormNetwork(0xff,networkSettings.SecurityLevel != 0)
 	//if err != nil {
@@ -164,12 +215,6 @@ func TickRunning(errs chan error) {
 	//	common.Log.Debug("add endpoint success!")
 	//}
 
-	err = ezsp.EzspNetworkInit()
-	if err != nil {
-		common.Log.Errorf("EzspNetworkInit failed: %v", err)
-	}
-	common.Log.Infof("EzspNetworkInit OK")
-
 	//err = c4.SetPermission(&c4.StPermission{60, []*c4.StPassport{&c4.StPassport{PS: "inSona:IN-C01-WR-4", MAC: "xxxxxxxxxxxxce73"}}})
 	//if err != nil {
 	//	common.Log.Errorf("C4SetPermission failed: %v", err)
@@ -184,11 +229,5 @@ func TickRunning(errs chan error) {
 
 	//go hetu.RemoveNetwork()
 
-	for {
-		if networkSettings.NetworkType == "hetu" {
-			hetu.HetuTick()
-		} else {
-			c4.C4Tick()
-		}
-	}
+	tickLoop()
 }
@@ -0,0 +1,109 @@
+package zgb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/conthing/ezsp/c4"
+	"github.com/conthing/ezsp/ezsp"
+	"github.com/conthing/ezsp/ezsp/ncpsim"
+)
+
+// waitFor 每10ms检查一次cond，超时返回false
+func waitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	return cond()
+}
+
+// TestTickRunning 在模拟NCP上跑通 TickRunning：启动、组网、设备入网、单播和callback上报
+func TestTickRunning(t *testing.T) {
+	sim := ncpsim.New()
+	TransportSet(sim)
+	NetworkSet(&StNetworkSettings{NetworkType: "c4", SecurityLevel: 5})
+	SupervisorSet(&StSupervisorSettings{MaxRetries: 3, BackoffMin: time.Millisecond * 100})
+
+	type stIncoming struct {
+		eui64   uint64
+		message []byte
+	}
+	incoming := make(chan stIncoming, 4)
+	c4.C4Callbacks.C4IncomingMessageHandler = func(eui64 uint64, profileId uint16, clusterId uint16, localEndpoint byte, remoteEndpoint byte, message []byte) {
+		incoming <- stIncoming{eui64, message}
+	}
+	defer func() { c4.C4Callbacks.C4IncomingMessageHandler = nil }()
+
+	// 和 TickRunning 一样，boot 返回时NCP已经配置好了
+	errs := make(chan error, 2)
+	if !boot(errs) {
+		t.Fatalf("boot failed: %v", <-errs)
+	}
+	go tickLoop()
+
+	if !Status().Healthy {
+		t.Fatalf("supervisor not running after boot: %+v", Status())
+	}
+	if status := Status(); status.ModuleInfo.ProtocolVersion != ezsp.EZSP_PROTOCOL_VERSION {
+		t.Fatalf("ModuleInfo = %+v", status.ModuleInfo)
+	}
+
+	if err := c4.FormNetwork(15); err != nil {
+		t.Fatalf("FormNetwork: %v", err)
+	}
+	if !waitFor(time.Second*10, ezsp.MeshStatusUp) {
+		t.Fatal("network not up after FormNetwork")
+	}
+
+	var received [][]byte
+	dev := &ncpsim.Device{NodeID: 0x2001, Eui64: 0x000d6f0000002001,
+		OnMessage: func(apsFrame *ezsp.EmberApsFrame, message []byte) []byte {
+			received = append(received, message)
+			return append([]byte{0x18}, message[1:]...)
+		}}
+	sim.Join(dev)
+	if !waitFor(time.Second*2, func() bool {
+		_, ok := c4.Nodes.Load(dev.NodeID)
+		return ok
+	}) {
+		t.Fatal("joined device not in c4.Nodes")
+	}
+
+	h, err := c4.SendUnicastConfirmed(dev.Eui64, 0x0104, 0x0006, 1, 1, []byte{0x01, 0x21, 0x01})
+	if err != nil {
+		t.Fatalf("SendUnicastConfirmed: %v", err)
+	}
+	if err = h.Wait(); err != nil {
+		t.Fatalf("SendUnicastConfirmed wait: %v", err)
+	}
+	if len(received) != 1 || received[0][1] != 0x21 {
+		t.Fatalf("device received %x", received)
+	}
+
+	select {
+	case m := <-incoming:
+		if m.eui64 != dev.Eui64 || m.message[1] != 0x21 {
+			t.Fatalf("C4IncomingMessageHandler(%016x, %x)", m.eui64, m.message)
+		}
+	case <-time.After(time.Second * 2):
+		t.Fatal("device reply not delivered to C4IncomingMessageHandler")
+	}
+
+	sim.Leave(dev)
+	if !waitFor(time.Second*2, func() bool {
+		_, ok := c4.Nodes.Load(dev.NodeID)
+		return !ok
+	}) {
+		t.Fatal("left device still in c4.Nodes")
+	}
+
+	select {
+	case err := <-errs:
+		t.Fatalf("TickRunning error: %v", err)
+	default:
+	}
+}